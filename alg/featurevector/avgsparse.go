@@ -176,8 +176,9 @@ type AvgSparse struct {
 }
 
 func (v *AvgSparse) Value(transition int, feature interface{}) int64 {
+	// GetValue bounds the transition (Len is the number of values of a map)
 	transitions, exists := v.Vals[feature]
-	if exists && transition < transitions.Len() {
+	if exists {
 		if histValue := transitions.GetValue(transition); histValue != nil {
 			return histValue.Value
 		}
@@ -278,6 +279,40 @@ func (v *AvgSparse) UpdateScalarDivide(byValue int64) *AvgSparse {
 	return v
 }
 
+// AddValue adds an amount to the current value of a feature for a transition,
+// without keeping averaging history (used for combining finalized models)
+func (v *AvgSparse) AddValue(transition int, feature interface{}, amount int64) {
+	v.Lock()
+	defer v.Unlock()
+	transitions, exists := v.Vals[feature]
+	if !exists {
+		transitions = &LockedMap{Vals: make(map[int]*HistoryValue, 5)}
+		v.Vals[feature] = transitions
+	}
+	if histValue := transitions.GetValue(transition); histValue != nil {
+		histValue.Value += amount
+	} else {
+		transitions.Add(0, transition, feature, amount)
+	}
+}
+
+// UpdateRescale multiplies all values by num/denom
+func (v *AvgSparse) UpdateRescale(num, denom int64) *AvgSparse {
+	if denom == 0 {
+		panic("Divide by 0")
+	}
+	v.RLock()
+	defer v.RUnlock()
+	for _, val := range v.Vals {
+		val.Each(func(i int, histValue *HistoryValue) {
+			if histValue != nil {
+				histValue.Value = histValue.Value * num / denom
+			}
+		})
+	}
+	return v
+}
+
 func (v *AvgSparse) String() string {
 	strs := make([]string, 0, len(v.Vals))
 	v.RLock()
@@ -288,7 +323,60 @@ func (v *AvgSparse) String() string {
 	return strings.Join(strs, "\n")
 }
 
+// SortedSerialization serializes AvgSparse values as sorted slices
+// (AvgSparseSerialized) instead of maps, so that serializing the same
+// model always results in the same bytes
+var SortedSerialization bool
+
+// AvgSparseSerialized is a deterministic serialization of AvgSparse values;
+// Transitions[i] and Values[i] are the sorted transitions and their values
+// of Features[i]
+type AvgSparseSerialized struct {
+	Features    []interface{}
+	Transitions [][]int
+	Values      [][]int64
+}
+
+func (v *AvgSparse) sortedSerialize(generation int) *AvgSparseSerialized {
+	keys := make(util.ByGeneric, 0, len(v.Vals))
+	for k, _ := range v.Vals {
+		keys = append(keys, util.Generic{fmt.Sprintf("%#v", k), k})
+	}
+	sort.Sort(keys)
+	retval := &AvgSparseSerialized{
+		Features:    make([]interface{}, len(keys)),
+		Transitions: make([][]int, len(keys)),
+		Values:      make([][]int64, len(keys)),
+	}
+	for i, k := range keys {
+		scores := make(map[int]int64)
+		v.Vals[k.Value].Each(func(j int, lastScore *HistoryValue) {
+			if lastScore != nil {
+				if generation < 0 {
+					scores[j] = lastScore.Value
+				} else {
+					scores[j] = lastScore.IntegratedValue(generation)
+				}
+			}
+		})
+		transitions := make([]int, 0, len(scores))
+		for j, _ := range scores {
+			transitions = append(transitions, j)
+		}
+		sort.Ints(transitions)
+		values := make([]int64, len(transitions))
+		for j, transition := range transitions {
+			values[j] = scores[transition]
+		}
+		retval.Features[i], retval.Transitions[i], retval.Values[i] = k.Value, transitions, values
+	}
+	return retval
+}
+
 func (v *AvgSparse) Serialize(generation int) interface{} {
+	if SortedSerialization {
+		return v.sortedSerialize(generation)
+	}
 	// retval := make(map[interface{}][]int64, len(v.Vals))
 	retval := make(map[interface{}]map[int]int64, len(v.Vals))
 	for k, v := range v.Vals {
@@ -317,6 +405,17 @@ func (v *AvgSparse) Serialize(generation int) interface{} {
 }
 
 func (v *AvgSparse) Deserialize(serialized interface{}, generation int) {
+	if sorted, isSorted := serialized.(*AvgSparseSerialized); isSorted {
+		v.Vals = make(map[Feature]TransitionScoreStore, len(sorted.Features))
+		for i, feature := range sorted.Features {
+			scoreStore := v.newTransitionScoreStore(len(sorted.Transitions[i]))
+			for j, transition := range sorted.Transitions[i] {
+				scoreStore.SetValue(transition, NewHistoryValue(generation, sorted.Values[i][j]))
+			}
+			v.Vals[feature] = scoreStore
+		}
+		return
+	}
 	data, ok := serialized.(map[interface{}]map[int]int64)
 	if !ok {
 		panic("Can't deserialize unknown serialization")
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	// "io"
 	"log"
+	"math/rand"
 
 // "os"
 )
@@ -26,6 +27,13 @@ type LinearPerceptron struct {
 
 	FailedInstances int
 
+	// UpdateAmount is the size of a single perceptron update, 0 means 1
+	UpdateAmount int64
+
+	// Shuffle the training instances at each iteration, using Seed
+	Shuffle bool
+	Seed    int64
+
 	Continue StopCondition
 }
 
@@ -58,14 +66,24 @@ func (m *LinearPerceptron) Train(goldInstances []DecodedInstance) {
 
 func (m *LinearPerceptron) train(goldInstances []DecodedInstance, decoder EarlyUpdateInstanceDecoder, iterations int) {
 	var (
-		generations int
-		logPrefix   string
+		generations  int
+		logPrefix    string
+		updateAmount int64 = m.UpdateAmount
 	)
 	if m.Model == nil {
 		panic("Model not initialized")
 	}
+	if updateAmount == 0 {
+		updateAmount = 1
+	}
 	prevPrefix := log.Prefix()
 	prevFlags := log.Flags()
+	instances := goldInstances
+	var shuffler *rand.Rand
+	if m.Shuffle {
+		shuffler = rand.New(rand.NewSource(m.Seed))
+		instances = make([]DecodedInstance, len(goldInstances))
+	}
 	// prevGC := debug.SetGCPercent(-1)
 	// var score int64
 	for i := m.TrainI; m.Continue(i, iterations, generations, m.Model); i++ {
@@ -76,7 +94,13 @@ func (m *LinearPerceptron) train(goldInstances []DecodedInstance, decoder EarlyU
 			log.SetPrefix("")
 			log.SetFlags(0)
 		}
-		for j, goldInstance := range goldInstances[m.TrainJ+1:] {
+		if m.Shuffle {
+			// the permutation of each iteration depends only on the seed
+			for j, k := range shuffler.Perm(len(goldInstances)) {
+				instances[j] = goldInstances[k]
+			}
+		}
+		for j, goldInstance := range instances[m.TrainJ+1:] {
 			// if m.Log {
 			// 	if j%100 == 0 {
 			// 		runtime.GC()
@@ -139,11 +163,11 @@ func (m *LinearPerceptron) train(goldInstances []DecodedInstance, decoder EarlyU
 				if PercepAllOut {
 					log.Println("Score 1 to")
 				}
-				m.Model.AddSubtract(goldFeatures, decodedFeatures, 1.0)
+				m.Model.AddSubtract(goldFeatures, decodedFeatures, updateAmount)
 				if PercepAllOut {
 					log.Println("Score -1 to")
 				}
-				m.Model.AddSubtract(decodedFeatures, decodedFeatures, -1.0)
+				m.Model.AddSubtract(decodedFeatures, decodedFeatures, -updateAmount)
 				if PercepAllOut {
 					log.Println("ITERATION COMPLETE")
 				}
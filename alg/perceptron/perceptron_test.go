@@ -1,34 +1,178 @@
 package perceptron
 
-import "testing"
+import (
+	"reflect"
+	"testing"
 
-func TestPerceptron(t *testing.T) {
+	"yap/util"
+)
 
+// testModel is a minimal sparse Model keyed by feature strings
+type testModel map[string]int64
+
+var _ Model = testModel{}
+
+func (t testModel) Score(features interface{}) int64 {
+	var score int64
+	for _, f := range features.([]string) {
+		score += t[f]
+	}
+	return score
+}
+
+func (t testModel) Add(features interface{}) Model {
+	for _, f := range features.([]string) {
+		t[f]++
+	}
+	return t
+}
+
+func (t testModel) Subtract(features interface{}) Model {
+	for _, f := range features.([]string) {
+		t[f]--
+	}
+	return t
+}
+
+func (t testModel) AddSubtract(goldFeatures, decodedFeatures interface{}, amount int64) {
+	for _, f := range goldFeatures.([]string) {
+		t[f] += amount
+	}
+}
+
+func (t testModel) ScalarDivide(val int64) {
+	for f, v := range t {
+		t[f] = v / val
+	}
+}
+
+func (t testModel) Copy() Model {
+	result := make(testModel, len(t))
+	for f, v := range t {
+		result[f] = v
+	}
+	return result
+}
+
+func (t testModel) AddModel(other Model) {
+	for f, v := range other.(testModel) {
+		t[f] += v
+	}
+}
+
+func (t testModel) New() Model {
+	return make(testModel)
+}
+
+type testInstance int
+
+func (i testInstance) Equal(otherEq util.Equaler) bool {
+	other, ok := otherEq.(testInstance)
+	return ok && i == other
+}
+
+// recordingDecoder decodes every instance to itself, recording the order in
+// which the trainer presents them
+type recordingDecoder struct {
+	Order []int
+}
+
+func (d *recordingDecoder) Decode(i Instance, m Model) (DecodedInstance, interface{}) {
+	return &Decoded{i, i.(testInstance)}, nil
+}
+
+func (d *recordingDecoder) DecodeGold(i DecodedInstance, m Model) (DecodedInstance, interface{}) {
+	return i, nil
+}
+
+func (d *recordingDecoder) DecodeEarlyUpdate(i DecodedInstance, m Model) (DecodedInstance, interface{}, interface{}, int, int, float64) {
+	d.Order = append(d.Order, int(i.Instance().(testInstance)))
+	return i, []string{}, []string{}, -1, 1, 0
+}
+
+func shuffledOrder(shuffle bool, seed int64, numInstances, iterations int) []int {
+	instances := make([]DecodedInstance, numInstances)
+	for i := range instances {
+		instances[i] = &Decoded{testInstance(i), testInstance(i)}
+	}
+	decoder := &recordingDecoder{}
+	perceptron := &LinearPerceptron{
+		Decoder:     decoder,
+		GoldDecoder: decoder,
+		Updater:     new(TrivialStrategy),
+		Iterations:  iterations,
+		Shuffle:     shuffle,
+		Seed:        seed,
+	}
+	perceptron.Init(make(testModel))
+	perceptron.Train(instances)
+	return decoder.Order
+}
+
+func TestShuffle(t *testing.T) {
+	const (
+		numInstances = 10
+		iterations   = 3
+	)
+	unshuffled := shuffledOrder(false, 1, numInstances, iterations)
+	for j, k := range unshuffled {
+		if k != j%numInstances {
+			t.Errorf("Got instance %v at position %v without shuffling", k, j)
+		}
+	}
+	first := shuffledOrder(true, 1, numInstances, iterations)
+	second := shuffledOrder(true, 1, numInstances, iterations)
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("Shuffling with the same seed is not reproducible: %v vs %v", first, second)
+	}
+	if len(first) != numInstances*iterations {
+		t.Fatalf("Got %v decoded instances, expected %v", len(first), numInstances*iterations)
+	}
+	for i := 0; i < iterations; i++ {
+		iteration := first[i*numInstances : (i+1)*numInstances]
+		seen := make(map[int]bool, numInstances)
+		for _, k := range iteration {
+			seen[k] = true
+		}
+		if len(seen) != numInstances {
+			t.Errorf("Iteration %v is not a permutation of the instances: %v", i, iteration)
+		}
+		if reflect.DeepEqual(iteration, unshuffled[:numInstances]) {
+			t.Errorf("Iteration %v was not shuffled: %v", i, iteration)
+		}
+	}
+	if reflect.DeepEqual(first[:numInstances], first[numInstances:2*numInstances]) {
+		t.Errorf("Iterations were shuffled identically: %v", first)
+	}
+	if other := shuffledOrder(true, 2, numInstances, iterations); reflect.DeepEqual(first, other) {
+		t.Errorf("Different seeds shuffled identically: %v", first)
+	}
 }
 
 func TestTrivialStrategy(t *testing.T) {
-	v := make(featurevector.Sparse)
+	v := make(testModel)
 	w := new(TrivialStrategy)
-	w.Init(&v, 10)
-	w.Update(&v)
-	if &v != w.Finalize(&v) {
+	w.Init(v, 10)
+	w.Update(v)
+	if !reflect.DeepEqual(v, w.Finalize(v)) {
 		t.Error("Should return trivial value")
 	}
 }
 
 func TestAveragedStrategy(t *testing.T) {
-	v := make(featurevector.Sparse)
-	v[Feature("a")] = 4.0 // (1/8)
-	v[Feature("b")] = 1.0
+	v := make(testModel)
+	v["a"] = 4
+	v["b"] = 1
 	w := new(AveragedStrategy)
-	w.Init(&v, 4)
-	w.Update(&v)
-	w.Update(&v)
-	avg := *(w.Finalize(&v))
-	if avg[Feature("a")] != 1.0 {
-		t.Error("Got averaged value", avg[Feature("a")], "expected", 1.0)
-	}
-	if avg[Feature("b")] != 0.25 {
-		t.Error("Got averaged value", avg[Feature("a")], "expected", 0.25)
+	w.Init(v, 4)
+	w.Update(v)
+	v["a"] = 8
+	w.Update(v)
+	avg := w.Finalize(v).(testModel)
+	if avg["a"] != 6 {
+		t.Error("Got averaged value", avg["a"], "expected", 6)
+	}
+	if avg["b"] != 1 {
+		t.Error("Got averaged value", avg["b"], "expected", 1)
 	}
 }
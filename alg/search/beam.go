@@ -144,7 +144,7 @@ func (b *Beam) Insert(cs chan Candidate, a Agenda) []Candidate { //Agenda {
 			// if the temp. agenda is the size of the beam
 			// there is no reason to add a new one if we can prune
 			// some in the beam's Insert function
-			if tempAgenda.Peek().Score() > currentScoredConf.Score() {
+			if !CompareConf(tempAgenda.Peek(), currentScoredConf, false) {
 				// log.Println("\t\tNot pushed onto Beam", b.Transitions.ValueOf(int(currentScoredConf.Transition)))
 				// if the current score has a worse score than the
 				// worst one in the temporary agenda, there is no point
@@ -213,7 +213,7 @@ func (b *Beam) Expand(c Candidate, p Problem, candidateNum int) chan Candidate {
 			// log.Println("\tSetting transitions to", transitions)
 		}
 		scores.SetTransitions(transitions)
-		scorer := b.Model.(*TransitionModel.AvgMatrixSparse)
+		scorer := b.Model.(TransitionModel.TransitionScorer)
 		if b.DecodeTest {
 			if b.ScoredStoreDense {
 
@@ -283,6 +283,40 @@ func (b *Beam) Expand(c Candidate, p Problem, candidateNum int) chan Candidate {
 	return retChan
 }
 
+// TransitionScores returns the model score of the last transition of each
+// configuration of a sequence (last configuration first, as returned by
+// GetSequence), scored as in Expand; the initial configuration scores 0
+func (b *Beam) TransitionScores(sequence transition.ConfigurationSequence) []int64 {
+	result := make([]int64, len(sequence))
+	var scores featurevector.ScoredStore
+	if b.ScoredStoreDense {
+		scores = featurevector.MakeDenseStore().(featurevector.ScoredStore)
+		if b.DecodeTest {
+			scores.(*featurevector.ArrayStore).Generation = b.IntegrationGeneration
+		}
+	} else {
+		scores = featurevector.MakeMapStore().(featurevector.ScoredStore)
+		if b.DecodeTest {
+			scores.(*featurevector.MapStore).Generation = b.IntegrationGeneration
+		}
+	}
+	scorer := b.Model.(TransitionModel.TransitionScorer)
+	for i := 0; i+1 < len(sequence); i++ {
+		last, previous := sequence[i].GetLastTransition(), sequence[i+1]
+		transType, transitions := b.TransFunc.GetTransitions(previous)
+		if last.Type() != transType {
+			// e.g. an idle transition
+			transType, transitions = last.Type(), []int{last.Value()}
+		}
+		scores.Clear()
+		scores.SetTransitions(transitions)
+		feats := b.FeatExtractor.Features(previous, false, transType, transitions)
+		scorer.SetTransitionScores(feats, scores, b.DecodeTest)
+		result[i], _ = scores.Get(last.Value())
+	}
+	return result
+}
+
 func (b *Beam) Best(a Agenda) Candidate {
 	agenda := a.(*BaseAgenda)
 	// agenda.ShowSwap = true
@@ -358,7 +392,7 @@ func (b *Beam) Top(a Agenda) Candidate {
 	}
 	var bestCandidate *ScoredConfiguration
 	for _, candidate := range agenda.Confs {
-		if bestCandidate == nil || candidate.Score() > bestCandidate.Score() {
+		if bestCandidate == nil || CompareConf(candidate, bestCandidate, true) {
 			bestCandidate = candidate
 		}
 	}
@@ -577,7 +611,7 @@ func (b *Beam) Idle(c Candidate, candidateNum int) Candidate {
 	scores := b.candidateScorePool.Get().(featurevector.ScoredStore)
 	scores.Clear()
 	scores.SetTransitions([]int{transition.IDLE.Value()})
-	scorer := b.Model.(*TransitionModel.AvgMatrixSparse)
+	scorer := b.Model.(TransitionModel.TransitionScorer)
 	if b.DecodeTest {
 		if b.ScoredStoreDense {
 			scores.(*featurevector.ArrayStore).Generation = b.IntegrationGeneration
@@ -590,7 +624,7 @@ func (b *Beam) Idle(c Candidate, candidateNum int) Candidate {
 	score, _ := scores.Get(transition.IDLE.Value())
 	newConf := conf.Copy()
 	newConf.SetLastTransition(transition.IDLE)
-	scored := &ScoredConfiguration{newConf, transition.Transition(transition.IDLE), candidate.InternalScores.Copy(), newFeatList, 0, 0, true, candidate.Averaged}
+	scored := &ScoredConfiguration{newConf, transition.Transition(transition.IDLE), candidate.InternalScores.Copy(), newFeatList, candidateNum, 0, true, candidate.Averaged}
 
 	scored.AddScore(score, conf.Assignment())
 	return scored
@@ -691,16 +725,10 @@ func (scs ScoredConfigurations) Get(i int) Candidate {
 }
 
 func (scs ScoredConfigurations) Equal(otherEq util.Equaler) bool {
-	switch other := otherEq.(type) {
-	case Candidate:
-		return scs[0].Equal(other)
-	default:
-		// log.Println("Equating", scs[len(scs)-1].C, "and", otherEq)
-		// log.Println(scs[len(scs)-1].C.GetSequence())
-		// log.Println(otherEq.GetSequence())
-		return otherEq.Equal(scs[len(scs)-1].C)
-		panic("Cannot compare to other")
-	}
+	// log.Println("Equating", scs[len(scs)-1].C, "and", otherEq)
+	// log.Println(scs[len(scs)-1].C.GetSequence())
+	// log.Println(otherEq.GetSequence())
+	return otherEq.Equal(scs[len(scs)-1].C)
 }
 
 func (s *ScoredConfiguration) AddScore(newScore int64, assignment uint16) {
@@ -810,7 +838,7 @@ func (a *BaseAgenda) AddCandidate(c, best Candidate) Candidate {
 	scored := c.(*ScoredConfiguration)
 	if best != nil {
 		bestScored := best.(*ScoredConfiguration)
-		if scored.Score() > bestScored.Score() {
+		if CompareConf(scored, bestScored, true) {
 			best = scored
 		}
 	} else {
@@ -834,7 +862,7 @@ func (a *BaseAgenda) AddCandidate(c, best Candidate) Candidate {
 		return best
 	}
 	peekScore := a.Peek()
-	if !(peekScore.Score() < scored.Score()) {
+	if !CompareConf(peekScore, scored, false) {
 		if AgendaOut {
 			log.Println("\t\tNot pushed onto Agenda", scored.Transition, "score", scored.Score())
 			log.Println("\t\tKeeping Current", peekScore.Transition, "score", peekScore.Score())
@@ -951,49 +979,42 @@ func NewAgenda(size int) *BaseAgenda {
 	return newAgenda
 }
 
+// CompareConf returns whether confA is less than confB, i.e. has a lower
+// score (or a higher one, if reverse).
+// Equal scores are broken deterministically, so that the agenda does not depend
+// on the order of insertion (and therefore on concurrent expansion): the
+// candidate expanded from the better (lower numbered) candidate is preferred,
+// then the lower transition.
 func CompareConf(confA, confB *ScoredConfiguration, reverse bool) bool {
-	// less in reverse, we want the highest scoring to be first in the heap
-	if reverse {
-		return confA.Score() > confB.Score()
+	scoreA, scoreB := confA.Score(), confB.Score()
+	if scoreA != scoreB {
+		// less in reverse, we want the highest scoring to be first in the heap
+		if reverse {
+			return scoreA > scoreB
+		}
+		return scoreA < scoreB
 	}
-	return confA.Score() < confB.Score() // less in reverse, we want the highest scoring to be first in the heap
-	var retval bool
 	if reverse {
-		return confA.Score() > confB.Score()
-		// if confA.Score() > confB.Score() {
-		// 	retval = true
-		// }
-		// if confA.Score() == confB.Score() {
-		// 	if confA.CandidateNum > confB.CandidateNum {
-		// 		retval = true
-		// 	}
-		// 	if confA.CandidateNum == confB.CandidateNum {
-		// 		if confA.TransNum > confB.TransNum {
-		// 			retval = true
-		// 		}
-		// 	}
-		// }
-	} else {
-		return confA.Score() < confB.Score()
-		// if confA.Score() < confB.Score() {
-		// 	retval = true
-		// }
-		// if confA.Score() == confB.Score() {
-		// 	if confA.CandidateNum > confB.CandidateNum {
-		// 		retval = true
-		// 	}
-		// 	if confA.CandidateNum == confB.CandidateNum {
-		// 		if confA.TransNum > confB.TransNum {
-		// 			retval = true
-		// 		}
-		// 	}
-		// }
+		return PreferConf(confA, confB)
 	}
-	// if reverse {
-	return retval
-	// } else {
-	// 	return !retval
-	// }
+	return PreferConf(confB, confA)
+}
+
+// PreferConf breaks a tie between two equally scored candidates, returning
+// true if confA should be preferred over confB
+func PreferConf(confA, confB *ScoredConfiguration) bool {
+	if confA.CandidateNum != confB.CandidateNum {
+		return confA.CandidateNum < confB.CandidateNum
+	}
+	if confA.Transition != nil && confB.Transition != nil {
+		if typeA, typeB := confA.Transition.Type(), confB.Transition.Type(); typeA != typeB {
+			return typeA < typeB
+		}
+		if valueA, valueB := confA.Transition.Value(), confB.Transition.Value(); valueA != valueB {
+			return valueA < valueB
+		}
+	}
+	return confA.TransNum < confB.TransNum
 }
 
 type ParseResultParameters struct {
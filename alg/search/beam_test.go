@@ -1,5 +1,13 @@
 package search
 
+import (
+	"yap/alg/transition"
+
+	"math/rand"
+	"sort"
+	"testing"
+)
+
 // import (
 // 	"yap/alg/featurevector"
 // 	"yap/alg/perceptron"
@@ -172,3 +180,72 @@ package search
 // 	}
 // 	t.Error("bla")
 // }
+
+func scoredConf(score int64, candidateNum int, ttype byte, value, transNum int) *ScoredConfiguration {
+	conf := &ScoredConfiguration{
+		Transition:   &transition.TypedTransition{ttype, value},
+		CandidateNum: candidateNum,
+		TransNum:     transNum,
+		Expanded:     true,
+	}
+	conf.AddScore(score, 0)
+	return conf
+}
+
+func TestCompareConf(t *testing.T) {
+	tests := []struct {
+		name         string
+		confA, confB *ScoredConfiguration
+		preferA      bool
+	}{
+		{"score", scoredConf(2, 1, 'S', 1, 1), scoredConf(1, 0, 'S', 0, 0), true},
+		{"candidate", scoredConf(1, 0, 'S', 1, 1), scoredConf(1, 1, 'S', 0, 0), true},
+		{"type", scoredConf(1, 0, 'L', 1, 1), scoredConf(1, 0, 'S', 0, 0), true},
+		{"value", scoredConf(1, 0, 'S', 0, 1), scoredConf(1, 0, 'S', 1, 0), true},
+		{"transnum", scoredConf(1, 0, 'S', 0, 0), scoredConf(1, 0, 'S', 0, 1), true},
+		{"identical", scoredConf(1, 0, 'S', 0, 0), scoredConf(1, 0, 'S', 0, 0), false},
+	}
+	for _, test := range tests {
+		// reversed heaps keep the preferred candidate first
+		if got := CompareConf(test.confA, test.confB, true); got != test.preferA {
+			t.Errorf("%s: CompareConf(a, b, true) = %v, expected %v", test.name, got, test.preferA)
+		}
+		if got := CompareConf(test.confB, test.confA, true); got {
+			t.Errorf("%s: CompareConf(b, a, true) = %v, expected false", test.name, got)
+		}
+		// non-reversed heaps keep the dispreferred candidate first
+		if got := CompareConf(test.confB, test.confA, false); got != test.preferA {
+			t.Errorf("%s: CompareConf(b, a, false) = %v, expected %v", test.name, got, test.preferA)
+		}
+		if got := CompareConf(test.confA, test.confB, false); got {
+			t.Errorf("%s: CompareConf(a, b, false) = %v, expected false", test.name, got)
+		}
+	}
+}
+
+func TestCompareConfOrder(t *testing.T) {
+	var confs ScoredConfigurations
+	for candidate := 0; candidate < 3; candidate++ {
+		for _, ttype := range []byte{'L', 'R', 'S'} {
+			for value := 0; value < 2; value++ {
+				confs = append(confs, scoredConf(5, candidate, ttype, value, len(confs)))
+			}
+		}
+	}
+	shuffler := rand.New(rand.NewSource(1))
+	for i := 0; i < 10; i++ {
+		shuffled := make(ScoredConfigurations, len(confs))
+		for j, k := range shuffler.Perm(len(confs)) {
+			shuffled[j] = confs[k]
+		}
+		sort.Slice(shuffled, func(a, b int) bool {
+			return CompareConf(shuffled[a], shuffled[b], true)
+		})
+		for j, conf := range shuffled {
+			if conf != confs[j] {
+				t.Errorf("Permutation %v: got candidate %v at position %v, expected %v", i, conf.TransNum, j, j)
+				break
+			}
+		}
+	}
+}
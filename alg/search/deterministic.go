@@ -29,6 +29,7 @@ type Deterministic struct {
 }
 
 var _ perceptron.InstanceDecoder = &Deterministic{}
+var _ perceptron.EarlyUpdateInstanceDecoder = &Deterministic{}
 
 // Parser functions
 func (d *Deterministic) Parse(problem Problem) (transition.Configuration, interface{}) {
@@ -232,7 +233,7 @@ func (d *Deterministic) DecodeGold(goldInstance perceptron.DecodedInstance, m pe
 	}
 }
 
-func (d *Deterministic) DecodeEarlyUpdate(goldInstance perceptron.DecodedInstance, m perceptron.Model) (perceptron.DecodedInstance, interface{}, interface{}, int, int, int64) {
+func (d *Deterministic) DecodeEarlyUpdate(goldInstance perceptron.DecodedInstance, m perceptron.Model) (perceptron.DecodedInstance, interface{}, interface{}, int, int, float64) {
 	sent := goldInstance.Instance().(nlp.Sentence)
 
 	// abstract casting >:-[
@@ -2,193 +2,279 @@ package search
 
 import (
 	"yap/alg/featurevector"
-
 	"yap/alg/perceptron"
 	"yap/alg/transition"
 	TransitionModel "yap/alg/transition/model"
-	"yap/nlp/parser/dependency"
-	"yap/nlp/types"
 	"yap/util"
-	// "fmt"
-	"log"
-	"runtime"
-	"sort"
+
+	"fmt"
+	"reflect"
 	"testing"
 )
 
-func PrintGraph(graph types.LabeledDependencyGraph) {
-	arcIndex := make(map[int]types.LabeledDepArc, graph.NumberOfNodes())
-	var (
-		// posTag string
-		node   types.DepNode
-		arc    types.LabeledDepArc
-		headID int
-		depRel string
-	)
-	for _, arcID := range graph.GetEdges() {
-		arc = graph.GetLabeledArc(arcID)
-		if arc == nil {
-			// panic("Can't find arc")
-		} else {
-			arcIndex[arc.GetModifier()] = arc
-		}
+// testSentence is a sentence of words to tag
+type testSentence []string
+
+func (s testSentence) Tokens() []string {
+	return s
+}
+
+func (s testSentence) Equal(otherEq util.Equaler) bool {
+	other, ok := otherEq.(testSentence)
+	return ok && reflect.DeepEqual(s, other)
+}
+
+// testTags are the gold tags of a sentence
+type testTags []int
+
+func (t testTags) Equal(otherEq util.Equaler) bool {
+	other, ok := otherEq.(testTags)
+	return ok && reflect.DeepEqual(t, other)
+}
+
+// tagConf tags the words of a sentence left to right, one transition per
+// word; the next word to tag is N0
+type tagConf struct {
+	Words []string
+	Tags  []int
+	Last  transition.Transition
+	Prev  *tagConf
+}
+
+var _ transition.Configuration = &tagConf{}
+
+func (c *tagConf) Init(sent interface{}) {
+	c.Words = sent.(testSentence)
+	c.Tags = make([]int, 0, len(c.Words))
+}
+
+func (c *tagConf) Terminal() bool {
+	return len(c.Tags) == len(c.Words)
+}
+
+func (c *tagConf) Copy() transition.Configuration {
+	newConf := new(tagConf)
+	c.CopyTo(newConf)
+	return newConf
+}
+
+func (c *tagConf) CopyTo(target transition.Configuration) {
+	newConf := target.(*tagConf)
+	newConf.Words = c.Words
+	newConf.Tags = append(make([]int, 0, len(c.Words)), c.Tags...)
+	newConf.Last = c.Last
+	newConf.Prev = c
+}
+
+func (c *tagConf) Clear() {
+	c.Words, c.Tags, c.Last, c.Prev = nil, nil, nil, nil
+}
+
+func (c *tagConf) Len() int {
+	if c.Prev == nil {
+		return 1
+	}
+	return 1 + c.Prev.Len()
+}
+
+func (c *tagConf) Previous() transition.Configuration {
+	if c.Prev == nil {
+		return nil
+	}
+	return c.Prev
+}
+
+func (c *tagConf) SetPrevious(prev transition.Configuration) {
+	c.Prev = prev.(*tagConf)
+}
+
+func (c *tagConf) GetSequence() transition.ConfigurationSequence {
+	retval := make(transition.ConfigurationSequence, 0, len(c.Words)+1)
+	for currentConf := c; currentConf != nil; currentConf = currentConf.Prev {
+		retval = append(retval, currentConf)
 	}
-	for _, nodeID := range graph.GetVertices() {
-		node = graph.GetNode(nodeID)
-		// posTag = ""
+	return retval
+}
 
-		// taggedToken, ok := node.(*TaggedDepNode)
-		// if ok {
-		// 	// posTag = taggedToken.RawPOS
-		// }
+func (c *tagConf) SetLastTransition(t transition.Transition) {
+	c.Last = t
+}
 
-		if node == nil {
-			panic("Can't find node")
+func (c *tagConf) GetLastTransition() transition.Transition {
+	return c.Last
+}
+
+func (c *tagConf) String() string {
+	return fmt.Sprintf("%v %v", c.Words, c.Tags)
+}
+
+func (c *tagConf) Equal(otherEq util.Equaler) bool {
+	other, ok := otherEq.(*tagConf)
+	return ok && reflect.DeepEqual(c.Words, other.Words) && reflect.DeepEqual(c.Tags, other.Tags)
+}
+
+func (c *tagConf) Address(location []byte, offset int) (int, bool, bool) {
+	if location[0] != 'N' {
+		return 0, false, false
+	}
+	nodeID := len(c.Tags) + offset
+	return nodeID, nodeID >= 0 && nodeID < len(c.Words), false
+}
+
+func (c *tagConf) GenerateAddresses(nodeID int, location []byte) []int {
+	return nil
+}
+
+func (c *tagConf) Attribute(source byte, nodeID int, attribute []byte, transitions []int) (interface{}, bool, bool) {
+	if attribute[0] != 'w' || nodeID < 0 || nodeID >= len(c.Words) {
+		return 0, false, false
+	}
+	return c.Words[nodeID], true, false
+}
+
+func (c *tagConf) Assignment() uint16 {
+	return 0
+}
+
+func (c *tagConf) State() byte {
+	return 'A'
+}
+
+// tagSystem tags each word with one of NumTags tags
+type tagSystem struct {
+	NumTags int
+	oracle  *tagOracle
+}
+
+var _ transition.TransitionSystem = &tagSystem{}
+
+func (s *tagSystem) Transition(from transition.Configuration, t transition.Transition) transition.Configuration {
+	c := from.Copy().(*tagConf)
+	c.Tags = append(c.Tags, t.Value())
+	c.SetLastTransition(t)
+	return c
+}
+
+func (s *tagSystem) TransitionTypes() []string {
+	return []string{"A"}
+}
+
+func (s *tagSystem) YieldTransitions(conf transition.Configuration) (byte, chan int) {
+	transType, transitions := s.GetTransitions(conf)
+	tChan := make(chan int, len(transitions))
+	for _, t := range transitions {
+		tChan <- t
+	}
+	close(tChan)
+	return transType, tChan
+}
+
+func (s *tagSystem) GetTransitions(conf transition.Configuration) (byte, []int) {
+	return 'A', util.RangeInt(s.NumTags)
+}
+
+func (s *tagSystem) Oracle() transition.Oracle {
+	return s.oracle
+}
+
+func (s *tagSystem) AddDefaultOracle() {
+	s.oracle = new(tagOracle)
+}
+
+func (s *tagSystem) Name() string {
+	return "Tagging"
+}
+
+// tagOracle tags each word with its gold tag
+type tagOracle struct {
+	gold testTags
+}
+
+func (o *tagOracle) Transition(conf transition.Configuration) transition.Transition {
+	return &transition.TypedTransition{'A', o.gold[len(conf.(*tagConf).Tags)]}
+}
+
+func (o *tagOracle) SetGold(gold interface{}) {
+	o.gold = gold.(testTags)
+}
+
+func (o *tagOracle) Name() string {
+	return "Tagging"
+}
+
+func testDeterministic(t *testing.T) (*Deterministic, *transition.GenericExtractor) {
+	extractor := &transition.GenericExtractor{EFeatures: util.NewEnumSet(2)}
+	extractor.InitTypes([]byte("A"))
+	for _, featurePair := range [][2]string{{"N0|w", "N0|w"}, {"N-1|w+N0|w", "N-1|w;N0|w"}} {
+		if err := extractor.LoadFeature(featurePair[0], featurePair[1], "A", false, false); err != nil {
+			t.Fatal("Failed to load feature", err)
 		}
-		arc, exists := arcIndex[node.ID()]
-		if exists {
-			log.Println("Exists")
-			headID = arc.GetHead()
-			depRel = string(arc.GetRelation())
-			if depRel == types.ROOT_LABEL {
-				headID = -1
-			}
-		} else {
-			log.Println("Not Exists")
-			headID = -1
-			depRel = "None"
+	}
+	transitionSystem := &tagSystem{NumTags: 3}
+	transitionSystem.AddDefaultOracle()
+	return &Deterministic{
+		TransFunc:        transitionSystem,
+		FeatExtractor:    extractor,
+		ReturnSequence:   true,
+		Base:             new(tagConf),
+		NoRecover:        true,
+		DefaultTransType: 'A',
+	}, extractor
+}
+
+func TestDeterministicParseOracle(t *testing.T) {
+	deterministic, _ := testDeterministic(t)
+	gold := testTags{0, 1, 2}
+	conf, result := deterministic.ParseOracle(&perceptron.Decoded{testSentence{"the", "dog", "barks"}, gold})
+	if conf == nil || result == nil {
+		t.Fatal("Got nil oracle parse")
+	}
+	if tags := conf.(*tagConf).Tags; !reflect.DeepEqual(testTags(tags), gold) {
+		t.Errorf("Got oracle tags %v, expected %v", tags, gold)
+	}
+	seq := result.(*ParseResultParameters).Sequence
+	if len(seq) != len(gold)+1 {
+		t.Fatalf("Got oracle sequence of length %d, expected %d", len(seq), len(gold)+1)
+	}
+	for i, tag := range gold {
+		if last := seq[len(gold)-i-1].GetLastTransition(); last.Value() != tag {
+			t.Errorf("Got transition %v at step %d, expected %v", last, i, tag)
 		}
-		log.Println(node.ID()+1, node.String(), headID+1, depRel)
 	}
 }
 
 func TestDeterministic(t *testing.T) {
-	SetupTestEnum()
-	SetupEagerTransEnum()
-	runtime.GOMAXPROCS(runtime.NumCPU())
-	extractor := &GenericExtractor{
-		EFeatures: util.NewEnumSet(len(TEST_RICH_FEATURES)),
-		EWord:     EWord,
-		EPOS:      EPOS,
-		EWPOS:     EWPOS,
-		ERel:      TEST_ENUM_RELATIONS,
-	}
-	extractor.Init()
-	// verify load
-	for _, featurePair := range TEST_RICH_FEATURES {
-		if err := extractor.LoadFeature(featurePair[0], featurePair[1]); err != nil {
-			t.Error("Failed to load feature", err.Error())
-			t.FailNow()
-		}
+	deterministic, extractor := testDeterministic(t)
+	goldInstances := []perceptron.DecodedInstance{
+		&perceptron.Decoded{testSentence{"the", "dog", "barks"}, testTags{0, 1, 2}},
+		&perceptron.Decoded{testSentence{"the", "cat", "sleeps"}, testTags{0, 1, 2}},
+		&perceptron.Decoded{testSentence{"a", "dog", "sleeps"}, testTags{0, 1, 2}},
+		&perceptron.Decoded{testSentence{"dogs", "bark"}, testTags{1, 2}},
 	}
-	arcSystem := &ArcStandard{
-		SHIFT:       SH,
-		LEFT:        LA,
-		RIGHT:       RA,
-		Relations:   TEST_ENUM_RELATIONS,
-		Transitions: TRANSITIONS_ENUM,
-	}
-
-	// arcSystem := &ArcEager{
-	// 	ArcStandard: ArcStandard{
-	// 		SHIFT:       SH,
-	// 		LEFT:        LA,
-	// 		RIGHT:       RA,
-	// 		Relations:   TEST_ENUM_RELATIONS,
-	// 		Transitions: TRANSITIONS_ENUM,
-	// 	},
-	// 	REDUCE:  RE,
-	// 	POPROOT: PR,
-	// }
-	arcSystem.AddDefaultOracle()
-	transitionSystem := transition.TransitionSystem(arcSystem)
-
-	conf := &SimpleConfiguration{
-		EWord:  EWord,
-		EPOS:   EPOS,
-		EWPOS:  EWPOS,
-		ERel:   TEST_ENUM_RELATIONS,
-		ETrans: TRANSITIONS_ENUM,
-	}
-
-	deterministic := &Deterministic{
-		TransFunc:          transitionSystem,
-		FeatExtractor:      extractor,
-		ReturnModelValue:   true,
-		ReturnSequence:     true,
-		ShowConsiderations: false,
-		Base:               conf,
-		NoRecover:          true,
-	}
-	decoder := perceptron.EarlyUpdateInstanceDecoder(deterministic)
-	goldDecoder := perceptron.InstanceDecoder(deterministic)
-	updater := new(TransitionModel.AveragedModelStrategy)
-
-	model := TransitionModel.NewAvgMatrixSparse(extractor.EFeatures.Len(), nil)
-	perceptronInstance := &perceptron.LinearPerceptron{Decoder: decoder, GoldDecoder: goldDecoder, Updater: updater}
-	perceptronInstance.Init(model)
-	goldModel := dependency.TransitionParameterModel(&PerceptronModel{model})
-
-	goldGraph, goldParams := deterministic.ParseOracle(GetTestDepGraph(), nil, goldModel)
-	if goldParams == nil {
-		t.Fatal("Got nil params from deterministic oracle parsing, can't test deterministic-perceptron model")
-	}
-	seq := goldParams.(*ParseResultParameters).Sequence
-	log.Println("\n", seq.String())
-	goldSequence := make(ScoredConfigurations, len(seq))
-	var (
-		lastFeatures *transition.FeaturesList
-		curFeats     []featurevector.Feature
-	)
-	// extractor.Log = true
-	for i := len(seq) - 1; i >= 0; i-- {
-		// for i := 0; i < len(seq); i++ {
-		val := seq[i]
-		// log.Println("Conf:", val)
-		curFeats = extractor.Features(val)
-		// log.Printf("\t%d %s %v\n", i, "Features:", curFeats)
-		lastFeatures = &transition.FeaturesList{curFeats, val.GetLastTransition(), lastFeatures}
-		goldSequence[len(seq)-i-1] = &ScoredConfiguration{val.(DependencyConfiguration), val.GetLastTransition(), 0.0, lastFeatures, 0, 0, true}
-	}
-	t.Errorf("bla")
-	goldDirected := goldGraph.(types.LabeledDependencyGraph)
-	for i := 0; i <= goldDirected.NumberOfArcs(); i++ {
-		arc := goldDirected.GetLabeledArc(i)
-		log.Println("Arc", i, arc)
+	model := TransitionModel.NewAvgMatrixSparse(extractor.EFeatures.Len(), nil, false)
+	perceptronInstance := &perceptron.LinearPerceptron{
+		Decoder:     deterministic,
+		GoldDecoder: deterministic,
+		Updater:     new(TransitionModel.AveragedModelStrategy),
+		Iterations:  5,
 	}
+	perceptronInstance.Init(model)
+	perceptronInstance.Train(goldInstances)
 
-	goldInstances := []perceptron.DecodedInstance{
-		&perceptron.Decoded{perceptron.Instance(rawTestSent), GetTestDepGraph()}}
-	// log.Println(goldSequence)
-	// train with increasing iterations
-	// convergenceIterations := []int{1, 8, 16, 24, 32}
-	// deterministic.ShowConsiderations = true
-	convergenceIterations := []int{32}
-	convergenceSharedSequence := make([]int, 0, len(convergenceIterations))
-	for _, iterations := range convergenceIterations {
-		perceptronInstance.Iterations = iterations
-		// perceptron.Log = true
-		model = TransitionModel.NewAvgMatrixSparse(extractor.EFeatures.Len(), nil)
-		perceptronInstance.Init(model)
-
-		// deterministic.ShowConsiderations = true
-		perceptronInstance.Train(goldInstances)
-
-		parseModel := dependency.TransitionParameterModel(&PerceptronModel{model})
-		deterministic.ShowConsiderations = false
-		graph, params := deterministic.Parse(TEST_SENT, nil, parseModel)
-		labeledGraph := graph.(types.LabeledDependencyGraph)
-		seq := params.(*ParseResultParameters).Sequence
-		log.Println("\n", seq.String())
-		PrintGraph(labeledGraph)
-		sharedSteps := goldSequence[len(goldSequence)-1].C.GetSequence().SharedTransitions(seq)
-		convergenceSharedSequence = append(convergenceSharedSequence, sharedSteps)
-	}
-
-	// verify convergence
-	log.Println(convergenceSharedSequence)
-	if !sort.IntsAreSorted(convergenceSharedSequence) || convergenceSharedSequence[0] == convergenceSharedSequence[len(convergenceSharedSequence)-1] {
-		t.Error("Model not converging, shared sequences lengths:", convergenceSharedSequence)
+	deterministic.Model = model
+	tests := []struct {
+		sent     testSentence
+		expected []int
+	}{
+		{testSentence{"the", "dog", "barks"}, []int{0, 1, 2}},
+		{testSentence{"a", "cat", "barks"}, []int{0, 1, 2}},
+		{testSentence{"cat", "sleeps"}, []int{1, 2}},
+	}
+	for _, test := range tests {
+		conf, _ := deterministic.Parse(test.sent)
+		if tags := conf.(*tagConf).Tags; !reflect.DeepEqual(tags, test.expected) {
+			t.Errorf("Parsing %v: got tags %v, expected %v", test.sent, tags, test.expected)
+		}
 	}
 }
 
@@ -0,0 +1,222 @@
+package transition
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"yap/util"
+
+	"gopkg.in/yaml.v2"
+)
+
+// SourceSchema describes the feature elements of a source of a
+// configuration (e.g. S for the stack); Address matches the address
+// following the source letter (e.g. 0h2 of S0h2), Attributes matches each
+// attribute of the element
+type SourceSchema struct {
+	Address, Attributes *regexp.Regexp
+}
+
+// FeatureSchema describes the feature elements a configuration supports,
+// by source letter
+type FeatureSchema map[byte]*SourceSchema
+
+// FeatureProblem is a problem of a feature template in a configuration
+// file, found before any feature is extracted; warnings are problems that
+// do not change the extracted features (e.g. duplicate templates)
+type FeatureProblem struct {
+	Line    int // 0 if the line was not found
+	Group   string
+	Feature string
+	Message string
+	Warning bool
+}
+
+func (p FeatureProblem) String() string {
+	var location string
+	if p.Line > 0 {
+		location = fmt.Sprintf("line %d: ", p.Line)
+	}
+	if p.Warning {
+		location += "warning: "
+	}
+	if p.Feature == "" {
+		return fmt.Sprintf("%sgroup %s: %s", location, p.Group, p.Message)
+	}
+	return fmt.Sprintf("%sgroup %s: %s: %s", location, p.Group, p.Feature, p.Message)
+}
+
+// CheckFeatureConf checks every template of a feature configuration by
+// loading it, as LoadFeatureSetup would, to an extractor of the transition
+// types of its parser, and checks the elements it loaded against the schema
+// of the parser's configuration. It returns the parsed setup and the
+// problems found, which would otherwise surface as panics while loading
+// features or as features that never fire. A nil schema skips the element
+// checks.
+func CheckFeatureConf(conf []byte, schema FeatureSchema, transTypes []byte) (*FeatureSetup, []FeatureProblem) {
+	setup := new(FeatureSetup)
+	if err := yaml.UnmarshalStrict(conf, setup); err != nil {
+		return setup, []FeatureProblem{{Message: err.Error()}}
+	}
+	var (
+		problems  []FeatureProblem
+		lines     = newConfLines(conf)
+		templates = make(map[byte]map[string]int)
+		groups    = make(map[string]bool)
+		extractor = &GenericExtractor{EFeatures: util.NewEnumSet(setup.NumFeatures())}
+	)
+	extractor.InitTypes(transTypes)
+	morphCombinations := make(map[string][]string)
+	for _, morphTemplate := range setup.MorphTemplates {
+		morphCombinations[morphTemplate.Group] = morphTemplate.Combinations
+	}
+	if len(setup.FeatureGroups) == 0 {
+		problems = append(problems, FeatureProblem{Message: "no feature groups"})
+	}
+	for _, group := range setup.FeatureGroups {
+		groupLine := lines.find("group:", group.Group)
+		groups[group.Group] = true
+		problem := func(line int, feature, format string, args ...interface{}) {
+			problems = append(problems, FeatureProblem{line, group.Group, feature, fmt.Sprintf(format, args...), false})
+		}
+		transType := ConstTransition(0).Type()
+		if len(group.Transition) > 0 {
+			transType = group.Transition[0]
+		}
+		_, knownType := extractor.TransTypeGroups[transType]
+		if !knownType {
+			problem(groupLine, "", "unknown transition type %q (%c), expected one of %q", group.Transition, transType, transTypes)
+		}
+		hashBits := setup.GroupHashBits(group)
+		if hashBits < 0 || hashBits > MAX_HASH_BITS {
+			problem(groupLine, "", "invalid hash bits %d, must be between 0 and %d", hashBits, MAX_HASH_BITS)
+			hashBits = 0
+		}
+		if templates[transType] == nil {
+			templates[transType] = make(map[string]int)
+		}
+		for _, featureConfig := range group.Features {
+			line := lines.find("-", featureConfig)
+			featurePair := strings.Split(featureConfig, FEATURE_REQUIREMENTS_SEPARATOR)
+			if len(featurePair) != 2 {
+				problem(line, featureConfig, "expected a template and its requirements separated by %q (multiple requirements are separated by %q)", FEATURE_REQUIREMENTS_SEPARATOR, REQUIREMENTS_SEPARATOR)
+				continue
+			}
+			template := strings.Replace(featurePair[0], " ", "", -1)
+			if prevLine, exists := templates[transType][template]; exists {
+				problem(line, template, "duplicate template (first at line %d)", prevLine)
+				problems[len(problems)-1].Warning = true
+			}
+			templates[transType][template] = line
+			if !knownType {
+				continue
+			}
+			if err := checkTemplate(extractor, schema, template, featurePair[1], group, hashBits); err != nil {
+				problem(line, template, "%v", err)
+				continue
+			}
+			for _, morphTmpl := range morphCombinations[group.Group] {
+				if err := checkTemplate(extractor, schema, template+FEATURE_SEPARATOR+morphTmpl, featurePair[1], group, hashBits); err != nil {
+					problem(line, template, "with morph combination %s: %v", morphTmpl, err)
+				}
+			}
+		}
+	}
+	for _, morphTemplate := range setup.MorphTemplates {
+		if !groups[morphTemplate.Group] {
+			problems = append(problems, FeatureProblem{lines.find("group:", morphTemplate.Group), morphTemplate.Group, "", "morph templates of an unknown feature group", false})
+		}
+	}
+	return setup, problems
+}
+
+// checkTemplate loads a template with its requirements to the extractor,
+// returning the error or panic of loading it, then checks its elements
+// against the schema
+func checkTemplate(extractor *GenericExtractor, schema FeatureSchema, template, requirements string, group FeatureGroup, hashBits int) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	elements := strings.Split(template, FEATURE_SEPARATOR)
+	for i, elementStr := range elements {
+		if len(elementStr) == 0 {
+			return fmt.Errorf("empty element")
+		}
+		if elementStr[0] != 'P' {
+			continue
+		}
+		// morphological properties of a previous element, e.g. Pgen|1
+		if parts := strings.Split(elementStr, ATTRIBUTE_SEPARATOR); len(parts) > 1 {
+			if ref, err := strconv.Atoi(parts[1]); err != nil || ref < 1 || ref > i {
+				return fmt.Errorf("element %s refers to element %s, expected one of 1..%d", elementStr, parts[1], i)
+			}
+		} else if i == 0 {
+			return fmt.Errorf("element %s has no element to refer to", elementStr)
+		}
+	}
+	if err := extractor.LoadHashedFeature(template, requirements, group.Transition, group.Idle, group.Associated, hashBits); err != nil {
+		return err
+	}
+	if schema == nil {
+		return nil
+	}
+	for _, elementStr := range elements {
+		if elementStr[0] == 'P' {
+			continue
+		}
+		element, err := extractor.ParseFeatureElement(elementStr)
+		if err != nil {
+			return err
+		}
+		source, exists := schema[element.Address[0]]
+		if !exists {
+			return fmt.Errorf("unknown address source %c in %s", element.Address[0], elementStr)
+		}
+		if !source.Address.Match(element.Address[1:]) {
+			return fmt.Errorf("unknown address %s in %s", element.Address, elementStr)
+		}
+		for _, attribute := range element.Attributes {
+			if !source.Attributes.Match(attribute) {
+				return fmt.Errorf("unknown attribute %q of %c in %s", attribute, element.Address[0], elementStr)
+			}
+		}
+	}
+	return nil
+}
+
+// confLines finds the lines of values in a configuration file, in order
+type confLines struct {
+	lines []string
+	next  int
+}
+
+func newConfLines(conf []byte) *confLines {
+	return &confLines{lines: strings.Split(string(conf), "\n")}
+}
+
+// find returns the (1 based) number of the next line of a value after a
+// prefix (e.g. "- S0|w,S0|w" or "- group: Basic"), or 0 if none is found
+func (c *confLines) find(prefix, value string) int {
+	for i := c.next; i < len(c.lines); i++ {
+		line := strings.TrimSpace(c.lines[i])
+		if prefix != "-" {
+			line = strings.TrimSpace(strings.TrimPrefix(line, "-"))
+		}
+		if !strings.HasPrefix(line, prefix) {
+			continue
+		}
+		line = line[len(prefix):]
+		if comment := strings.Index(line, " #"); comment >= 0 {
+			line = line[:comment]
+		}
+		if strings.Trim(strings.TrimSpace(line), `"'`) == value {
+			c.next = i + 1
+			return i + 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,80 @@
+package transition
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var testSchema = FeatureSchema{
+	'S': &SourceSchema{regexp.MustCompile(`^[0-9]+(h2?)?$`), regexp.MustCompile(`^(wp?|p|l)$`)},
+	'N': &SourceSchema{regexp.MustCompile(`^[0-9]+$`), regexp.MustCompile(`^(wp?|p)$`)},
+}
+
+const testFeatureConf = `feature groups:
+ - group: Basic
+   transition: Arc
+   features:
+   - S0|w,S0|w
+   - S0|w|p,S0|w
+   - S0,S0|w
+   - Sx|w,S0|w
+   - S1|q,S0|w
+   - X0|w,S0|w
+   - S0x|w,S0|w
+   - N0|p,N0|w
+   - S0|w,S0|w
+   - S0h|l+N0|p,S0h|l;N0|p
+   - S0|p
+   - S0|w+Pgen|2,S0|w
+ - group: Idle
+   transition: Idle
+   features:
+   - S0|p,n/a
+morph templates:
+ - group: Basic
+   combinations:
+   - Pgen|1
+ - group: Missing
+   combinations:
+   - Pgen|1
+`
+
+func TestCheckFeatureConf(t *testing.T) {
+	setup, problems := CheckFeatureConf([]byte(testFeatureConf), testSchema, []byte("A"))
+	if len(setup.FeatureGroups) != 2 {
+		t.Fatalf("Expected 2 feature groups, got %d", len(setup.FeatureGroups))
+	}
+	expected := []struct {
+		line    int
+		message string
+		warning bool
+	}{
+		{7, "Not enough parts for element S0", false},
+		{8, "Error parsing feature element Sx|w", false},
+		{9, `unknown attribute "q" of S in S1|q`, false},
+		{10, "unknown address source X in X0|w", false},
+		{11, "unknown address S0x in S0x|w", false},
+		{12, "Can't find requirement element N0|w", false},
+		{13, "duplicate template (first at line 5)", true},
+		{15, "expected a template and its requirements", false},
+		{16, "element Pgen|2 refers to element 2, expected one of 1..1", false},
+		{17, `unknown transition type "Idle"`, false},
+		{25, "morph templates of an unknown feature group", false},
+	}
+	if len(problems) != len(expected) {
+		t.Fatalf("Expected %d problems, got %d: %v", len(expected), len(problems), problems)
+	}
+	for i, problem := range problems {
+		if problem.Line != expected[i].line || !strings.Contains(problem.Message, expected[i].message) || problem.Warning != expected[i].warning {
+			t.Errorf("Problem %d: got %v, expected line %d %q", i, problem, expected[i].line, expected[i].message)
+		}
+	}
+}
+
+func TestCheckFeatureConfYAML(t *testing.T) {
+	_, problems := CheckFeatureConf([]byte("feature group:\n - group: Basic\n"), testSchema, []byte("A"))
+	if len(problems) != 1 || !strings.Contains(problems[0].Message, "feature group") {
+		t.Errorf("Expected an unknown field problem, got %v", problems)
+	}
+}
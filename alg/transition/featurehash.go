@@ -0,0 +1,150 @@
+package transition
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	. "yap/alg/featurevector"
+)
+
+const (
+	MAX_HASH_BITS = 32
+)
+
+// Type tags written before every hashed value so that, e.g.,
+// the int 5 and the string "5" do not collide
+const (
+	hashTagNil byte = iota
+	hashTagInt
+	hashTagString
+	hashTagSeq
+	hashTagOther
+)
+
+// HashFeature maps a structured feature value (as produced by
+// GenericExtractor.Features) to an int in [0, 2^bits)
+func HashFeature(value interface{}, bits int) int {
+	h := fnv.New64a()
+	hashValue(h, value)
+	sum := h.Sum64()
+	// fold the high bits into the low bits before masking
+	sum ^= sum >> 32
+	return int(sum & (uint64(1)<<uint(bits) - 1))
+}
+
+// HashFeatureValue hashes a feature value, hashing each generated value
+// separately for generator features, and each feature of transition
+// associated features (keeping the transitions it is associated with)
+func HashFeatureValue(value interface{}, bits int) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case TAF:
+		return hashTAF(v, bits)
+	case []interface{}:
+		hashed := make([]interface{}, len(v))
+		for i, generated := range v {
+			hashed[i] = HashFeature(generated, bits)
+		}
+		return hashed
+	default:
+		return HashFeature(v, bits)
+	}
+}
+
+// hashTAF hashes the features of a transition associated feature; features
+// hashed to the same value are associated with the union of their transitions
+func hashTAF(value TAF, bits int) TAF {
+	transFeatures := value.GetTransFeatures()
+	hashed := &SimpleTAF{FTMap: make(FeatureTransMap, len(transFeatures))}
+	for feature, transitions := range transFeatures {
+		hashedFeature := HashFeature(feature, bits)
+		existing, exists := hashed.FTMap[hashedFeature]
+		if !exists {
+			hashed.FTMap[hashedFeature] = transitions
+			continue
+		}
+		// the transition maps belong to the original feature, don't modify them
+		union := make(map[int]bool, len(existing)+len(transitions))
+		for _, transMap := range []map[int]bool{existing, transitions} {
+			for transition, val := range transMap {
+				union[transition] = union[transition] || val
+			}
+		}
+		hashed.FTMap[hashedFeature] = union
+	}
+	return hashed
+}
+
+func hashValue(h hash.Hash64, value interface{}) {
+	var buf [8]byte
+	switch v := value.(type) {
+	case nil:
+		h.Write([]byte{hashTagNil})
+	case int:
+		h.Write([]byte{hashTagInt})
+		binary.LittleEndian.PutUint64(buf[:], uint64(v))
+		h.Write(buf[:])
+	case string:
+		h.Write([]byte{hashTagString})
+		h.Write([]byte(v))
+		h.Write([]byte{0})
+	case []interface{}:
+		hashSeq(h, v)
+	case [2]interface{}:
+		hashSeq(h, v[:])
+	case [3]interface{}:
+		hashSeq(h, v[:])
+	case [4]interface{}:
+		hashSeq(h, v[:])
+	case [5]interface{}:
+		hashSeq(h, v[:])
+	case [6]interface{}:
+		hashSeq(h, v[:])
+	case [7]interface{}:
+		hashSeq(h, v[:])
+	case [8]interface{}:
+		hashSeq(h, v[:])
+	case []int:
+		hashIntSeq(h, v)
+	case [2]int:
+		hashIntSeq(h, v[:])
+	case [3]int:
+		hashIntSeq(h, v[:])
+	case [4]int:
+		hashIntSeq(h, v[:])
+	case [5]int:
+		hashIntSeq(h, v[:])
+	case [6]int:
+		hashIntSeq(h, v[:])
+	case [7]int:
+		hashIntSeq(h, v[:])
+	default:
+		h.Write([]byte{hashTagOther})
+		fmt.Fprintf(h, "%v", v)
+		h.Write([]byte{0})
+	}
+}
+
+func hashSeq(h hash.Hash64, values []interface{}) {
+	var buf [8]byte
+	h.Write([]byte{hashTagSeq})
+	binary.LittleEndian.PutUint64(buf[:], uint64(len(values)))
+	h.Write(buf[:])
+	for _, val := range values {
+		hashValue(h, val)
+	}
+}
+
+func hashIntSeq(h hash.Hash64, values []int) {
+	var buf [8]byte
+	h.Write([]byte{hashTagSeq})
+	binary.LittleEndian.PutUint64(buf[:], uint64(len(values)))
+	h.Write(buf[:])
+	for _, val := range values {
+		h.Write([]byte{hashTagInt})
+		binary.LittleEndian.PutUint64(buf[:], uint64(val))
+		h.Write(buf[:])
+	}
+}
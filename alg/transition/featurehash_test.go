@@ -0,0 +1,99 @@
+package transition
+
+import (
+	"testing"
+	. "yap/alg/featurevector"
+)
+
+func TestHashFeature(t *testing.T) {
+	values := []interface{}{
+		nil,
+		5,
+		"5",
+		[2]interface{}{1, 2},
+		[2]interface{}{2, 1},
+		[3]interface{}{1, nil, "a"},
+		[2]int{1, 2},
+		[]interface{}{[2]interface{}{1, 2}, 3},
+	}
+	for _, bits := range []int{1, 8, 20, MAX_HASH_BITS} {
+		for _, val := range values {
+			hashed := HashFeature(val, bits)
+			if hashed < 0 || hashed >= 1<<uint(bits) {
+				t.Error("Hash of", val, "with", bits, "bits out of range:", hashed)
+			}
+			if again := HashFeature(val, bits); again != hashed {
+				t.Error("Hash of", val, "not deterministic, got", hashed, "and", again)
+			}
+		}
+	}
+	if HashFeature([2]interface{}{1, 2}, 20) == HashFeature([2]interface{}{2, 1}, 20) {
+		t.Error("Expected element order to affect hash")
+	}
+	if HashFeature(5, 20) == HashFeature("5", 20) {
+		t.Error("Expected value type to affect hash")
+	}
+}
+
+func TestHashFeatureValue(t *testing.T) {
+	if HashFeatureValue(nil, 10) != nil {
+		t.Error("Expected nil feature to remain nil")
+	}
+	generated := HashFeatureValue([]interface{}{1, 2, 3}, 10)
+	asSlice, ok := generated.([]interface{})
+	if !ok || len(asSlice) != 3 {
+		t.Fatal("Expected generator feature to hash to a slice of 3, got", generated)
+	}
+	for i, val := range asSlice {
+		if val != HashFeature(i+1, 10) {
+			t.Error("Generated value", i, "hashed to", val, "expected", HashFeature(i+1, 10))
+		}
+	}
+}
+
+func TestHashFeatureValueTAF(t *testing.T) {
+	taf := &SimpleTAF{FTMap: FeatureTransMap{
+		"a": {1: true, 2: true},
+		"b": {3: true},
+		"c": {4: true},
+	}}
+	hashed, ok := HashFeatureValue(taf, 10).(TAF)
+	if !ok {
+		t.Fatal("Expected transition associated feature to hash to a TAF, got", hashed)
+	}
+	for feature, transitions := range taf.GetTransFeatures() {
+		hashedTrans, exists := hashed.GetTransFeatures()[HashFeature(feature, 10)]
+		if !exists {
+			t.Errorf("Feature %v was not hashed to %v", feature, HashFeature(feature, 10))
+			continue
+		}
+		for transition := range transitions {
+			if !hashedTrans[transition] {
+				t.Errorf("Hashed feature %v is not associated with transition %v", feature, transition)
+			}
+		}
+	}
+	if len(taf.GetTransFeatures()["a"]) != 2 {
+		t.Error("Hashing modified the original feature", taf.GetTransFeatures())
+	}
+
+	// with 1 bit, at least two of the features collide
+	collided := HashFeatureValue(taf, 1).(TAF).GetTransFeatures()
+	transitions := make(map[int]bool)
+	for feature, featTrans := range collided {
+		if feature.(int) > 1 {
+			t.Errorf("Feature hashed to %v, expected 0 or 1", feature)
+		}
+		for transition := range featTrans {
+			transitions[transition] = true
+		}
+	}
+	if len(collided) > 2 || len(transitions) != 4 {
+		t.Errorf("Got hashed features %v, expected at most 2 features associated with all 4 transitions", collided)
+	}
+	for _, featTrans := range taf.GetTransFeatures() {
+		if len(featTrans) > 2 {
+			t.Error("Hashing with collisions modified the original feature", taf.GetTransFeatures())
+		}
+	}
+}
@@ -13,6 +13,7 @@ type FeatureGroup struct {
 	Features   []string
 	Idle       bool
 	Associated bool
+	HashBits   int `yaml:"hash bits"` // overrides FeatureSetup.HashBits if > 0
 }
 
 type MorphTemplate struct {
@@ -22,6 +23,16 @@ type MorphTemplate struct {
 type FeatureSetup struct {
 	FeatureGroups  []FeatureGroup  `yaml:"feature groups"`
 	MorphTemplates []MorphTemplate `yaml:"morph templates"`
+	HashBits       int             `yaml:"hash bits"` // if > 0, hash feature values into 2^HashBits buckets per template
+}
+
+// GroupHashBits returns the hash bit width used for the templates of a
+// feature group, 0 if hashing is disabled
+func (s *FeatureSetup) GroupHashBits(group FeatureGroup) int {
+	if group.HashBits > 0 {
+		return group.HashBits
+	}
+	return s.HashBits
 }
 
 func (s *FeatureSetup) NumFeatures() int {
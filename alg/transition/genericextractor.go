@@ -50,6 +50,7 @@ type FeatureTemplate struct {
 	EMorphProp, EToken                         *util.EnumSet
 	TransitionType                             string
 	Associated                                 bool
+	HashBits                                   int // if > 0, feature values are hashed into 2^HashBits buckets
 }
 
 type MorphElement struct {
@@ -85,6 +86,9 @@ func (f FeatureTemplate) Format(val interface{}) string {
 }
 
 func (f FeatureTemplate) FormatWithGenerator(val interface{}, isGenerator bool) string {
+	if f.HashBits > 0 {
+		return fmt.Sprintf("#%v", val)
+	}
 	var (
 		valueSlice    []interface{}
 		valueOneSlice [1]interface{}
@@ -560,6 +564,9 @@ func (x *GenericExtractor) Features(instance Instance, idle bool, transType byte
 				}
 				features[i] = GetArray(valuesSlice)
 			}
+			if template.HashBits > 0 {
+				features[i] = HashFeatureValue(features[i], template.HashBits)
+			}
 			if x.Log && features[i] != nil {
 				// log.Println(x.EWord)
 				log.Printf("\t\t%s", template.FormatWithGenerator(features[i], elements[template.CachedElementIDs[0]].IsGenerator))
@@ -819,10 +826,20 @@ func (x *GenericExtractor) UpdateFeatureElementCache(feat *FeatureTemplate, idle
 }
 
 func (x *GenericExtractor) LoadFeature(featTemplateStr string, requirements string, transitionType string, idle, associated bool) error {
+	return x.LoadHashedFeature(featTemplateStr, requirements, transitionType, idle, associated, 0)
+}
+
+// LoadHashedFeature loads a feature template whose values are hashed
+// into 2^hashBits buckets; a hashBits of 0 disables hashing
+func (x *GenericExtractor) LoadHashedFeature(featTemplateStr string, requirements string, transitionType string, idle, associated bool, hashBits int) error {
+	if hashBits < 0 || hashBits > MAX_HASH_BITS {
+		return fmt.Errorf("Invalid hash bits %d for feature %s, must be between 0 and %d", hashBits, featTemplateStr, MAX_HASH_BITS)
+	}
 	template, err := x.ParseFeatureTemplate(featTemplateStr, requirements)
 	if err != nil {
 		return err
 	}
+	template.HashBits = hashBits
 	var transType byte
 	if len(transitionType) == 0 {
 		transType = ConstTransition(0).Type()
@@ -887,6 +904,10 @@ func (x *GenericExtractor) LoadFeatureSetup(setup *FeatureSetup) {
 				log.Println("Loading feature group", group.Group)
 			}
 		}
+		hashBits := setup.GroupHashBits(group)
+		if hashBits > 0 {
+			log.Println(" with feature hashing of", hashBits, "bits")
+		}
 		morphId, exists = morphGroups[group.Group]
 		if exists {
 			morphCombinations = setup.MorphTemplates[morphId].Combinations
@@ -899,14 +920,14 @@ func (x *GenericExtractor) LoadFeatureSetup(setup *FeatureSetup) {
 			// e.g. S0p,S0w: feature is S0p, requires S0w
 			featurePair = strings.Split(featureConfig, FEATURE_REQUIREMENTS_SEPARATOR)
 			// log.Println("\tLoading feature", featurePair[0])
-			if err := x.LoadFeature(featurePair[0], featurePair[1], group.Transition, group.Idle, group.Associated); err != nil {
+			if err := x.LoadHashedFeature(featurePair[0], featurePair[1], group.Transition, group.Idle, group.Associated, hashBits); err != nil {
 				log.Fatalln("Failed to load feature", err.Error())
 			}
 			if morphCombinations != nil {
 				for _, morphTmpl := range morphCombinations {
 					morphAddedFeature = fmt.Sprintf("%s%s%s", featurePair[0], FEATURE_SEPARATOR, morphTmpl)
 					// log.Println("\t generating with morph ", morphAddedFeature)
-					if err := x.LoadFeature(morphAddedFeature, featurePair[1], group.Transition, group.Idle, group.Associated); err != nil {
+					if err := x.LoadHashedFeature(morphAddedFeature, featurePair[1], group.Transition, group.Idle, group.Associated, hashBits); err != nil {
 						log.Fatalln("Failed to load morph feature", err.Error())
 					}
 				}
@@ -17,6 +17,7 @@ func init() {
 	gob.Register(&AvgMatrixSparseSerialized{})
 	gob.Register(make(map[interface{}][]int64))
 	gob.Register(make(map[interface{}]map[int]int64))
+	gob.Register(&AvgSparseSerialized{})
 	gob.Register([2]interface{}{})
 	gob.Register([3]interface{}{})
 	gob.Register([4]interface{}{})
@@ -50,6 +51,7 @@ type AvgMatrixSparseSerialized struct {
 
 var _ perceptron.Model = &AvgMatrixSparse{}
 var _ Interface = &AvgMatrixSparse{}
+var _ TransitionScorer = &AvgMatrixSparse{}
 
 func (t *AvgMatrixSparse) Score(features interface{}) int64 {
 	var (
@@ -216,8 +218,55 @@ func (t *AvgMatrixSparse) Make(dense bool) perceptron.Model {
 	return NewAvgMatrixSparse(t.Features, nil, dense)
 }
 
+// AddModel adds the current (finalized) values of another model with the
+// same features to this model
 func (t *AvgMatrixSparse) AddModel(m perceptron.Model) {
-	panic("Cannot add two avg matrix sparse types")
+	other, ok := m.(*AvgMatrixSparse)
+	if !ok {
+		panic("Cannot add a non avg matrix sparse model")
+	}
+	if len(other.Mat) != len(t.Mat) {
+		panic(fmt.Sprintf("Cannot add models with different number of features (%d, %d)", len(t.Mat), len(other.Mat)))
+	}
+	for i, avgsparse := range other.Mat {
+		for feat, scores := range avgsparse.Vals {
+			scores.Each(func(trans int, histValue *HistoryValue) {
+				if histValue != nil {
+					t.Mat[i].AddValue(trans, feat, histValue.Value)
+				}
+			})
+		}
+	}
+}
+
+// Rescale multiplies all (finalized) values of the model by num/denom
+func (t *AvgMatrixSparse) Rescale(num, denom int64) {
+	for _, avgsparse := range t.Mat {
+		avgsparse.UpdateRescale(num, denom)
+	}
+}
+
+// FeatureRemapFunc maps a feature value of the feature template at index
+// featureID, and the transition it is scored for, to new values
+type FeatureRemapFunc func(featureID, transition int, feature interface{}) (int, interface{})
+
+// Remap returns a copy of a finalized model with all features and transitions
+// mapped by remap; values of features mapped to the same key are summed
+func (t *AvgMatrixSparse) Remap(remap FeatureRemapFunc) *AvgMatrixSparse {
+	result := NewAvgMatrixSparse(len(t.Mat), t.Formatters, false)
+	result.Generation = t.Generation
+	result.Extractor = t.Extractor
+	for i, avgsparse := range t.Mat {
+		for feat, scores := range avgsparse.Vals {
+			scores.Each(func(trans int, histValue *HistoryValue) {
+				if histValue != nil {
+					newTrans, newFeat := remap(i, trans, feat)
+					result.Mat[i].AddValue(newTrans, newFeat, histValue.Value)
+				}
+			})
+		}
+	}
+	return result
 }
 
 func (t *AvgMatrixSparse) TransitionScore(transition transition.Transition, features []Feature) int64 {
@@ -303,6 +352,33 @@ func (t *AvgMatrixSparse) Deserialize(data *AvgMatrixSparseSerialized) {
 	}
 }
 
+// PrepareContinuedTraining resets the averaging history of a deserialized
+// model so that perceptron training can continue from its weights.
+// A finalized model holds weights integrated over all of its training
+// generations, i.e. its averaged weights scaled by the number of generations.
+// These are kept as the initial weights, and the returned scale (the number
+// of generations) should be used as the update amount of further training,
+// so that new updates are proportional to the averaged weights without
+// losing precision to integer division.
+func (t *AvgMatrixSparse) PrepareContinuedTraining() int64 {
+	scale := int64(t.Generation)
+	if scale < 1 {
+		scale = 1
+	}
+	for _, avgsparse := range t.Mat {
+		for _, scores := range avgsparse.Vals {
+			scores.Each(func(i int, histValue *HistoryValue) {
+				if histValue != nil {
+					histValue.Generation, histValue.PrevGeneration = 0, 0
+					histValue.Total = 0
+				}
+			})
+		}
+	}
+	t.Generation = 0
+	return scale
+}
+
 // func (t *AvgMatrixSparse) Write(writer io.Writer) {
 // 	// marshalled, _ := json.Marshal(t.Serialize(), "", " ")
 // 	// writer.Write(marshalled)
@@ -0,0 +1,154 @@
+package model
+
+import (
+	"yap/alg/featurevector"
+
+	"bytes"
+	"encoding/gob"
+	"testing"
+)
+
+type weight struct {
+	feature, transition int
+	value               interface{}
+	score               int64
+}
+
+// makeModel returns a finalized model of the given number of features with
+// the given weights
+func makeModel(features int, weights []weight) *AvgMatrixSparse {
+	m := NewAvgMatrixSparse(features, nil, false)
+	for _, w := range weights {
+		m.Mat[w.feature].AddValue(w.transition, w.value, w.score)
+	}
+	return m
+}
+
+// weightOf returns the weight of a feature value for a transition
+func weightOf(m *AvgMatrixSparse, feature, transition int, value interface{}) int64 {
+	if transitions, exists := m.Mat[feature].Vals[value]; exists {
+		if histValue := transitions.GetValue(transition); histValue != nil {
+			return histValue.Value
+		}
+	}
+	return 0
+}
+
+func checkWeights(t *testing.T, name string, m *AvgMatrixSparse, expected []weight) {
+	for _, w := range expected {
+		if score := weightOf(m, w.feature, w.transition, w.value); score != w.score {
+			t.Errorf("%s: got weight %v for feature %d value %v transition %d, expected %v", name, score, w.feature, w.value, w.transition, w.score)
+		}
+	}
+}
+
+func TestAddModelAverage(t *testing.T) {
+	models := []*AvgMatrixSparse{
+		makeModel(2, []weight{{0, 1, "a", 10}, {0, 2, "a", -4}, {1, 1, 7, 6}}),
+		makeModel(2, []weight{{0, 1, "a", 20}, {0, 1, "b", 8}, {1, 3, 7, 2}}),
+		makeModel(2, []weight{{0, 1, "a", 30}, {1, 1, 7, 3}}),
+	}
+	averaged := models[0]
+	for _, m := range models[1:] {
+		averaged.AddModel(m)
+	}
+	averaged.ScalarDivide(int64(len(models)))
+	checkWeights(t, "average", averaged, []weight{
+		{0, 1, "a", 20},
+		{0, 2, "a", -1},
+		{0, 1, "b", 2},
+		{0, 2, "b", 0},
+		{1, 1, 7, 3},
+		{1, 3, 7, 0},
+	})
+	// the added models are unchanged
+	checkWeights(t, "added", models[1], []weight{{0, 1, "a", 20}, {0, 1, "b", 8}, {1, 3, 7, 2}})
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic adding a model with a different number of features")
+		}
+	}()
+	averaged.AddModel(makeModel(3, nil))
+}
+
+func TestRescale(t *testing.T) {
+	m := makeModel(1, []weight{{0, 0, "a", 9}, {0, 1, "a", -6}})
+	m.Rescale(4, 3)
+	checkWeights(t, "rescale", m, []weight{{0, 0, "a", 12}, {0, 1, "a", -8}})
+}
+
+func TestRemap(t *testing.T) {
+	m := makeModel(2, []weight{
+		{0, 0, 1, 5},
+		{0, 1, 2, 3},
+		{0, 1, 3, 4},
+		{1, 0, "x", 7},
+	})
+	m.Generation = 5
+	// swap transitions 0 and 1, map feature value 3 to 2 of the first
+	// feature, and keep the second feature
+	remapped := m.Remap(func(featureID, transition int, feature interface{}) (int, interface{}) {
+		if featureID == 0 && feature == 3 {
+			feature = 2
+		}
+		return 1 - transition, feature
+	})
+	if remapped.Generation != m.Generation {
+		t.Errorf("Got generation %d, expected %d", remapped.Generation, m.Generation)
+	}
+	checkWeights(t, "remapped", remapped, []weight{
+		{0, 1, 1, 5},
+		{0, 0, 1, 0},
+		// values mapped to the same key are summed
+		{0, 0, 2, 7},
+		{0, 0, 3, 0},
+		{1, 1, "x", 7},
+		{1, 0, "x", 0},
+	})
+	// the original model is unchanged
+	checkWeights(t, "original", m, []weight{{0, 0, 1, 5}, {0, 1, 2, 3}, {0, 1, 3, 4}, {1, 0, "x", 7}})
+}
+
+func encodeModel(t *testing.T, m *AvgMatrixSparse) []byte {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(m.Serialize(-1)); err != nil {
+		t.Fatal("Failed encoding model", err)
+	}
+	return buf.Bytes()
+}
+
+func TestSortedSerialization(t *testing.T) {
+	defer func(prev bool) { featurevector.SortedSerialization = prev }(featurevector.SortedSerialization)
+	featurevector.SortedSerialization = true
+	weights := []weight{
+		{0, 1, "a", 10}, {0, 2, "a", -4}, {0, 1, "b", 8}, {0, 3, "c", 1},
+		{0, 4, "d", 2}, {0, 5, "e", 3}, {0, 1, "f", 4}, {0, 2, "g", 5},
+		{1, 1, 7, 6}, {1, 3, 7, 2}, {1, 2, 8, 9}, {1, 1, 9, -1},
+	}
+	reversed := make([]weight, len(weights))
+	for i, w := range weights {
+		reversed[len(weights)-1-i] = w
+	}
+	expected := encodeModel(t, makeModel(2, weights))
+	// map iteration order varies between runs and between identical maps
+	for i := 0; i < 10; i++ {
+		if encoded := encodeModel(t, makeModel(2, weights)); !bytes.Equal(encoded, expected) {
+			t.Fatalf("Serializing the same model resulted in different bytes at attempt %v", i)
+		}
+		if encoded := encodeModel(t, makeModel(2, reversed)); !bytes.Equal(encoded, expected) {
+			t.Fatalf("Serializing a model built in reverse resulted in different bytes at attempt %v", i)
+		}
+	}
+
+	serialized := &AvgMatrixSparseSerialized{}
+	if err := gob.NewDecoder(bytes.NewReader(expected)).Decode(serialized); err != nil {
+		t.Fatal("Failed decoding model", err)
+	}
+	deserialized := &AvgMatrixSparse{}
+	deserialized.Deserialize(serialized)
+	checkWeights(t, "deserialized", deserialized, weights)
+	if encoded := encodeModel(t, deserialized); !bytes.Equal(encoded, expected) {
+		t.Error("Serializing a deserialized model resulted in different bytes")
+	}
+}
@@ -0,0 +1,71 @@
+package model
+
+import (
+	. "yap/alg/featurevector"
+	"yap/alg/perceptron"
+	"yap/alg/transition"
+)
+
+// Ensemble scores transitions as the sum of the scores of several models.
+// The models must share feature templates and enumerations (see Remap), and
+// are only used for parsing.
+type Ensemble struct {
+	Models []*AvgMatrixSparse
+}
+
+var _ perceptron.Model = &Ensemble{}
+var _ Interface = &Ensemble{}
+var _ TransitionScorer = &Ensemble{}
+
+func (e *Ensemble) Score(features interface{}) int64 {
+	var retval int64
+	for _, m := range e.Models {
+		retval += m.Score(features)
+	}
+	return retval
+}
+
+func (e *Ensemble) TransitionScore(transition transition.Transition, features []Feature) int64 {
+	var retval int64
+	for _, m := range e.Models {
+		retval += m.TransitionScore(transition, features)
+	}
+	return retval
+}
+
+func (e *Ensemble) SetTransitionScores(features []Feature, scores ScoredStore, integrated bool) {
+	// scores are incremented by each model, resulting in the sum of scores
+	for _, m := range e.Models {
+		m.SetTransitionScores(features, scores, integrated)
+	}
+}
+
+func (e *Ensemble) Add(features interface{}) perceptron.Model {
+	panic("Cannot train an ensemble model")
+}
+
+func (e *Ensemble) Subtract(features interface{}) perceptron.Model {
+	panic("Cannot train an ensemble model")
+}
+
+func (e *Ensemble) AddSubtract(goldFeatures, decodedFeatures interface{}, amount int64) {
+	panic("Cannot train an ensemble model")
+}
+
+func (e *Ensemble) ScalarDivide(val int64) {
+	for _, m := range e.Models {
+		m.ScalarDivide(val)
+	}
+}
+
+func (e *Ensemble) Copy() perceptron.Model {
+	panic("Cannot copy an ensemble model")
+}
+
+func (e *Ensemble) AddModel(m perceptron.Model) {
+	panic("Cannot add to an ensemble model")
+}
+
+func (e *Ensemble) New() perceptron.Model {
+	return &Ensemble{}
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	. "yap/alg/featurevector"
+	"yap/alg/transition"
+
+	"testing"
+)
+
+func TestEnsemble(t *testing.T) {
+	ensemble := &Ensemble{Models: []*AvgMatrixSparse{
+		makeModel(2, []weight{{0, 0, "a", 1}, {0, 1, "a", 2}, {1, 1, 5, 4}}),
+		makeModel(2, []weight{{0, 0, "a", 10}, {0, 2, "a", 20}, {1, 2, 5, 40}}),
+	}}
+	features := []Feature{"a", 5}
+	// scores are the sum of the scores of the models
+	expected := []int64{11, 6, 60}
+
+	for trans, score := range expected {
+		if got := ensemble.TransitionScore(transition.ConstTransition(trans), features); got != score {
+			t.Errorf("Got transition score %d for transition %d, expected %d", got, trans, score)
+		}
+	}
+
+	scores := &ArrayStore{}
+	scores.Init()
+	scores.SetTransitions([]int{0, 1, 2})
+	ensemble.SetTransitionScores(features, scores, false)
+	for trans, score := range expected {
+		if got, _ := scores.Get(trans); got != score {
+			t.Errorf("Got set score %d for transition %d, expected %d", got, trans, score)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic training an ensemble")
+		}
+	}()
+	ensemble.AddSubtract(nil, nil, 1)
+}
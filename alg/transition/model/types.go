@@ -25,6 +25,11 @@ type Interface interface {
 	TransitionScore(transition Transition, features []Feature) int64
 }
 
+// TransitionScorer sets the scores of all transitions for a set of features
+type TransitionScorer interface {
+	SetTransitionScores(features []Feature, scores ScoredStore, integrated bool)
+}
+
 func MakeFeature(transition, i int, feat interface{}) interface{} {
 	return [3]interface{}{transition, i, feat}
 }
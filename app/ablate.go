@@ -0,0 +1,329 @@
+package app
+
+import (
+	"yap/alg/transition"
+	"yap/nlp/format/conll"
+	"yap/util"
+
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/gonuts/commander"
+	"github.com/gonuts/flag"
+	"gopkg.in/yaml.v2"
+)
+
+const ABLATE_BASELINE = "baseline"
+
+var (
+	ablDevConll          string
+	ablIterations        int
+	ablParallel, ablCPUs int
+	ablWorkDir           string
+	ablKeep              bool
+)
+
+// ablation is a training run with the templates of a feature group
+// removed, or the baseline run with all templates
+type ablation struct {
+	group     string
+	templates int
+	dir       string
+	features  string
+	outConll  string
+	model     string
+	uas, las  float64
+	err       error
+}
+
+func AblateConfigOut() {
+	log.Println("Configuration")
+	log.Printf("Model Type:\t\tdep")
+	log.Printf("Features File:\t%s", featFeaturesFile)
+	log.Printf("Train Gold Conll:\t%s", tConll)
+	log.Printf("Dev Gold Conll:\t%s", ablDevConll)
+	log.Printf("Iterations:\t\t%d", ablIterations)
+	log.Printf("Parallel Runs:\t%d (%d CPUs each)", ablateParallel(), ablCPUs)
+	log.Printf("Work Dir:\t\t%s", ablWorkDir)
+	log.Println()
+}
+
+// ablateParallel returns the number of runs to train concurrently, bounded
+// by the CPUs given to the command
+func ablateParallel() int {
+	parallel := ablParallel
+	if ablCPUs > 0 && CPUs/ablCPUs < parallel {
+		parallel = CPUs / ablCPUs
+	}
+	if parallel < 1 {
+		parallel = 1
+	}
+	return parallel
+}
+
+// AblateFeatureSetup returns the setup without the templates (and morph
+// templates) of a feature group
+func AblateFeatureSetup(setup *transition.FeatureSetup, group string) *transition.FeatureSetup {
+	ablated := &transition.FeatureSetup{HashBits: setup.HashBits}
+	for _, featureGroup := range setup.FeatureGroups {
+		if featureGroup.Group != group {
+			ablated.FeatureGroups = append(ablated.FeatureGroups, featureGroup)
+		}
+	}
+	for _, morphTemplate := range setup.MorphTemplates {
+		if morphTemplate.Group != group {
+			ablated.MorphTemplates = append(ablated.MorphTemplates, morphTemplate)
+		}
+	}
+	return ablated
+}
+
+// attachmentScores returns the unlabeled and labeled attachment scores of
+// parsed sentences against gold sentences, over all tokens as depeval
+func attachmentScores(parsed, gold []conll.Sentence) (float64, float64, error) {
+	if len(parsed) != len(gold) {
+		return 0, 0, fmt.Errorf("got %d parsed sentences and %d gold sentences", len(parsed), len(gold))
+	}
+	var total, unlabeled, labeled int
+	for i, goldSent := range gold {
+		for id, goldRow := range goldSent {
+			total++
+			row, exists := parsed[i][id]
+			if !exists || row.Head != goldRow.Head {
+				continue
+			}
+			unlabeled++
+			if row.DepRel == goldRow.DepRel {
+				labeled++
+			}
+		}
+	}
+	if total == 0 {
+		return 0, 0, fmt.Errorf("no gold tokens")
+	}
+	return float64(unlabeled) / float64(total), float64(labeled) / float64(total), nil
+}
+
+// runAblation trains a dep model with the features of an ablation and
+// parses the dev set, in a separate process as the app keeps its state in
+// package globals
+func runAblation(executable string, run *ablation, appArgs []string) error {
+	args := []string{"dep",
+		"-f", run.features,
+		"-tc", tConll,
+		"-in", ablDevConll,
+		"-oc", run.outConll,
+		"-it", fmt.Sprintf("%d", ablIterations),
+		"-cpus", fmt.Sprintf("%d", ablCPUs),
+		"-m", run.model,
+		// -mn is set to a model name that won't be found, so that dep
+		// trains the model of the run
+		"-mn", run.model + ".none"}
+	args = append(args, appArgs...)
+	logFile, err := os.Create(filepath.Join(run.dir, "train.log"))
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+	cmd := exec.Command(executable, args...)
+	cmd.Stdout, cmd.Stderr = logFile, logFile
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed (see %s): %v", run.group, logFile.Name(), err)
+	}
+	parsed, err := conll.ReadFile(run.outConll, 0)
+	if err != nil {
+		return err
+	}
+	gold, err := conll.ReadFile(ablDevConll, 0)
+	if err != nil {
+		return err
+	}
+	run.uas, run.las, err = attachmentScores(parsed, gold)
+	return err
+}
+
+func FeaturesAblate(cmd *commander.Command, args []string) error {
+	VerifyFlags(cmd, []string{"f", "tc", "dev"})
+	if featModelType != "dep" {
+		log.Fatalln("Ablation is only supported for dep features, got model type", featModelType)
+	}
+	if ablIterations < 1 || ablCPUs < 1 {
+		log.Fatalln("Iterations and CPUs per run must be positive, got", ablIterations, ablCPUs)
+	}
+	featuresLocation, found := util.LocateFile(featFeaturesFile, DEFAULT_CONF_DIRS)
+	if !found {
+		featuresLocation = featFeaturesFile
+	}
+	data, err := ioutil.ReadFile(featuresLocation)
+	if err != nil {
+		log.Fatalln("Failed reading feature configuration file:", featuresLocation, err)
+	}
+	groups, _ := featureTypeGroups(featModelType)
+	setup, problems := transition.CheckFeatureConf(data, featureSchemas[featModelType], groups)
+	for _, problem := range problems {
+		if !problem.Warning {
+			return fmt.Errorf("%s: %v (run features check)", featuresLocation, problem)
+		}
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if len(ablWorkDir) == 0 {
+		ablWorkDir, err = ioutil.TempDir("", "yap-ablate")
+		if err != nil {
+			return err
+		}
+		if !ablKeep {
+			defer os.RemoveAll(ablWorkDir)
+		}
+	} else if err = os.MkdirAll(ablWorkDir, 0755); err != nil {
+		return err
+	}
+	if ablWorkDir, err = filepath.Abs(ablWorkDir); err != nil {
+		return err
+	}
+	if tConll, err = filepath.Abs(tConll); err != nil {
+		return err
+	}
+	if ablDevConll, err = filepath.Abs(ablDevConll); err != nil {
+		return err
+	}
+	if allOut {
+		AblateConfigOut()
+	}
+
+	runs := make([]*ablation, 0, len(setup.FeatureGroups)+1)
+	variants := []string{ABLATE_BASELINE}
+	for _, group := range setup.FeatureGroups {
+		variants = append(variants, group.Group)
+	}
+	for i, group := range variants {
+		variant := setup
+		if i > 0 {
+			variant = AblateFeatureSetup(setup, group)
+		}
+		dir := filepath.Join(ablWorkDir, fmt.Sprintf("%02d.%s", i, group))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		run := &ablation{
+			group:     group,
+			templates: setup.NumFeatures() - variant.NumFeatures(),
+			dir:       dir,
+			features:  filepath.Join(dir, "features.yaml"),
+			outConll:  filepath.Join(dir, "dev.conll"),
+			model:     filepath.Join(dir, "model"),
+		}
+		// removing a group may remove the requirements of later groups
+		marshaled, err := yaml.Marshal(variant)
+		if err != nil {
+			return err
+		}
+		if _, variantProblems := transition.CheckFeatureConf(marshaled, featureSchemas[featModelType], groups); len(variantProblems) > 0 {
+			for _, problem := range variantProblems {
+				if !problem.Warning {
+					if problem.Group == "" {
+						run.err = fmt.Errorf("without %s: %s", group, problem.Message)
+					} else {
+						run.err = fmt.Errorf("without %s: %v", group, problem)
+					}
+					break
+				}
+			}
+		}
+		if err := ioutil.WriteFile(run.features, marshaled, 0644); err != nil {
+			return err
+		}
+		runs = append(runs, run)
+	}
+
+	var (
+		wg        sync.WaitGroup
+		semaphore = make(chan bool, ablateParallel())
+	)
+	for _, run := range runs {
+		if run.err != nil {
+			log.Println("Skipping", run.group+":", run.err)
+			continue
+		}
+		wg.Add(1)
+		go func(run *ablation) {
+			defer wg.Done()
+			semaphore <- true
+			defer func() { <-semaphore }()
+			if allOut {
+				log.Println("Started", run.group)
+			}
+			run.err = runAblation(executable, run, args)
+			if allOut {
+				log.Println("Done", run.group)
+			}
+		}(run)
+	}
+	wg.Wait()
+	baseline := runs[0]
+	if baseline.err != nil {
+		return baseline.err
+	}
+
+	ablated := runs[1:]
+	sort.SliceStable(ablated, func(i, j int) bool {
+		if ablated[i].err != nil || ablated[j].err != nil {
+			return ablated[j].err != nil && ablated[i].err == nil
+		}
+		return ablated[i].las < ablated[j].las
+	})
+	log.Printf("Ablation of %d feature groups (%d templates), %d iterations", len(ablated), setup.NumFeatures(), ablIterations)
+	log.Printf("%-24s\t%9s\t%7s\t%7s\t%7s\t%7s", "Removed group", "Templates", "LAS", "UAS", "ΔLAS", "ΔUAS")
+	log.Printf("%-24s\t%9d\t%7.2f\t%7.2f\t%7s\t%7s", "(none)", 0, 100*baseline.las, 100*baseline.uas, "", "")
+	for _, run := range ablated {
+		if run.err != nil {
+			log.Printf("%-24s\t%9d\tfailed: %v", run.group, run.templates, run.err)
+			continue
+		}
+		log.Printf("%-24s\t%9d\t%7.2f\t%7.2f\t%+7.2f\t%+7.2f", run.group, run.templates,
+			100*run.las, 100*run.uas, 100*(run.las-baseline.las), 100*(run.uas-baseline.uas))
+	}
+	if ablKeep {
+		log.Println("Runs are in", ablWorkDir)
+	}
+	return nil
+}
+
+func FeaturesAblateCmd() *commander.Command {
+	cmd := &commander.Command{
+		Run:       FeaturesAblate,
+		UsageLine: "ablate <file options> [arguments] [-- <dep options>]",
+		Short:     "train dep models without each feature group and compare dev scores",
+		Long: `
+train a dep model with the base feature configuration, and a model without
+the templates of each of its feature groups, for a few iterations; report the
+dev scores of each model and their difference from the base model, sorted by
+the labeled score difference (groups that hurt the most when removed first)
+
+runs are trained in parallel processes, within the CPUs given by -cpus;
+options after -- are passed to dep (e.g. -l, -a, -b)
+
+	$ ./yap features ablate -f <features> -tc <conll> -dev <conll> [-it 3] [-j 4 -runcpus 1] [-- <dep options>]
+
+`,
+		Flag: *flag.NewFlagSet("ablate", flag.ExitOnError),
+	}
+	cmd.Flag.StringVar(&featModelType, "t", "dep", "Model type [dep]")
+	cmd.Flag.StringVar(&featFeaturesFile, "f", "", "Base Features Configuration File")
+	cmd.Flag.StringVar(&tConll, "tc", "", "Training Conll File")
+	cmd.Flag.StringVar(&ablDevConll, "dev", "", "Dev Gold Conll File, parsed and scored by each run")
+	cmd.Flag.IntVar(&ablIterations, "it", 3, "Optional - Number of Perceptron Iterations of each run")
+	cmd.Flag.IntVar(&ablParallel, "j", 4, "Optional - Maximal number of runs to train concurrently")
+	cmd.Flag.IntVar(&ablCPUs, "runcpus", 1, "Optional - Number of CPUs of each run")
+	cmd.Flag.StringVar(&ablWorkDir, "dir", "", "Optional - Work directory of the runs (default: a temporary directory)")
+	cmd.Flag.BoolVar(&ablKeep, "keep", false, "Optional - Keep the temporary work directory")
+	return cmd
+}
@@ -0,0 +1,132 @@
+package app
+
+import (
+	"yap/alg/transition"
+	"yap/nlp/format/conll"
+
+	"reflect"
+	"testing"
+)
+
+func testAblateSetup() *transition.FeatureSetup {
+	return &transition.FeatureSetup{
+		FeatureGroups: []transition.FeatureGroup{
+			{Group: "Unigram", Transition: "Arc", Features: []string{"S0|w,S0|w", "N0|p,N0|p"}},
+			{Group: "Bigram", Transition: "Arc", Features: []string{"S0|w+N0|w,S0|w;N0|w"}},
+			{Group: "Distance", Transition: "Arc", Features: []string{"S0|d,S0|d"}},
+		},
+		MorphTemplates: []transition.MorphTemplate{
+			{Group: "Unigram", Combinations: []string{"Pgen|1"}},
+			{Group: "Bigram", Combinations: []string{"Pgen|1", "Pgen|2"}},
+		},
+		HashBits: 18,
+	}
+}
+
+func TestAblateFeatureSetup(t *testing.T) {
+	tests := []struct {
+		group          string
+		expectedGroups []string
+		expectedMorph  []string
+	}{
+		{"Unigram", []string{"Bigram", "Distance"}, []string{"Bigram"}},
+		{"Bigram", []string{"Unigram", "Distance"}, []string{"Unigram"}},
+		{"Distance", []string{"Unigram", "Bigram"}, []string{"Unigram", "Bigram"}},
+		{"Missing", []string{"Unigram", "Bigram", "Distance"}, []string{"Unigram", "Bigram"}},
+	}
+	for _, test := range tests {
+		setup := testAblateSetup()
+		ablated := AblateFeatureSetup(setup, test.group)
+		var groups, morph []string
+		for _, group := range ablated.FeatureGroups {
+			groups = append(groups, group.Group)
+		}
+		for _, morphTemplate := range ablated.MorphTemplates {
+			morph = append(morph, morphTemplate.Group)
+		}
+		if !reflect.DeepEqual(groups, test.expectedGroups) {
+			t.Errorf("Without %s: got groups %v, expected %v", test.group, groups, test.expectedGroups)
+		}
+		if !reflect.DeepEqual(morph, test.expectedMorph) {
+			t.Errorf("Without %s: got morph templates of %v, expected %v", test.group, morph, test.expectedMorph)
+		}
+		if ablated.HashBits != setup.HashBits {
+			t.Errorf("Without %s: got hash bits %d, expected %d", test.group, ablated.HashBits, setup.HashBits)
+		}
+		if !reflect.DeepEqual(setup, testAblateSetup()) {
+			t.Errorf("Without %s: the base setup was modified", test.group)
+		}
+	}
+}
+
+func TestAttachmentScores(t *testing.T) {
+	row := func(head int, rel string) conll.Row {
+		return conll.Row{Head: head, DepRel: rel}
+	}
+	gold := []conll.Sentence{
+		{1: row(2, "subj"), 2: row(0, "ROOT"), 3: row(2, "obj")},
+		{1: row(0, "ROOT"), 2: row(1, "obj")},
+	}
+	tests := []struct {
+		name     string
+		parsed   []conll.Sentence
+		uas, las float64
+		fails    bool
+	}{
+		{"gold", gold, 1, 1, false},
+		{"wrong label",
+			[]conll.Sentence{
+				{1: row(2, "obj"), 2: row(0, "ROOT"), 3: row(2, "obj")},
+				{1: row(0, "ROOT"), 2: row(1, "obj")},
+			}, 1, 0.8, false},
+		{"wrong head",
+			[]conll.Sentence{
+				{1: row(3, "subj"), 2: row(0, "ROOT"), 3: row(2, "obj")},
+				{1: row(0, "ROOT"), 2: row(1, "subj")},
+			}, 0.8, 0.6, false},
+		{"missing token",
+			[]conll.Sentence{
+				{1: row(2, "subj"), 2: row(0, "ROOT")},
+				{1: row(0, "ROOT"), 2: row(1, "obj")},
+			}, 0.8, 0.8, false},
+		{"missing sentence", gold[:1], 0, 0, true},
+		{"extra sentence", append(append([]conll.Sentence{}, gold...), gold[0]), 0, 0, true},
+	}
+	for _, test := range tests {
+		uas, las, err := attachmentScores(test.parsed, gold)
+		if (err != nil) != test.fails {
+			t.Errorf("%s: got error %v, expected failure %v", test.name, err, test.fails)
+			continue
+		}
+		if uas != test.uas || las != test.las {
+			t.Errorf("%s: got UAS %v LAS %v, expected %v %v", test.name, uas, las, test.uas, test.las)
+		}
+	}
+	if _, _, err := attachmentScores([]conll.Sentence{{}}, []conll.Sentence{{}}); err == nil {
+		t.Errorf("Expected an error scoring sentences without gold tokens")
+	}
+}
+
+func TestAblateParallel(t *testing.T) {
+	defer func(parallel, runCPUs, cpus int) {
+		ablParallel, ablCPUs, CPUs = parallel, runCPUs, cpus
+	}(ablParallel, ablCPUs, CPUs)
+	tests := []struct {
+		parallel, runCPUs, cpus int
+		expected                int
+	}{
+		{4, 1, 8, 4},
+		{4, 1, 2, 2},
+		{4, 2, 8, 4},
+		{4, 3, 8, 2},
+		{4, 4, 2, 1},
+		{0, 1, 8, 1},
+		{4, 0, 2, 4},
+	}
+	for _, test := range tests {
+		ablParallel, ablCPUs, CPUs = test.parallel, test.runCPUs, test.cpus
+		if parallel := ablateParallel(); parallel != test.expected {
+			t.Errorf("-j %d -runcpus %d with %d CPUs: got %d parallel runs, expected %d", test.parallel, test.runCPUs, test.cpus, parallel, test.expected)
+		}
+	}
+}
@@ -28,9 +28,13 @@ var AppCommands = []*commander.Command{
 	DepCmd(),
 	MdCmd(),
 	JointCmd(),
-	//MALearnCmd(),
+	MALearnCmd(),
 	MACmd(),
 	HebMACmd(),
+	ModelCmd(),
+	FeaturesCmd(),
+	LexCmd(),
+	JackknifeCmd(),
 	// ValidateMAGoldCmd(),
 	// GenLemmasCmd(),
 	// GenUnAmbLemmasCmd(),
@@ -45,11 +49,22 @@ func AllCommands() *commander.Command {
 		Flag:        *flag.NewFlagSet("app", flag.ExitOnError),
 	}
 	for _, app := range cmd.Subcommands {
+		wrapAppCommand(app)
+	}
+	return cmd
+}
+
+// wrapAppCommand wraps a runnable command and adds the common flags,
+// recursing into command groups (e.g. model average)
+func wrapAppCommand(app *commander.Command) {
+	if app.Run != nil {
 		app.Run = NewAppWrapCommand(app.Run)
 		app.Flag.IntVar(&CPUs, NUM_CPUS_FLAG, 0, "Max CPUS to use (runtime.GOMAXPROCS); 0 = all")
 		app.Flag.StringVar(&CPUProfile, "cpuprofile", "", "write cpu profile to file")
 	}
-	return cmd
+	for _, sub := range app.Subcommands {
+		wrapAppCommand(sub)
+	}
 }
 
 func InitCommand() {
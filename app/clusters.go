@@ -0,0 +1,100 @@
+package app
+
+import (
+	"yap/nlp/format/clusters"
+
+	"log"
+)
+
+const (
+	// k-means clustering of word2vec vectors
+	KMEANS_ITERATIONS       = 50
+	KMEANS_SEED       int64 = 1
+)
+
+var (
+	// Brown clusters (wcluster paths) file of the c attributes
+	BrownFile string
+	// word2vec text file clustered for the k attribute
+	W2VFile string
+	// number of k-means clusters of the word2vec vectors
+	W2VClusters int = 256
+
+	// clusters of the model being trained or parsed with, nil if it has none
+	Clusters *clusters.Set
+)
+
+// LoadClusters reads the clusters given by flags as the clusters of the
+// model being trained. When fine-tuning, the clusters of the initial model
+// are used instead (see LoadInitModel), so the flags are not read.
+func LoadClusters() {
+	if (BrownFile == "" && W2VFile == "") || InitModelFile != "" {
+		return
+	}
+	set := &clusters.Set{}
+	if BrownFile != "" {
+		brown, err := clusters.ReadBrownFile(BrownFile)
+		if err != nil {
+			log.Fatalln("Failed reading Brown clusters from", BrownFile, err)
+		}
+		set.Brown = brown
+	}
+	if W2VFile != "" {
+		if W2VClusters < 1 {
+			log.Fatalln("Number of word2vec clusters must be positive, got", W2VClusters)
+		}
+		kmeans, err := clusters.ReadWord2VecFile(W2VFile, W2VClusters, KMEANS_ITERATIONS, KMEANS_SEED)
+		if err != nil {
+			log.Fatalln("Failed reading word2vec vectors from", W2VFile, err)
+		}
+		set.KMeans = kmeans
+	}
+	if allOut {
+		log.Printf("Brown clusters:\t\t%v", set.Brown)
+		log.Printf("K-means clusters:\t\t%v", set.KMeans)
+	}
+	Clusters = set
+}
+
+// flagClustersVersion returns the versions of the clusters given by flags,
+// without reading (and clustering) them, or nil if none are given
+func flagClustersVersion() *clusters.Set {
+	if BrownFile == "" && W2VFile == "" {
+		return nil
+	}
+	set := &clusters.Set{}
+	if BrownFile != "" {
+		digest, err := clusters.FileDigest(BrownFile)
+		if err != nil {
+			log.Fatalln("Failed reading Brown clusters from", BrownFile, err)
+		}
+		set.Brown = &clusters.Clusters{Digest: digest}
+	}
+	if W2VFile != "" {
+		digest, err := clusters.FileDigest(W2VFile)
+		if err != nil {
+			log.Fatalln("Failed reading word2vec vectors from", W2VFile, err)
+		}
+		set.KMeans = &clusters.Clusters{Digest: clusters.KMeansDigest(digest, W2VClusters, KMEANS_ITERATIONS, KMEANS_SEED)}
+	}
+	return set
+}
+
+// RestoreClusters makes the clusters a model was trained with the clusters
+// of the run, and returns them for the configurations of the model; features
+// of the model are only meaningful with the same clusters, so clusters given
+// by flags that differ are ignored
+func RestoreClusters(set *clusters.Set) *clusters.Set {
+	if flagSet := flagClustersVersion(); flagSet != nil && !set.Equal(flagSet) {
+		if set == nil {
+			log.Println("Warning: model was trained without clusters, ignoring the given clusters")
+		} else {
+			log.Println("Warning: model was trained with different clusters, using the clusters of the model")
+		}
+	}
+	if allOut && !parseOut && set != nil {
+		log.Printf("Model clusters: Brown %v, k-means %v", set.Brown, set.KMeans)
+	}
+	Clusters = set
+	return set
+}
@@ -13,7 +13,6 @@ import (
 	. "yap/nlp/parser/dependency/transition"
 	nlp "yap/nlp/types"
 	"yap/util"
-	"yap/util/conf"
 
 	"log"
 	"os"
@@ -30,8 +29,11 @@ var (
 	DepModelFile    string
 	//DepBeamSize   int
 	DepArcSystemStr string
+	DepParserStr    string
 )
 
+const DEFAULT_DEP_FEATURES = "zhangnivre2011.yaml"
+
 func SetupDepEnum(relations []string) {
 	SetupRelationEnum(relations)
 	SetupTransEnum(relations)
@@ -64,6 +66,19 @@ func DepConfigOut(outModelFile string, b search.Interface, t transition.Transiti
 	log.Printf("Beam Size:\t\t%d", BeamSize)
 	log.Printf("Beam Concurrent:\t%v", ConcurrentBeam)
 	log.Printf("Model file:\t\t%s", outModelFile)
+	if len(InitModelFile) > 0 {
+		log.Printf("Init model file:\t%s", InitModelFile)
+	}
+	if len(EnsembleModelFiles) > 0 {
+		log.Printf("Ensemble models:\t%s", EnsembleModelFiles)
+	}
+	if TrainSeed != 0 {
+		log.Printf("Shuffle Seed:\t\t%d", TrainSeed)
+	}
+	if len(PseudoProjStr) > 0 {
+		log.Printf("Pseudo-projective:\t%s", PseudoProjStr)
+	}
+	log.Printf("Repair:\t\t\t%s", RepairStr)
 	log.Printf("Use Lemmas:\t\t%v", !lattice.IGNORE_LEMMA)
 	log.Printf("Word Type:\t\t%v", conll.WORD_TYPE)
 
@@ -72,8 +87,7 @@ func DepConfigOut(outModelFile string, b search.Interface, t transition.Transiti
 	if !VerifyExists(DepFeaturesFile) {
 		os.Exit(1)
 	}
-	log.Printf("Labels File:\t\t%s", DepLabelsFile)
-	if !VerifyExists(DepLabelsFile) {
+	if !LabelsConfigOut() {
 		os.Exit(1)
 	}
 	log.Println()
@@ -102,25 +116,87 @@ func DepConfigOut(outModelFile string, b search.Interface, t transition.Transiti
 	}
 }
 
-func DepTrainAndParse(cmd *commander.Command, args []string) error {
-	// instantiate the arc system for config output only
-	// it will be reinstantiated later on with struct values
+// DepArcSystem returns the named arc system, set up with the transitions
+// and relations enumerated by SetupDepEnum
+func DepArcSystem(name string) transition.TransitionSystem {
+	switch name {
+	case "standard":
+		return &ArcStandard{
+			SHIFT:       SH.Value(),
+			LEFT:        LA.Value(),
+			RIGHT:       RA.Value(),
+			Transitions: ETrans,
+			Relations:   ERel,
+		}
+	case "swap":
+		return &ArcSwap{
+			ArcStandard: ArcStandard{
+				SHIFT:       SH.Value(),
+				LEFT:        LA.Value(),
+				RIGHT:       RA.Value(),
+				Relations:   ERel,
+				Transitions: ETrans,
+			},
+			SWAP: SW.Value(),
+		}
+	case "hybrid":
+		return &ArcHybrid{
+			ArcStandard: ArcStandard{
+				SHIFT:       SH.Value(),
+				LEFT:        LA.Value(),
+				RIGHT:       RA.Value(),
+				Relations:   ERel,
+				Transitions: ETrans,
+			},
+		}
+	case "eager":
+		return &ArcEager{
+			ArcStandard: ArcStandard{
+				SHIFT:       SH.Value(),
+				LEFT:        LA.Value(),
+				RIGHT:       RA.Value(),
+				Relations:   ERel,
+				Transitions: ETrans,
+			},
+			REDUCE:  RE.Value(),
+			POPROOT: PR.Value(),
+		}
+	default:
+		panic("Unknown arc system")
+	}
+}
 
-	var (
-		arcSystem     transition.TransitionSystem
-		terminalStack int
-	)
-	switch DepArcSystemStr {
+// depArcSystemStub returns the named arc system before the enumerations are
+// set up (see DepArcSystem), and the stack size of its terminal configurations
+func depArcSystemStub(name string) (transition.TransitionSystem, int) {
+	switch name {
 	case "standard":
-		arcSystem = &ArcStandard{}
-		terminalStack = 1
+		return &ArcStandard{}, 1
+	case "swap":
+		return &ArcSwap{}, 1
+	case "hybrid":
+		return &ArcHybrid{}, 1
 	case "eager":
-		arcSystem = &ArcEager{}
-		terminalStack = 0
+		return &ArcEager{}, 0
 	default:
 		panic("Unknown arc system")
 	}
+}
+
+func DepTrainAndParse(cmd *commander.Command, args []string) error {
+	RepairStrategy()
+	switch DepParserStr {
+	case "transition":
+	case "mst":
+		return MSTTrainAndParse(cmd, args)
+	default:
+		log.Fatalln("Unknown parser", DepParserStr)
+	}
+	SetupSeed(false)
+	// instantiate the arc system for config output only
+	// it will be reinstantiated later on with struct values
 
+	arcSystem, terminalStack := depArcSystemStub(DepArcSystemStr)
 	arcSystem.AddDefaultOracle()
 
 	transitionSystem := transition.TransitionSystem(arcSystem)
@@ -135,7 +211,7 @@ func DepTrainAndParse(cmd *commander.Command, args []string) error {
 	labelsLocation, found := util.LocateFile(DepLabelsFile, DEFAULT_CONF_DIRS)
 	if found {
 		DepLabelsFile = labelsLocation
-	} else {
+	} else if !DepLabelsAuto || DepLabelsMerge {
 		REQUIRED_FLAGS = append(REQUIRED_FLAGS, "l")
 	}
 	if VerifyExists(inputLat) {
@@ -159,53 +235,55 @@ func DepTrainAndParse(cmd *commander.Command, args []string) error {
 		log.Println("Pre-trained model not found in default directories, looking for", outModelFile)
 		modelExists = VerifyExists(outModelFile)
 	}
+	if err := checkInitModel(InitModelFile, outModelFile, modelExists); err != nil {
+		log.Fatalln(err)
+	}
 	if !modelExists {
 		log.Println("No model found, training")
 		REQUIRED_FLAGS = []string{"it", "tc"}
 		VerifyFlags(cmd, REQUIRED_FLAGS)
+		LoadClusters()
 	}
 	if allOut && !parseOut {
 		DepConfigOut(outModelFile, &search.Beam{}, transitionSystem)
 	}
 	// modelExists := false
-	relations, err := conf.ReadFile(DepLabelsFile)
-	if err != nil {
-		log.Println("Failed reading dependency labels configuration file:", DepLabelsFile)
-		log.Fatalln(err)
-	}
+	relations := ReadRelations(!modelExists)
 	if allOut && !parseOut {
 		log.Println()
 		// start processing - setup enumerations
 		log.Println("Setup enumerations")
 	}
-	SetupDepEnum(relations.Values)
+	SetupDepEnum(relations)
+	var initModel *transitionmodel.AvgMatrixSparse
+	if !modelExists && InitModelFile != "" {
+		initModel = LoadInitModel(InitModelFile, false)
+	}
+	var (
+		goldGraphs    []interface{}
+		serialization *Serialization
+	)
+	if PseudoProjStr != "" {
+		if modelExists {
+			// the transitions of the model include the encoded labels of its training data
+			serialization = ReadModel(outModelFile)
+			RestorePseudoProjectiveRelations(serialization.ETrans, relations, SetupTransEnum)
+		} else {
+			if allOut {
+				log.Println("Reading training sentences from", tConll, "for pseudo-projective labels")
+			}
+			graphs, e := ReadGoldGraphs(tConll, false)
+			if e != nil {
+				log.Println(e)
+				return e
+			}
+			goldGraphs = PseudoProjectivize(graphs, relations, SetupTransEnum)
+		}
+	}
 
 	// after calling SetupDepEnum, enums are instantiated and set according to the relations
 	// therefore we re-instantiate the arc system with the right parameters
-	switch DepArcSystemStr {
-	case "standard":
-		arcSystem = &ArcStandard{
-			SHIFT:       SH.Value(),
-			LEFT:        LA.Value(),
-			RIGHT:       RA.Value(),
-			Transitions: ETrans,
-			Relations:   ERel,
-		}
-	case "eager":
-		arcSystem = &ArcEager{
-			ArcStandard: ArcStandard{
-				SHIFT:       SH.Value(),
-				LEFT:        LA.Value(),
-				RIGHT:       RA.Value(),
-				Relations:   ERel,
-				Transitions: ETrans,
-			},
-			REDUCE:  RE.Value(),
-			POPROOT: PR.Value(),
-		}
-	default:
-		panic("Unknown arc system")
-	}
+	arcSystem = DepArcSystem(DepArcSystemStr)
 
 	arcSystem.AddDefaultOracle()
 
@@ -217,10 +295,6 @@ func DepTrainAndParse(cmd *commander.Command, args []string) error {
 	}
 
 	// features, err := conf.ReadFile(featuresFile)
-	if err != nil {
-		log.Println("Failed reading feature configuration file:", DepFeaturesFile)
-		log.Fatalln(err)
-	}
 	featureSetup, err := transition.LoadFeatureConfFile(DepFeaturesFile)
 	if err != nil {
 		log.Println("Failed reading feature configuration file:", DepFeaturesFile)
@@ -237,6 +311,7 @@ func DepTrainAndParse(cmd *commander.Command, args []string) error {
 	var (
 		sents       []interface{}
 		sentsStream chan interface{}
+		parseModel  transitionmodel.Interface
 	)
 	if !modelExists {
 		var (
@@ -287,31 +362,13 @@ func DepTrainAndParse(cmd *commander.Command, args []string) error {
 			log.Println("Generating Gold Sequences For Training")
 			log.Println("Reading training sentences from", tConll)
 		}
-		var goldGraphs []interface{}
-		if useConllU {
-			s, _, e := conllu.ReadFile(tConll, limit)
-			if e != nil {
-				log.Println(e)
-				return e
-			}
-			if allOut {
-				log.Println("Conll:\tRead", len(s), "sentences")
-				log.Println("Conll:\tConverting from conll to internal structure")
-			}
-			goldGraphs = conllu.ConllU2GraphCorpus(s, EWord, EPOS, EWPOS, ERel, EMHost, EMSuffix)
-			//goldMorphGraphs = conllu.ConllU2MorphGraphCorpus(s, EWord, EPOS, EWPOS, ERel, EMorphProp, EMHost, EMSuffix)
-
-		} else {
-			s, e := conll.ReadFile(tConll, limit)
+		if goldGraphs == nil {
+			var e error
+			goldGraphs, e = ReadGoldGraphs(tConll, false)
 			if e != nil {
 				log.Println(e)
 				return e
 			}
-			if allOut {
-				log.Println("Conll:\tRead", len(s), "sentences")
-				log.Println("Conll:\tConverting from conll to internal structure")
-			}
-			goldGraphs = conll.Conll2GraphCorpus(s, EWord, EPOS, EWPOS, ERel, EMHost, EMSuffix)
 		}
 		if allOut {
 			log.Println()
@@ -325,7 +382,12 @@ func DepTrainAndParse(cmd *commander.Command, args []string) error {
 			log.Println()
 			log.Println("Training", Iterations, "iteration(s)")
 		}
-		model = transitionmodel.NewAvgMatrixSparse(featureSetup.NumFeatures(), formatters, true)
+		if initModel != nil {
+			VerifyInitModel(initModel, featureSetup.NumFeatures(), formatters)
+			model = initModel
+		} else {
+			model = transitionmodel.NewAvgMatrixSparse(featureSetup.NumFeatures(), formatters, true)
+		}
 		// model.Log = true
 
 		conf := &SimpleConfiguration{
@@ -336,6 +398,7 @@ func DepTrainAndParse(cmd *commander.Command, args []string) error {
 			EMSuffix:      EMSuffix,
 			ERel:          ERel,
 			ETrans:        ETrans,
+			Clusters:      Clusters,
 			TerminalStack: terminalStack,
 			TerminalQueue: 0,
 		}
@@ -448,6 +511,7 @@ func DepTrainAndParse(cmd *commander.Command, args []string) error {
 		serialization := &Serialization{
 			model.Serialize(-1),
 			EWord, EPOS, EWPOS, EMHost, EMSuffix, EMorphProp, ETrans, ETokens,
+			Clusters,
 		}
 		WriteModel(outModelFile, serialization)
 		if allOut {
@@ -457,14 +521,24 @@ func DepTrainAndParse(cmd *commander.Command, args []string) error {
 		if allOut && !parseOut {
 			log.Println("Found model file", outModelFile, " ... loading model")
 		}
-		serialization := ReadModel(outModelFile)
+		if serialization == nil {
+			serialization = ReadModel(outModelFile)
+		}
+		RestoreClusters(serialization.Clusters)
 		model.Deserialize(serialization.WeightModel)
 		EWord, EPOS, EWPOS, EMHost, EMSuffix = serialization.EWord, serialization.EPOS, serialization.EWPOS, serialization.EMHost, serialization.EMSuffix
 		if allOut && !parseOut {
 			log.Println("Loaded model")
 		}
+		if EnsembleModelFiles != "" {
+			parseModel = LoadEnsemble(outModelFile, extractor, []byte("A"))
+		}
 		// model.Log = true
 	}
+	// the model is replaced when training, so the parse model is set last
+	if parseModel == nil {
+		parseModel = model
+	}
 	if allOut {
 		log.Println()
 	}
@@ -478,80 +552,29 @@ func DepTrainAndParse(cmd *commander.Command, args []string) error {
 	//
 	// model.Formatters = formatters
 	// sents = sents[:NUM_SENTS]
-	var asMorphGraphs, asGraphs []interface{}
-	if len(inputLat) > 0 {
-		if Stream {
-			lDisamb, lDisambE := lattice.StreamFile(inputLat, limit)
-			if lDisambE != nil {
-				log.Fatalln(lDisambE)
-			}
-			if allOut {
-				log.Println("Streaming lattice conversion to sentence")
-			}
-			internalStream := lattice.Lattice2SentenceStream(lDisamb, EWord, EPOS, EWPOS, EMorphProp, EMHost, EMSuffix)
-			sentsStream = make(chan interface{}, 2)
-			if allOut {
-				log.Println("Streaming sentence conversion to taggged sentence")
-			}
-			go func() {
-				for instance := range internalStream {
-					converted := instance.(nlp.LatticeSentence).TaggedSentence()
-					sentsStream <- converted
-				}
-				close(sentsStream)
-			}()
-		} else {
-			lDisamb, lDisambE := lattice.ReadFile(inputLat, limit)
-			if lDisambE != nil {
-				log.Fatalln(lDisambE)
-			}
-			if allOut {
-				log.Println("Read", len(lDisamb), "disambiguated lattices from", inputLat)
-				log.Println("Converting lattice format to TaggedSentence internal structure")
-				log.Println("\tlattice format to sentence")
-			}
-			internalSents := lattice.Lattice2SentenceCorpus(lDisamb, EWord, EPOS, EWPOS, EMorphProp, EMHost, EMSuffix)
-			if allOut {
-				log.Println("\tsentence to TaggedSentence")
-			}
-			sents = make([]interface{}, len(internalSents))
-			for i, instance := range internalSents {
-				sents[i] = instance.(nlp.LatticeSentence).TaggedSentence()
-			}
+	var asMorphGraphs []interface{}
+	if len(inputLat) > 0 && Stream {
+		lDisamb, lDisambE := lattice.StreamFile(inputLat, limit)
+		if lDisambE != nil {
+			log.Fatalln(lDisambE)
 		}
-	} else {
-		if useConllU {
-			devi, _, e2 := conllu.ReadFile(input, limit)
-			if e2 != nil {
-				log.Fatalln(e2)
-			}
-			// const NUM_SENTS = 20
-
-			// s = s[:NUM_SENTS]
-			if allOut {
-				log.Println("Read", len(devi), "sentences from", input)
-				log.Println("Converting from conllu to internal format")
-			}
-			asGraphs = conllu.ConllU2GraphCorpus(devi, EWord, EPOS, EWPOS, ERel, EMHost, EMSuffix)
-			asMorphGraphs = conllu.ConllU2MorphGraphCorpus(devi, EWord, EPOS, EWPOS, ERel, EMorphProp, EMHost, EMSuffix)
-		} else {
-			devi, e2 := conll.ReadFile(input, limit)
-			if e2 != nil {
-				log.Fatalln(e2)
-			}
-			// const NUM_SENTS = 20
-
-			// s = s[:NUM_SENTS]
-			if allOut {
-				log.Println("Read", len(devi), "sentences from", input)
-				log.Println("Converting from conll to internal format")
-			}
-			asGraphs = conll.Conll2GraphCorpus(devi, EWord, EPOS, EWPOS, ERel, EMHost, EMSuffix)
+		if allOut {
+			log.Println("Streaming lattice conversion to sentence")
 		}
-		sents = make([]interface{}, len(asGraphs))
-		for i, instance := range asGraphs {
-			sents[i] = GetAsTaggedSentence(instance)
+		internalStream := lattice.Lattice2SentenceStream(lDisamb, EWord, EPOS, EWPOS, EMorphProp, EMHost, EMSuffix)
+		sentsStream = make(chan interface{}, 2)
+		if allOut {
+			log.Println("Streaming sentence conversion to taggged sentence")
 		}
+		go func() {
+			for instance := range internalStream {
+				converted := instance.(nlp.LatticeSentence).TaggedSentence()
+				sentsStream <- converted
+			}
+			close(sentsStream)
+		}()
+	} else {
+		sents, asMorphGraphs = ReadDepInput()
 	}
 
 	conf := &SimpleConfiguration{
@@ -562,6 +585,7 @@ func DepTrainAndParse(cmd *commander.Command, args []string) error {
 		EMSuffix:      EMSuffix,
 		ERel:          ERel,
 		ETrans:        ETrans,
+		Clusters:      Clusters,
 		TerminalStack: terminalStack,
 		TerminalQueue: 0,
 	}
@@ -570,7 +594,7 @@ func DepTrainAndParse(cmd *commander.Command, args []string) error {
 		TransFunc:            transitionSystem,
 		FeatExtractor:        extractor,
 		Base:                 conf,
-		Model:                model,
+		Model:                parseModel,
 		Size:                 BeamSize,
 		ConcurrentExec:       ConcurrentBeam,
 		ShortTempAgenda:      true,
@@ -583,12 +607,17 @@ func DepTrainAndParse(cmd *commander.Command, args []string) error {
 			log.Println("Starting parser")
 		}
 		go ParseStream(sentsStream, parsedStream, beam)
+		if PseudoProjStr != "" {
+			parsedStream = DeprojectivizeStream(parsedStream)
+		}
+		parsedStream, report := ValidateStream(parsedStream, beam)
 		log.Println("Streaming conversion to conll")
 		graphAsConllStream := conll.Graph2ConllStream(parsedStream, EMHost, EMSuffix)
 		if allOut {
 			log.Println("Creating writer stream to", outConll)
 		}
 		conll.WriteStreamToFile(outConll, graphAsConllStream)
+		LogValidation(report)
 		return nil
 	}
 	if allOut {
@@ -601,6 +630,10 @@ func DepTrainAndParse(cmd *commander.Command, args []string) error {
 		}
 
 		parsedGraphs := Parse(sents, beam)
+		if PseudoProjStr != "" {
+			parsedGraphs = Deprojectivize(parsedGraphs)
+		}
+		parsedGraphs = ValidateGraphs(parsedGraphs, beam)
 		if !parseOut {
 			log.Println("Converting to conll")
 		}
@@ -627,6 +660,10 @@ func DepTrainAndParse(cmd *commander.Command, args []string) error {
 		log.SetFlags(0)
 		log.Print("Parsing started")
 		parsedGraphs := Parse(sents, beam)
+		if PseudoProjStr != "" {
+			parsedGraphs = Deprojectivize(parsedGraphs)
+		}
+		parsedGraphs = ValidateGraphs(parsedGraphs, beam)
 		graphAsConll := conll.Graph2ConllCorpus(parsedGraphs, EMHost, EMSuffix)
 		conll.WriteFile(outConll, graphAsConll)
 		log.Println("Wrote", len(parsedGraphs), "in conll format to", outConll)
@@ -634,6 +671,65 @@ func DepTrainAndParse(cmd *commander.Command, args []string) error {
 	return nil
 }
 
+// ReadDepInput reads the sentences to parse from the input lattice or
+// CoNLL(-U) file, with the morphological graphs of CoNLL-U input
+func ReadDepInput() (sents []interface{}, asMorphGraphs []interface{}) {
+	if len(inputLat) > 0 {
+		lDisamb, lDisambE := lattice.ReadFile(inputLat, limit)
+		if lDisambE != nil {
+			log.Fatalln(lDisambE)
+		}
+		if allOut {
+			log.Println("Read", len(lDisamb), "disambiguated lattices from", inputLat)
+			log.Println("Converting lattice format to TaggedSentence internal structure")
+			log.Println("\tlattice format to sentence")
+		}
+		internalSents := lattice.Lattice2SentenceCorpus(lDisamb, EWord, EPOS, EWPOS, EMorphProp, EMHost, EMSuffix)
+		if allOut {
+			log.Println("\tsentence to TaggedSentence")
+		}
+		sents = make([]interface{}, len(internalSents))
+		for i, instance := range internalSents {
+			sents[i] = instance.(nlp.LatticeSentence).TaggedSentence()
+		}
+		return
+	}
+	var asGraphs []interface{}
+	if useConllU {
+		devi, _, e2 := conllu.ReadFile(input, limit)
+		if e2 != nil {
+			log.Fatalln(e2)
+		}
+		// const NUM_SENTS = 20
+
+		// s = s[:NUM_SENTS]
+		if allOut {
+			log.Println("Read", len(devi), "sentences from", input)
+			log.Println("Converting from conllu to internal format")
+		}
+		asGraphs = conllu.ConllU2GraphCorpus(devi, EWord, EPOS, EWPOS, ERel, EMHost, EMSuffix)
+		asMorphGraphs = conllu.ConllU2MorphGraphCorpus(devi, EWord, EPOS, EWPOS, ERel, EMorphProp, EMHost, EMSuffix)
+	} else {
+		devi, e2 := conll.ReadFile(input, limit)
+		if e2 != nil {
+			log.Fatalln(e2)
+		}
+		// const NUM_SENTS = 20
+
+		// s = s[:NUM_SENTS]
+		if allOut {
+			log.Println("Read", len(devi), "sentences from", input)
+			log.Println("Converting from conll to internal format")
+		}
+		asGraphs = conll.Conll2GraphCorpus(devi, EWord, EPOS, EWPOS, ERel, EMHost, EMSuffix)
+	}
+	sents = make([]interface{}, len(asGraphs))
+	for i, instance := range asGraphs {
+		sents[i] = GetAsTaggedSentence(instance)
+	}
+	return
+}
+
 func DepCmd() *commander.Command {
 	cmd := &commander.Command{
 		Run:       DepTrainAndParse,
@@ -642,7 +738,11 @@ func DepCmd() *commander.Command {
 		Long: `
 runs dependency training/parsing
 
-	$ ./yap dep -f <features> -l <labels> -tc <conll> -in <input tagged> -oc <out conll> [-a eager|standard] [options]
+	$ ./yap dep -f <features> -l <labels> -tc <conll> -in <input tagged> -oc <out conll> [-a eager|standard|swap|hybrid] [options]
+
+or with the graph-based parser
+
+	$ ./yap dep -parser mst -l <labels> -tc <conll> -in <input tagged> -oc <out conll> [-proj] [options]
 
 `,
 		Flag: *flag.NewFlagSet("dep", flag.ExitOnError),
@@ -652,7 +752,14 @@ runs dependency training/parsing
 	cmd.Flag.IntVar(&BeamSize, "b", 64, "Dependency Beam Size")
 	cmd.Flag.StringVar(&DepModelFile, "m", "model", "Prefix for model file ({m}.b{b}.i{it}.model)")
 	cmd.Flag.StringVar(&DepModelName, "mn", "dep.b64", "Modelfile")
-	cmd.Flag.StringVar(&DepArcSystemStr, "a", "eager", "Optional - Arc System [standard, eager]")
+	cmd.Flag.StringVar(&DepArcSystemStr, "a", "eager", "Optional - Arc System [standard, eager, swap, hybrid]")
+	cmd.Flag.StringVar(&DepParserStr, "parser", "transition", "Optional - Parser [transition, mst]")
+	cmd.Flag.BoolVar(&MSTProjective, "proj", false, "Optional - Decode projective trees (Eisner) with the mst parser")
+	cmd.Flag.StringVar(&PseudoProjStr, "pp", "", "Optional - Pseudo-projective label encoding of non-projective arcs [head, path, headpath]")
+	cmd.Flag.StringVar(&RepairStr, "repair", "none", "Optional - Repair parsed graphs that are not well formed trees [none, root, score (keep the highest scored root)]")
+	cmd.Flag.StringVar(&InitModelFile, "init", "", "Optional - Initialize training from an existing model (fine-tuning), requires that the output model does not exist")
+	cmd.Flag.StringVar(&EnsembleModelFiles, "ensemble", "", "Optional - Comma separated models to ensemble with the model when parsing")
+	cmd.Flag.Int64Var(&TrainSeed, "seed", 0, "Optional - Shuffle training instances every iteration with this seed, and train deterministically (0 = file order)")
 
 	cmd.Flag.StringVar(&tConll, "tc", "", "Training Conll File")
 	cmd.Flag.StringVar(&input, "in", "", "Dev Tagged Sentences File")
@@ -660,8 +767,15 @@ runs dependency training/parsing
 	cmd.Flag.StringVar(&inputGold, "ing", "", "Optional - Dev Gold Parsed Sentences (for convergence)")
 	cmd.Flag.StringVar(&test, "test", "", "Test Conll File")
 	cmd.Flag.StringVar(&outConll, "oc", "", "Output Conll File")
-	cmd.Flag.StringVar(&DepFeaturesFile, "f", "zhangnivre2011.yaml", "Features Configuration File")
+	cmd.Flag.StringVar(&DepFeaturesFile, "f", DEFAULT_DEP_FEATURES, "Features Configuration File")
 	cmd.Flag.StringVar(&DepLabelsFile, "l", "hebtb.labels.conf", "Dependency Labels Configuration File")
+	cmd.Flag.BoolVar(&DepLabelsAuto, "lauto", false, "Optional - Derive the dependency labels from the training file")
+	cmd.Flag.IntVar(&DepLabelsMin, "lmin", 1, "Optional - Minimal count of derived labels, rarer labels are trained as dep (with -lauto)")
+	cmd.Flag.BoolVar(&DepLabelsMerge, "lmerge", false, "Optional - Merge the derived labels with the labels file (with -lauto)")
+	cmd.Flag.StringVar(&DepLabelsOut, "lout", "", "Optional - Write the dependency labels to a file")
+	cmd.Flag.StringVar(&BrownFile, "brown", "", "Optional - Brown clusters file (wcluster paths) for cluster attributes (c, c<N>)")
+	cmd.Flag.StringVar(&W2VFile, "w2v", "", "Optional - word2vec text file clustered with k-means for the cluster id attribute (k)")
+	cmd.Flag.IntVar(&W2VClusters, "w2vk", 256, "Optional - Number of k-means clusters of the word2vec vectors")
 	//cmd.Flag.BoolVar(&conll.IGNORE_LEMMA, "nolemma", false, "Ignore lemmas")
 	cmd.Flag.BoolVar(&lattice.IGNORE_LEMMA, "nolemma", true, "Ignore lemmas")
 	cmd.Flag.StringVar(&conll.WORD_TYPE, "wordtype", "form", "Word type [form, lemma, lemma+f (=lemma if present else form)]")
@@ -669,6 +783,7 @@ runs dependency training/parsing
 	cmd.Flag.BoolVar(&search.SHOW_ORACLE, "showoracle", false, "Show oracle transitions")
 	cmd.Flag.BoolVar(&search.AllOut, "showbeam", false, "Show candidates in beam")
 	cmd.Flag.BoolVar(&useConllU, "conllu", false, "use CoNLL-U-format input file (for disamb lattices)")
+	cmd.Flag.BoolVar(&conllu.ENHANCED, "enhanced", false, "Optional - Write enhanced UD dependencies to the DEPS column of CoNLL-U output")
 	cmd.Flag.BoolVar(&Stream, "stream", false, "Stream data from input through parser to output")
 	return cmd
 }
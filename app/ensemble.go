@@ -0,0 +1,419 @@
+package app
+
+import (
+	"yap/alg/transition"
+	transitionmodel "yap/alg/transition/model"
+	nlp "yap/nlp/types"
+	"yap/util"
+
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/gonuts/commander"
+	"github.com/gonuts/flag"
+)
+
+var (
+	// comma separated list of models to ensemble with the main model when parsing
+	EnsembleModelFiles string
+
+	avgModelType, avgFeaturesFile, avgOutModel string
+)
+
+// Feature attributes whose values are enumerated, by the enumeration used.
+// This mirrors the attributes decoded by transition.FeatureTemplate's formatter.
+var attributeEnums = map[string]string{
+	"w":  "word",
+	"m":  "word",
+	"p":  "pos",
+	"fp": "pos",
+	"wp": "wpos",
+	"mp": "wpos",
+	"h":  "mhost",
+	"s":  "msuffix",
+	"x":  "msuffix",
+	"f":  "morphprop",
+}
+
+// feature groups (transition types) of the models of each app
+var modelTypeGroups = map[string][]byte{
+	"dep":   []byte("A"),
+	"md":    []byte("MPL"),
+	"joint": []byte("MPLA"),
+}
+
+func serializationEnums(s *Serialization) map[string]*util.EnumSet {
+	return map[string]*util.EnumSet{
+		"word":      s.EWord,
+		"pos":       s.EPOS,
+		"wpos":      s.EWPOS,
+		"mhost":     s.EMHost,
+		"msuffix":   s.EMSuffix,
+		"morphprop": s.EMorphProp,
+		"trans":     s.ETrans,
+		"tokens":    s.ETokens,
+	}
+}
+
+// mergeEnum adds all values of enum to merged, returning the mapping of
+// enum's indices to merged's indices, and whether the mapping is the identity
+func mergeEnum(merged, enum *util.EnumSet) ([]int, bool) {
+	if enum == nil {
+		return nil, true
+	}
+	identity := true
+	mapping := make([]int, enum.Len())
+	for i := 0; i < enum.Len(); i++ {
+		mapping[i], _ = merged.Add(enum.ValueOf(i))
+		identity = identity && mapping[i] == i
+	}
+	return mapping, identity
+}
+
+func mapIndex(mapping []int, index int) int {
+	if index >= 0 && index < len(mapping) {
+		return mapping[index]
+	}
+	return index
+}
+
+func remapAttribute(mapping []int, value interface{}) interface{} {
+	if mapping == nil {
+		return value
+	}
+	switch v := value.(type) {
+	case int:
+		return mapIndex(mapping, v)
+	case []interface{}:
+		retval := make([]interface{}, len(v))
+		for i, val := range v {
+			retval[i] = remapAttribute(mapping, val)
+		}
+		return retval
+	case []int:
+		retval := make([]int, len(v))
+		for i, val := range v {
+			retval[i] = mapIndex(mapping, val)
+		}
+		return retval
+	case [2]int:
+		for i, val := range v {
+			v[i] = mapIndex(mapping, val)
+		}
+		return v
+	case [3]int:
+		for i, val := range v {
+			v[i] = mapIndex(mapping, val)
+		}
+		return v
+	case [4]int:
+		for i, val := range v {
+			v[i] = mapIndex(mapping, val)
+		}
+		return v
+	case [5]int:
+		for i, val := range v {
+			v[i] = mapIndex(mapping, val)
+		}
+		return v
+	case [6]int:
+		for i, val := range v {
+			v[i] = mapIndex(mapping, val)
+		}
+		return v
+	case [7]int:
+		for i, val := range v {
+			v[i] = mapIndex(mapping, val)
+		}
+		return v
+	}
+	return value
+}
+
+// remapFeature maps the enumerated values of a feature of a template
+func remapFeature(tmpl *transition.FeatureTemplate, value interface{}, enumMaps map[string][]int) interface{} {
+	attributes := make([]string, 0, len(tmpl.Elements))
+	for _, element := range tmpl.Elements {
+		for _, attr := range element.Attributes {
+			attributes = append(attributes, string(attr))
+		}
+	}
+	if len(attributes) == 1 {
+		return remapAttribute(enumMaps[attributeEnums[attributes[0]]], value)
+	}
+	remap := func(values []interface{}) {
+		for i, val := range values {
+			if i < len(attributes) {
+				values[i] = remapAttribute(enumMaps[attributeEnums[attributes[i]]], val)
+			}
+		}
+	}
+	// v is a copy of the feature array, remap it in place and return it
+	switch v := value.(type) {
+	case [2]interface{}:
+		remap(v[:])
+		return v
+	case [3]interface{}:
+		remap(v[:])
+		return v
+	case [4]interface{}:
+		remap(v[:])
+		return v
+	case [5]interface{}:
+		remap(v[:])
+		return v
+	case [6]interface{}:
+		remap(v[:])
+		return v
+	case [7]interface{}:
+		remap(v[:])
+		return v
+	case [8]interface{}:
+		remap(v[:])
+		return v
+	}
+	// features of more than 8 attributes are joined strings
+	return value
+}
+
+// transitionGroup returns the feature group (transition type) of a transition
+// given its enumerated name. Lemma transitions share the enumeration with
+// morphological ones and can't be told apart, so they are treated as the latter.
+func transitionGroup(name interface{}, groups []byte) byte {
+	if len(groups) == 1 {
+		return groups[0]
+	}
+	var group byte = 'M'
+	str := fmt.Sprintf("%v", name)
+	switch {
+	case str == "POP":
+		group = 'P'
+	case str == "IDLE" || str == "NO" || str == "SH" || str == "SW" || str == "RE" || str == "AL" || str == "AR" || str == "PR",
+		strings.HasPrefix(str, "LA-"), strings.HasPrefix(str, "RA-"):
+		group = 'A'
+	}
+	for _, g := range groups {
+		if g == group {
+			return group
+		}
+	}
+	return groups[0]
+}
+
+// CombineModels reads several models trained with the same feature
+// configuration, merges their enumerations and maps the weights of each model
+// to the merged enumerations. The weights of all models are rescaled to the
+// largest number of training generations among them, so that models trained
+// on different amounts of data have comparable weights.
+// Returns the remapped models and a serialization holding the merged enumerations.
+func CombineModels(files []string, extractor *transition.GenericExtractor, groups []byte) ([]*transitionmodel.AvgMatrixSparse, *Serialization) {
+	var (
+		merged       *Serialization
+		mergedEnums  map[string]*util.EnumSet
+		models       = make([]*transitionmodel.AvgMatrixSparse, len(files))
+		maxGenerated int
+	)
+	for k, file := range files {
+		location, found := util.LocateFile(file, DEFAULT_MODEL_DIRS)
+		if !found {
+			log.Fatalln("Model file", file, "not found")
+		}
+		if allOut {
+			log.Println("Reading model", location)
+		}
+		serialization := ReadModel(location)
+		if serialization.WeightModel == nil {
+			log.Fatalln("Failed reading model from", location)
+		}
+		model := &transitionmodel.AvgMatrixSparse{}
+		model.Deserialize(serialization.WeightModel)
+		if k > 0 && len(model.Mat) != len(models[0].Mat) {
+			log.Fatalln("Model", location, "has", len(model.Mat), "features, expected", len(models[0].Mat))
+		}
+		if k > 0 && !serialization.Clusters.Equal(merged.Clusters) {
+			log.Fatalln("Model", location, "was trained with different clusters than", files[0])
+		}
+		if k == 0 {
+			// the first model's enumerations are the base of the merged enumerations
+			merged = &Serialization{}
+			*merged = *serialization
+			merged.WeightModel = nil
+			mergedEnums = serializationEnums(merged)
+			models[k] = model
+		} else {
+			enumMaps := make(map[string][]int)
+			identity := true
+			enums := serializationEnums(serialization)
+			for name, enum := range enums {
+				if mergedEnums[name] == nil {
+					continue
+				}
+				mergedEnums[name].Frozen = false
+				mapping, isIdentity := mergeEnum(mergedEnums[name], enum)
+				if !isIdentity {
+					enumMaps[name] = mapping
+					identity = false
+				}
+			}
+			if identity {
+				models[k] = model
+			} else {
+				if allOut {
+					log.Println("Remapping enumerations of", location)
+				}
+				models[k] = model.Remap(modelRemapFunc(extractor, groups, serialization.ETrans, enumMaps))
+			}
+		}
+		if models[k].Generation > maxGenerated {
+			maxGenerated = models[k].Generation
+		}
+	}
+	for k, model := range models {
+		if model.Generation > 0 && model.Generation != maxGenerated {
+			if allOut {
+				log.Println("Rescaling model", files[k], "from", model.Generation, "to", maxGenerated, "generations")
+			}
+			model.Rescale(int64(maxGenerated), int64(model.Generation))
+			model.Generation = maxGenerated
+		}
+	}
+	return models, merged
+}
+
+func modelRemapFunc(extractor *transition.GenericExtractor, groups []byte, eTrans *util.EnumSet, enumMaps map[string][]int) transitionmodel.FeatureRemapFunc {
+	transMap := enumMaps["trans"]
+	transGroups := make([]byte, 0)
+	if eTrans != nil {
+		transGroups = make([]byte, eTrans.Len())
+		for i := range transGroups {
+			transGroups[i] = transitionGroup(eTrans.ValueOf(i), groups)
+		}
+	}
+	for _, g := range groups {
+		for _, tmpl := range extractor.TransTypeGroups[g].FeatureTemplates {
+			if tmpl.HashBits > 0 {
+				log.Fatalln("Can't merge enumerations of models with hashed features (", tmpl, ")")
+			}
+		}
+	}
+	return func(featureID, trans int, feature interface{}) (int, interface{}) {
+		group := groups[0]
+		if trans < len(transGroups) {
+			group = transGroups[trans]
+		}
+		templates := extractor.TransTypeGroups[group].FeatureTemplates
+		newTrans := mapIndex(transMap, trans)
+		if featureID >= len(templates) {
+			return newTrans, feature
+		}
+		return newTrans, remapFeature(&templates[featureID], feature, enumMaps)
+	}
+}
+
+// SetEnums sets the global enumerations to those of a serialization
+func SetEnums(s *Serialization) {
+	EWord, EPOS, EWPOS, EMHost, EMSuffix = s.EWord, s.EPOS, s.EWPOS, s.EMHost, s.EMSuffix
+	if s.EMorphProp != nil {
+		EMorphProp = s.EMorphProp
+	}
+	if s.ETrans != nil {
+		ETrans = s.ETrans
+	}
+	if s.ETokens != nil {
+		ETokens = s.ETokens
+	}
+}
+
+// LoadEnsemble combines the main model with the models of EnsembleModelFiles
+// into an ensemble model, setting the global enumerations to the merged ones
+func LoadEnsemble(mainModel string, extractor *transition.GenericExtractor, groups []byte) *transitionmodel.Ensemble {
+	files := append([]string{mainModel}, strings.Split(EnsembleModelFiles, ",")...)
+	if allOut {
+		log.Println("Ensembling", len(files), "models")
+	}
+	models, merged := CombineModels(files, extractor, groups)
+	SetEnums(merged)
+	return &transitionmodel.Ensemble{Models: models}
+}
+
+func ModelAverageConfigOut(files []string) {
+	log.Println("Configuration")
+	log.Printf("Model Type:\t\t%s", avgModelType)
+	log.Printf("Features File:\t%s", avgFeaturesFile)
+	log.Printf("Models:\t\t%s", strings.Join(files, ", "))
+	log.Printf("Output:\t\t%s", avgOutModel)
+	log.Println()
+}
+
+func ModelAverage(cmd *commander.Command, args []string) error {
+	VerifyFlags(cmd, []string{"f", "out"})
+	if len(args) < 2 {
+		cmd.Usage()
+		return fmt.Errorf("at least two models are required for averaging, got %d", len(args))
+	}
+	groups, exists := modelTypeGroups[avgModelType]
+	if !exists {
+		log.Fatalln("Unknown model type", avgModelType)
+	}
+	featuresLocation, found := util.LocateFile(avgFeaturesFile, DEFAULT_CONF_DIRS)
+	if !found {
+		log.Fatalln("Features file", avgFeaturesFile, "not found")
+	}
+	avgFeaturesFile = featuresLocation
+	ModelAverageConfigOut(args)
+
+	featureSetup, err := transition.LoadFeatureConfFile(avgFeaturesFile)
+	if err != nil {
+		log.Println("Failed reading feature configuration file:", avgFeaturesFile)
+		log.Fatalln(err)
+	}
+	if avgModelType == "md" {
+		nlp.InitOpenParamFamily("HEBTB")
+	}
+	extractor := SetupExtractor(featureSetup, groups)
+
+	models, merged := CombineModels(args, extractor, groups)
+	averaged := models[0]
+	for _, model := range models[1:] {
+		averaged.AddModel(model)
+	}
+	averaged.ScalarDivide(int64(len(models)))
+	if allOut {
+		log.Println("Writing averaged model to", avgOutModel)
+	}
+	merged.WeightModel = averaged.Serialize(-1)
+	WriteModel(avgOutModel, merged)
+	return nil
+}
+
+func ModelAverageCmd() *commander.Command {
+	cmd := &commander.Command{
+		Run:       ModelAverage,
+		UsageLine: "average <file options> model1 model2 [model3 ...]",
+		Short:     "average the weights of several models",
+		Long: `
+average the weights of several models trained with the same features (and labels)
+
+	$ ./yap model average -t dep|md|joint -f <features> -out <output model> model1 model2 [model3 ...]
+
+`,
+		Flag: *flag.NewFlagSet("average", flag.ExitOnError),
+	}
+	cmd.Flag.StringVar(&avgModelType, "t", "joint", "Model type [dep, md, joint]")
+	cmd.Flag.StringVar(&avgFeaturesFile, "f", "", "Features Configuration File the models were trained with")
+	cmd.Flag.StringVar(&avgOutModel, "out", "", "Output model file")
+	return cmd
+}
+
+func ModelCmd() *commander.Command {
+	cmd := &commander.Command{
+		UsageLine: "model <command> [arguments]",
+		Short:     "model manipulation tools",
+		Subcommands: []*commander.Command{
+			ModelAverageCmd(),
+		},
+		Flag: *flag.NewFlagSet("model", flag.ExitOnError),
+	}
+	return cmd
+}
@@ -0,0 +1,474 @@
+package app
+
+import (
+	"yap/alg/featurevector"
+	"yap/alg/perceptron"
+	"yap/alg/search"
+	"yap/alg/transition"
+	"yap/nlp/format/conll"
+	"yap/nlp/format/conllu"
+	"yap/nlp/format/lattice"
+	"yap/nlp/parser/dependency/mst"
+	dep "yap/nlp/parser/dependency/transition"
+	"yap/nlp/parser/disambig"
+	"yap/nlp/parser/joint"
+	nlp "yap/nlp/types"
+	"yap/util"
+
+	"fmt"
+	"io/ioutil"
+	"log"
+	"regexp"
+
+	"github.com/gonuts/commander"
+	"github.com/gonuts/flag"
+)
+
+var (
+	featModelType    string
+	featFeaturesFile string
+)
+
+var (
+	depSourceSchema = &transition.SourceSchema{
+		regexp.MustCompile(`^[0-9]+(l2?|r2?|h2?|Ci)?$`),
+		regexp.MustCompile(`^(o|d|wp?|p|l|v[lrf]|s[lrf]|fp|h|x|c[0-9]*|k)$`),
+	}
+	morphemeSourceSchema = &transition.SourceSchema{
+		regexp.MustCompile(`^-?[0-9]+$`),
+		regexp.MustCompile(`^(mp?|p|f|t|i|c[0-9]*|k)$`),
+	}
+	latticeSourceSchema = &transition.SourceSchema{
+		regexp.MustCompile(`^-?[0-9]+(Ci)?$`),
+		regexp.MustCompile(`^(c(q|mq|r|mp2?|m2?|p2?|f|g|pg|fg|fp|fpg)|a|t|g|e|x|n|i)$`),
+	}
+	arcSourceSchema = &transition.SourceSchema{
+		regexp.MustCompile(`^-?[0-9]+$`),
+		regexp.MustCompile(`^(d|o|wp?|p|h|x|c[0-9]*|k)$`),
+	}
+
+	// feature elements of the configurations of each model type, see the
+	// Address and Attribute methods of the configurations
+	featureSchemas = map[string]transition.FeatureSchema{
+		"dep":   {'S': depSourceSchema, 'N': depSourceSchema},
+		"md":    {'M': morphemeSourceSchema, 'L': latticeSourceSchema},
+		"joint": {'S': depSourceSchema, 'N': depSourceSchema, 'M': morphemeSourceSchema, 'L': latticeSourceSchema},
+		"mst": {
+			'H': arcSourceSchema,
+			'D': arcSourceSchema,
+			'B': &transition.SourceSchema{regexp.MustCompile(`^0$`), arcSourceSchema.Attributes},
+		},
+	}
+)
+
+// featureTypeGroups returns the transition types of the feature groups of
+// a model type
+func featureTypeGroups(modelType string) ([]byte, bool) {
+	if modelType == "mst" {
+		return []byte{mst.ARC_TRANSITION_TYPE}, true
+	}
+	groups, exists := modelTypeGroups[modelType]
+	return groups, exists
+}
+
+func FeaturesCheck(cmd *commander.Command, args []string) error {
+	VerifyFlags(cmd, []string{"f"})
+	groups, exists := featureTypeGroups(featModelType)
+	if !exists {
+		log.Fatalln("Unknown model type", featModelType)
+	}
+	featuresLocation, found := util.LocateFile(featFeaturesFile, DEFAULT_CONF_DIRS)
+	if !found {
+		featuresLocation = featFeaturesFile
+	}
+	data, err := ioutil.ReadFile(featuresLocation)
+	if err != nil {
+		log.Fatalln("Failed reading feature configuration file:", featuresLocation, err)
+	}
+	setup, problems := transition.CheckFeatureConf(data, featureSchemas[featModelType], groups)
+	var errors int
+	for _, problem := range problems {
+		log.Printf("%s: %v", featuresLocation, problem)
+		if !problem.Warning {
+			errors++
+		}
+	}
+	log.Printf("Checked %d templates of %d feature groups in %s: %d error(s), %d warning(s)", setup.NumFeatures(), len(setup.FeatureGroups), featuresLocation, errors, len(problems)-errors)
+	if errors > 0 {
+		return fmt.Errorf("%d error(s) in feature configuration %s", errors, featuresLocation)
+	}
+	switch featModelType {
+	case "dep", "mst":
+		if len(tConll) == 0 {
+			return nil
+		}
+	case "md":
+		if len(tLatDis) == 0 || len(tLatAmb) == 0 {
+			return nil
+		}
+	case "joint":
+		if len(tConll) == 0 || len(tLatDis) == 0 || len(tLatAmb) == 0 {
+			return nil
+		}
+	}
+	LoadClusters()
+	var firing *FeatureFiring
+	switch featModelType {
+	case "dep":
+		firing = DepFeatureFiring(setup, tConll)
+	case "mst":
+		firing = MSTFeatureFiring(setup, tConll)
+	case "md":
+		firing = MDFeatureFiring(setup)
+	case "joint":
+		firing = JointFeatureFiring(setup)
+	}
+	firing.Report()
+	return nil
+}
+
+// TemplateFiring counts the configurations a template fired in, and its
+// distinct values
+type TemplateFiring struct {
+	Template string
+	Fired    int
+	Values   map[string]bool
+}
+
+// FeatureFiring counts the firing of the templates of the feature groups
+// of an extractor, in the configurations of each transition type
+type FeatureFiring struct {
+	Groups         []byte
+	Templates      map[byte][]*TemplateFiring
+	Configurations map[byte]int
+	Sentences      int
+}
+
+func NewFeatureFiring(extractor *transition.GenericExtractor, groups []byte) *FeatureFiring {
+	f := &FeatureFiring{
+		Groups:         groups,
+		Templates:      make(map[byte][]*TemplateFiring, len(groups)),
+		Configurations: make(map[byte]int, len(groups)),
+	}
+	for _, transType := range groups {
+		templates := extractor.TransTypeGroups[transType].FeatureTemplates
+		f.Templates[transType] = make([]*TemplateFiring, len(templates))
+		for i, template := range templates {
+			f.Templates[transType][i] = &TemplateFiring{template.String(), 0, make(map[string]bool)}
+		}
+	}
+	return f
+}
+
+// Add counts the features of a configuration extracted for a transition
+// type
+func (f *FeatureFiring) Add(transType byte, features []featurevector.Feature) {
+	f.Configurations[transType]++
+	for j, feature := range features {
+		if feature == nil {
+			continue
+		}
+		firing := f.Templates[transType][j]
+		firing.Fired++
+		if generated, isGenerated := feature.([]interface{}); isGenerated {
+			for _, value := range generated {
+				firing.Values[fmt.Sprint(value)] = true
+			}
+		} else {
+			firing.Values[fmt.Sprint(feature)] = true
+		}
+	}
+}
+
+// AddOracle counts the features of the gold configurations of the oracle
+// sequence of each instance, as extracted for their next transition
+func (f *FeatureFiring) AddOracle(deterministic *search.Deterministic, extractor *transition.GenericExtractor, instances []perceptron.DecodedInstance) {
+	var failed int
+	for _, instance := range instances {
+		_, result := deterministic.ParseOracle(instance)
+		if result == nil {
+			failed++
+			continue
+		}
+		f.Sentences++
+		seq := result.(*search.ParseResultParameters).Sequence
+		for i := len(seq) - 1; i > 0; i-- {
+			next := seq[i-1].GetLastTransition()
+			f.Add(next.Type(), extractor.Features(seq[i], false, next.Type(), []int{next.Value()}))
+		}
+	}
+	if failed > 0 {
+		log.Println("Warning: the oracle failed on", failed, "of", len(instances), "sentences (non-projective trees?)")
+	}
+}
+
+// Report logs the rate each template fires in and its number of distinct
+// values; templates that never fire, or always have the same value, are
+// flagged
+func (f *FeatureFiring) Report() {
+	var never, constant []string
+	for _, transType := range f.Groups {
+		templates, configurations := f.Templates[transType], f.Configurations[transType]
+		if len(templates) == 0 {
+			continue
+		}
+		if configurations == 0 {
+			log.Printf("No configurations of transition type %c, skipping its %d templates", transType, len(templates))
+			continue
+		}
+		log.Printf("Firing rates of %d templates of transition type %c in %d configurations of %d sentences", len(templates), transType, configurations, f.Sentences)
+		for _, t := range templates {
+			var flag string
+			switch {
+			case t.Fired == 0:
+				flag = "\tNEVER FIRES"
+				never = append(never, t.Template)
+			case len(t.Values) == 1:
+				flag = "\tCONSTANT"
+				constant = append(constant, t.Template)
+			}
+			log.Printf("%6.2f%%\t%7d values\t%s%s", 100*float64(t.Fired)/float64(util.Max(configurations, 1)), len(t.Values), t.Template, flag)
+		}
+	}
+	if len(never) > 0 {
+		log.Printf("%d template(s) never fire (unknown addresses or attributes?): %v", len(never), never)
+	}
+	if len(constant) > 0 {
+		log.Printf("%d template(s) always have the same value: %v", len(constant), constant)
+	}
+}
+
+// DepFeatureFiring counts the features of the gold configurations of a
+// dependency corpus
+func DepFeatureFiring(setup *transition.FeatureSetup, corpus string) *FeatureFiring {
+	labels, err := conll.ReadLabelsFile(corpus)
+	if err != nil {
+		log.Fatalln("Failed reading labels from", corpus, err)
+	}
+	SetupDepEnum(labels.Values)
+	_, terminalStack := depArcSystemStub(DepArcSystemStr)
+	arcSystem := DepArcSystem(DepArcSystemStr)
+	arcSystem.AddDefaultOracle()
+	extractor := SetupExtractor(setup, []byte("A"))
+	deterministic := &search.Deterministic{
+		TransFunc:      arcSystem,
+		FeatExtractor:  extractor,
+		ReturnSequence: true,
+		Base: &dep.SimpleConfiguration{
+			EWord:         EWord,
+			EPOS:          EPOS,
+			EWPOS:         EWPOS,
+			EMHost:        EMHost,
+			EMSuffix:      EMSuffix,
+			ERel:          ERel,
+			ETrans:        ETrans,
+			Clusters:      Clusters,
+			TerminalStack: terminalStack,
+			TerminalQueue: 0,
+		},
+		DefaultTransType: 'A',
+	}
+	goldGraphs, err := ReadGoldGraphs(corpus, false)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	firing := NewFeatureFiring(extractor, []byte("A"))
+	firing.AddOracle(deterministic, extractor, TrainingSequences(goldGraphs, GetAsTaggedSentence, GetAsLabeledDepGraph))
+	return firing
+}
+
+// MSTFeatureFiring counts the features of the gold arcs of a dependency
+// corpus
+func MSTFeatureFiring(setup *transition.FeatureSetup, corpus string) *FeatureFiring {
+	labels, err := conll.ReadLabelsFile(corpus)
+	if err != nil {
+		log.Fatalln("Failed reading labels from", corpus, err)
+	}
+	SetupDepEnum(labels.Values)
+	extractor := SetupExtractor(setup, []byte{mst.ARC_TRANSITION_TYPE})
+	goldGraphs, err := ReadGoldGraphs(corpus, false)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	firing := NewFeatureFiring(extractor, []byte{mst.ARC_TRANSITION_TYPE})
+	for _, instance := range TrainingSequences(goldGraphs, GetAsTaggedSentence, GetAsLabeledDepGraph) {
+		tokens := instance.Instance().(nlp.EnumTaggedSentence).EnumTaggedTokens()
+		graph := instance.Decoded().(nlp.LabeledDependencyGraph)
+		for modifier := range tokens {
+			arc := graph.GetLabeledArc(modifier)
+			if arc == nil {
+				continue
+			}
+			features := extractor.Features(&mst.Arc{tokens, arc.GetHead(), modifier, Clusters}, false, mst.ARC_TRANSITION_TYPE, nil)
+			firing.Add(mst.ARC_TRANSITION_TYPE, features)
+		}
+		firing.Sentences++
+	}
+	return firing
+}
+
+// mdFeatureParams returns the parameter function of md given by flags,
+// and sets up the MD transition system with it
+func mdFeatureParams() (nlp.MDParam, *disambig.MDTrans) {
+	paramFunc, exists := nlp.MDParams[MdParamFuncName]
+	if !exists {
+		log.Fatalln("Param Func", MdParamFuncName, "does not exist")
+	}
+	if useConllU {
+		nlp.InitOpenParamFamily("UD")
+		conllu.IGNORE_LEMMA = lattice.IGNORE_LEMMA
+	} else {
+		nlp.InitOpenParamFamily("HEBTB")
+	}
+	disambig.UsePOP = UsePOP
+	disambig.SwitchFormLemma = !lattice.IGNORE_LEMMA
+	mdTrans := &disambig.MDTrans{
+		ParamFunc:   paramFunc,
+		UsePOP:      UsePOP,
+		POP:         POP,
+		Transitions: ETrans,
+	}
+	mdTrans.AddDefaultOracle()
+	return paramFunc, mdTrans
+}
+
+// MDFeatureFiring counts the features of the gold configurations of the
+// md training lattices (-td and -tl)
+func MDFeatureFiring(setup *transition.FeatureSetup) *FeatureFiring {
+	SetupMDEnum()
+	paramFunc, mdTrans := mdFeatureParams()
+	groups, _ := featureTypeGroups("md")
+	extractor := SetupExtractor(setup, groups)
+	combined, err := ReadMDTrainingSet()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	deterministic := &search.Deterministic{
+		TransFunc:      mdTrans,
+		FeatExtractor:  extractor,
+		ReturnSequence: true,
+		Base: &disambig.MDConfig{
+			ETokens:     ETokens,
+			Clusters:    Clusters,
+			POP:         POP,
+			Transitions: ETrans,
+			ParamFunc:   paramFunc,
+		},
+		DefaultTransType: 'M',
+	}
+	firing := NewFeatureFiring(extractor, groups)
+	firing.AddOracle(deterministic, extractor, TrainingSequences(combined, GetMDConfigAsLattices, GetMDConfigAsMappings))
+	return firing
+}
+
+// JointFeatureFiring counts the features of the gold configurations of the
+// joint training graphs and lattices (-tc, -td and -tl)
+func JointFeatureFiring(setup *transition.FeatureSetup) *FeatureFiring {
+	labels, err := conll.ReadLabelsFile(tConll)
+	if err != nil {
+		log.Fatalln("Failed reading labels from", tConll, err)
+	}
+	SetupEnum(labels.Values)
+	paramFunc, mdTrans := mdFeatureParams()
+	disambig.LEMMAS = !lattice.IGNORE_LEMMA
+	_, terminalStack := depArcSystemStub(DepArcSystemStr)
+	arcSystem := DepArcSystem(DepArcSystemStr)
+	arcSystem.AddDefaultOracle()
+	jointTrans := &joint.JointTrans{
+		MDTrans:       mdTrans,
+		ArcSys:        arcSystem,
+		Transitions:   ETrans,
+		JointStrategy: JointStrategy,
+		MDTransition:  MD,
+	}
+	jointTrans.AddDefaultOracle()
+	jointTrans.Oracle().(*joint.JointOracle).OracleStrategy = OracleStrategy
+	groups, _ := featureTypeGroups("joint")
+	extractor := SetupExtractor(setup, groups)
+	combined, err := ReadJointTrainingSet(nil)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	deterministic := &search.Deterministic{
+		TransFunc:      jointTrans,
+		FeatExtractor:  extractor,
+		ReturnSequence: true,
+		Base: &joint.JointConfig{
+			SimpleConfiguration: dep.SimpleConfiguration{
+				EWord:         EWord,
+				EPOS:          EPOS,
+				EWPOS:         EWPOS,
+				EMHost:        EMHost,
+				EMSuffix:      EMSuffix,
+				ERel:          ERel,
+				ETrans:        ETrans,
+				Clusters:      Clusters,
+				TerminalStack: terminalStack,
+				TerminalQueue: 0,
+			},
+			MDConfig: disambig.MDConfig{
+				ETokens:     ETokens,
+				Clusters:    Clusters,
+				POP:         POP,
+				Transitions: ETrans,
+				ParamFunc:   paramFunc,
+			},
+			MDTrans: MD,
+		},
+		DefaultTransType: 'M',
+	}
+	firing := NewFeatureFiring(extractor, groups)
+	firing.AddOracle(deterministic, extractor, TrainingSequences(combined, GetMorphGraphAsLattices, GetMorphGraph))
+	return firing
+}
+
+func FeaturesCheckCmd() *commander.Command {
+	cmd := &commander.Command{
+		Run:       FeaturesCheck,
+		UsageLine: "check <file options> [arguments]",
+		Short:     "check a feature configuration file",
+		Long: `
+check a feature configuration file by loading its templates, reporting
+malformed templates, addresses, attributes, requirements and transition
+types unknown to the configurations of the model with their lines; given a
+(small) training corpus, report the rate each template fires in and its
+number of distinct values, flagging templates that never fire
+
+	$ ./yap features check -t dep|mst -f <features> [-tc <conll> [-a eager]]
+	$ ./yap features check -t md -f <features> [-td <train disamb. lat> -tl <train amb. lat>]
+	$ ./yap features check -t joint -f <features> [-tc <conll> -td <train disamb. lat> -tl <train amb. lat>]
+
+`,
+		Flag: *flag.NewFlagSet("check", flag.ExitOnError),
+	}
+	cmd.Flag.StringVar(&featModelType, "t", "dep", "Model type [dep, md, joint, mst]")
+	cmd.Flag.StringVar(&featFeaturesFile, "f", "", "Features Configuration File")
+	cmd.Flag.StringVar(&tConll, "tc", "", "Optional - Training Conll File to compute firing rates with (dep, mst, joint)")
+	cmd.Flag.StringVar(&tLatDis, "td", "", "Optional - Training Disambiguated Lattices File to compute firing rates with (md, joint)")
+	cmd.Flag.StringVar(&tLatAmb, "tl", "", "Optional - Training Ambiguous Lattices File to compute firing rates with (md, joint)")
+	cmd.Flag.BoolVar(&useConllU, "conllu", false, "Optional - use CoNLL-U-format training files (md, joint)")
+	cmd.Flag.StringVar(&DepArcSystemStr, "a", "eager", "Optional - Arc System of the firing rates [standard, eager, swap, hybrid]")
+	cmd.Flag.StringVar(&MdParamFuncName, "p", "Funcs_Main_POS_Both_Prop", "Optional - Param Func types of the firing rates (md, joint): ["+nlp.AllParamFuncNames+"]")
+	cmd.Flag.BoolVar(&UsePOP, "pop", true, "Optional - Add POP operation to MD (md, joint)")
+	cmd.Flag.BoolVar(&lattice.IGNORE_LEMMA, "nolemma", true, "Optional - Ignore lemmas (md, joint)")
+	cmd.Flag.StringVar(&JointStrategy, "jointstr", "ArcGreedy", "Optional - Joint Strategy of the firing rates (joint): ["+joint.JointStrategies+"]")
+	cmd.Flag.StringVar(&OracleStrategy, "oraclestr", "ArcGreedy", "Optional - Oracle Strategy of the firing rates (joint): ["+joint.OracleStrategies+"]")
+	cmd.Flag.IntVar(&limit, "limit", 0, "Optional - Limit the sentences of the training corpus")
+	cmd.Flag.StringVar(&BrownFile, "brown", "", "Optional - Brown clusters file (wcluster paths) for cluster attributes (c, c<N>)")
+	cmd.Flag.StringVar(&W2VFile, "w2v", "", "Optional - word2vec text file clustered with k-means for the cluster id attribute (k)")
+	cmd.Flag.IntVar(&W2VClusters, "w2vk", 256, "Optional - Number of k-means clusters of the word2vec vectors")
+	return cmd
+}
+
+func FeaturesCmd() *commander.Command {
+	cmd := &commander.Command{
+		UsageLine: "features <command> [arguments]",
+		Short:     "feature configuration tools",
+		Subcommands: []*commander.Command{
+			FeaturesCheckCmd(),
+			FeaturesAblateCmd(),
+		},
+		Flag: *flag.NewFlagSet("features", flag.ExitOnError),
+	}
+	return cmd
+}
@@ -0,0 +1,101 @@
+package app
+
+import (
+	transitionmodel "yap/alg/transition/model"
+	"yap/util"
+
+	"fmt"
+	"log"
+)
+
+var (
+	// model file to initialize training from (fine-tuning), empty to train from scratch
+	InitModelFile string
+	// perceptron update amount used when continuing training of an existing model
+	InitUpdateScale int64 = 1
+)
+
+// checkInitModel verifies that an initial model is only given when training;
+// an existing output model is used as is, so the initial model would be ignored
+func checkInitModel(initFile, modelFile string, modelExists bool) error {
+	if initFile != "" && modelExists {
+		return fmt.Errorf("Model %s exists, initial model %s would be ignored (remove the model to fine-tune it, or drop -init to use it)", modelFile, initFile)
+	}
+	return nil
+}
+
+// LoadInitModel reads an existing model for fine-tuning. The enumerations
+// of the model replace the freshly setup ones (and are unfrozen so that new
+// words, morphemes and transitions of the new data can be added), so it
+// must be called after the enumerations are setup and before any data is read.
+// If restoreTrans is false, the transition enumeration of the current setup
+// is kept, and is only verified against that of the model.
+func LoadInitModel(file string, restoreTrans bool) *transitionmodel.AvgMatrixSparse {
+	location, found := file, VerifyExists(file)
+	if !found {
+		location, found = util.LocateFile(file, DEFAULT_MODEL_DIRS)
+	}
+	if !found {
+		log.Fatalln("Initial model file", file, "not found")
+	}
+	if allOut {
+		log.Println("Initializing training from model", location)
+	}
+	serialization := ReadModel(location)
+	if serialization.WeightModel == nil {
+		log.Fatalln("Failed reading initial model from", location)
+	}
+	if serialization.ETrans != nil {
+		verifyTransPrefix(serialization.ETrans)
+	}
+	EWord, EPOS, EWPOS = serialization.EWord, serialization.EPOS, serialization.EWPOS
+	EMHost, EMSuffix = serialization.EMHost, serialization.EMSuffix
+	if serialization.EMorphProp != nil {
+		EMorphProp = serialization.EMorphProp
+	}
+	if serialization.ETokens != nil {
+		ETokens = serialization.ETokens
+	}
+	if restoreTrans && serialization.ETrans != nil {
+		ETrans = serialization.ETrans
+	}
+	RestoreClusters(serialization.Clusters)
+	for _, enum := range []*util.EnumSet{EWord, EPOS, EWPOS, EMHost, EMSuffix, EMorphProp, ETokens, ETrans} {
+		if enum != nil {
+			enum.Frozen = false
+		}
+	}
+
+	model := &transitionmodel.AvgMatrixSparse{}
+	model.Deserialize(serialization.WeightModel)
+	InitUpdateScale = model.PrepareContinuedTraining()
+	if allOut {
+		log.Println("Loaded initial model with", len(model.Mat), "features; update scale", InitUpdateScale)
+	}
+	return model
+}
+
+// VerifyInitModel verifies the initial model matches the loaded feature
+// configuration and sets its feature formatters
+func VerifyInitModel(model *transitionmodel.AvgMatrixSparse, numFeatures int, formatters []util.Format) {
+	if len(model.Mat) != numFeatures {
+		log.Fatalln("Initial model has", len(model.Mat), "features, but the feature configuration has", numFeatures)
+	}
+	model.Formatters = formatters
+}
+
+// verifyTransPrefix verifies that the transitions setup from the labels
+// configuration are enumerated identically in the initial model
+func verifyTransPrefix(modelTrans *util.EnumSet) {
+	if ETrans == nil {
+		return
+	}
+	if modelTrans.Len() < ETrans.Len() {
+		log.Fatalln("Initial model has", modelTrans.Len(), "transitions, expected at least", ETrans.Len(), "(different labels file?)")
+	}
+	for i := 0; i < ETrans.Len(); i++ {
+		if ETrans.ValueOf(i) != modelTrans.ValueOf(i) {
+			log.Fatalln("Initial model transition", i, "is", modelTrans.ValueOf(i), "expected", ETrans.ValueOf(i), "(different labels file?)")
+		}
+	}
+}
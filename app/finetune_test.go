@@ -0,0 +1,83 @@
+package app
+
+import (
+	"yap/util"
+
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckInitModel(t *testing.T) {
+	tests := []struct {
+		name        string
+		initFile    string
+		modelExists bool
+		fails       bool
+	}{
+		{"train", "", false, false},
+		{"parse", "", true, false},
+		{"fine-tune", "init.b64", false, false},
+		{"fine-tune existing", "init.b64", true, true},
+	}
+	for _, test := range tests {
+		err := checkInitModel(test.initFile, "model.b64", test.modelExists)
+		if (err != nil) != test.fails {
+			t.Errorf("%s: got error %v, expected failure %v", test.name, err, test.fails)
+		}
+	}
+}
+
+func TestLoadInitModel(t *testing.T) {
+	dir, err := ioutil.TempDir("", "yap-finetune-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(word, pos, wpos, trans *util.EnumSet, scale int64) {
+		EWord, EPOS, EWPOS, ETrans, InitUpdateScale = word, pos, wpos, trans, scale
+	}(EWord, EPOS, EWPOS, ETrans, InitUpdateScale)
+
+	serialization := testSerialization()
+	serialization.WeightModel.Generation = 4
+	serialization.ETrans = util.NewEnumSet(3)
+	for _, trans := range []string{"SH", "RE", "LA-subj"} {
+		serialization.ETrans.Add(trans)
+	}
+	serialization.EWord.Frozen = true
+	filename := filepath.Join(dir, "init.b64")
+	WriteModel(filename, serialization)
+
+	for _, restoreTrans := range []bool{false, true} {
+		EWord, EPOS, EWPOS = util.NewEnumSet(1), util.NewEnumSet(1), nil
+		ETrans = util.NewEnumSet(2)
+		ETrans.Add("SH")
+		ETrans.Add("RE")
+		setupTrans := ETrans
+		model := LoadInitModel(filename, restoreTrans)
+		if len(model.Mat) != 2 {
+			t.Errorf("restore %v: got %v features, expected 2", restoreTrans, len(model.Mat))
+		}
+		if model.Generation != 0 {
+			t.Errorf("restore %v: got generation %v, expected 0", restoreTrans, model.Generation)
+		}
+		if InitUpdateScale != 4 {
+			t.Errorf("restore %v: got update scale %v, expected 4", restoreTrans, InitUpdateScale)
+		}
+		if EWord.Len() != serialization.EWord.Len() || EPOS.Len() != serialization.EPOS.Len() {
+			t.Errorf("restore %v: got %v words and %v tags, expected the %v words and %v tags of the model",
+				restoreTrans, EWord.Len(), EPOS.Len(), serialization.EWord.Len(), serialization.EPOS.Len())
+		}
+		if EWord.Frozen || EPOS.Frozen {
+			t.Errorf("restore %v: enumerations of the initial model should be unfrozen", restoreTrans)
+		}
+		if restoreTrans {
+			if ETrans.Len() != 3 || ETrans.Frozen {
+				t.Errorf("restore %v: got %v transitions (frozen %v), expected the 3 unfrozen transitions of the model", restoreTrans, ETrans.Len(), ETrans.Frozen)
+			}
+		} else if ETrans != setupTrans {
+			t.Errorf("restore %v: the transitions of the setup should be kept", restoreTrans)
+		}
+	}
+}
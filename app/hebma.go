@@ -14,6 +14,7 @@ import (
 
 	"fmt"
 	"log"
+	"strings"
 	// "os"
 
 	"github.com/gonuts/commander"
@@ -25,15 +26,47 @@ var (
 	HebMaXliter8out, HebMaAlwaysnnp   bool
 	HebMaNnpnofeats              bool
 	HebMaShowoov                 bool
+	// comma separated user lexicon files, added to (or overriding) the lexicon
+	HebMaUserLexFiles   string
+	HebMaUserLexOverride bool
+	// comma separated pattern recognizers of the analyzer, all if empty,
+	// and their configuration file (the default recognizers if empty)
+	HebMaRecognizers, HebMaRecognizersConf string
+	// malearn dictionary with the OOV guesser of the analyzer
+	HebMaOOVGuesserFile string
+	// comma separated normalization steps of raw input, and spelling
+	// variants file of the variants step
+	RawNormalization, RawNormVariantsFile string
+	// RawNormalizer normalizes raw input, set by SetupRawNormalizer
+	RawNormalizer *raw.Normalizer
+	// write the characters of raw input of tokens and morphemes to UD lattices
+	RawOffsets bool
 	outJSON                 bool
 	HEB_MA_DEFAULT_DATA_DIRS       = []string{".", "data/bgulex"}
 )
 
 func HebMAConfigOut() {
 	log.Println("Configuration")
-	log.Printf("Heb Lexicon:\t\t%s", HebMaPrefixFile)
-	log.Printf("Heb Prefix:\t\t%s", HebMaLexiconFile)
-	log.Printf("OOV Strategy:\t%v", "Const:NNP")
+	log.Printf("Heb Lexicon:\t\t%s", HebMaLexiconFile)
+	if lex.IsCompiledFile(HebMaLexiconFile) {
+		log.Printf("Heb Prefix:\t\t(compiled lexicon)")
+	} else {
+		log.Printf("Heb Prefix:\t\t%s", HebMaPrefixFile)
+	}
+	if len(HebMaUserLexFiles) > 0 {
+		log.Printf("User Lexicons:\t%s (override: %v)", HebMaUserLexFiles, HebMaUserLexOverride)
+	}
+	if len(HebMaRecognizersConf) > 0 {
+		log.Printf("Recognizers Conf:\t%s", HebMaRecognizersConf)
+	}
+	if len(HebMaRecognizers) > 0 {
+		log.Printf("Recognizers:\t%s", HebMaRecognizers)
+	}
+	if len(HebMaOOVGuesserFile) > 0 {
+		log.Printf("OOV Strategy:\t%v", "Guesser:"+HebMaOOVGuesserFile)
+	} else {
+		log.Printf("OOV Strategy:\t%v", "Const:NNP")
+	}
 	log.Printf("xliter8 out:\t\t%v", HebMaXliter8out)
 	log.Println()
 	if useConllU {
@@ -44,6 +77,8 @@ func HebMAConfigOut() {
 		if len(inRawFile) > 0 {
 			log.Printf("Raw Input:\t\t%s", inRawFile)
 		}
+		log.Printf("Normalization:\t%s", RawNormalization)
+		log.Printf("Offsets:\t\t%v", RawOffsets)
 	}
 	if len(outLatticeFile) > 0 {
 		log.Printf("Output:\t\t%s", outLatticeFile)
@@ -51,6 +86,142 @@ func HebMAConfigOut() {
 	log.Println()
 }
 
+// SetupHebMaFormat sets the lexicon reading options of an output format
+func SetupHebMaFormat(format string) {
+	if format == "ud" {
+		// override all skips in HEBLEX
+		lex.SKIP_POLAR = false
+		lex.SKIP_BINYAN = false
+		lex.SKIP_ALL_TYPE = false
+		lex.SKIP_TYPES = make(map[string]bool)
+		lattice.IGNORE_LEMMA = false
+		// Compatibility: No features for PROPN in UD Hebrew
+		lex.STRIP_ALL_NNP_OF_FEATS = true
+	}
+}
+
+// SetupHebMaRecognizers sets the recognizers given by flags of the analyzer,
+// selected from the configured recognizers or the default ones
+func SetupHebMaRecognizers(maData *ma.BGULex) {
+	recognizers := ma.DefaultRecognizers
+	if len(HebMaRecognizersConf) > 0 {
+		if !VerifyExists(HebMaRecognizersConf) {
+			if location, found := util.LocateFile(HebMaRecognizersConf, DEFAULT_CONF_DIRS); found {
+				HebMaRecognizersConf = location
+			}
+		}
+		var err error
+		if recognizers, err = ma.LoadRecognizersFile(HebMaRecognizersConf); err != nil {
+			log.Fatalln("Failed reading recognizers configuration", HebMaRecognizersConf, err)
+		}
+	}
+	recognizers, err := ma.SelectRecognizers(recognizers, HebMaRecognizers)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	maData.Recognizers = recognizers
+}
+
+// SetupRawNormalizer sets the normalizer of raw input given by flags; a
+// variants file adds the variants step
+func SetupRawNormalizer() {
+	steps := RawNormalization
+	if len(RawNormVariantsFile) > 0 && steps != "all" && !strings.Contains(steps, raw.NORM_VARIANTS) {
+		steps = strings.Trim(strings.TrimPrefix(steps, "none")+","+raw.NORM_VARIANTS, ",")
+	}
+	normalizer, err := raw.NewNormalizer(steps)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if len(RawNormVariantsFile) > 0 {
+		if err := normalizer.ReadVariantsFile(RawNormVariantsFile); err != nil {
+			log.Fatalln("Failed reading spelling variants file", err)
+		}
+	}
+	RawNormalizer = normalizer
+}
+
+// VerifyRawOffsets fails if offsets are requested for an output format that
+// can't carry them; only UD lattices have a MISC column for their ranges
+func VerifyRawOffsets(format string) {
+	if RawOffsets && format != "ud" {
+		log.Fatalln("Offsets of raw input are only written to UD lattices (-format ud), not", format)
+	}
+}
+
+// SetRawRanges sets the characters of the raw input each token of a sentence,
+// and each morpheme of its lattice, was read from
+func SetRawRanges(lattices nlp.LatticeSentence, offsets []*raw.TokenOffsets) {
+	for i := range lattices {
+		if i < len(offsets) {
+			lattices[i].SetRanges(offsets[i].Range)
+		}
+	}
+}
+
+// LoadHebMaOOVGuesser sets the OOV guesser of the malearn dictionary given
+// by flags to the analyzer
+func LoadHebMaOOVGuesser(maData *ma.BGULex) {
+	if len(HebMaOOVGuesserFile) == 0 {
+		return
+	}
+	log.Println("Reading Morphological Analyzer OOV Guesser", HebMaOOVGuesserFile)
+	dict := new(ma.MADict)
+	if err := dict.ReadFile(HebMaOOVGuesserFile); err != nil {
+		log.Fatalln("Failed reading MA dict file", HebMaOOVGuesserFile, err)
+	}
+	if dict.OOVGuesser == nil {
+		log.Fatalln(HebMaOOVGuesserFile, "has no OOV guesser, learn one with malearn -oovguesser")
+	}
+	maData.OOVGuesser = dict.OOVGuesser
+}
+
+// LocateHebMaLexicon locates the lexicon and prefix files given by flags in
+// the default data dirs, and returns the flags of the files not found; a
+// compiled lexicon has its own prefixes, so the prefix file is not needed
+func LocateHebMaLexicon() []string {
+	var missing []string
+	if location, found := util.LocateFile(HebMaLexiconFile, HEB_MA_DEFAULT_DATA_DIRS); found {
+		HebMaLexiconFile = location
+		if lex.IsCompiledFile(HebMaLexiconFile) {
+			return nil
+		}
+	} else {
+		missing = append(missing, "lexicon")
+	}
+	if location, found := util.LocateFile(HebMaPrefixFile, HEB_MA_DEFAULT_DATA_DIRS); found {
+		HebMaPrefixFile = location
+	} else {
+		missing = append(missing, "prefix")
+	}
+	return missing
+}
+
+// LoadHebMaLexicon loads the prefix and lexicon files given by flags to the
+// analyzer; the prefix file is skipped with a compiled lexicon
+func LoadHebMaLexicon(maData *ma.BGULex) {
+	if !lex.IsCompiledFile(HebMaLexiconFile) {
+		log.Println("Reading Morphological Analyzer BGU Prefixes")
+		maData.LoadPrefixes(HebMaPrefixFile)
+	}
+	log.Println("Reading Morphological Analyzer BGU Lexicon")
+	maData.LoadLex(HebMaLexiconFile, HebMaNnpnofeats)
+}
+
+// LoadHebMaUserLex adds the user lexicon files given by flags to the
+// lexicon of the analyzer
+func LoadHebMaUserLex(maData *ma.BGULex) {
+	if len(HebMaUserLexFiles) == 0 {
+		return
+	}
+	for _, file := range strings.Split(HebMaUserLexFiles, ",") {
+		log.Println("Reading Morphological Analyzer User Lexicon", file)
+		if err := maData.LoadUserLex(file, HebMaUserLexOverride); err != nil {
+			log.Fatalln(err)
+		}
+	}
+}
+
 func HebMA(cmd *commander.Command, args []string) error {
 	useConllU = len(conlluFile) > 0
 	var REQUIRED_FLAGS []string
@@ -60,40 +231,26 @@ func HebMA(cmd *commander.Command, args []string) error {
 	} else {
 		REQUIRED_FLAGS = []string{"raw", "out"}
 	}
-	prefixLocation, found := util.LocateFile(HebMaPrefixFile, HEB_MA_DEFAULT_DATA_DIRS)
-	if found {
-		HebMaPrefixFile = prefixLocation
-	} else {
-		REQUIRED_FLAGS = append(REQUIRED_FLAGS, "prefix")
-	}
-	lexiconLocation, found := util.LocateFile(HebMaLexiconFile, HEB_MA_DEFAULT_DATA_DIRS)
-	if found {
-		HebMaLexiconFile = lexiconLocation
-	} else {
-		REQUIRED_FLAGS = append(REQUIRED_FLAGS, "lexicon")
-	}
+	REQUIRED_FLAGS = append(REQUIRED_FLAGS, LocateHebMaLexicon()...)
 	VerifyFlags(cmd, REQUIRED_FLAGS)
 	HebMAConfigOut()
-	if outFormat == "ud" {
-		// override all skips in HEBLEX
-		lex.SKIP_POLAR = false
-		lex.SKIP_BINYAN = false
-		lex.SKIP_ALL_TYPE = false
-		lex.SKIP_TYPES = make(map[string]bool)
-		lattice.IGNORE_LEMMA = false
-		// Compatibility: No features for PROPN in UD Hebrew
-		lex.STRIP_ALL_NNP_OF_FEATS = true
+	VerifyRawOffsets(outFormat)
+	if RawOffsets && Stream {
+		log.Fatalln("Offsets of raw input are not written to streamed lattices (-stream)")
 	}
+	SetupRawNormalizer()
+	SetupHebMaFormat(outFormat)
 	maData := new(ma.BGULex)
 	maData.MAType = outFormat
-	log.Println("Reading Morphological Analyzer BGU Prefixes")
-	maData.LoadPrefixes(HebMaPrefixFile)
-	log.Println("Reading Morphological Analyzer BGU Lexicon")
-	maData.LoadLex(HebMaLexiconFile, HebMaNnpnofeats)
+	LoadHebMaLexicon(maData)
+	LoadHebMaUserLex(maData)
+	SetupHebMaRecognizers(maData)
+	LoadHebMaOOVGuesser(maData)
 	log.Println()
 	var (
 		sents        []nlp.BasicSentence
 		sentComments [][]string
+		sentOffsets  [][]*raw.TokenOffsets
 		sentsStream  chan nlp.BasicSentence
 		err          error
 	)
@@ -136,7 +293,7 @@ func HebMA(cmd *commander.Command, args []string) error {
 				sents[i] = newSent
 			}
 		} else {
-			sents, err = raw.ReadFile(inRawFile, limit)
+			sents, sentOffsets, err = raw.ReadFileWithOffsets(inRawFile, limit, RawNormalizer)
 			if err != nil {
 				panic(fmt.Sprintf("Failed reading raw file - %v", err))
 			}
@@ -183,6 +340,9 @@ func HebMA(cmd *commander.Command, args []string) error {
 		for i, sent := range sents {
 			log.SetPrefix(fmt.Sprintf("%v graph# %v ", prefix, i))
 			lattices[i], oovInd[i] = maData.Analyze(sent.Tokens())
+			if RawOffsets && sentOffsets != nil {
+				SetRawRanges(lattices[i], sentOffsets[i])
+			}
 		}
 		var hebrew xliter8.Interface
 		if HebMaXliter8out {
@@ -227,14 +387,22 @@ run lexicon-based morphological analyzer on raw input
 `,
 		Flag: *flag.NewFlagSet("ma", flag.ExitOnError),
 	}
-	cmd.Flag.StringVar(&HebMaPrefixFile, "prefix", "bgupreflex_withdef.utf8.hr", "Prefix file for morphological analyzer")
-	cmd.Flag.StringVar(&HebMaLexiconFile, "lexicon", "bgulex.utf8.hr", "Lexicon file for morphological analyzer")
+	cmd.Flag.StringVar(&HebMaPrefixFile, "prefix", "bgupreflex_withdef.utf8.hr", "Prefix file for morphological analyzer (not used with a compiled lexicon)")
+	cmd.Flag.StringVar(&HebMaLexiconFile, "lexicon", "bgulex.utf8.hr", "Lexicon file for morphological analyzer, or compiled lexicon (see lex compile)")
 	cmd.Flag.StringVar(&inRawFile, "raw", "", "Input raw (tokenized) file")
 	cmd.Flag.StringVar(&conlluFile, "conllu", "", "CoNLL-U-format input file")
 	cmd.Flag.StringVar(&outLatticeFile, "out", "", "Output lattice file")
 	cmd.Flag.BoolVar(&HebMaXliter8out, "xliter8out", false, "Transliterate output lattice file")
 	cmd.Flag.BoolVar(&HebMaAlwaysnnp, "alwaysnnp", false, "Always add NNP to tokens and prefixed subtokens")
 	cmd.Flag.BoolVar(&HebMaNnpnofeats, "addnnpnofeats", false, "Add NNP in lex but without features")
+	cmd.Flag.StringVar(&HebMaUserLexFiles, "userlex", "", "Optional - Comma separated user lexicon files (lexicon format, or tab separated token, lemma, POS, features)")
+	cmd.Flag.StringVar(&HebMaRecognizers, "recognizers", "", "Optional - Comma separated pattern recognizers of tokens not in the lexicon, or none (default: all)")
+	cmd.Flag.StringVar(&HebMaRecognizersConf, "recognizers_conf", "", "Optional - Pattern recognizers configuration file, e.g. recognizers.yaml (default: recognizers ["+ma.RecognizerNames(ma.DefaultRecognizers)+"])")
+	cmd.Flag.BoolVar(&HebMaUserLexOverride, "userlexoverride", false, "Optional - User lexicon analyses replace the lexicon analyses of their tokens")
+	cmd.Flag.StringVar(&RawNormalization, "normalize", raw.DEFAULT_NORMALIZATION, "Optional - Comma separated normalization steps of raw input, all or none ["+strings.Join(raw.NORMALIZATION_STEPS, ", ")+"]")
+	cmd.Flag.StringVar(&RawNormVariantsFile, "normvariants", "", "Optional - Spelling variants file (tab separated variant and standard form) of the variants normalization step")
+	cmd.Flag.BoolVar(&RawOffsets, "offsets", false, "Optional - Write the characters of the raw input of tokens and morphemes (TokenRange in the MISC column), requires -format ud")
+	cmd.Flag.StringVar(&HebMaOOVGuesserFile, "oovguesser", "", "Optional - Dictionary learned by malearn -oovguesser, whose OOV guesser analyzes OOV tokens (default: constant NNP/NN analyses)")
 	cmd.Flag.IntVar(&limit, "limit", 0, "Limit input set")
 	cmd.Flag.BoolVar(&HebMaShowoov, "showoov", false, "Output OOV tokens")
 	cmd.Flag.StringVar(&oovFile, "oov", "", "Output OOV File")
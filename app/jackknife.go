@@ -0,0 +1,402 @@
+package app
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+
+	"github.com/gonuts/commander"
+	"github.com/gonuts/flag"
+)
+
+var (
+	jkModelType                    string
+	jkFolds, jkParallel            int
+	jkMemBudget, jkFoldMem         int
+	jkWorkDir                      string
+	jkKeep                         bool
+	jkOutConll, jkOutMap, jkOutSeg string
+)
+
+// input and output files of a single jackknife fold
+type jackknifeFold struct {
+	num                                        int
+	dir                                        string
+	trainConll, trainDis, trainAmb, heldOutAmb string
+	outConll, outMap, outSeg                   string
+	model                                      string
+}
+
+func JackknifeConfigOut() {
+	log.Println("Configuration")
+	log.Printf("Model Type:\t\t%s", jkModelType)
+	log.Printf("Folds:\t\t%d", jkFolds)
+	log.Printf("Parallel Folds:\t%d", jackknifeParallel())
+	if jkMemBudget > 0 {
+		log.Printf("Memory Budget:\t%d MB (%d MB per fold)", jkMemBudget, jkFoldMem)
+	}
+	log.Printf("Work Dir:\t\t%s", jkWorkDir)
+	log.Println()
+	if jkModelType == "joint" {
+		log.Printf("Train Gold Conll:\t%s", tConll)
+	}
+	log.Printf("Train Gold Lattices:\t%s", tLatDis)
+	log.Printf("Train Amb Lattices:\t%s", tLatAmb)
+	log.Println()
+	log.Printf("Out Mapping:\t\t%s", jkOutMap)
+	if jkModelType == "joint" {
+		log.Printf("Out Conll:\t\t%s", jkOutConll)
+		log.Printf("Out Segmentation:\t%s", jkOutSeg)
+	}
+	log.Println()
+}
+
+// jackknifeParallel returns the number of folds to run concurrently,
+// bounded by the memory budget
+func jackknifeParallel() int {
+	parallel := jkParallel
+	if jkMemBudget > 0 && jkFoldMem > 0 && jkMemBudget/jkFoldMem < parallel {
+		parallel = jkMemBudget / jkFoldMem
+	}
+	if parallel < 1 {
+		parallel = 1
+	}
+	return parallel
+}
+
+// readBlocks reads a file of sentences separated by empty lines
+// (lattice, mapping and conll formats), each block including its
+// terminating empty line
+func readBlocks(filename string) ([][]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var (
+		blocks  [][]string
+		current []string
+	)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		current = append(current, line)
+		if len(line) == 0 {
+			blocks = append(blocks, current)
+			current = nil
+		}
+	}
+	if len(current) > 0 {
+		blocks = append(blocks, append(current, ""))
+	}
+	return blocks, scanner.Err()
+}
+
+func writeBlocks(filename string, blocks [][]string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer := bufio.NewWriter(file)
+	for _, block := range blocks {
+		for _, line := range block {
+			writer.WriteString(line)
+			writer.WriteByte('\n')
+		}
+	}
+	return writer.Flush()
+}
+
+// splitFold returns the blocks of fold k (of numFolds contiguous folds),
+// and all other blocks
+func splitFold(blocks [][]string, k, numFolds int) (heldOut, rest [][]string) {
+	start, end := k*len(blocks)/numFolds, (k+1)*len(blocks)/numFolds
+	rest = make([][]string, 0, len(blocks)-(end-start))
+	rest = append(rest, blocks[:start]...)
+	rest = append(rest, blocks[end:]...)
+	return blocks[start:end], rest
+}
+
+func concatFiles(output string, inputs []string) error {
+	out, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	for _, input := range inputs {
+		in, err := os.Open(input)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(out, in)
+		in.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// absPathArgs returns args with relative paths of existing files made
+// absolute, as fold apps run in the fold directory
+func absPathArgs(args []string) ([]string, error) {
+	absArgs := make([]string, len(args))
+	for i, arg := range args {
+		absArgs[i] = arg
+		if len(arg) == 0 || arg[0] == '-' || filepath.IsAbs(arg) {
+			continue
+		}
+		if _, err := os.Stat(arg); err != nil {
+			continue
+		}
+		abs, err := filepath.Abs(arg)
+		if err != nil {
+			return nil, err
+		}
+		absArgs[i] = abs
+	}
+	return absArgs, nil
+}
+
+// runFoldApp runs the md or joint app for a fold in a separate process,
+// as the apps keep their state in package globals
+func runFoldApp(executable string, fold *jackknifeFold, appArgs []string, stage string) error {
+	args := []string{jkModelType}
+	args = append(args, appArgs...)
+	if jkModelType == "joint" {
+		args = append(args,
+			"-tc", fold.trainConll,
+			"-td", fold.trainDis,
+			"-tl", fold.trainAmb,
+			"-in", fold.heldOutAmb,
+			"-oc", fold.outConll,
+			"-om", fold.outMap,
+			"-os", fold.outSeg,
+			"-m", fold.model)
+	} else {
+		// -mn is set to a model name that won't be found, so that md
+		// uses (trains or loads) the model file of the fold
+		args = append(args,
+			"-td", fold.trainDis,
+			"-tl", fold.trainAmb,
+			"-in", fold.heldOutAmb,
+			"-om", fold.outMap,
+			"-m", fold.model,
+			"-mn", fold.model+".none")
+	}
+	logFile, err := os.Create(filepath.Join(fold.dir, stage+".log"))
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+	cmd := exec.Command(executable, args...)
+	// the apps write temporary and intermediate files to the working directory,
+	// so that folds running in parallel must not share it
+	cmd.Dir = fold.dir
+	cmd.Stdout, cmd.Stderr = logFile, logFile
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("fold %d %s failed (see %s): %v", fold.num, stage, logFile.Name(), err)
+	}
+	return nil
+}
+
+func Jackknife(cmd *commander.Command, args []string) error {
+	REQUIRED_FLAGS := []string{"td", "tl", "om"}
+	switch jkModelType {
+	case "md":
+	case "joint":
+		REQUIRED_FLAGS = append(REQUIRED_FLAGS, "tc", "oc", "os")
+	default:
+		log.Fatalln("Unknown model type", jkModelType, "(expected md or joint)")
+	}
+	VerifyFlags(cmd, REQUIRED_FLAGS)
+	if jkFolds < 2 {
+		log.Fatalln("At least 2 folds are required, got", jkFolds)
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if args, err = absPathArgs(args); err != nil {
+		return err
+	}
+	if len(jkWorkDir) == 0 {
+		jkWorkDir, err = ioutil.TempDir("", "yap-jackknife")
+		if err != nil {
+			return err
+		}
+		if !jkKeep {
+			defer os.RemoveAll(jkWorkDir)
+		}
+	} else if err = os.MkdirAll(jkWorkDir, 0755); err != nil {
+		return err
+	}
+	if jkWorkDir, err = filepath.Abs(jkWorkDir); err != nil {
+		return err
+	}
+	if allOut {
+		JackknifeConfigOut()
+	}
+
+	disBlocks, err := readBlocks(tLatDis)
+	if err != nil {
+		return err
+	}
+	ambBlocks, err := readBlocks(tLatAmb)
+	if err != nil {
+		return err
+	}
+	if len(disBlocks) != len(ambBlocks) {
+		return fmt.Errorf("Got %d disambiguated lattices and %d ambiguous lattices", len(disBlocks), len(ambBlocks))
+	}
+	var conllBlocks [][]string
+	if jkModelType == "joint" {
+		if conllBlocks, err = readBlocks(tConll); err != nil {
+			return err
+		}
+		if len(conllBlocks) != len(disBlocks) {
+			return fmt.Errorf("Got %d conll sentences and %d lattices", len(conllBlocks), len(disBlocks))
+		}
+	}
+	if len(disBlocks) < jkFolds {
+		return fmt.Errorf("Got %d sentences, less than the %d folds", len(disBlocks), jkFolds)
+	}
+	if allOut {
+		log.Println("Read", len(disBlocks), "training sentences")
+	}
+
+	folds := make([]*jackknifeFold, jkFolds)
+	for k := range folds {
+		dir := filepath.Join(jkWorkDir, fmt.Sprintf("fold%d", k))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		fold := &jackknifeFold{
+			num:        k,
+			dir:        dir,
+			trainConll: filepath.Join(dir, "train.conll"),
+			trainDis:   filepath.Join(dir, "train.dis.lattice"),
+			trainAmb:   filepath.Join(dir, "train.amb.lattice"),
+			heldOutAmb: filepath.Join(dir, "heldout.amb.lattice"),
+			outConll:   filepath.Join(dir, "heldout.conll"),
+			outMap:     filepath.Join(dir, "heldout.mapping"),
+			outSeg:     filepath.Join(dir, "heldout.seg"),
+			model:      filepath.Join(dir, "model"),
+		}
+		_, trainDis := splitFold(disBlocks, k, jkFolds)
+		heldOutAmb, trainAmb := splitFold(ambBlocks, k, jkFolds)
+		for _, f := range []struct {
+			name   string
+			blocks [][]string
+		}{{fold.trainDis, trainDis}, {fold.trainAmb, trainAmb}, {fold.heldOutAmb, heldOutAmb}} {
+			if err := writeBlocks(f.name, f.blocks); err != nil {
+				return err
+			}
+		}
+		if jkModelType == "joint" {
+			_, trainConll := splitFold(conllBlocks, k, jkFolds)
+			if err := writeBlocks(fold.trainConll, trainConll); err != nil {
+				return err
+			}
+		}
+		folds[k] = fold
+	}
+
+	var (
+		wg        sync.WaitGroup
+		errLock   sync.Mutex
+		foldErr   error
+		semaphore = make(chan bool, jackknifeParallel())
+	)
+	for _, fold := range folds {
+		wg.Add(1)
+		go func(fold *jackknifeFold) {
+			defer wg.Done()
+			semaphore <- true
+			defer func() { <-semaphore }()
+			// the first run trains the fold's model, the second finds it and parses
+			for _, stage := range []string{"train", "parse"} {
+				if allOut {
+					log.Println("Fold", fold.num, "started", stage)
+				}
+				if err := runFoldApp(executable, fold, args, stage); err != nil {
+					errLock.Lock()
+					foldErr = err
+					errLock.Unlock()
+					return
+				}
+			}
+			if allOut {
+				log.Println("Fold", fold.num, "done")
+			}
+		}(fold)
+	}
+	wg.Wait()
+	if foldErr != nil {
+		return foldErr
+	}
+
+	outputs := map[string]func(*jackknifeFold) string{
+		jkOutMap: func(f *jackknifeFold) string { return f.outMap },
+	}
+	if jkModelType == "joint" {
+		outputs[jkOutConll] = func(f *jackknifeFold) string { return f.outConll }
+		outputs[jkOutSeg] = func(f *jackknifeFold) string { return f.outSeg }
+	}
+	for output, foldFile := range outputs {
+		inputs := make([]string, len(folds))
+		for k, fold := range folds {
+			inputs[k] = foldFile(fold)
+		}
+		if err := concatFiles(output, inputs); err != nil {
+			return err
+		}
+		if allOut {
+			log.Println("Wrote jackknifed predictions to", output)
+		}
+	}
+	return nil
+}
+
+func JackknifeCmd() *commander.Command {
+	cmd := &commander.Command{
+		Run:       Jackknife,
+		UsageLine: "jackknife <file options> [-- <md/joint options>]",
+		Short:     "produces predicted morphology of a training set by jackknifing",
+		Long: `
+produces predicted morphological analyses (and trees, for joint) of a training set,
+by splitting it into K folds, training md (or joint) on K-1 folds and parsing the held-out fold
+
+	$ ./yap jackknife -t md -k 10 -td <train disamb. lat> -tl <train amb. lat> -om <out map> [options] -- [md options]
+	$ ./yap jackknife -t joint -k 10 -tc <conll> -td <train disamb. lat> -tl <train amb. lat> -oc <out conll> -om <out map> -os <out seg> [options] -- [joint options]
+
+Options following -- are passed to md (or joint) for every fold, with relative paths of
+existing files made absolute. Folds run in separate processes in their fold directory;
+each fold requires about -foldmem MB of memory.
+
+`,
+		Flag: *flag.NewFlagSet("jackknife", flag.ExitOnError),
+	}
+	cmd.Flag.StringVar(&jkModelType, "t", "md", "Model type [md, joint]")
+	cmd.Flag.IntVar(&jkFolds, "k", 10, "Number of folds")
+	cmd.Flag.IntVar(&jkParallel, "j", 1, "Max number of folds to run in parallel")
+	cmd.Flag.IntVar(&jkMemBudget, "mem", 0, "Optional - Memory budget in MB, limiting the folds run in parallel (0 = no limit)")
+	cmd.Flag.IntVar(&jkFoldMem, "foldmem", 8192, "Estimated memory of a single fold in MB")
+	cmd.Flag.StringVar(&jkWorkDir, "dir", "", "Optional - Directory for fold files and models (default: temporary directory)")
+	cmd.Flag.BoolVar(&jkKeep, "keep", false, "Keep the temporary fold directory")
+	cmd.Flag.StringVar(&tConll, "tc", "", "Training Conll File (joint)")
+	cmd.Flag.StringVar(&tLatDis, "td", "", "Training Disambiguated Lattices File")
+	cmd.Flag.StringVar(&tLatAmb, "tl", "", "Training Ambiguous Lattices File")
+	cmd.Flag.StringVar(&jkOutMap, "om", "", "Output Mapping File")
+	cmd.Flag.StringVar(&jkOutConll, "oc", "", "Output Conll File (joint)")
+	cmd.Flag.StringVar(&jkOutSeg, "os", "", "Output Segmentation File (joint)")
+	return cmd
+}
@@ -0,0 +1,111 @@
+package app
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadBlocks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "yap-jackknife-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tests := []struct {
+		name, content string
+		expected      [][]string
+	}{
+		{"terminated", "a\nb\n\nc\n\n", [][]string{{"a", "b", ""}, {"c", ""}}},
+		{"unterminated", "a\n\nb\nc\n", [][]string{{"a", ""}, {"b", "c", ""}}},
+		{"empty", "", nil},
+	}
+	for _, test := range tests {
+		filename := filepath.Join(dir, test.name)
+		if err := ioutil.WriteFile(filename, []byte(test.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		blocks, err := readBlocks(filename)
+		if err != nil {
+			t.Errorf("%s: got error %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(blocks, test.expected) {
+			t.Errorf("%s: got blocks %q, expected %q", test.name, blocks, test.expected)
+		}
+		// blocks are written back with their terminating empty lines
+		if len(blocks) == 0 {
+			continue
+		}
+		rewritten := filepath.Join(dir, test.name+".out")
+		if err := writeBlocks(rewritten, blocks); err != nil {
+			t.Fatal(err)
+		}
+		if reread, err := readBlocks(rewritten); err != nil || !reflect.DeepEqual(reread, blocks) {
+			t.Errorf("%s: got rewritten blocks %q (error %v), expected %q", test.name, reread, err, blocks)
+		}
+	}
+	if _, err := readBlocks(filepath.Join(dir, "missing")); err == nil {
+		t.Error("Expected error reading a missing file")
+	}
+}
+
+func TestSplitFold(t *testing.T) {
+	blocks := make([][]string, 7)
+	for i := range blocks {
+		blocks[i] = []string{string('a' + rune(i)), ""}
+	}
+	const numFolds = 3
+	var heldOutAll [][]string
+	for k := 0; k < numFolds; k++ {
+		heldOut, rest := splitFold(blocks, k, numFolds)
+		if len(heldOut) < len(blocks)/numFolds || len(heldOut) > len(blocks)/numFolds+1 {
+			t.Errorf("Fold %d: got %d held out blocks of %d", k, len(heldOut), len(blocks))
+		}
+		if len(heldOut)+len(rest) != len(blocks) {
+			t.Errorf("Fold %d: got %d held out and %d rest blocks, expected %d in total", k, len(heldOut), len(rest), len(blocks))
+		}
+		for _, block := range rest {
+			for _, held := range heldOut {
+				if reflect.DeepEqual(block, held) {
+					t.Errorf("Fold %d: block %q is both held out and in the rest", k, block)
+				}
+			}
+		}
+		heldOutAll = append(heldOutAll, heldOut...)
+	}
+	// the held out folds partition the blocks, in order
+	if !reflect.DeepEqual(heldOutAll, blocks) {
+		t.Errorf("Got concatenated held out folds %q, expected %q", heldOutAll, blocks)
+	}
+	// splitting a middle fold must not modify the blocks
+	original := append([][]string(nil), blocks...)
+	splitFold(blocks, 1, numFolds)
+	if !reflect.DeepEqual(blocks, original) {
+		t.Errorf("splitFold modified the blocks to %q, expected %q", blocks, original)
+	}
+}
+
+func TestAbsPathArgs(t *testing.T) {
+	file, err := ioutil.TempFile(".", "yap-jackknife-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	defer os.Remove(file.Name())
+	relative := filepath.Base(file.Name())
+	abs, err := filepath.Abs(relative)
+	if err != nil {
+		t.Fatal(err)
+	}
+	args, err := absPathArgs([]string{"-f", relative, "-b", "64", "-l", "missing.labels", "/abs/path"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"-f", abs, "-b", "64", "-l", "missing.labels", "/abs/path"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("Got args %v, expected %v", args, expected)
+	}
+}
@@ -10,13 +10,13 @@ import (
 	"yap/nlp/format/lattice"
 	"yap/nlp/format/mapping"
 	"yap/nlp/format/segmentation"
+	"yap/nlp/parser/dependency/pseudoproj"
 	. "yap/nlp/parser/dependency/transition"
 	"yap/nlp/parser/dependency/transition/morph"
 	"yap/nlp/parser/disambig"
 	"yap/nlp/parser/joint"
 	nlp "yap/nlp/types"
 	"yap/util"
-	"yap/util/conf"
 
 	"fmt"
 	"log"
@@ -49,7 +49,7 @@ func SetupEnum(relations []string) {
 
 func CombineJointCorpus(graphs, goldLats, ambLats []interface{}) ([]interface{}, int) {
 	if len(graphs) != len(goldLats) || len(graphs) != len(ambLats) {
-		panic(fmt.Sprintf("Got mismatched training slice inputs (graphs, gold lattices, ambiguous lattices):", len(graphs), len(goldLats), len(ambLats)))
+		panic(fmt.Sprintf("Got mismatched training slice inputs (graphs, gold lattices, ambiguous lattices): %v %v %v", len(graphs), len(goldLats), len(ambLats)))
 	}
 	morphGraphs := make([]interface{}, len(graphs))
 	var (
@@ -71,7 +71,7 @@ func CombineJointCorpus(graphs, goldLats, ambLats []interface{}) ([]interface{},
 
 func CombineToGoldMorphs(goldLats, ambLats []interface{}) ([]interface{}, int) {
 	if len(goldLats) != len(ambLats) {
-		panic(fmt.Sprintf("Got mismatched training slice inputs (gold lattices, ambiguous lattices):", len(goldLats), len(ambLats)))
+		panic(fmt.Sprintf("Got mismatched training slice inputs (gold lattices, ambiguous lattices): %v %v", len(goldLats), len(ambLats)))
 	}
 	morphGraphs := make([]interface{}, len(goldLats))
 	var (
@@ -92,6 +92,79 @@ func CombineToGoldMorphs(goldLats, ambLats []interface{}) ([]interface{}, int) {
 	return morphGraphs, numSentNoGold
 }
 
+// ReadJointTrainingSet reads the training graphs (-tc, unless given as
+// goldConll), disambiguated and ambiguous lattices (-td and -tl), and
+// combines them to the gold graphs of joint
+func ReadJointTrainingSet(goldConll []interface{}) ([]interface{}, error) {
+	if goldConll == nil {
+		var e error
+		goldConll, e = ReadGoldGraphs(tConll, true)
+		if e != nil {
+			log.Println(e)
+			return nil, e
+		}
+	}
+
+	var goldDisLat []interface{}
+	if !useConllU {
+		if allOut {
+			log.Println("Dis. Lat.:\tReading training disambiguated lattices from", tLatDis)
+		}
+		lDis, lDisE := lattice.ReadFile(tLatDis, limit)
+		if lDisE != nil {
+			log.Println(lDisE)
+			return nil, lDisE
+		}
+		if allOut {
+			log.Println("Dis. Lat.:\tRead", len(lDis), "disambiguated lattices")
+			log.Println("Dis. Lat.:\tConverting lattice format to internal structure")
+		}
+		goldDisLat = lattice.Lattice2SentenceCorpus(lDis, EWord, EPOS, EWPOS, EMorphProp, EMHost, EMSuffix)
+	} else {
+		goldDisLat = make([]interface{}, len(goldConll))
+		for i, sent := range goldConll {
+			if projGraph, ok := sent.(*pseudoproj.MorphGraph); ok {
+				sent = projGraph.Morph
+			}
+			goldDisLat[i] = sent.(*morph.BasicMorphGraph).Lattice
+		}
+	}
+
+	if allOut {
+		log.Println("Amb. Lat:\tReading ambiguous lattices from", tLatAmb)
+	}
+	var (
+		lAmb  []lattice.Lattice
+		lAmbE error
+	)
+	if useConllU {
+		lAmb, lAmbE = lattice.ReadULFile(tLatAmb, limit)
+	} else {
+		lAmb, lAmbE = lattice.ReadFile(tLatAmb, limit)
+	}
+	if lAmbE != nil {
+		log.Println(lAmbE)
+		return nil, lAmbE
+	}
+	if allOut {
+		log.Println("Amb. Lat:\tRead", len(lAmb), "ambiguous lattices")
+		log.Println("Amb. Lat:\tConverting lattice format to internal structure")
+	}
+	goldAmbLat := lattice.Lattice2SentenceCorpus(lAmb, EWord, EPOS, EWPOS, EMorphProp, EMHost, EMSuffix)
+	if allOut {
+		log.Println("Combining train files into gold morph graphs with original lattices")
+	}
+	combined, missingGold := CombineJointCorpus(goldConll, goldDisLat, goldAmbLat)
+
+	if allOut {
+		log.Println("Combined", len(combined), "graphs, with", missingGold, "missing at least one gold path in lattice")
+
+		log.Println()
+
+	}
+	return combined, nil
+}
+
 func JointConfigOut(outModelFile string, b search.Interface, t transition.TransitionSystem) {
 	log.Println("*** CONFIGURATION ***")
 	log.Printf("Beam:             \t%s", b.Name())
@@ -107,6 +180,19 @@ func JointConfigOut(outModelFile string, b search.Interface, t transition.Transi
 	log.Printf("Limit (thousands):\t%v", limit)
 	log.Printf("Use CoNLL-U:\t\t%v", useConllU)
 	// log.Printf("Model file:\t\t%s", outModelFile)
+	if len(InitModelFile) > 0 {
+		log.Printf("Init model file:\t%s", InitModelFile)
+	}
+	if len(EnsembleModelFiles) > 0 {
+		log.Printf("Ensemble models:\t%s", EnsembleModelFiles)
+	}
+	if TrainSeed != 0 {
+		log.Printf("Shuffle Seed:\t\t%d", TrainSeed)
+	}
+	if len(PseudoProjStr) > 0 {
+		log.Printf("Pseudo-projective:\t%s", PseudoProjStr)
+	}
+	log.Printf("Repair:\t\t\t%s", RepairStr)
 
 	log.Println()
 	log.Printf("Features File:\t%s", JointFeaturesFile)
@@ -119,16 +205,14 @@ func JointConfigOut(outModelFile string, b search.Interface, t transition.Transi
 		os.Exit(1)
 	}
 	JointFeaturesFile = outFeaturesFile
-	log.Printf("Labels File:\t\t%s", DepLabelsFile)
-	outLabelsFile := DepLabelsFile
-	labelsExists := VerifyExists(outLabelsFile)
-	if !labelsExists {
-		outLabelsFile, labelsExists = util.LocateFile(outLabelsFile, DEFAULT_CONF_DIRS)
+	if !VerifyExists(DepLabelsFile) {
+		if outLabelsFile, labelsExists := util.LocateFile(DepLabelsFile, DEFAULT_CONF_DIRS); labelsExists {
+			DepLabelsFile = outLabelsFile
+		}
 	}
-	if !labelsExists {
+	if !LabelsConfigOut() {
 		os.Exit(1)
 	}
-	DepLabelsFile = outLabelsFile
 	log.Println()
 	log.Println("Data")
 	if len(tConll) > 0 {
@@ -176,6 +260,8 @@ func JointConfigOut(outModelFile string, b search.Interface, t transition.Transi
 }
 
 func JointTrainAndParse(cmd *commander.Command, args []string) error {
+	SetupSeed(true)
+	RepairStrategy()
 	// *** SETUP ***
 	paramFunc, exists := nlp.MDParams[MdParamFuncName]
 	if !exists {
@@ -188,24 +274,11 @@ func JointTrainAndParse(cmd *commander.Command, args []string) error {
 	}
 
 	var (
-		arcSystem     transition.TransitionSystem
-		model         *transitionmodel.AvgMatrixSparse = &transitionmodel.AvgMatrixSparse{}
-		terminalStack int
+		model      *transitionmodel.AvgMatrixSparse = &transitionmodel.AvgMatrixSparse{}
+		parseModel transitionmodel.Interface        = model
 	)
 
-	switch DepArcSystemStr {
-	case "standard":
-		arcSystem = &ArcStandard{}
-		terminalStack = 1
-	case "eager":
-		arcSystem = &ArcEager{
-			ArcStandard: ArcStandard{},
-		}
-		terminalStack = 0
-	default:
-		panic("Unknown arc system")
-	}
-
+	arcSystem, terminalStack := depArcSystemStub(DepArcSystemStr)
 	arcSystem.AddDefaultOracle()
 
 	jointTrans := &joint.JointTrans{
@@ -220,7 +293,12 @@ func JointTrainAndParse(cmd *commander.Command, args []string) error {
 	outModelFile := JointModelFile
 	modelExists := VerifyExists(outModelFile)
 	if !modelExists {
-		outModelFile, modelExists = util.LocateFile(outModelFile, DEFAULT_MODEL_DIRS)
+		if location, found := util.LocateFile(outModelFile, DEFAULT_MODEL_DIRS); found {
+			outModelFile, modelExists = location, true
+		}
+	}
+	if err := checkInitModel(InitModelFile, outModelFile, modelExists); err != nil {
+		log.Fatalln(err)
 	}
 	REQUIRED_FLAGS := []string{"in", "oc", "om", "os"}
 	VerifyFlags(cmd, REQUIRED_FLAGS)
@@ -228,6 +306,7 @@ func JointTrainAndParse(cmd *commander.Command, args []string) error {
 	if !modelExists {
 		REQUIRED_FLAGS = []string{"it", "tc", "td", "tl", "in", "oc", "om", "os", "ots", "f", "l", "jointstr", "oraclestr"}
 		VerifyFlags(cmd, REQUIRED_FLAGS)
+		LoadClusters()
 	}
 
 	// RegisterTypes()
@@ -240,45 +319,34 @@ func JointTrainAndParse(cmd *commander.Command, args []string) error {
 
 	JointConfigOut(outModelFile, confBeam, transitionSystem)
 
-	relations, err := conf.ReadFile(DepLabelsFile)
-	if err != nil {
-		log.Println("Failed reading dependency labels configuration file:", DepLabelsFile)
-		log.Fatalln(err)
-	}
+	relations := ReadRelations(!modelExists)
 	if allOut {
 		log.Println()
 		// start processing - setup enumerations
 		log.Println("Setup enumerations")
 	}
-	SetupEnum(relations.Values)
+	SetupEnum(relations)
+	var initModel *transitionmodel.AvgMatrixSparse
+	if !modelExists && InitModelFile != "" {
+		initModel = LoadInitModel(InitModelFile, true)
+	}
+	var goldConll []interface{}
+	if PseudoProjStr != "" && !modelExists {
+		if allOut {
+			log.Println("Reading training sentences from", tConll, "for pseudo-projective labels")
+		}
+		graphs, e := ReadGoldGraphs(tConll, true)
+		if e != nil {
+			log.Println(e)
+			return e
+		}
+		goldConll = PseudoProjectivize(graphs, relations, SetupMorphTransEnum)
+	}
 
 	// after calling SetupEnum, enums are instantiated and set according to the relations
 	// therefore we re-instantiate the arc system with the right parameters
 	// DON'T REMOVE!!
-	switch DepArcSystemStr {
-	case "standard":
-		arcSystem = &ArcStandard{
-			SHIFT:       SH.Value(),
-			LEFT:        LA.Value(),
-			RIGHT:       RA.Value(),
-			Transitions: ETrans,
-			Relations:   ERel,
-		}
-	case "eager":
-		arcSystem = &ArcEager{
-			ArcStandard: ArcStandard{
-				SHIFT:       SH.Value(),
-				LEFT:        LA.Value(),
-				RIGHT:       RA.Value(),
-				Relations:   ERel,
-				Transitions: ETrans,
-			},
-			REDUCE:  RE.Value(),
-			POPROOT: PR.Value(),
-		}
-	default:
-		panic("Unknown arc system")
-	}
+	arcSystem = DepArcSystem(DepArcSystemStr)
 	arcSystem.AddDefaultOracle()
 	jointTrans.ArcSys = arcSystem
 	jointTrans.Transitions = ETrans
@@ -334,84 +402,9 @@ func JointTrainAndParse(cmd *commander.Command, args []string) error {
 			log.Println("Generating Gold Sequences For Training")
 			log.Println("Conll:\tReading training conll sentences from", tConll)
 		}
-		var goldConll []interface{}
-		if useConllU {
-			s, _, e := conllu.ReadFile(tConll, limit)
-			if e != nil {
-				log.Println(e)
-				return e
-			}
-			if allOut {
-				log.Println("Conll:\tRead", len(s), "sentences")
-				log.Println("Conll:\tConverting from conll to internal structure")
-			}
-			goldConll = conllu.ConllU2MorphGraphCorpus(s, EWord, EPOS, EWPOS, ERel, EMorphProp, EMHost, EMSuffix)
-		} else {
-			s, e := conll.ReadFile(tConll, limit)
-			if e != nil {
-				log.Println(e)
-				return e
-			}
-			if allOut {
-				log.Println("Conll:\tRead", len(s), "sentences")
-				log.Println("Conll:\tConverting from conll to internal structure")
-			}
-			goldConll = conll.Conll2GraphCorpus(s, EWord, EPOS, EWPOS, ERel, EMHost, EMSuffix)
-		}
-
-		var goldDisLat []interface{}
-		if !useConllU {
-			if allOut {
-				log.Println("Dis. Lat.:\tReading training disambiguated lattices from", tLatDis)
-			}
-			lDis, lDisE := lattice.ReadFile(tLatDis, limit)
-			if lDisE != nil {
-				log.Println(lDisE)
-				return lDisE
-			}
-			if allOut {
-				log.Println("Dis. Lat.:\tRead", len(lDis), "disambiguated lattices")
-				log.Println("Dis. Lat.:\tConverting lattice format to internal structure")
-			}
-			goldDisLat = lattice.Lattice2SentenceCorpus(lDis, EWord, EPOS, EWPOS, EMorphProp, EMHost, EMSuffix)
-		} else {
-			goldDisLat = make([]interface{}, len(goldConll))
-			for i, sent := range goldConll {
-				goldDisLat[i] = sent.(*morph.BasicMorphGraph).Lattice
-			}
-		}
-
-		if allOut {
-			log.Println("Amb. Lat:\tReading ambiguous lattices from", tLatAmb)
-		}
-		var (
-			lAmb  []lattice.Lattice
-			lAmbE error
-		)
-		if useConllU {
-			lAmb, lAmbE = lattice.ReadUDFile(tLatAmb, limit)
-		} else {
-			lAmb, lAmbE = lattice.ReadFile(tLatAmb, limit)
-		}
-		if lAmbE != nil {
-			log.Println(lAmbE)
-			return lAmbE
-		}
-		if allOut {
-			log.Println("Amb. Lat:\tRead", len(lAmb), "ambiguous lattices")
-			log.Println("Amb. Lat:\tConverting lattice format to internal structure")
-		}
-		goldAmbLat := lattice.Lattice2SentenceCorpus(lAmb, EWord, EPOS, EWPOS, EMorphProp, EMHost, EMSuffix)
-		if allOut {
-			log.Println("Combining train files into gold morph graphs with original lattices")
-		}
-		combined, missingGold := CombineJointCorpus(goldConll, goldDisLat, goldAmbLat)
-
-		if allOut {
-			log.Println("Combined", len(combined), "graphs, with", missingGold, "missing at least one gold path in lattice")
-
-			log.Println()
-
+		combined, err := ReadJointTrainingSet(goldConll)
+		if err != nil {
+			return err
 		}
 
 		if allOut {
@@ -436,7 +429,13 @@ func JointTrainAndParse(cmd *commander.Command, args []string) error {
 				formatters = append(formatters, formatter)
 			}
 		}
-		model := transitionmodel.NewAvgMatrixSparse(NumFeatures, formatters, false)
+		var model *transitionmodel.AvgMatrixSparse
+		if initModel != nil {
+			VerifyInitModel(initModel, NumFeatures, formatters)
+			model = initModel
+		} else {
+			model = transitionmodel.NewAvgMatrixSparse(NumFeatures, formatters, false)
+		}
 		model.Extractor = extractor
 		// model.Classifier = func(t transition.Transition) string {
 		// 	if t.Value() < MD.Value() {
@@ -455,11 +454,13 @@ func JointTrainAndParse(cmd *commander.Command, args []string) error {
 				EMSuffix:      EMSuffix,
 				ERel:          ERel,
 				ETrans:        ETrans,
+				Clusters:      Clusters,
 				TerminalStack: terminalStack,
 				TerminalQueue: 0,
 			},
 			MDConfig: disambig.MDConfig{
 				ETokens:     ETokens,
+				Clusters:    Clusters,
 				POP:         POP,
 				Transitions: ETrans,
 				ParamFunc:   paramFunc,
@@ -550,7 +551,7 @@ func JointTrainAndParse(cmd *commander.Command, args []string) error {
 				lConvAmbE error
 			)
 			if useConllU {
-				lConvAmb, lConvAmbE = lattice.ReadUDFile(input, limitdev)
+				lConvAmb, lConvAmbE = lattice.ReadULFile(input, limitdev)
 			} else {
 				lConvAmb, lConvAmbE = lattice.ReadFile(input, limitdev)
 			}
@@ -631,16 +632,17 @@ func JointTrainAndParse(cmd *commander.Command, args []string) error {
 		if allOut {
 			log.Println("Done Training")
 			// util.LogMemory()
-			// log.Println()
-			// serialization := &Serialization{
-			// 	model.Serialize(-1),
-			// 	EWord, EPOS, EWPOS, EMHost, EMSuffix, EMorphProp, ETrans, ETokens,
-			// }
-			// log.Println("Writing final model to", outModelFile)
-			// WriteModel(outModelFile, serialization)
-			// if allOut {
-			// 	log.Println("Done writing model")
-			// }
+			log.Println()
+			log.Println("Writing final model to", outModelFile)
+		}
+		serialization := &Serialization{
+			model.Serialize(-1),
+			EWord, EPOS, EWPOS, EMHost, EMSuffix, EMorphProp, ETrans, ETokens,
+			Clusters,
+		}
+		WriteModel(outModelFile, serialization)
+		if allOut {
+			log.Println("Done writing model")
 		}
 		return nil
 	} else {
@@ -649,34 +651,19 @@ func JointTrainAndParse(cmd *commander.Command, args []string) error {
 		}
 		serialization := ReadModel(outModelFile)
 		model.Deserialize(serialization.WeightModel)
+		RestoreClusters(serialization.Clusters)
+		if PseudoProjStr != "" {
+			// the transitions of the model include the encoded labels of its training data
+			RestorePseudoProjectiveRelations(serialization.ETrans, relations, SetupMorphTransEnum)
+		}
 		EWord, EPOS, EWPOS, EMHost, EMSuffix, EMorphProp, ETrans, ETokens = serialization.EWord, serialization.EPOS, serialization.EWPOS, serialization.EMHost, serialization.EMSuffix, serialization.EMorphProp, serialization.ETrans, serialization.ETokens
 		if allOut && !parseOut {
 			log.Println("Loaded model")
 		}
-		switch DepArcSystemStr {
-		case "standard":
-			arcSystem = &ArcStandard{
-				SHIFT:       SH.Value(),
-				LEFT:        LA.Value(),
-				RIGHT:       RA.Value(),
-				Transitions: ETrans,
-				Relations:   ERel,
-			}
-		case "eager":
-			arcSystem = &ArcEager{
-				ArcStandard: ArcStandard{
-					SHIFT:       SH.Value(),
-					LEFT:        LA.Value(),
-					RIGHT:       RA.Value(),
-					Relations:   ERel,
-					Transitions: ETrans,
-				},
-				REDUCE:  RE.Value(),
-				POPROOT: PR.Value(),
-			}
-		default:
-			panic("Unknown arc system")
+		if EnsembleModelFiles != "" {
+			parseModel = LoadEnsemble(outModelFile, extractor, groups)
 		}
+		arcSystem = DepArcSystem(DepArcSystemStr)
 		arcSystem.AddDefaultOracle()
 		jointTrans.ArcSys = arcSystem
 		jointTrans.Transitions = ETrans
@@ -705,7 +692,7 @@ func JointTrainAndParse(cmd *commander.Command, args []string) error {
 		lAmbE error
 	)
 	if useConllU {
-		lAmb, lAmbE = lattice.ReadUDFile(input, limit)
+		lAmb, lAmbE = lattice.ReadULFile(input, limit)
 	} else {
 		lAmb, lAmbE = lattice.ReadFile(input, limit)
 	}
@@ -763,11 +750,13 @@ func JointTrainAndParse(cmd *commander.Command, args []string) error {
 			EMSuffix:      EMSuffix,
 			ERel:          ERel,
 			ETrans:        ETrans,
+			Clusters:      Clusters,
 			TerminalStack: terminalStack,
 			TerminalQueue: 0,
 		},
 		MDConfig: disambig.MDConfig{
 			ETokens:     ETokens,
+			Clusters:    Clusters,
 			POP:         POP,
 			Transitions: ETrans,
 			ParamFunc:   paramFunc,
@@ -783,7 +772,7 @@ func JointTrainAndParse(cmd *commander.Command, args []string) error {
 		Transitions:          ETrans,
 		EstimatedTransitions: 1000, // chosen by random dice roll
 	}
-	beam.Model = model
+	beam.Model = parseModel
 	beam.ShortTempAgenda = true
 	parsedGraphs := Parse(predAmbLat, beam)
 
@@ -794,11 +783,16 @@ func JointTrainAndParse(cmd *commander.Command, args []string) error {
 		log.Println("Writing to output file")
 	}
 	var graphAsConll []interface{}
+	depGraphs := parsedGraphs
+	if PseudoProjStr != "" {
+		depGraphs = Deprojectivize(parsedGraphs)
+	}
+	depGraphs = ValidateGraphs(depGraphs, beam)
 	if useConllU {
-		graphAsConll = conllu.MorphGraph2ConllCorpus(parsedGraphs)
+		graphAsConll = conllu.MorphGraph2ConllCorpus(depGraphs)
 		conllu.WriteFile(outConll, graphAsConll)
 	} else {
-		graphAsConll = conll.MorphGraph2ConllCorpus(parsedGraphs)
+		graphAsConll = conll.MorphGraph2ConllCorpus(depGraphs)
 		conll.WriteFile(outConll, graphAsConll)
 	}
 	if allOut {
@@ -838,7 +832,12 @@ runs morpho-syntactic training and parsing
 	cmd.Flag.IntVar(&Iterations, "it", 1, "Number of Perceptron Iterations")
 	cmd.Flag.IntVar(&BeamSize, "b", 64, "Beam Size")
 	cmd.Flag.StringVar(&JointModelFile, "m", "joint_arc_zeager_model_temp_i33.b64", "Joint model name")
-	cmd.Flag.StringVar(&DepArcSystemStr, "a", "eager", "Optional - Arc System [standard, eager]")
+	cmd.Flag.StringVar(&DepArcSystemStr, "a", "eager", "Optional - Arc System [standard, eager, swap, hybrid]")
+	cmd.Flag.StringVar(&PseudoProjStr, "pp", "", "Optional - Pseudo-projective label encoding of non-projective arcs [head, path, headpath]")
+	cmd.Flag.StringVar(&RepairStr, "repair", "none", "Optional - Repair parsed graphs that are not well formed trees [none, root, score (keep the highest scored root)]")
+	cmd.Flag.StringVar(&InitModelFile, "init", "", "Optional - Initialize training from an existing model (fine-tuning), requires that the output model does not exist")
+	cmd.Flag.StringVar(&EnsembleModelFiles, "ensemble", "", "Optional - Comma separated models to ensemble with the model when parsing")
+	cmd.Flag.Int64Var(&TrainSeed, "seed", 0, "Optional - Shuffle training instances every iteration with this seed, and train deterministically (0 = file order)")
 
 	cmd.Flag.StringVar(&tConll, "tc", "", "Training Conll File")
 	cmd.Flag.StringVar(&tLatDis, "td", "", "Training Disambiguated Lattices File")
@@ -853,6 +852,13 @@ runs morpho-syntactic training and parsing
 	cmd.Flag.StringVar(&tSeg, "ots", "", "Output Training Segmentation File")
 	cmd.Flag.StringVar(&JointFeaturesFile, "f", "jointzeager.yaml", "Features Configuration File")
 	cmd.Flag.StringVar(&DepLabelsFile, "l", "hebtb.labels.conf", "Dependency Labels Configuration File")
+	cmd.Flag.BoolVar(&DepLabelsAuto, "lauto", false, "Optional - Derive the dependency labels from the training file")
+	cmd.Flag.IntVar(&DepLabelsMin, "lmin", 1, "Optional - Minimal count of derived labels, rarer labels are trained as dep (with -lauto)")
+	cmd.Flag.BoolVar(&DepLabelsMerge, "lmerge", false, "Optional - Merge the derived labels with the labels file (with -lauto)")
+	cmd.Flag.StringVar(&DepLabelsOut, "lout", "", "Optional - Write the dependency labels to a file")
+	cmd.Flag.StringVar(&BrownFile, "brown", "", "Optional - Brown clusters file (wcluster paths) for cluster attributes (c, c<N>)")
+	cmd.Flag.StringVar(&W2VFile, "w2v", "", "Optional - word2vec text file clustered with k-means for the cluster id attribute (k)")
+	cmd.Flag.IntVar(&W2VClusters, "w2vk", 256, "Optional - Number of k-means clusters of the word2vec vectors")
 	cmd.Flag.StringVar(&MdParamFuncName, "p", "Funcs_Main_POS_Both_Prop", "Param Func types: ["+nlp.AllParamFuncNames+"]")
 	cmd.Flag.StringVar(&JointStrategy, "jointstr", "ArcGreedy", "Joint Strategy: ["+joint.JointStrategies+"]")
 	cmd.Flag.StringVar(&OracleStrategy, "oraclestr", "ArcGreedy", "Oracle Strategy: ["+joint.OracleStrategies+"]")
@@ -866,6 +872,7 @@ runs morpho-syntactic training and parsing
 	cmd.Flag.IntVar(&limit, "limit", 0, "limit training set (in thousands)")
 	cmd.Flag.IntVar(&limitdev, "limitdev", 0, "limit dev set (in thousands)")
 	cmd.Flag.BoolVar(&useConllU, "conllu", false, "use CoNLL-U-format input file (for disamb lattices)")
+	cmd.Flag.BoolVar(&conllu.ENHANCED, "enhanced", false, "Optional - Write enhanced UD dependencies to the DEPS column of CoNLL-U output")
 	cmd.Flag.BoolVar(&hebMACompat, "hebcompat", false, "Read CoNLLu with hebrew lexicon compatability")
 	// cmd.Flag.BoolVar(&AlignBeam, "align", false, "Use Beam Alignment")
 	// cmd.Flag.BoolVar(&AverageScores, "average", false, "Use Average Scoring")
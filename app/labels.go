@@ -0,0 +1,192 @@
+package app
+
+import (
+	"yap/nlp/format/conll"
+	dep "yap/nlp/parser/dependency/transition"
+	"yap/nlp/parser/dependency/validate"
+	nlp "yap/nlp/types"
+	"yap/util/conf"
+
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// maximal number of line numbers reported per unknown label
+const MAX_REPORTED_LINES = 10
+
+var (
+	// derive the dependency labels from the training data
+	DepLabelsAuto bool
+	// minimal count of derived labels, rarer labels are trained as validate.DEFAULT_LABEL
+	DepLabelsMin int = 1
+	// merge the derived labels with the labels file
+	DepLabelsMerge bool
+	// optional file to write the dependency labels to
+	DepLabelsOut string
+
+	// training labels below the DepLabelsMin threshold
+	rareLabels map[string]bool
+)
+
+// LabelsConfigOut logs the source of the dependency labels, and returns
+// false if the labels file is required but missing
+func LabelsConfigOut() bool {
+	if DepLabelsAuto {
+		log.Printf("Labels:\t\t\tderived from training data (min count %d)", DepLabelsMin)
+		if !DepLabelsMerge {
+			return true
+		}
+		log.Printf("Merged Labels File:\t%s", DepLabelsFile)
+	} else {
+		log.Printf("Labels File:\t\t%s", DepLabelsFile)
+	}
+	return VerifyExists(DepLabelsFile)
+}
+
+// ReadRelations returns the dependency labels of the labels file or, with
+// -lauto, the labels derived from the training file. The training (if
+// training) and dev gold files are checked for labels missing from the
+// result, which is fatal for training data.
+func ReadRelations(training bool) []string {
+	var labels []string
+	if !DepLabelsAuto || DepLabelsMerge {
+		relations, err := conf.ReadFile(DepLabelsFile)
+		if err != nil {
+			log.Println("Failed reading dependency labels configuration file:", DepLabelsFile)
+			log.Fatalln(err)
+		}
+		labels = relations.Values
+	}
+	if DepLabelsAuto {
+		if len(tConll) == 0 {
+			log.Fatalln("Deriving dependency labels (-lauto) requires a training file (-tc)")
+		}
+		trainLabels, err := conll.ReadLabelsFile(tConll)
+		if err != nil {
+			log.Println("Failed reading dependency labels of", tConll)
+			log.Fatalln(err)
+		}
+		labels = DeriveLabels(trainLabels, labels, DepLabelsMin)
+	}
+	if training && len(tConll) > 0 {
+		if unknown := CheckLabels(tConll, labels); unknown > 0 {
+			log.Fatalln("Found", unknown, "unknown dependency labels in training file", tConll, "(add them to", DepLabelsFile, "or use -lauto)")
+		}
+	}
+	if len(inputGold) > 0 {
+		if unknown := CheckLabels(inputGold, labels); unknown > 0 {
+			log.Println("Warning: found", unknown, "unknown dependency labels in dev gold file", inputGold)
+		}
+	}
+	if len(DepLabelsOut) > 0 {
+		if err := WriteLabels(DepLabelsOut, labels); err != nil {
+			log.Fatalln("Failed writing dependency labels to", DepLabelsOut, err)
+		}
+		if allOut {
+			log.Println("Wrote", len(labels), "dependency labels to", DepLabelsOut)
+		}
+	}
+	return labels
+}
+
+// DeriveLabels appends the labels of the training data that occur at least
+// minCount times to the given labels. Rarer labels are trained as
+// validate.DEFAULT_LABEL, which is added if needed.
+func DeriveLabels(trainLabels *conll.Labels, labels []string, minCount int) []string {
+	var (
+		result = append([]string{}, labels...)
+		known  = make(map[string]bool, len(labels))
+	)
+	for _, label := range labels {
+		known[label] = true
+	}
+	rareLabels = make(map[string]bool)
+	for _, label := range trainLabels.Values {
+		if known[label] || label == nlp.ROOT_LABEL {
+			continue
+		}
+		if trainLabels.Count(label) < minCount {
+			rareLabels[label] = true
+			continue
+		}
+		known[label] = true
+		result = append(result, label)
+	}
+	if len(rareLabels) > 0 && !known[string(validate.DEFAULT_LABEL)] {
+		result = append(result, string(validate.DEFAULT_LABEL))
+	}
+	if allOut {
+		log.Printf("Labels:\tderived %d labels from %s", len(result)-len(labels), tConll)
+		if len(rareLabels) > 0 {
+			log.Printf("Labels:\t%d labels occurring less than %d times are trained as %s", len(rareLabels), minCount, validate.DEFAULT_LABEL)
+		}
+	}
+	return result
+}
+
+// CheckLabels logs the labels of a CoNLL(-U) file that are not in labels
+// (or rare training labels) with their line numbers, and returns their number
+func CheckLabels(file string, labels []string) int {
+	fileLabels, err := conll.ReadLabelsFile(file)
+	if err != nil {
+		log.Println("Failed reading dependency labels of", file)
+		log.Fatalln(err)
+	}
+	known := make(map[string]bool, len(labels)+1)
+	known[nlp.ROOT_LABEL] = true
+	for _, label := range labels {
+		known[label] = true
+	}
+	var unknown int
+	for _, label := range fileLabels.Values {
+		if known[label] || rareLabels[label] {
+			continue
+		}
+		unknown++
+		lines := fileLabels.Lines[label]
+		reported := make([]string, 0, MAX_REPORTED_LINES)
+		for i := 0; i < len(lines) && i < MAX_REPORTED_LINES; i++ {
+			reported = append(reported, fmt.Sprintf("%d", lines[i]))
+		}
+		if len(lines) > MAX_REPORTED_LINES {
+			reported = append(reported, "...")
+		}
+		log.Printf("Unknown dependency label %s in %s (%d occurrences) at lines %s", label, file, len(lines), strings.Join(reported, ", "))
+	}
+	return unknown
+}
+
+// WriteLabels writes labels in the labels configuration file format
+func WriteLabels(file string, labels []string) error {
+	fObj, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer fObj.Close()
+	for _, label := range labels {
+		if _, err = fmt.Fprintln(fObj, label); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// relabelRare relabels the arcs of gold graphs with labels below the
+// DepLabelsMin threshold as validate.DEFAULT_LABEL
+func relabelRare(graphs []interface{}) {
+	if len(rareLabels) == 0 {
+		return
+	}
+	relation, _ := ERel.IndexOf(validate.DEFAULT_LABEL)
+	for _, instance := range graphs {
+		g := instance.(nlp.LabeledDependencyGraph)
+		for i := 0; i < g.NumberOfNodes(); i++ {
+			arc, ok := g.GetLabeledArc(i).(*dep.BasicDepArc)
+			if ok && rareLabels[string(arc.RawRelation)] {
+				arc.Relation, arc.RawRelation = relation, validate.DEFAULT_LABEL
+			}
+		}
+	}
+}
@@ -0,0 +1,402 @@
+package app
+
+import (
+	"yap/nlp/format/lex"
+	"yap/nlp/parser/ma"
+	"yap/nlp/types"
+	"yap/util"
+
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gonuts/commander"
+	"github.com/gonuts/flag"
+)
+
+var (
+	lexCompiledFile               string
+	lexLemma, lexPOS, lexFeatures string
+	lexLimit, lexSample           int
+)
+
+// lexSampleTokens returns up to n tokens spread over the sorted tokens of the
+// lexicon, and each of them following a prefix, taken in turn
+func lexSampleTokens(prefixes, lexicon map[string][]types.BasicMorphemes, n int) []string {
+	tokens := make([]string, 0, len(lexicon))
+	for token := range lexicon {
+		tokens = append(tokens, token)
+	}
+	sort.Strings(tokens)
+	prefixTokens := make([]string, 0, len(prefixes))
+	for prefix := range prefixes {
+		prefixTokens = append(prefixTokens, prefix)
+	}
+	sort.Strings(prefixTokens)
+	if n > len(tokens) {
+		n = len(tokens)
+	}
+	var sample []string
+	for i := 0; i < n; i++ {
+		token := tokens[i*len(tokens)/n]
+		sample = append(sample, token)
+		if len(prefixTokens) > 0 {
+			sample = append(sample, prefixTokens[i%len(prefixTokens)]+token)
+		}
+	}
+	return sample
+}
+
+// latticeMorphemes returns the morphemes of the lattices of a sentence,
+// sorted, by all their fields
+func latticeMorphemes(sent types.LatticeSentence) []string {
+	var morphemes []string
+	for _, lat := range sent {
+		for _, m := range lat.Morphemes {
+			morphemes = append(morphemes, fmt.Sprintf("%d %d %s %s %s %s %s", m.From(), m.To(), m.Form, m.Lemma, m.CPOS, m.POS, m.FeatureStr))
+		}
+	}
+	sort.Strings(morphemes)
+	return morphemes
+}
+
+// compareAnalyzers returns the tokens that two analyzers analyze differently
+func compareAnalyzers(analyzer, other ma.MorphologicalAnalyzer, tokens []string) []string {
+	var differ []string
+	for _, token := range tokens {
+		sent, _ := analyzer.Analyze([]string{token})
+		otherSent, _ := other.Analyze([]string{token})
+		if !reflect.DeepEqual(latticeMorphemes(sent), latticeMorphemes(otherSent)) {
+			differ = append(differ, token)
+		}
+	}
+	return differ
+}
+
+// lexSource describes a source file of a compiled lexicon by its name and
+// digest
+func lexSource(file string) string {
+	digest, err := util.MD5File(file)
+	if err != nil {
+		log.Fatalln("Failed reading", file, err)
+	}
+	return fmt.Sprintf("%s md5:%s", file, digest)
+}
+
+func LexCompile(cmd *commander.Command, args []string) error {
+	REQUIRED_FLAGS := []string{"out"}
+	var (
+		compiled                   *lex.Compiled
+		prefixes, lexicon          map[string][]types.BasicMorphemes
+		textMA, compiledMA         ma.MorphologicalAnalyzer
+		maType                     string
+		start                      = time.Now()
+		textDuration, readDuration time.Duration
+	)
+	if len(udLex) > 0 {
+		VerifyFlags(cmd, REQUIRED_FLAGS)
+		log.Println("Reading UD Lex file", udLex)
+		maData := new(ma.MADict)
+		if lex.IsCompiledFile(udLex) {
+			log.Fatalln(udLex, "is already compiled")
+		}
+		if err := maData.ReadUDLexFile(udLex); err != nil {
+			return err
+		}
+		textDuration = time.Since(start)
+		lexicon, maType = maData.Data, ma.COMPILED_UD_LEX
+		compiled = maData.CompileUDLex([]string{lexSource(udLex)})
+		textMA = maData
+	} else {
+		prefixLocation, found := util.LocateFile(HebMaPrefixFile, HEB_MA_DEFAULT_DATA_DIRS)
+		if found {
+			HebMaPrefixFile = prefixLocation
+		} else {
+			REQUIRED_FLAGS = append(REQUIRED_FLAGS, "prefix")
+		}
+		lexiconLocation, found := util.LocateFile(HebMaLexiconFile, HEB_MA_DEFAULT_DATA_DIRS)
+		if found {
+			HebMaLexiconFile = lexiconLocation
+		} else {
+			REQUIRED_FLAGS = append(REQUIRED_FLAGS, "lexicon")
+		}
+		VerifyFlags(cmd, REQUIRED_FLAGS)
+		if lex.IsCompiledFile(HebMaLexiconFile) {
+			log.Fatalln(HebMaLexiconFile, "is already compiled")
+		}
+		SetupHebMaFormat(outFormat)
+		maData := new(ma.BGULex)
+		maData.MAType = outFormat
+		log.Println("Reading Morphological Analyzer BGU Prefixes")
+		maData.LoadPrefixes(HebMaPrefixFile)
+		log.Println("Reading Morphological Analyzer BGU Lexicon")
+		maData.LoadLex(HebMaLexiconFile, HebMaNnpnofeats)
+		textDuration = time.Since(start)
+		prefixes, lexicon, maType = maData.Prefixes, maData.Lex, outFormat
+		compiled = maData.Compile([]string{lexSource(HebMaPrefixFile), lexSource(HebMaLexiconFile)})
+		textMA = maData
+	}
+	log.Println("Compiled", len(compiled.Prefixes), "prefixes and", len(compiled.Lexicon), "tokens with", len(compiled.Strings), "distinct strings")
+	if err := lex.WriteCompiledFile(lexCompiledFile, compiled); err != nil {
+		return err
+	}
+	log.Println("Wrote compiled lexicon to", lexCompiledFile)
+
+	// the compiled lexicon is read back and checked entry by entry
+	start = time.Now()
+	written, err := lex.ReadCompiledFile(lexCompiledFile)
+	if err != nil {
+		return err
+	}
+	if err := written.Verify(maType); err != nil {
+		return err
+	}
+	writtenPrefixes, writtenLexicon := written.Expand()
+	readDuration = time.Since(start)
+	var differ []string
+	if len(udLex) == 0 {
+		differ = lex.CompareAnalyses(prefixes, writtenPrefixes)
+	}
+	differ = append(differ, lex.CompareAnalyses(lexicon, writtenLexicon)...)
+	if len(differ) > 0 {
+		if len(differ) > 10 {
+			differ = append(differ[:10], "...")
+		}
+		return fmt.Errorf("analyses of the compiled lexicon %s differ from the text lexicon: %v", lexCompiledFile, differ)
+	}
+	log.Printf("Checked the analyses of %d prefixes and %d tokens: identical", len(writtenPrefixes), len(writtenLexicon))
+	log.Printf("Loading time: text %v, compiled %v", textDuration, readDuration)
+
+	// the analyzer loading the compiled lexicon is checked to analyze a
+	// sample of tokens as the analyzer loading the text lexicon
+	if len(udLex) > 0 {
+		maData := new(ma.MADict)
+		if err := maData.ReadUDLexFile(lexCompiledFile); err != nil {
+			return err
+		}
+		compiledMA = maData
+	} else {
+		maData := new(ma.BGULex)
+		maData.MAType = outFormat
+		maData.LoadLex(lexCompiledFile, HebMaNnpnofeats)
+		compiledMA = maData
+	}
+	sample := lexSampleTokens(prefixes, lexicon, lexSample)
+	differ = compareAnalyzers(textMA, compiledMA, sample)
+	if len(differ) > 0 {
+		if len(differ) > 10 {
+			differ = append(differ[:10], "...")
+		}
+		return fmt.Errorf("analyzing with the compiled lexicon %s differs from the text lexicon for tokens: %v", lexCompiledFile, differ)
+	}
+	log.Printf("Checked the analysis of a sample of %d tokens: identical", len(sample))
+	return nil
+}
+
+func LexCompileCmd() *commander.Command {
+	cmd := &commander.Command{
+		Run:       LexCompile,
+		UsageLine: "compile <file options> [arguments]",
+		Short:     "compile a lexicon for fast loading",
+		Long: `
+compile the prefixes and lexicon of the Hebrew analyzer (hebma), or a UD
+lexicon (ma -udlex), to a binary lexicon; the analyses of the compiled
+lexicon are checked to be identical to the text lexicon for every entry, and
+the analyzer loading it to analyze a sample of tokens (lexicon tokens, with
+and without prefixes) as the analyzer loading the text lexicon
+
+compiled lexicons are given to hebma (and api) as their -lexicon, and to ma
+as its -udlex; the -format and -addnnpnofeats options must match
+
+	$ ./yap lex compile [-prefix <file> -lexicon <file> [-format spmrl|ud] [-addnnpnofeats]] [-udlex <file>] [-sample <n>] -out <file>
+
+`,
+		Flag: *flag.NewFlagSet("compile", flag.ExitOnError),
+	}
+	cmd.Flag.StringVar(&HebMaPrefixFile, "prefix", "bgupreflex_withdef.utf8.hr", "Prefix file for morphological analyzer")
+	cmd.Flag.StringVar(&HebMaLexiconFile, "lexicon", "bgulex.utf8.hr", "Lexicon file for morphological analyzer")
+	cmd.Flag.StringVar(&outFormat, "format", "spmrl", "Lattice format of the analyses [spmrl|ud]")
+	cmd.Flag.BoolVar(&HebMaNnpnofeats, "addnnpnofeats", false, "Add NNP in lex but without features")
+	cmd.Flag.BoolVar(&lex.LOG_FAILURES, "showlexerror", false, "Log errors encountered when loading the lexicon")
+	cmd.Flag.StringVar(&udLex, "udlex", "", "Optional - UD Lexicon to compile instead of the Hebrew analyzer lexicon")
+	cmd.Flag.StringVar(&lexCompiledFile, "out", "", "Output compiled lexicon file")
+	cmd.Flag.IntVar(&lexSample, "sample", 10000, "Optional - Number of lexicon tokens, also following a prefix, analyzed with the text and compiled lexicons to check that they are analyzed the same")
+	return cmd
+}
+
+// loadLexExplainer loads the analyzer given by flags: the data-driven
+// analyzer of a dictionary (ma) if given, otherwise the Hebrew analyzer
+// (hebma)
+func loadLexExplainer(cmd *commander.Command) ma.Explainer {
+	if len(dictFile) > 0 || len(udLex) > 0 {
+		maData := new(ma.MADict)
+		if len(dictFile) > 0 {
+			log.Println("Reading Morphological Analyzer Dictionary")
+			if err := maData.ReadFile(dictFile); err != nil {
+				log.Fatalln("Failed reading MA dict file", err)
+			}
+			maData.ComputeOOVMSRs(maxOOVMSRPerPOS)
+		}
+		if len(udLex) > 0 {
+			log.Println("Reading UD Lex file", udLex)
+			if err := maData.ReadUDLexFile(udLex); err != nil {
+				log.Fatalln("Failed reading UD lex file", err)
+			}
+		}
+		return maData
+	}
+	VerifyFlags(cmd, LocateHebMaLexicon())
+	SetupHebMaFormat(outFormat)
+	maData := new(ma.BGULex)
+	maData.MAType = outFormat
+	LoadHebMaLexicon(maData)
+	LoadHebMaUserLex(maData)
+	SetupHebMaRecognizers(maData)
+	LoadHebMaOOVGuesser(maData)
+	maData.AlwaysNNP = HebMaAlwaysnnp
+	return maData
+}
+
+// lexAnalyzerFlags adds the flags of the analyzers of lookup and search
+func lexAnalyzerFlags(cmd *commander.Command) {
+	cmd.Flag.StringVar(&HebMaPrefixFile, "prefix", "bgupreflex_withdef.utf8.hr", "Prefix file for morphological analyzer")
+	cmd.Flag.StringVar(&HebMaLexiconFile, "lexicon", "bgulex.utf8.hr", "Lexicon file for morphological analyzer")
+	cmd.Flag.StringVar(&outFormat, "format", "spmrl", "Lattice format of the analyses [spmrl|ud]")
+	cmd.Flag.BoolVar(&HebMaNnpnofeats, "addnnpnofeats", false, "Add NNP in lex but without features")
+	cmd.Flag.BoolVar(&HebMaAlwaysnnp, "alwaysnnp", false, "Always add NNP to tokens and prefixed subtokens")
+	cmd.Flag.StringVar(&HebMaUserLexFiles, "userlex", "", "Optional - Comma separated user lexicon files (lexicon format, or tab separated token, lemma, POS, features)")
+	cmd.Flag.BoolVar(&HebMaUserLexOverride, "userlexoverride", false, "Optional - User lexicon analyses replace the lexicon analyses of their tokens")
+	cmd.Flag.StringVar(&HebMaRecognizers, "recognizers", "", "Optional - Comma separated pattern recognizers of tokens not in the lexicon, or none (default: all)")
+	cmd.Flag.StringVar(&HebMaRecognizersConf, "recognizers_conf", "", "Optional - Pattern recognizers configuration file, e.g. recognizers.yaml (default: recognizers ["+ma.RecognizerNames(ma.DefaultRecognizers)+"])")
+	cmd.Flag.StringVar(&HebMaOOVGuesserFile, "oovguesser", "", "Optional - Dictionary learned by malearn -oovguesser, whose OOV guesser analyzes OOV tokens")
+	cmd.Flag.BoolVar(&lex.LOG_FAILURES, "showlexerror", false, "Log errors encountered when loading the lexicon")
+	cmd.Flag.StringVar(&dictFile, "dict", "", "Optional - Dictionary of the data-driven analyzer (ma) instead of the Hebrew analyzer")
+	cmd.Flag.StringVar(&udLex, "udlex", "", "Optional - UD Lexicon of the data-driven analyzer (ma)")
+	cmd.Flag.IntVar(&maxOOVMSRPerPOS, "maxmsrperpos", 10, "For OOV tokens of the data-driven analyzer, max MSRs per POS to add")
+}
+
+// formatAnalysis formats the morphemes of an analysis as lemma/POS/features
+func formatAnalysis(morphs types.BasicMorphemes) string {
+	formatted := make([]string, len(morphs))
+	for i, m := range morphs {
+		lemma, features := m.Lemma, m.FeatureStr
+		if len(lemma) == 0 {
+			lemma = "_"
+		}
+		if len(features) == 0 {
+			features = "_"
+		}
+		formatted[i] = fmt.Sprintf("%s/%s/%s", lemma, m.CPOS, features)
+	}
+	return strings.Join(formatted, " + ")
+}
+
+func LexLookup(cmd *commander.Command, args []string) error {
+	explainer := loadLexExplainer(cmd)
+	tokens := args
+	if len(tokens) == 0 {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			tokens = append(tokens, strings.Fields(scanner.Text())...)
+		}
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+	}
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	for _, token := range tokens {
+		segmentations, groups := ma.GroupBySegmentation(explainer.Explain(token))
+		fmt.Fprintf(out, "%s\t%d segmentations\n", token, len(segmentations))
+		for _, segmentation := range segmentations {
+			fmt.Fprintf(out, "  %s\n", segmentation)
+			for _, e := range groups[segmentation] {
+				fmt.Fprintf(out, "    %-24s %s\n", e.Source, formatAnalysis(e.Morphemes))
+			}
+		}
+	}
+	return nil
+}
+
+func LexLookupCmd() *commander.Command {
+	cmd := &commander.Command{
+		Run:       LexLookup,
+		UsageLine: "lookup <file options> [tokens]",
+		Short:     "print the analyses of tokens with their source",
+		Long: `
+print all analyses of tokens, given as arguments or read from stdin,
+grouped by segmentation, with the source of each analysis: the lexicon, a
+pattern recognizer, an OOV guess, or a prefix followed by a host from any of
+these (prefix+lexicon, prefix+recognizer:<name>, prefix+oov)
+
+the Hebrew analyzer (hebma) is used, unless a dictionary or UD lexicon of the
+data-driven analyzer (ma) is given
+
+	$ ./yap lex lookup [-prefix <file> -lexicon <file>] [-dict <file>] [-udlex <file>] [tokens]
+
+`,
+		Flag: *flag.NewFlagSet("lookup", flag.ExitOnError),
+	}
+	lexAnalyzerFlags(cmd)
+	return cmd
+}
+
+func LexSearch(cmd *commander.Command, args []string) error {
+	if len(lexLemma) == 0 && len(lexPOS) == 0 && len(lexFeatures) == 0 {
+		log.Fatalln("Expected at least one of -lemma, -pos and -feats")
+	}
+	explainer := loadLexExplainer(cmd)
+	query := ma.ParseMorphemeQuery(lexLemma, lexPOS, lexFeatures)
+	matches := ma.Search(explainer.Dictionary(), query, lexLimit)
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	for _, match := range matches {
+		for _, analysis := range match.Analyses {
+			fmt.Fprintf(out, "%s\t%s\n", match.Token, formatAnalysis(analysis))
+		}
+	}
+	log.Println("Found", len(matches), "tokens")
+	return nil
+}
+
+func LexSearchCmd() *commander.Command {
+	cmd := &commander.Command{
+		Run:       LexSearch,
+		UsageLine: "search <file options> [-lemma <lemma>] [-pos <POS>] [-feats <features>]",
+		Short:     "find the lexicon tokens with analyses of a lemma or POS and features",
+		Long: `
+print the tokens of the lexicon, with their analyses that have a morpheme of
+a lemma, POS (or CPOS) and features, e.g. -pos NN -feats gen=F|num=P; all
+given criteria must match the same morpheme
+
+	$ ./yap lex search [-prefix <file> -lexicon <file>] [-dict <file>] [-udlex <file>] [-lemma <lemma>] [-pos <POS>] [-feats <features>] [-limit <n>]
+
+`,
+		Flag: *flag.NewFlagSet("search", flag.ExitOnError),
+	}
+	lexAnalyzerFlags(cmd)
+	cmd.Flag.StringVar(&lexLemma, "lemma", "", "Optional - Lemma of the morpheme")
+	cmd.Flag.StringVar(&lexPOS, "pos", "", "Optional - POS or CPOS of the morpheme")
+	cmd.Flag.StringVar(&lexFeatures, "feats", "", "Optional - | separated features of the morpheme (e.g. gen=F|num=P)")
+	cmd.Flag.IntVar(&lexLimit, "limit", 0, "Optional - Maximal number of tokens to print (default: all)")
+	return cmd
+}
+
+func LexCmd() *commander.Command {
+	cmd := &commander.Command{
+		UsageLine: "lex <command> [arguments]",
+		Short:     "lexicon tools",
+		Subcommands: []*commander.Command{
+			LexCompileCmd(),
+			LexLookupCmd(),
+			LexSearchCmd(),
+		},
+		Flag: *flag.NewFlagSet("lex", flag.ExitOnError),
+	}
+	return cmd
+}
@@ -40,6 +40,8 @@ func MAConfigOut() {
 		log.Printf("CoNLL-U Input:\t%s", conlluFile)
 	} else {
 		log.Printf("Raw Input:\t\t%s", inRawFile)
+		log.Printf("Normalization:\t%s", RawNormalization)
+		log.Printf("Offsets:\t\t%v", RawOffsets)
 	}
 	log.Printf("Output:\t\t%s", outLatticeFile)
 	log.Printf("Output Format:\t%v", outFormat)
@@ -58,6 +60,8 @@ func MA(cmd *commander.Command, args []string) error {
 	VerifyFlags(cmd, REQUIRED_FLAGS)
 
 	MAConfigOut()
+	VerifyRawOffsets(outFormat)
+	SetupRawNormalizer()
 
 	log.Println("Reading Morphological Analyzer Dictionary")
 	maData := new(ma.MADict)
@@ -66,6 +70,9 @@ func MA(cmd *commander.Command, args []string) error {
 	}
 	log.Println("OOV POSs:", strings.Join(maData.TopPOS, ", "))
 	maData.ComputeOOVMSRs(maxOOVMSRPerPOS)
+	if maData.OOVGuesser != nil {
+		log.Println("OOV Guesser:", maData.OOVGuesser)
+	}
 	log.Println()
 	if udLex != "" {
 		// Reading a UD lexicon will override the data-driven lexicon
@@ -79,6 +86,7 @@ func MA(cmd *commander.Command, args []string) error {
 	var (
 		sents        []nlp.BasicSentence
 		sentComments [][]string
+		sentOffsets  [][]*raw.TokenOffsets
 		oovVectors   []interface{}
 		rawOOV       interface{}
 		err          error
@@ -99,7 +107,7 @@ func MA(cmd *commander.Command, args []string) error {
 			sents[i] = newSent
 		}
 	} else {
-		sents, err = raw.ReadFile(inRawFile, limit)
+		sents, sentOffsets, err = raw.ReadFileWithOffsets(inRawFile, limit, RawNormalizer)
 		sentComments = make([][]string, len(sents))
 		for i, sent := range sents {
 			sentComments[i] = []string{fmt.Sprintf("# text %s", strings.Join(sent.Tokens(), " ")) }
@@ -140,11 +148,17 @@ func MA(cmd *commander.Command, args []string) error {
 	for i, sent := range sents {
 		if streamOut {
 			lattices[0], rawOOV = maData.Analyze(sent.Tokens())
+			if RawOffsets && sentOffsets != nil {
+				SetRawRanges(lattices[0], sentOffsets[i])
+			}
 			output := lattice.Sentence2LatticeCorpus(lattices, nil)
 			lattice.UDWrite(outFile, output, sentComments[i:i+1], []nlp.BasicSentence{rawOOV.(nlp.BasicSentence)})
 			latticesWritten += 1
 		} else {
 			lattices[i], rawOOV = maData.Analyze(sent.Tokens())
+			if RawOffsets && sentOffsets != nil {
+				SetRawRanges(lattices[i], sentOffsets[i])
+			}
 			if oovVectors != nil {
 				oovVectors[i] = rawOOV
 			}
@@ -192,6 +206,9 @@ run data-driven morphological analyzer on raw input
 	cmd.Flag.StringVar(&dictFile, "dict", "", "Dictionary for morphological analyzer")
 	cmd.Flag.StringVar(&udLex, "udlex", "", "UD Lexicon for morphological analyzer")
 	cmd.Flag.StringVar(&inRawFile, "raw", "", "Input raw (tokenized) file")
+	cmd.Flag.StringVar(&RawNormalization, "normalize", raw.DEFAULT_NORMALIZATION, "Optional - Comma separated normalization steps of raw input, all or none ["+strings.Join(raw.NORMALIZATION_STEPS, ", ")+"]")
+	cmd.Flag.StringVar(&RawNormVariantsFile, "normvariants", "", "Optional - Spelling variants file (tab separated variant and standard form) of the variants normalization step")
+	cmd.Flag.BoolVar(&RawOffsets, "offsets", false, "Optional - Write the characters of the raw input of tokens and morphemes (TokenRange in the MISC column), requires -format ud")
 	cmd.Flag.StringVar(&conlluFile, "conllu", "", "CoNLL-U-format input file")
 	cmd.Flag.StringVar(&outLatticeFile, "out", "", "Output lattice file")
 	cmd.Flag.StringVar(&outFormat, "format", "spmrl", "Output lattice format [spmrl|ud]")
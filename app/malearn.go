@@ -2,6 +2,7 @@ package app
 
 import (
 	// "yap/nlp/format/lattice"
+	"yap/nlp/format/raw"
 
 	// nlp "yap/nlp/types"
 	"yap/nlp/parser/ma"
@@ -10,6 +11,7 @@ import (
 	// "fmt"
 	"log"
 	// "os"
+	"strings"
 
 	"github.com/gonuts/commander"
 	"github.com/gonuts/flag"
@@ -19,6 +21,10 @@ var (
 	latFile, rawFile, conlluFile, dataFile string
 	useConllU                              bool // TODO: whatever i don't care anymore
 	maxPOS, maxMSRPerPOS                   int
+	// OOV guesser learning
+	learnOOVGuesser                         bool
+	oovMaxAffix, oovRareFreq, oovMaxGuesses int
+	oovMinProb                              float64
 )
 
 func MALearnConfigOut() {
@@ -28,8 +34,12 @@ func MALearnConfigOut() {
 	} else {
 		log.Printf("Lattice:\t%s", latFile)
 		log.Printf("Raw:\t\t%s", rawFile)
+		log.Printf("Normalization:\t%s", RawNormalization)
 	}
 	log.Printf("Limit:\t%v", limit)
+	if learnOOVGuesser {
+		log.Printf("OOV Guesser:\tmax affix %d, rare freq %d, max guesses %d, min prob %v", oovMaxAffix, oovRareFreq, oovMaxGuesses, oovMinProb)
+	}
 	log.Println()
 	log.Printf("Output:\t%s", dataFile)
 	log.Println()
@@ -48,11 +58,17 @@ func MALearn(cmd *commander.Command, args []string) error {
 	VerifyFlags(cmd, REQUIRED_FLAGS)
 
 	MALearnConfigOut()
+	if !useConllU {
+		SetupRawNormalizer()
+	}
 	log.Println("Starting learning for data-driven morphological analyzer")
 	maData := new(ma.MADict)
 	maData.Language = "Test"
 	maData.MaxTopPOS = maxPOS
 	maData.MaxMSRsPerPOS = maxMSRPerPOS
+	if learnOOVGuesser {
+		maData.OOVGuesser = ma.NewOOVGuesser(oovMaxAffix, oovRareFreq, oovMaxGuesses, oovMinProb)
+	}
 	var (
 		numLearned int
 		err        error
@@ -60,7 +76,7 @@ func MALearn(cmd *commander.Command, args []string) error {
 	if useConllU {
 		numLearned, err = maData.LearnFromConllU(conlluFile, limit)
 	} else {
-		numLearned, err = maData.LearnFromLat(latFile, rawFile, limit)
+		numLearned, err = maData.LearnFromLat(latFile, rawFile, limit, RawNormalizer)
 	}
 	if err != nil {
 		log.Println("Got error learning", err)
@@ -86,10 +102,17 @@ generate a data-driven morphological analysis dictionary for a set of files
 	}
 	cmd.Flag.StringVar(&latFile, "lattice", "", "Lattice-format input file")
 	cmd.Flag.StringVar(&rawFile, "raw", "", "raw sentences input file")
+	cmd.Flag.StringVar(&RawNormalization, "normalize", raw.DEFAULT_NORMALIZATION, "Optional - Comma separated normalization steps of raw input, all or none ["+strings.Join(raw.NORMALIZATION_STEPS, ", ")+"]")
+	cmd.Flag.StringVar(&RawNormVariantsFile, "normvariants", "", "Optional - Spelling variants file (tab separated variant and standard form) of the variants normalization step")
 	cmd.Flag.StringVar(&conlluFile, "conllu", "", "CoNLL-U-format input file")
 	cmd.Flag.StringVar(&dataFile, "out", "", "output file")
 	cmd.Flag.IntVar(&maxMSRPerPOS, "maxmsrperpos", 5, "For OOV tokens, max MSRs per POS to add")
 	cmd.Flag.IntVar(&maxPOS, "maxpos", 5, "For OOV tokens, max POS to add")
 	cmd.Flag.IntVar(&limit, "limit", 0, "limit training set")
+	cmd.Flag.BoolVar(&learnOOVGuesser, "oovguesser", false, "Optional - Learn an OOV guesser of analyses (segmentation, POS and features) by token affixes and shape, used by ma and hebma -oovguesser")
+	cmd.Flag.IntVar(&oovMaxAffix, "oovmaxaffix", 3, "Optional - Longest token prefix and suffix (in characters) of the OOV guesser")
+	cmd.Flag.IntVar(&oovRareFreq, "oovrarefreq", 2, "Optional - Max frequency of the tokens the OOV guesser learns from")
+	cmd.Flag.IntVar(&oovMaxGuesses, "oovmaxguesses", 10, "Optional - Max analyses of an OOV token")
+	cmd.Flag.Float64Var(&oovMinProb, "oovminprob", 0.01, "Optional - Min probability of an analysis of an OOV token")
 	return cmd
 }
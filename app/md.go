@@ -140,6 +140,98 @@ func CombineLatticesCorpus(goldLats, ambLats []interface{}) ([]interface{}, int,
 	return configs, numLatticeNoGold, totalLattices, numSentNoGold
 }
 
+// ReadMDTrainingSet reads the training disambiguated and ambiguous lattices
+// (-td and -tl), and combines them to the gold configurations of md
+func ReadMDTrainingSet() ([]interface{}, error) {
+	var goldDisLat, goldAmbLat []interface{}
+	if useConllU {
+		conllu.IGNORE_LEMMA = lattice.IGNORE_LEMMA
+		if allOut {
+			log.Println("Dis. Lat.:\tReading training disambiguated lattices from (conllU)", tLatDis)
+		}
+		conllus, hasSegmentation, err := conllu.ReadFile(tLatDis, limit)
+		if err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		if allOut {
+			if hasSegmentation {
+				log.Println("Dis. Lat.:\tRead", len(conllus), "disambiguated lattices (conllU) WITH SEGMENTATION")
+			} else {
+				log.Println("Dis. Lat.:\tRead", len(conllus), "disambiguated lattices (conllU) WITHOUT SEGMENTATION")
+			}
+			log.Println("Dis. Lat.:\tConverting lattice format to internal structure")
+		}
+		ERel = util.NewEnumSet(100)
+		morphGraphs := conllu.ConllU2MorphGraphCorpus(conllus, EWord, EPOS, EWPOS, ERel, EMorphProp, EMHost, EMSuffix)
+		goldDisLat = make([]interface{}, len(morphGraphs))
+		for i, val := range morphGraphs {
+			basicMorphGraph := val.(*morph.BasicMorphGraph)
+			goldDisLat[i] = basicMorphGraph.Lattice
+		}
+		if allOut {
+			log.Println("Amb. Lat:\tReading ambiguous conllu lattices from", tLatAmb)
+		}
+		//lAmb, lAmbE := lattice.ReadUDFile(tLatAmb, limit)
+		lAmb, lAmbE := lattice.ReadULFile(tLatAmb, limit)
+		if lAmbE != nil {
+			log.Println(lAmbE)
+			return nil, lAmbE
+		}
+		//clAmb, clAmbE := conllul.ReadFile(tLatAmb, limit)
+		//if clAmbE != nil {
+		//	log.Println(clAmbE)
+		//	return clAmbE
+		//}
+		//lAmb := conllul2Lattices(clAmb)
+		if allOut {
+			log.Println("Amb. Lat:\tRead", len(lAmb), "ambiguous lattices")
+			log.Println("Amb. Lat:\tConverting lattice format to internal structure")
+		}
+		goldAmbLat = lattice.Lattice2SentenceCorpus(lAmb, EWord, EPOS, EWPOS, EMorphProp, EMHost, EMSuffix)
+	} else {
+		if allOut {
+			log.Println("Dis. Lat.:\tReading training disambiguated lattices from", tLatDis)
+		}
+		lDis, lDisE := lattice.ReadFile(tLatDis, limit)
+		if lDisE != nil {
+			log.Println(lDisE)
+			return nil, lDisE
+		}
+		if allOut {
+			log.Println("Dis. Lat.:\tRead", len(lDis), "disambiguated lattices")
+			log.Println("Dis. Lat.:\tConverting lattice format to internal structure")
+		}
+		goldDisLat = lattice.Lattice2SentenceCorpus(lDis, EWord, EPOS, EWPOS, EMorphProp, EMHost, EMSuffix)
+		if allOut {
+			log.Println("Amb. Lat:\tReading ambiguous lattices from", tLatAmb)
+		}
+		lAmb, lAmbE := lattice.ReadFile(tLatAmb, limit)
+		if lAmbE != nil {
+			log.Println(lAmbE)
+			return nil, lAmbE
+		}
+		if allOut {
+			log.Println("Amb. Lat:\tRead", len(lAmb), "ambiguous lattices")
+			log.Println("Amb. Lat:\tConverting lattice format to internal structure")
+		}
+		goldAmbLat = lattice.Lattice2SentenceCorpus(lAmb, EWord, EPOS, EWPOS, EMorphProp, EMHost, EMSuffix)
+	}
+	if allOut {
+		log.Println("Combining train files into gold morph graphs with original lattices")
+	}
+	combined, missingGold, numLattices, sentMissingGold := CombineLatticesCorpus(goldDisLat, goldAmbLat)
+	if limit > 0 {
+		combined = Limit(combined, limit*1000)
+	}
+
+	if allOut {
+		log.Println("Combined", len(combined), "graphs, with", missingGold, "lattices of", numLattices, "missing at least one gold path in lattice in", sentMissingGold, "sentences")
+		log.Println()
+	}
+	return combined, nil
+}
+
 func conllul2Lattices(cls []conllul.ConlluLattice) []lattice.Lattice {
 	result := []lattice.Lattice{}
 	for _, cl := range cls {
@@ -192,6 +284,15 @@ func MDConfigOut(outModelFile string, b search.Interface, t transition.Transitio
 	if len(outModelFile) > 0 {
 		log.Printf("Model file:\t\t%s", outModelFile)
 	}
+	if len(InitModelFile) > 0 {
+		log.Printf("Init model file:\t%s", InitModelFile)
+	}
+	if len(EnsembleModelFiles) > 0 {
+		log.Printf("Ensemble models:\t%s", EnsembleModelFiles)
+	}
+	if TrainSeed != 0 {
+		log.Printf("Shuffle Seed:\t\t%d", TrainSeed)
+	}
 
 	log.Println()
 	log.Printf("Features File:\t%s", MdFeaturesFile)
@@ -230,6 +331,7 @@ func MDConfigOut(outModelFile string, b search.Interface, t transition.Transitio
 }
 
 func MDTrainAndParse(cmd *commander.Command, args []string) error {
+	SetupSeed(true)
 	//BeamSize = MdBeamSize
 	paramFunc, exists := nlp.MDParams[MdParamFuncName]
 	if !exists {
@@ -278,11 +380,15 @@ func MDTrainAndParse(cmd *commander.Command, args []string) error {
 		log.Println("Pre-trained model not found in default directories, looking for", outModelFile)
 		modelExists = VerifyExists(outModelFile)
 	}
+	if err := checkInitModel(InitModelFile, outModelFile, modelExists); err != nil {
+		log.Fatalln(err)
+	}
 
 	if !modelExists {
 		log.Println("No model found, training")
 		REQUIRED_FLAGS = []string{"it", "td", "tl"}
 		VerifyFlags(cmd, REQUIRED_FLAGS)
+		LoadClusters()
 	}
 
 	// RegisterTypes()
@@ -302,6 +408,10 @@ func MDTrainAndParse(cmd *commander.Command, args []string) error {
 		log.Println("Setup enumerations")
 	}
 	SetupMDEnum()
+	var initModel *transitionmodel.AvgMatrixSparse
+	if !modelExists && InitModelFile != "" {
+		initModel = LoadInitModel(InitModelFile, true)
+	}
 	if MdUseWB {
 		mdTrans.(*disambig.MDWBTrans).POP = POP
 		mdTrans.(*disambig.MDWBTrans).Transitions = ETrans
@@ -334,92 +444,9 @@ func MDTrainAndParse(cmd *commander.Command, args []string) error {
 			log.Println("Generating Gold Sequences For Training")
 		}
 
-		const NUM_SENTS = 10
-		var goldDisLat, goldAmbLat []interface{}
-		if useConllU {
-			conllu.IGNORE_LEMMA = lattice.IGNORE_LEMMA
-			if allOut {
-				log.Println("Dis. Lat.:\tReading training disambiguated lattices from (conllU)", tLatDis)
-			}
-			conllus, hasSegmentation, err := conllu.ReadFile(tLatDis, limit)
-			if err != nil {
-				log.Println(err)
-				return err
-			}
-			if allOut {
-				if hasSegmentation {
-					log.Println("Dis. Lat.:\tRead", len(conllus), "disambiguated lattices (conllU) WITH SEGMENTATION")
-				} else {
-					log.Println("Dis. Lat.:\tRead", len(conllus), "disambiguated lattices (conllU) WITHOUT SEGMENTATION")
-				}
-				log.Println("Dis. Lat.:\tConverting lattice format to internal structure")
-			}
-			ERel = util.NewEnumSet(100)
-			morphGraphs := conllu.ConllU2MorphGraphCorpus(conllus, EWord, EPOS, EWPOS, ERel, EMorphProp, EMHost, EMSuffix)
-			goldDisLat = make([]interface{}, len(morphGraphs))
-			for i, val := range morphGraphs {
-				basicMorphGraph := val.(*morph.BasicMorphGraph)
-				goldDisLat[i] = basicMorphGraph.Lattice
-			}
-			if allOut {
-				log.Println("Amb. Lat:\tReading ambiguous conllu lattices from", tLatAmb)
-			}
-			//lAmb, lAmbE := lattice.ReadUDFile(tLatAmb, limit)
-			lAmb, lAmbE := lattice.ReadULFile(tLatAmb, limit)
-			if lAmbE != nil {
-				log.Println(lAmbE)
-				return lAmbE
-			}
-			//clAmb, clAmbE := conllul.ReadFile(tLatAmb, limit)
-			//if clAmbE != nil {
-			//	log.Println(clAmbE)
-			//	return clAmbE
-			//}
-			//lAmb := conllul2Lattices(clAmb)
-			if allOut {
-				log.Println("Amb. Lat:\tRead", len(lAmb), "ambiguous lattices")
-				log.Println("Amb. Lat:\tConverting lattice format to internal structure")
-			}
-			goldAmbLat = lattice.Lattice2SentenceCorpus(lAmb, EWord, EPOS, EWPOS, EMorphProp, EMHost, EMSuffix)
-		} else {
-			if allOut {
-				log.Println("Dis. Lat.:\tReading training disambiguated lattices from", tLatDis)
-			}
-			lDis, lDisE := lattice.ReadFile(tLatDis, limit)
-			if lDisE != nil {
-				log.Println(lDisE)
-				return lDisE
-			}
-			if allOut {
-				log.Println("Dis. Lat.:\tRead", len(lDis), "disambiguated lattices")
-				log.Println("Dis. Lat.:\tConverting lattice format to internal structure")
-			}
-			goldDisLat = lattice.Lattice2SentenceCorpus(lDis, EWord, EPOS, EWPOS, EMorphProp, EMHost, EMSuffix)
-			if allOut {
-				log.Println("Amb. Lat:\tReading ambiguous lattices from", tLatAmb)
-			}
-			lAmb, lAmbE := lattice.ReadFile(tLatAmb, limit)
-			if lAmbE != nil {
-				log.Println(lAmbE)
-				return lAmbE
-			}
-			if allOut {
-				log.Println("Amb. Lat:\tRead", len(lAmb), "ambiguous lattices")
-				log.Println("Amb. Lat:\tConverting lattice format to internal structure")
-			}
-			goldAmbLat = lattice.Lattice2SentenceCorpus(lAmb, EWord, EPOS, EWPOS, EMorphProp, EMHost, EMSuffix)
-		}
-		if allOut {
-			log.Println("Combining train files into gold morph graphs with original lattices")
-		}
-		combined, missingGold, numLattices, sentMissingGold := CombineLatticesCorpus(goldDisLat, goldAmbLat)
-		if limit > 0 {
-			combined = Limit(combined, limit*1000)
-		}
-
-		if allOut {
-			log.Println("Combined", len(combined), "graphs, with", missingGold, "lattices of", numLattices, "missing at least one gold path in lattice in", sentMissingGold, "sentences")
-			log.Println()
+		combined, err := ReadMDTrainingSet()
+		if err != nil {
+			return err
 		}
 
 		if allOut {
@@ -440,10 +467,16 @@ func MDTrainAndParse(cmd *commander.Command, args []string) error {
 		for i, formatter := range group.FeatureTemplates {
 			formatters[i] = formatter
 		}
-		model = transitionmodel.NewAvgMatrixSparse(NumFeatures, formatters, false)
+		if initModel != nil {
+			VerifyInitModel(initModel, NumFeatures, formatters)
+			model = initModel
+		} else {
+			model = transitionmodel.NewAvgMatrixSparse(NumFeatures, formatters, false)
+		}
 
 		conf := &disambig.MDConfig{
 			ETokens:     ETokens,
+			Clusters:    Clusters,
 			POP:         POP,
 			Transitions: ETrans,
 			ParamFunc:   paramFunc,
@@ -661,6 +694,7 @@ func MDTrainAndParse(cmd *commander.Command, args []string) error {
 			serialization := &Serialization{
 				model.Serialize(-1),
 				EWord, EPOS, EWPOS, EMHost, EMSuffix, EMorphProp, ETrans, ETokens,
+				Clusters,
 			}
 			WriteModel(outModelFile, serialization)
 			log.Println("Done")
@@ -673,7 +707,12 @@ func MDTrainAndParse(cmd *commander.Command, args []string) error {
 	}
 	serialization := ReadModel(outModelFile)
 	model.Deserialize(serialization.WeightModel)
+	RestoreClusters(serialization.Clusters)
 	EWord, EPOS, EWPOS, EMHost, EMSuffix, EMorphProp, ETrans, ETokens = serialization.EWord, serialization.EPOS, serialization.EWPOS, serialization.EMHost, serialization.EMSuffix, serialization.EMorphProp, serialization.ETrans, serialization.ETokens
+	var parseModel transitionmodel.Interface = model
+	if EnsembleModelFiles != "" {
+		parseModel = LoadEnsemble(outModelFile, extractor, []byte("MPL"))
+	}
 
 	if MdUseWB {
 		mdTrans = &disambig.MDWBTrans{
@@ -697,6 +736,7 @@ func MDTrainAndParse(cmd *commander.Command, args []string) error {
 	// setup configuration and beam
 	conf := &disambig.MDConfig{
 		ETokens:     ETokens,
+		Clusters:    Clusters,
 		POP:         POP,
 		Transitions: ETrans,
 		ParamFunc:   paramFunc,
@@ -726,7 +766,7 @@ func MDTrainAndParse(cmd *commander.Command, args []string) error {
 		}
 		predAmbLatStream := lattice.Lattice2SentenceStream(lAmb, EWord, EPOS, EWPOS, EMorphProp, EMHost, EMSuffix)
 		beam.ShortTempAgenda = true
-		beam.Model = model
+		beam.Model = parseModel
 		mappings := make(chan interface{}, 2)
 		if allOut {
 			log.Println("Starting parser")
@@ -825,7 +865,7 @@ func MDTrainAndParse(cmd *commander.Command, args []string) error {
 		}
 	}
 	beam.ShortTempAgenda = true
-	beam.Model = model
+	beam.Model = parseModel
 
 	mappings := Parse(predAmbLat, beam)
 
@@ -883,6 +923,9 @@ runs standalone morphological disambiguation training and parsing
 	cmd.Flag.IntVar(&BeamSize, "b", 32, "Beam Size")
 	cmd.Flag.StringVar(&MdModelFile, "m", "model", "Prefix for model file ({m}.b{b}.model)")
 	cmd.Flag.StringVar(&MdModelName, "mn", "hebmd.b32", "Modelfile")
+	cmd.Flag.StringVar(&InitModelFile, "init", "", "Optional - Initialize training from an existing model (fine-tuning), requires that the output model does not exist")
+	cmd.Flag.StringVar(&EnsembleModelFiles, "ensemble", "", "Optional - Comma separated models to ensemble with the model when parsing")
+	cmd.Flag.Int64Var(&TrainSeed, "seed", 0, "Optional - Shuffle training instances every iteration with this seed, and train deterministically (0 = file order)")
 
 	cmd.Flag.StringVar(&tLatDis, "td", "", "Training Disambiguated Lattices File")
 	cmd.Flag.StringVar(&tLatAmb, "tl", "", "Training Ambiguous Lattices File")
@@ -907,6 +950,9 @@ runs standalone morphological disambiguation training and parsing
 	cmd.Flag.BoolVar(&useConllU, "conllu", false, "use CoNLL-U-format input file (for disamb lattices)")
 	cmd.Flag.IntVar(&limit, "limit", 0, "limit training set")
 	cmd.Flag.BoolVar(&MdNoconverge, "noconverge", false, "don't test convergence (run -it number of iterations)")
+	cmd.Flag.StringVar(&BrownFile, "brown", "", "Optional - Brown clusters file (wcluster paths) for cluster attributes (c, c<N>)")
+	cmd.Flag.StringVar(&W2VFile, "w2v", "", "Optional - word2vec text file clustered with k-means for the cluster id attribute (k)")
+	cmd.Flag.IntVar(&W2VClusters, "w2vk", 256, "Optional - Number of k-means clusters of the word2vec vectors")
 	cmd.Flag.BoolVar(&Stream, "stream", false, "Stream data from input through parser to output")
 	return cmd
 }
@@ -0,0 +1,276 @@
+package app
+
+import (
+	"yap/alg/perceptron"
+	"yap/alg/transition"
+	"yap/nlp/format/clusters"
+	"yap/nlp/format/conll"
+	"yap/nlp/format/conllu"
+	"yap/nlp/format/lattice"
+	"yap/nlp/parser/dependency/mst"
+	nlp "yap/nlp/types"
+	"yap/util"
+
+	"encoding/gob"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/gonuts/commander"
+)
+
+const MST_FEATURES_FILE = "mst.yaml"
+
+var (
+	// decode projective trees with the mst parser
+	MSTProjective bool
+)
+
+type MSTSerialization struct {
+	Model                                *mst.Model
+	EWord, EPOS, EWPOS, EMHost, EMSuffix *util.EnumSet
+	ERel                                 *util.EnumSet
+	Clusters                             *clusters.Set
+}
+
+func WriteMSTModel(file string, data *MSTSerialization) {
+	fObj, err := os.Create(file)
+	if err != nil {
+		log.Fatalln("Failed creating model file", file, err)
+	}
+	defer fObj.Close()
+	writer := gob.NewEncoder(fObj)
+	if err = writer.Encode(data); err != nil {
+		log.Fatalln("Failed writing model to", file, err)
+	}
+}
+
+func ReadMSTModel(file string) *MSTSerialization {
+	data := &MSTSerialization{}
+	fObj, err := os.Open(file)
+	if err != nil {
+		log.Fatalln("Failed reading model from", file, err)
+	}
+	defer fObj.Close()
+	reader := gob.NewDecoder(fObj)
+	if err = reader.Decode(data); err != nil {
+		log.Fatalln("Failed decoding model from", file, err)
+	}
+	for _, enum := range []*util.EnumSet{data.EWord, data.EPOS, data.EWPOS, data.EMHost, data.EMSuffix, data.ERel} {
+		if enum != nil && len(enum.Enum) != len(enum.Index) {
+			enum.RebuildEnum()
+		}
+	}
+	return data
+}
+
+func MSTConfigOut(outModelFile string, parser *mst.Parser) {
+	log.Println("Configuration")
+	log.Printf("Parser:\t\t\t%s", parser.Name())
+	log.Printf("Iterations:\t\t%d", Iterations)
+	log.Printf("Repair:\t\t\t%s", RepairStr)
+	log.Printf("Model file:\t\t%s", outModelFile)
+	if TrainSeed != 0 {
+		log.Printf("Shuffle Seed:\t\t%d", TrainSeed)
+	}
+	log.Printf("Use Lemmas:\t\t%v", !lattice.IGNORE_LEMMA)
+	log.Printf("Word Type:\t\t%v", conll.WORD_TYPE)
+
+	log.Println()
+	log.Printf("Features File:\t%s", DepFeaturesFile)
+	if !VerifyExists(DepFeaturesFile) {
+		os.Exit(1)
+	}
+	if !LabelsConfigOut() {
+		os.Exit(1)
+	}
+	log.Println()
+	log.Println("Data")
+	if len(tConll) > 0 {
+		log.Printf("Train file (conll):\t\t\t%s", tConll)
+	}
+	if len(inputLat) > 0 {
+		log.Printf("Input file  (lattice sentences):\t%s", inputLat)
+		if !VerifyExists(inputLat) {
+			os.Exit(1)
+		}
+	}
+	if len(input) > 0 {
+		log.Printf("Input file  (tagged sentences):\t%s", input)
+		if !VerifyExists(input) {
+			os.Exit(1)
+		}
+	}
+	if len(outConll) > 0 {
+		log.Printf("Out (conll) file:\t\t\t%s", outConll)
+	}
+}
+
+// MSTTrainAndParse trains and parses with the graph-based parser, the mst
+// variant of the dep command
+func MSTTrainAndParse(cmd *commander.Command, args []string) error {
+	if PseudoProjStr != "" {
+		log.Fatalln("Pseudo-projective encoding is not supported by the mst parser")
+	}
+	if Stream {
+		log.Println("Streaming is not supported by the mst parser, parsing all input at once")
+	}
+	// the default features are transition features
+	if DepFeaturesFile == DEFAULT_DEP_FEATURES {
+		DepFeaturesFile = MST_FEATURES_FILE
+	}
+	REQUIRED_FLAGS := []string{"oc"}
+	featuresLocation, found := util.LocateFile(DepFeaturesFile, DEFAULT_CONF_DIRS)
+	if found {
+		DepFeaturesFile = featuresLocation
+	} else {
+		REQUIRED_FLAGS = append(REQUIRED_FLAGS, "f")
+	}
+	labelsLocation, found := util.LocateFile(DepLabelsFile, DEFAULT_CONF_DIRS)
+	if found {
+		DepLabelsFile = labelsLocation
+	} else if !DepLabelsAuto || DepLabelsMerge {
+		REQUIRED_FLAGS = append(REQUIRED_FLAGS, "l")
+	}
+	if VerifyExists(inputLat) {
+		REQUIRED_FLAGS = append(REQUIRED_FLAGS, "inl")
+	} else {
+		REQUIRED_FLAGS = append(REQUIRED_FLAGS, "in")
+	}
+	VerifyFlags(cmd, REQUIRED_FLAGS)
+
+	outModelFile := fmt.Sprintf("%s.mst", DepModelFile)
+	modelExists := VerifyExists(outModelFile)
+	if !modelExists {
+		log.Println("No model found, training")
+		VerifyFlags(cmd, []string{"it", "tc"})
+		LoadClusters()
+	}
+	parser := &mst.Parser{Projective: MSTProjective}
+	if allOut && !parseOut {
+		MSTConfigOut(outModelFile, parser)
+	}
+	relations := ReadRelations(!modelExists)
+	if allOut && !parseOut {
+		log.Println()
+		log.Println("Setup enumerations")
+	}
+	SetupDepEnum(relations)
+	var serialization *MSTSerialization
+	if modelExists {
+		if allOut && !parseOut {
+			log.Println("Found model file", outModelFile, " ... loading model")
+		}
+		serialization = ReadMSTModel(outModelFile)
+		EWord, EPOS, EWPOS, EMHost, EMSuffix = serialization.EWord, serialization.EPOS, serialization.EWPOS, serialization.EMHost, serialization.EMSuffix
+		ERel = serialization.ERel
+		RestoreClusters(serialization.Clusters)
+	}
+
+	if allOut && !parseOut {
+		log.Println()
+		log.Println("Loading features")
+	}
+	featureSetup, err := transition.LoadFeatureConfFile(DepFeaturesFile)
+	if err != nil {
+		log.Println("Failed reading feature configuration file:", DepFeaturesFile)
+		log.Fatalln(err)
+	}
+	parser.Extractor = SetupExtractor(featureSetup, []byte{mst.ARC_TRANSITION_TYPE})
+	parser.Relations = ERel
+	parser.Clusters = Clusters
+
+	if modelExists {
+		parser.Model = serialization.Model
+		if allOut && !parseOut {
+			log.Println("Loaded model")
+		}
+	} else {
+		if allOut {
+			log.Println()
+			log.Println("Reading training sentences from", tConll)
+		}
+		goldGraphs, e := ReadGoldGraphs(tConll, false)
+		if e != nil {
+			log.Println(e)
+			return e
+		}
+		goldSequences := TrainingSequences(goldGraphs, GetAsTaggedSentence, GetAsLabeledDepGraph)
+		if allOut {
+			log.Println("Generated", len(goldSequences), "training sequences")
+			log.Println()
+			log.Println("Training", Iterations, "iteration(s)")
+		}
+		parser.Model = TrainMST(goldSequences, Iterations, parser)
+		if allOut {
+			log.Println("Done Training")
+			log.Println()
+			log.Println("Writing model to", outModelFile)
+		}
+		WriteMSTModel(outModelFile, &MSTSerialization{
+			parser.Model,
+			EWord, EPOS, EWPOS, EMHost, EMSuffix, ERel,
+			Clusters,
+		})
+		if allOut {
+			log.Println("Done writing model")
+			log.Println()
+		}
+	}
+
+	sents, asMorphGraphs := ReadDepInput()
+	if allOut {
+		log.Print("Parsing")
+	}
+	parsedGraphs := ParseMST(sents, parser)
+	parsedGraphs = ValidateGraphs(parsedGraphs, parser)
+	if useConllU {
+		graphAsConll := conllu.Graph2ConllUCorpus(parsedGraphs, EMHost, EMSuffix)
+		morphGraphs := conllu.MergeGraphAndMorphCorpus(graphAsConll, asMorphGraphs)
+		conllu.WriteFile(outConll, morphGraphs)
+		if allOut {
+			log.Println("Wrote", len(parsedGraphs), "in conllu format to", outConll)
+		}
+	} else {
+		graphAsConll := conll.Graph2ConllCorpus(parsedGraphs, EMHost, EMSuffix)
+		conll.WriteFile(outConll, graphAsConll)
+		if allOut {
+			log.Println("Wrote", len(parsedGraphs), "in conll format to", outConll)
+		}
+	}
+	return nil
+}
+
+func TrainMST(trainingSet []perceptron.DecodedInstance, iterations int, parser *mst.Parser) *mst.Model {
+	trainer := &perceptron.LinearPerceptron{
+		Decoder:     parser,
+		GoldDecoder: parser,
+		Updater:     &mst.AveragedStrategy{},
+		Iterations:  iterations,
+		Log:         true,
+	}
+	if TrainSeed != 0 {
+		trainer.Shuffle = true
+		trainer.Seed = TrainSeed
+	}
+	trainer.Init(mst.NewModel())
+	startTime := time.Now()
+	trainer.Train(trainingSet)
+	if allOut {
+		log.Println("TRAIN Total Time:", time.Since(startTime))
+	}
+	return trainer.Model.(*mst.Model)
+}
+
+func ParseMST(instances []interface{}, parser *mst.Parser) []interface{} {
+	startTime := time.Now()
+	parsed := make([]interface{}, len(instances))
+	for i, instance := range instances {
+		parsed[i] = parser.Parse(instance.(nlp.EnumTaggedSentence))
+	}
+	if allOut {
+		log.Println("PARSE Total Time:", time.Since(startTime))
+	}
+	return parsed
+}
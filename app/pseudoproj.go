@@ -0,0 +1,134 @@
+package app
+
+import (
+	"yap/nlp/format/conll"
+	"yap/nlp/format/conllu"
+	"yap/nlp/parser/dependency/pseudoproj"
+	nlp "yap/nlp/types"
+	"yap/util"
+
+	"log"
+	"strings"
+)
+
+var (
+	// pseudo-projective label encoding (head, path or headpath), empty to
+	// train and parse without projectivizing
+	PseudoProjStr string
+)
+
+func pseudoProjEncoding() pseudoproj.Encoding {
+	enc, err := pseudoproj.ParseEncoding(PseudoProjStr)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	return enc
+}
+
+// ReadGoldGraphs reads gold dependency graphs from a CoNLL (or CoNLL-U if
+// useConllU is set) training file. If asMorph is set CoNLL-U sentences are
+// read as morphological dependency graphs. Labels below the -lmin threshold
+// are relabeled (see DeriveLabels).
+func ReadGoldGraphs(file string, asMorph bool) ([]interface{}, error) {
+	graphs, err := readGoldGraphs(file, asMorph)
+	if err == nil {
+		relabelRare(graphs)
+	}
+	return graphs, err
+}
+
+func readGoldGraphs(file string, asMorph bool) ([]interface{}, error) {
+	if useConllU {
+		s, _, e := conllu.ReadFile(file, limit)
+		if e != nil {
+			return nil, e
+		}
+		if allOut {
+			log.Println("Conll:\tRead", len(s), "sentences")
+			log.Println("Conll:\tConverting from conll to internal structure")
+		}
+		if asMorph {
+			return conllu.ConllU2MorphGraphCorpus(s, EWord, EPOS, EWPOS, ERel, EMorphProp, EMHost, EMSuffix), nil
+		}
+		return conllu.ConllU2GraphCorpus(s, EWord, EPOS, EWPOS, ERel, EMHost, EMSuffix), nil
+	}
+	s, e := conll.ReadFile(file, limit)
+	if e != nil {
+		return nil, e
+	}
+	if allOut {
+		log.Println("Conll:\tRead", len(s), "sentences")
+		log.Println("Conll:\tConverting from conll to internal structure")
+	}
+	return conll.Conll2GraphCorpus(s, EWord, EPOS, EWPOS, ERel, EMHost, EMSuffix), nil
+}
+
+// PseudoProjectivize lifts the non-projective arcs of the gold graphs, and
+// adds the encoded labels to the relation and transition enumerations using
+// setupTrans (SetupTransEnum or SetupMorphTransEnum). It must therefore be
+// called before the transition system is instantiated.
+func PseudoProjectivize(goldGraphs []interface{}, relations []string, setupTrans func([]string)) []interface{} {
+	enc := pseudoProjEncoding()
+	projGraphs, report := pseudoproj.ProjectivizeCorpus(goldGraphs, enc, nil)
+	if allOut {
+		log.Println("Pseudo-projective:\t", enc, "encoding,", report)
+	}
+	var added []string
+	for _, label := range pseudoproj.Labels(projGraphs) {
+		if label == pseudoproj.BaseLabel(label) {
+			continue
+		}
+		if _, exists := ERel.IndexOf(nlp.DepRel(label)); !exists {
+			added = append(added, label)
+		}
+	}
+	if len(added) > 0 {
+		if allOut {
+			log.Println("Pseudo-projective:\tadding", len(added), "encoded labels")
+		}
+		setupRelations(append(append([]string{}, relations...), added...), setupTrans)
+	}
+	// reproject with the extended relations so that arcs have relation indices
+	projGraphs, _ = pseudoproj.ProjectivizeCorpus(goldGraphs, enc, ERel)
+	return projGraphs
+}
+
+// RestorePseudoProjectiveRelations adds the encoded labels of a
+// pseudo-projective model's transitions to the relation and transition
+// enumerations, so that the enumerations match those used for training.
+func RestorePseudoProjectiveRelations(modelTrans *util.EnumSet, relations []string, setupTrans func([]string)) {
+	known := make(map[string]bool, len(relations))
+	for _, rel := range relations {
+		known[rel] = true
+	}
+	var added []string
+	for i := 0; i < modelTrans.Len(); i++ {
+		trans, ok := modelTrans.ValueOf(i).(string)
+		if !ok || !strings.HasPrefix(trans, "LA-") {
+			continue
+		}
+		label := trans[3:]
+		if !known[label] && label != pseudoproj.BaseLabel(label) {
+			added = append(added, label)
+		}
+	}
+	if len(added) > 0 {
+		setupRelations(append(append([]string{}, relations...), added...), setupTrans)
+	}
+}
+
+// Deprojectivize restores the non-projective arcs of parsed graphs
+func Deprojectivize(graphs []interface{}) []interface{} {
+	return pseudoproj.DeprojectivizeCorpus(graphs, pseudoProjEncoding(), ERel)
+}
+
+// DeprojectivizeStream restores the non-projective arcs of a stream of parsed graphs
+func DeprojectivizeStream(graphs chan interface{}) chan interface{} {
+	return pseudoproj.DeprojectivizeStream(graphs, pseudoProjEncoding(), ERel)
+}
+
+func setupRelations(relations []string, setupTrans func([]string)) {
+	ERel, DepERel = nil, nil
+	SetupRelationEnum(relations)
+	setupTrans(relations)
+}
@@ -0,0 +1,145 @@
+package app
+
+import (
+	"yap/alg/search"
+	"yap/alg/transition"
+	"yap/nlp/parser/dependency/mst"
+	"yap/nlp/parser/dependency/pseudoproj"
+	"yap/nlp/parser/dependency/validate"
+	nlp "yap/nlp/types"
+	"yap/util"
+
+	"log"
+)
+
+var (
+	// repair strategy of parsed graphs that are not well formed trees
+	// (none, root or score)
+	RepairStr string = "none"
+)
+
+// RepairStrategy returns the -repair strategy, and fails on an unknown one
+func RepairStrategy() validate.Strategy {
+	strategy, err := validate.ParseStrategy(RepairStr)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	return strategy
+}
+
+func parseValidator(relations *util.EnumSet, parser interface{}) *validate.Validator {
+	return &validate.Validator{Relations: relations, Strategy: RepairStrategy(), Scores: RootScores(parser)}
+}
+
+// RootScores returns the root scores of the graphs parsed by a parser (a
+// *search.Beam or *mst.Parser) for the score repair strategy, nil for
+// other parsers
+func RootScores(parser interface{}) func(nlp.LabeledDependencyGraph) validate.RootScorer {
+	switch p := parser.(type) {
+	case *search.Beam:
+		return func(g nlp.LabeledDependencyGraph) validate.RootScorer {
+			return beamRootScores(p, g)
+		}
+	case *mst.Parser:
+		return func(g nlp.LabeledDependencyGraph) validate.RootScorer {
+			sent, ok := g.TaggedSentence().(nlp.EnumTaggedSentence)
+			if !ok {
+				return nil
+			}
+			return p.ArcScores(sent)
+		}
+	}
+	return nil
+}
+
+// parsedConfiguration returns the configuration a beam parsed a graph as,
+// unwrapping deprojectivized graphs
+func parsedConfiguration(g nlp.LabeledDependencyGraph) transition.Configuration {
+	for {
+		switch typed := g.(type) {
+		case *pseudoproj.MorphGraph:
+			g = typed.Graph
+		case *pseudoproj.Graph:
+			g = typed.LabeledDependencyGraph
+		case transition.Configuration:
+			return typed
+		default:
+			return nil
+		}
+	}
+}
+
+// beamRootScores scores the nodes of a graph parsed by a beam as roots by the
+// scores of their subtrees: the sum of the scores of the transitions that
+// attached the nodes they dominate. Root attachments are forced transitions,
+// so their scores do not tell the candidate roots apart.
+func beamRootScores(b *search.Beam, g nlp.LabeledDependencyGraph) validate.RootScorer {
+	conf := parsedConfiguration(g)
+	if conf == nil {
+		return nil
+	}
+	sequence := conf.GetSequence()
+	if len(sequence) == 0 {
+		return nil
+	}
+	final, ok := sequence[0].(nlp.LabeledDependencyGraph)
+	if !ok {
+		return nil
+	}
+	heads := make([]int, final.NumberOfNodes())
+	for n := range heads {
+		heads[n] = -1
+		if arc := final.GetLabeledArc(n); arc != nil && arc.GetHead() != n && string(arc.GetRelation()) != nlp.ROOT_LABEL {
+			heads[n] = arc.GetHead()
+		}
+	}
+	transitionScores := b.TransitionScores(sequence)
+	result := make(validate.RootScores, len(heads))
+	for i := 0; i+1 < len(sequence); i++ {
+		current, currentOk := sequence[i].(nlp.LabeledDependencyGraph)
+		previous, previousOk := sequence[i+1].(nlp.LabeledDependencyGraph)
+		if !currentOk || !previousOk {
+			continue
+		}
+		for n := 0; n < current.NumberOfNodes() && n < len(heads); n++ {
+			if current.GetLabeledArc(n) == nil || (n < previous.NumberOfNodes() && previous.GetLabeledArc(n) != nil) {
+				continue
+			}
+			// the arc of n was added by the transition to current, its
+			// score counts for n and its ancestors
+			for h, steps := n, 0; h >= 0 && h < len(heads) && steps <= len(heads); h, steps = heads[h], steps+1 {
+				result[h] += float64(transitionScores[i])
+			}
+		}
+	}
+	return result
+}
+
+// LogValidation logs the validation report of a run, always if any
+// sentence was invalid
+func LogValidation(report *validate.Report) {
+	if allOut || report.Invalid > 0 {
+		log.Println("Validation:", report)
+	}
+}
+
+// ValidateGraphs checks that the graphs parsed by a parser are well formed
+// trees before they are written out, repairing them with the -repair
+// strategy (see RootScores for the parsers of the score strategy)
+func ValidateGraphs(graphs []interface{}, parser interface{}) []interface{} {
+	return ValidateGraphsWith(graphs, ERel, parser)
+}
+
+// ValidateGraphsWith checks and repairs parsed graphs like ValidateGraphs,
+// with the labels of a parser whose label set is not ERel
+func ValidateGraphsWith(graphs []interface{}, relations *util.EnumSet, parser interface{}) []interface{} {
+	result, report := parseValidator(relations, parser).Corpus(graphs)
+	LogValidation(report)
+	return result
+}
+
+// ValidateStream checks and repairs a stream of graphs parsed by a parser,
+// the report is logged by LogValidation once the stream is drained
+func ValidateStream(graphs chan interface{}, parser interface{}) (chan interface{}, *validate.Report) {
+	return parseValidator(ERel, parser).Stream(graphs)
+}
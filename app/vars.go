@@ -1,6 +1,7 @@
 package app
 
 import (
+	"yap/alg/featurevector"
 	"yap/alg/perceptron"
 	"yap/alg/search"
 	"yap/alg/transition"
@@ -8,6 +9,7 @@ import (
 	"yap/alg/transition/model"
 	// dep "yap/nlp/parser/dependency/transition"
 	"yap/eval"
+	"yap/nlp/format/clusters"
 	"yap/nlp/format/conll"
 	"yap/nlp/format/mapping"
 	"yap/nlp/format/raw"
@@ -36,6 +38,7 @@ func init() {
 	gob.Register(&Serialization{})
 	var t nlp.Token
 	gob.Register(&t)
+	gob.Register(nlp.DepRel(""))
 }
 
 var (
@@ -51,6 +54,8 @@ var (
 	UsePOP         bool
 	limit          int
 	Stream         bool
+	// seed of training instance shuffling, 0 to train in file order
+	TrainSeed int64
 
 	// global enumerations
 	ERel, ETrans, EWord, EPOS, EWPOS, EMHost, EMSuffix *util.EnumSet
@@ -65,7 +70,7 @@ var (
 	MdEMorphProp                                             *util.EnumSet
 
 	// enumeration offsets of transitions
-	SH, RE, PR, LA, RA, IDLE, POP, MD transition.Transition
+	SH, RE, PR, LA, RA, SW, IDLE, POP, MD transition.Transition
 	//DepSH, DepRE, DepPR, DepLA, DepRA, DepIDLE, DepPOP, DepMD transition.Transition
 
 	// file names
@@ -110,6 +115,7 @@ type Serialization struct {
 	EMorphProp                           *util.EnumSet
 	ETrans                               *util.EnumSet
 	ETokens                              *util.EnumSet
+	Clusters                             *clusters.Set
 }
 
 func WriteModel(file string, data *Serialization) {
@@ -125,6 +131,9 @@ func WriteModel(file string, data *Serialization) {
 		}
 	}()
 	//defer fObj.Close()
+	if featurevector.SortedSerialization {
+		data = data.indexOnly()
+	}
 	writer := gob.NewEncoder(fObj)
 	err = writer.Encode(data)
 	if err != nil {
@@ -143,9 +152,42 @@ func ReadModel(file string) *Serialization {
 	defer fObj.Close()
 	reader := gob.NewDecoder(fObj)
 	reader.Decode(data)
+	for _, enum := range []*util.EnumSet{data.EWord, data.EPOS, data.EWPOS, data.EMHost, data.EMSuffix, data.EMorphProp, data.ETrans, data.ETokens} {
+		if enum != nil && len(enum.Enum) != len(enum.Index) {
+			enum.RebuildEnum()
+		}
+	}
 	return data
 }
 
+// indexOnly returns a copy of the serialization with enum sets holding only
+// their index, as gob encodes maps in random order; the enum maps are rebuilt
+// by ReadModel
+func (s *Serialization) indexOnly() *Serialization {
+	enums := []*util.EnumSet{s.EWord, s.EPOS, s.EWPOS, s.EMHost, s.EMSuffix, s.EMorphProp, s.ETrans, s.ETokens}
+	for i, enum := range enums {
+		if enum != nil {
+			enums[i] = &util.EnumSet{Index: enum.Index, Frozen: enum.Frozen}
+		}
+	}
+	return &Serialization{s.WeightModel, enums[0], enums[1], enums[2], enums[3], enums[4], enums[5], enums[6], enums[7], s.Clusters}
+}
+
+// SetupSeed sets up deterministic training if a seed is given: training
+// instances are shuffled at each iteration, and models are serialized
+// deterministically. If transitions are enumerated while parsing (md, joint),
+// beam expansion is made sequential so that they are enumerated in order.
+func SetupSeed(enumeratesTransitions bool) {
+	if TrainSeed == 0 {
+		return
+	}
+	featurevector.SortedSerialization = true
+	if enumeratesTransitions && ConcurrentBeam {
+		log.Println("Seeded training: disabling concurrent beam for deterministic transition enumeration")
+		ConcurrentBeam = false
+	}
+}
+
 func SetupRelationEnum(labels []string) {
 	if ERel != nil {
 		return
@@ -189,6 +231,10 @@ func SetupTransEnum(relations []string) {
 	for _, transition := range relations {
 		ETrans.Add("RA-" + string(transition))
 	}
+	if DepArcSystemStr == "swap" {
+		iSW, _ := ETrans.Add("SW")
+		SW = transition.ConstTransition(iSW)
+	}
 
 	DepETrans = util.NewEnumSet((len(relations)+1)*2 + 2)
 	DepETrans.Add("IDLE") // dummy no action transition for zpar equivalence
@@ -205,6 +251,9 @@ func SetupTransEnum(relations []string) {
 	for _, transition := range relations {
 		DepETrans.Add("RA-" + string(transition))
 	}
+	if DepArcSystemStr == "swap" {
+		DepETrans.Add("SW")
+	}
 }
 
 func SetupMorphTransEnum(relations []string) {
@@ -231,6 +280,10 @@ func SetupMorphTransEnum(relations []string) {
 	for _, transition := range relations {
 		ETrans.Add("RA-" + string(transition))
 	}
+	if DepArcSystemStr == "swap" {
+		iSW, _ := ETrans.Add("SW")
+		SW = transition.ConstTransition(iSW)
+	}
 	log.Println("ETrans Len is", ETrans.Len())
 	iPOP, _ := ETrans.Add("POP")
 	POP = &transition.TypedTransition{'P', iPOP}
@@ -251,6 +304,9 @@ func SetupMorphTransEnum(relations []string) {
 	for _, transition := range relations {
 		MdETrans.Add("RA-" + string(transition))
 	}
+	if DepArcSystemStr == "swap" {
+		MdETrans.Add("SW")
+	}
 	MdETrans.Add("POP")
 }
 
@@ -461,6 +517,14 @@ func Train(trainingSet []perceptron.DecodedInstance, Iterations int, filename st
 		Tempfile:    filename,
 		TempLines:   500}
 
+	if InitModelFile != "" {
+		perceptron.UpdateAmount = InitUpdateScale
+	}
+	if TrainSeed != 0 {
+		perceptron.Shuffle = true
+		perceptron.Seed = TrainSeed
+	}
+
 	perceptron.Iterations = Iterations
 	perceptron.Init(paramModel)
 	// perceptron.TempLoad("model.b64.i1")
@@ -889,6 +953,7 @@ func serialize(perceptronModel perceptron.Model, iteration, generations int) str
 	serialization := &Serialization{
 		perceptronModel.(*model.AvgMatrixSparse).Serialize(generations),
 		EWord, EPOS, EWPOS, EMHost, EMSuffix, EMorphProp, ETrans, ETokens,
+		Clusters,
 	}
 	modelFile := fmt.Sprintf("model.temp.i%d", iteration)
 	WriteModel(modelFile, serialization)
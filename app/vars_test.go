@@ -0,0 +1,70 @@
+package app
+
+import (
+	"yap/alg/featurevector"
+	"yap/alg/transition/model"
+	"yap/util"
+
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testSerialization() *Serialization {
+	weights := model.NewAvgMatrixSparse(2, nil, false)
+	for i, value := range []string{"a", "b", "c", "d", "e", "f"} {
+		weights.Mat[i%2].AddValue(i, value, int64(i+1))
+	}
+	words, tags := util.NewEnumSet(10), util.NewEnumSet(10)
+	for _, word := range []string{"w1", "w2", "w3", "w4", "w5", "w6", "w7", "w8"} {
+		words.Add(word)
+	}
+	for _, tag := range []string{"NN", "VB", "JJ"} {
+		tags.Add(tag)
+	}
+	return &Serialization{WeightModel: weights.Serialize(-1), EWord: words, EPOS: tags}
+}
+
+func TestWriteModelSorted(t *testing.T) {
+	defer func(prev bool) { featurevector.SortedSerialization = prev }(featurevector.SortedSerialization)
+	featurevector.SortedSerialization = true
+	dir, err := ioutil.TempDir("", "yap-vars-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var expected []byte
+	for i := 0; i < 5; i++ {
+		filename := filepath.Join(dir, "model")
+		WriteModel(filename, testSerialization())
+		written, err := ioutil.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected == nil {
+			expected = written
+		} else if !bytes.Equal(written, expected) {
+			t.Fatalf("Writing the same model resulted in different bytes at attempt %v", i)
+		}
+	}
+
+	read := ReadModel(filepath.Join(dir, "model"))
+	original := testSerialization()
+	for _, enums := range [][2]*util.EnumSet{{read.EWord, original.EWord}, {read.EPOS, original.EPOS}} {
+		if enums[0].Len() != enums[1].Len() {
+			t.Errorf("Got %v enumerated values, expected %v", enums[0].Len(), enums[1].Len())
+			continue
+		}
+		for i, value := range enums[1].Index {
+			if index, exists := enums[0].IndexOf(value); !exists || index != i {
+				t.Errorf("Got index %v (exists %v) of %v, expected %v", index, exists, value, i)
+			}
+		}
+	}
+	if read.EWPOS != nil {
+		t.Errorf("Got enum set %v, expected nil", read.EWPOS)
+	}
+}
@@ -0,0 +1,255 @@
+// Package clusters reads word clusterings that back cluster feature
+// attributes: Brown clusters (bit strings, as written by wcluster) and
+// k-means clusters of word2vec vectors.
+//
+// Feature templates address clusters of words with the attributes
+//
+//	c	the Brown cluster bit string
+//	c<N>	the N bit prefix of the Brown cluster, e.g. S0|c4
+//	k	the k-means cluster id
+package clusters
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Clusters maps words to their cluster
+type Clusters struct {
+	// Source is the base name of the file the clusters were read from
+	Source string
+	// Digest is the sha1 of the source file, and identifies its version
+	Digest string
+	Words  map[string]string
+}
+
+func (c *Clusters) Cluster(word string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	cluster, exists := c.Words[word]
+	return cluster, exists
+}
+
+// Prefix returns the prefix of length bits of a word's cluster, or the
+// whole cluster if it is shorter
+func (c *Clusters) Prefix(word string, length int) (string, bool) {
+	cluster, exists := c.Cluster(word)
+	if exists && length > 0 && length < len(cluster) {
+		cluster = cluster[:length]
+	}
+	return cluster, exists
+}
+
+func (c *Clusters) String() string {
+	if c == nil {
+		return "none"
+	}
+	return fmt.Sprintf("%s (%d words, sha1 %s)", c.Source, len(c.Words), c.Digest)
+}
+
+// serializedClusters holds the words of clusters in sorted order, as gob
+// encodes maps in random order and models are serialized deterministically
+type serializedClusters struct {
+	Source, Digest  string
+	Words, Clusters []string
+}
+
+func (c *Clusters) GobEncode() ([]byte, error) {
+	serialized := &serializedClusters{c.Source, c.Digest, make([]string, 0, len(c.Words)), make([]string, len(c.Words))}
+	for word := range c.Words {
+		serialized.Words = append(serialized.Words, word)
+	}
+	sort.Strings(serialized.Words)
+	for i, word := range serialized.Words {
+		serialized.Clusters[i] = c.Words[word]
+	}
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(serialized)
+	return buf.Bytes(), err
+}
+
+func (c *Clusters) GobDecode(data []byte) error {
+	serialized := &serializedClusters{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(serialized); err != nil {
+		return err
+	}
+	c.Source, c.Digest = serialized.Source, serialized.Digest
+	c.Words = make(map[string]string, len(serialized.Words))
+	for i, word := range serialized.Words {
+		c.Words[word] = serialized.Clusters[i]
+	}
+	return nil
+}
+
+// Set are the clusterings of the cluster attributes
+type Set struct {
+	Brown, KMeans *Clusters
+}
+
+// Equal returns true if both sets have the same versions of clusters
+func (s *Set) Equal(other *Set) bool {
+	digest := func(c *Clusters) string {
+		if c == nil {
+			return ""
+		}
+		return c.Digest
+	}
+	if s == nil || other == nil {
+		return s == other
+	}
+	return digest(s.Brown) == digest(other.Brown) && digest(s.KMeans) == digest(other.KMeans)
+}
+
+// IsAttribute returns true if the attribute is a cluster attribute
+func IsAttribute(attribute []byte) bool {
+	return len(attribute) > 0 && (attribute[0] == 'c' || attribute[0] == 'k')
+}
+
+// Attribute returns the value of a cluster attribute of a word in the set;
+// it does not exist for words without a cluster, or if the set is nil
+func (s *Set) Attribute(word string, attribute []byte) (interface{}, bool) {
+	if s == nil {
+		return nil, false
+	}
+	switch attribute[0] {
+	case 'c':
+		if len(attribute) == 1 {
+			return s.Brown.Cluster(word)
+		}
+		length, err := strconv.Atoi(string(attribute[1:]))
+		if err != nil {
+			return nil, false
+		}
+		return s.Brown.Prefix(word, length)
+	case 'k':
+		return s.KMeans.Cluster(word)
+	}
+	return nil, false
+}
+
+// digestReader reads a file while computing its digest
+func digestReader(file *os.File) (io.Reader, func() string) {
+	hash := sha1.New()
+	return io.TeeReader(file, hash), func() string {
+		return fmt.Sprintf("%x", hash.Sum(nil))
+	}
+}
+
+// FileDigest returns the digest of a file, which identifies the version of
+// Brown clusters read from it
+func FileDigest(filename string) (string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	reader, digest := digestReader(file)
+	if _, err := io.Copy(ioutil.Discard, reader); err != nil {
+		return "", err
+	}
+	return digest(), nil
+}
+
+// KMeansDigest returns the digest of k-means clusters of a word2vec file,
+// as they depend on the clustering parameters as well as the file
+func KMeansDigest(fileDigest string, k, iterations int, seed int64) string {
+	return fmt.Sprintf("%s/k%d/i%d/s%d", fileDigest, k, iterations, seed)
+}
+
+// ReadBrown reads Brown clusters in the wcluster paths format, lines of
+// bit string, word and (ignored) count separated by tabs
+func ReadBrown(reader io.Reader) (*Clusters, error) {
+	c := &Clusters{Words: make(map[string]string)}
+	scanner := bufio.NewScanner(reader)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Text()) == 0 {
+			continue
+		}
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("Line %d: expected bit string and word, got %s", line, scanner.Text())
+		}
+		c.Words[fields[1]] = fields[0]
+	}
+	return c, scanner.Err()
+}
+
+func ReadBrownFile(filename string) (*Clusters, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	reader, digest := digestReader(file)
+	c, err := ReadBrown(reader)
+	if err != nil {
+		return nil, err
+	}
+	c.Source, c.Digest = filepath.Base(filename), digest()
+	return c, nil
+}
+
+// ReadWord2Vec reads vectors in the word2vec text format, an optional
+// header line of vocabulary size and dimension followed by lines of a word
+// and its vector separated by spaces
+func ReadWord2Vec(reader io.Reader) ([]string, [][]float64, error) {
+	var (
+		words   []string
+		vectors [][]float64
+		dim     int
+	)
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 65536), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || (line == 1 && len(fields) == 2) {
+			continue
+		}
+		if dim == 0 {
+			dim = len(fields) - 1
+		}
+		if len(fields)-1 != dim {
+			return nil, nil, fmt.Errorf("Line %d: expected %d dimensions, got %d", line, dim, len(fields)-1)
+		}
+		vector := make([]float64, dim)
+		for i, field := range fields[1:] {
+			value, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				return nil, nil, fmt.Errorf("Line %d: %s", line, err.Error())
+			}
+			vector[i] = value
+		}
+		words = append(words, fields[0])
+		vectors = append(vectors, vector)
+	}
+	return words, vectors, scanner.Err()
+}
+
+// ReadWord2VecFile reads a word2vec text file and clusters its vectors
+// into k clusters (see KMeans)
+func ReadWord2VecFile(filename string, k, iterations int, seed int64) (*Clusters, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	reader, digest := digestReader(file)
+	words, vectors, err := ReadWord2Vec(reader)
+	if err != nil {
+		return nil, err
+	}
+	c := KMeans(words, vectors, k, iterations, seed)
+	c.Source, c.Digest = filepath.Base(filename), KMeansDigest(digest(), k, iterations, seed)
+	return c, nil
+}
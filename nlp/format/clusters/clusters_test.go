@@ -0,0 +1,102 @@
+package clusters
+
+import (
+	"bytes"
+	"encoding/gob"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testBrown = "0010\tdog\t10\n0011\tcat\t8\n110\tran\t5\n"
+
+const testWord2Vec = `6 2
+dog 1.0 0.1
+cat 0.9 0.2
+puppy 2.0 0.1
+ran -0.1 1.0
+walked 0.1 0.9
+jumped -0.2 3.0
+`
+
+func TestAttribute(t *testing.T) {
+	brown, err := ReadBrown(strings.NewReader(testBrown))
+	if err != nil {
+		t.Fatal(err)
+	}
+	words, vectors, err := ReadWord2Vec(strings.NewReader(testWord2Vec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(words) != 6 || len(vectors[0]) != 2 {
+		t.Fatalf("Read %d words of %d dimensions, expected 6 of 2", len(words), len(vectors[0]))
+	}
+	set := &Set{brown, KMeans(words, vectors, 2, 10, 1)}
+
+	tests := []struct {
+		word, attribute string
+		value           interface{}
+		exists          bool
+	}{
+		{"dog", "c", "0010", true},
+		{"dog", "c2", "00", true},
+		{"ran", "c6", "110", true},
+		{"walked", "c4", nil, false},
+		{"dog", "k", set.KMeans.Words["puppy"], true},
+		{"ran", "k", set.KMeans.Words["jumped"], true},
+		{"fish", "k", nil, false},
+	}
+	for _, test := range tests {
+		value, exists := set.Attribute(test.word, []byte(test.attribute))
+		if exists != test.exists || (exists && value != test.value) {
+			t.Errorf("%s|%s: got %v %v, expected %v %v", test.word, test.attribute, value, exists, test.value, test.exists)
+		}
+	}
+	if set.KMeans.Words["dog"] == set.KMeans.Words["ran"] {
+		t.Errorf("Expected nouns and verbs in different k-means clusters, got %v", set.KMeans.Words)
+	}
+}
+
+func TestAttributeNoSet(t *testing.T) {
+	var set *Set
+	if value, exists := set.Attribute("dog", []byte("c")); exists {
+		t.Errorf("Got %v for a nil set, expected no attribute", value)
+	}
+}
+
+func TestKMeansEmptyCluster(t *testing.T) {
+	// identical vectors chosen as the initial centroids leave a cluster
+	// empty, which must be reseeded rather than kept at the zero vector
+	words := []string{"a", "a2", "b"}
+	vectors := [][]float64{{1, 0}, {1, 0}, {0.8, 0.6}}
+	for seed := int64(0); seed < 10; seed++ {
+		c := KMeans(words, vectors, 2, 10, seed)
+		if c.Words["a"] != c.Words["a2"] || c.Words["a"] == c.Words["b"] {
+			t.Errorf("Seed %d: got clusters %v, expected a and a2 together and b apart", seed, c.Words)
+		}
+	}
+}
+
+func TestGob(t *testing.T) {
+	brown, err := ReadBrown(strings.NewReader(testBrown))
+	if err != nil {
+		t.Fatal(err)
+	}
+	brown.Source, brown.Digest = "paths", "abc"
+	var first, second bytes.Buffer
+	for _, buf := range []*bytes.Buffer{&first, &second} {
+		if err := gob.NewEncoder(buf).Encode(&Set{Brown: brown}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Error("Expected identical encodings of the same clusters")
+	}
+	decoded := &Set{}
+	if err := gob.NewDecoder(&first).Decode(decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded.Brown, brown) || decoded.KMeans != nil {
+		t.Errorf("Decoded %v, expected %v", decoded, brown)
+	}
+}
@@ -0,0 +1,122 @@
+package clusters
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+)
+
+// KMeans clusters the (length normalized) vectors of words into k clusters
+// with Lloyd's algorithm, starting from k vectors chosen with the seed, for
+// at most the given iterations. Cluster ids are the cluster numbers.
+func KMeans(words []string, vectors [][]float64, k, iterations int, seed int64) *Clusters {
+	c := &Clusters{Words: make(map[string]string, len(words))}
+	if len(vectors) == 0 {
+		return c
+	}
+	if k > len(vectors) {
+		k = len(vectors)
+	}
+	points := make([][]float64, len(vectors))
+	for i, vector := range vectors {
+		points[i] = normalized(vector)
+	}
+	centroids := make([][]float64, k)
+	for i, p := range rand.New(rand.NewSource(seed)).Perm(len(points))[:k] {
+		centroids[i] = append([]float64{}, points[p]...)
+	}
+	assignments := make([]int, len(points))
+	for i := range assignments {
+		assignments[i] = -1
+	}
+	for it := 0; it < iterations; it++ {
+		changed := false
+		for i, point := range points {
+			if nearest := nearest(centroids, point); nearest != assignments[i] {
+				assignments[i], changed = nearest, true
+			}
+		}
+		if !changed {
+			break
+		}
+		sizes := make([]int, k)
+		for j := range centroids {
+			for d := range centroids[j] {
+				centroids[j][d] = 0
+			}
+		}
+		for i, point := range points {
+			sizes[assignments[i]]++
+			for d, value := range point {
+				centroids[assignments[i]][d] += value
+			}
+		}
+		for j, size := range sizes {
+			if size == 0 {
+				continue
+			}
+			for d := range centroids[j] {
+				centroids[j][d] /= float64(size)
+			}
+		}
+		for j, size := range sizes {
+			if size == 0 {
+				// reseed an empty cluster with the point farthest from its centroid
+				far := farthest(centroids, assignments, points)
+				copy(centroids[j], points[far])
+				assignments[far] = j
+			}
+		}
+	}
+	for i, word := range words {
+		c.Words[word] = strconv.Itoa(nearest(centroids, points[i]))
+	}
+	return c
+}
+
+func normalized(vector []float64) []float64 {
+	var norm float64
+	for _, value := range vector {
+		norm += value * value
+	}
+	result := make([]float64, len(vector))
+	if norm == 0 {
+		return result
+	}
+	norm = math.Sqrt(norm)
+	for i, value := range vector {
+		result[i] = value / norm
+	}
+	return result
+}
+
+// distance returns the squared euclidean distance of a point from a centroid
+func distance(centroid, point []float64) float64 {
+	var dist float64
+	for d, value := range point {
+		diff := value - centroid[d]
+		dist += diff * diff
+	}
+	return dist
+}
+
+func nearest(centroids [][]float64, point []float64) int {
+	best, bestDist := 0, math.Inf(1)
+	for j, centroid := range centroids {
+		if dist := distance(centroid, point); dist < bestDist {
+			best, bestDist = j, dist
+		}
+	}
+	return best
+}
+
+// farthest returns the point farthest from the centroid it is assigned to
+func farthest(centroids [][]float64, assignments []int, points [][]float64) int {
+	best, bestDist := 0, -1.0
+	for i, point := range points {
+		if dist := distance(centroids[assignments[i]], point); dist > bestDist {
+			best, bestDist = i, dist
+		}
+	}
+	return best
+}
@@ -336,6 +336,52 @@ func ReadFile(filename string, limit int) ([]Sentence, error) {
 	return Read(file, limit)
 }
 
+// Labels are the dependency labels of a CoNLL or CoNLL-U file in order of
+// appearance, with the (1-based) line numbers of their arcs
+type Labels struct {
+	Values []string
+	Lines  map[string][]int
+}
+
+func (l *Labels) Count(label string) int {
+	return len(l.Lines[label])
+}
+
+// ReadLabels reads the DEPREL column of CoNLL and CoNLL-U rows, skipping
+// multi-word token and empty node rows of CoNLL-U and unlabeled ("_") rows
+func ReadLabels(reader io.Reader) (*Labels, error) {
+	labels := &Labels{Lines: make(map[string][]int)}
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 16384), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		record := strings.Split(scanner.Text(), "\t")
+		if len(record[0]) == 0 || record[0][0] == '#' || strings.ContainsAny(record[0], "-.") {
+			continue
+		}
+		if len(record) < 8 {
+			return nil, fmt.Errorf("Line %d: expected at least 8 columns, got %d", line, len(record))
+		}
+		label := record[7]
+		if label == "_" {
+			continue
+		}
+		if _, exists := labels.Lines[label]; !exists {
+			labels.Values = append(labels.Values, label)
+		}
+		labels.Lines[label] = append(labels.Lines[label], line)
+	}
+	return labels, scanner.Err()
+}
+
+func ReadLabelsFile(filename string) (*Labels, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadLabels(file)
+}
+
 func ReadFileAsStream(filename string, limit int) (chan Sentence, error) {
 	file, err := os.Open(filename)
 	defer file.Close()
@@ -573,12 +619,16 @@ func MorphGraph2Conll(graph nlp.MorphDependencyGraph) Sentence {
 			arcIndex[arc.GetModifier()] = arc
 		}
 	}
+	partialMWEs := nlp.GraphPartialMWEs(graph)
 	for i, nodeID := range graph.GetVertices() {
 		node = graph.GetMorpheme(nodeID)
 
 		if node == nil {
 			panic("Can't find node")
 		}
+		if partialMWEs[node] {
+			node = nlp.WithoutMWE(node)
+		}
 
 		arc, exists := arcIndex[i]
 		if exists {
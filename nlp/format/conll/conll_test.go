@@ -81,3 +81,28 @@ func TestParseRowWithRepeatingParams(t *testing.T) {
 		t.Error("Failure concatenating multiple features: should be F,M got " + parsed.Feats["suf_gen"])
 	}
 }
+
+func TestReadLabels(t *testing.T) {
+	input := strings.Join([]string{
+		"# sent_id = 1",
+		"1-2	HAIN	_	_	_	_	_	_	_	_",
+		"1	H	_	DEF	DEF	_	2	def	_	_",
+		"2	AIN	_	NN	NN	_	0	ROOT	_	_",
+		"2.1	_	_	_	_	_	_	_	_	_",
+		"",
+		"1	HW	_	PRP	PRP	_	2	subj	_	_",
+		"2	HLK	_	VB	VB	_	0	ROOT	_	_",
+		"3	.	_	yyDOT	yyDOT	_	2	_	_	_",
+		"",
+	}, "\n")
+	labels, err := ReadLabels(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(labels.Values, " ") != "def ROOT subj" {
+		t.Errorf("Got labels %v, expected def ROOT subj", labels.Values)
+	}
+	if labels.Count("ROOT") != 2 || labels.Lines["ROOT"][1] != 8 {
+		t.Errorf("Got ROOT lines %v, expected [4 8]", labels.Lines["ROOT"])
+	}
+}
@@ -110,7 +110,7 @@ func (r Row) String() string {
 		r.FeatStr,
 		fmt.Sprintf("%d", r.Head),
 		r.DepRel,
-		strings.Join(r.Deps, FEATURE_SEPARATOR),
+		strings.Join(r.Deps, FEATURES_SEPARATOR),
 		r.Misc,
 	}
 	for i, field := range fields {
@@ -246,7 +246,7 @@ func ParseRow(record []string) (Row, error) {
 
 	deps := ParseString(record[8])
 	if len(deps) > 0 {
-		row.Deps = strings.Split(deps, FEATURE_SEPARATOR)
+		row.Deps = strings.Split(deps, FEATURES_SEPARATOR)
 	}
 
 	row.Misc = ParseString(record[9])
@@ -460,6 +460,9 @@ func Write(writer io.Writer, sents []interface{}) {
 		lastToken = 0
 		// log.Println("Write sent")
 		sent := genericsent.(Sentence)
+		if ENHANCED {
+			Enhance(sent)
+		}
 		for i := 1; i <= len(sent.Deps); i++ {
 			// log.Println("At dep", i)
 			row := sent.Deps[i]
@@ -467,10 +470,14 @@ func Write(writer io.Writer, sents []interface{}) {
 				mapping := sent.Mappings[row.TokenID-1]
 				if len(mapping.Spellout) > 1 {
 					writer.Write([]byte(fmt.Sprintf("%d-%d\t%s", i, i+len(mapping.Spellout)-1, mapping.Token)))
-					for j := 0; j < 8; j++ {
+					for j := 0; j < 7; j++ {
 						writer.Write([]byte("\t_"))
 					}
-					writer.Write([]byte("\n"))
+					misc := "_"
+					if tokenRange := mapping.Spellout.Range(); tokenRange != nil {
+						misc = tokenRange.Misc()
+					}
+					writer.Write([]byte("\t" + misc + "\n"))
 				}
 			}
 			writer.Write(append([]byte(row.String()), '\n'))
@@ -486,6 +493,9 @@ func WriteStream(writer io.Writer, sents chan interface{}) {
 		lastToken = 0
 		// log.Println("Write sent")
 		sent := genericsent.(Sentence)
+		if ENHANCED {
+			Enhance(sent)
+		}
 		for i := 1; i <= len(sent.Deps); i++ {
 			// log.Println("At dep", i)
 			row := sent.Deps[i]
@@ -493,10 +503,14 @@ func WriteStream(writer io.Writer, sents chan interface{}) {
 				mapping := sent.Mappings[row.TokenID-1]
 				if len(mapping.Spellout) > 1 {
 					writer.Write([]byte(fmt.Sprintf("%d-%d\t%s", i, i+len(mapping.Spellout)-1, mapping.Token)))
-					for j := 0; j < 8; j++ {
+					for j := 0; j < 7; j++ {
 						writer.Write([]byte("\t_"))
 					}
-					writer.Write([]byte("\n"))
+					misc := "_"
+					if tokenRange := mapping.Spellout.Range(); tokenRange != nil {
+						misc = tokenRange.Misc()
+					}
+					writer.Write([]byte("\t" + misc + "\n"))
 				}
 			}
 			writer.Write(append([]byte(row.String()), '\n'))
@@ -708,6 +722,7 @@ func ConllU2MorphGraph(sent *Sentence, eWord, ePOS, eWPOS, eRel, eMFeat, eMHost,
 			row.FeatStr,
 		}
 		eFeat, _ := eMFeat.Add(row.FeatStr)
+		textRange, _ := nlp.MiscTextRange(row.Misc)
 		lattice.Morphemes = append(lattice.Morphemes, &nlp.EMorpheme{
 			morph,
 			node.Token,
@@ -717,6 +732,7 @@ func ConllU2MorphGraph(sent *Sentence, eWord, ePOS, eWPOS, eRel, eMFeat, eMHost,
 			eFeat,
 			node.MHost,
 			node.MSuffix,
+			textRange,
 		})
 
 		curLatNode++
@@ -833,12 +849,16 @@ func MorphGraph2ConllU(graph nlp.MorphDependencyGraph) Sentence {
 			}
 		}
 	}
+	partialMWEs := nlp.GraphPartialMWEs(graph)
 	for i, nodeID := range graph.GetVertices() {
 		node = graph.GetMorpheme(nodeID)
 
 		if node == nil {
 			panic("Can't find node")
 		}
+		if partialMWEs[node] {
+			node = nlp.WithoutMWE(node)
+		}
 
 		arc, exists := arcIndex[i]
 		if exists {
@@ -867,6 +887,12 @@ func MorphGraph2ConllU(graph nlp.MorphDependencyGraph) Sentence {
 			DepRel:  depRel,
 			TokenID: node.TokenID,
 		}
+		if node.Range != nil {
+			row.Misc = node.Range.Misc()
+		}
+		if mwe := nlp.MWEMisc(node.Features, node.TokenID); len(mwe) > 0 {
+			row.Misc = nlp.AddMisc(row.Misc, mwe)
+		}
 		sent.Deps[row.ID] = row
 	}
 	return *sent
@@ -0,0 +1,69 @@
+package conllu
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	morphtypes "yap/nlp/parser/dependency/transition/morph"
+	nlp "yap/nlp/types"
+)
+
+func TestWriteTokenRange(t *testing.T) {
+	morph := func(form string, start, end int) *nlp.EMorpheme {
+		return &nlp.EMorpheme{Morpheme: nlp.Morpheme{Form: form}, Range: &nlp.TextRange{start, end}}
+	}
+	spellout := nlp.Spellout{morph("ב", 0, 1), morph("ה", 1, 1), morph("בית", 1, 4)}
+	sent := NewSentence()
+	sent.Mappings = nlp.Mappings{&nlp.Mapping{"בבית", spellout}}
+	for i, m := range spellout {
+		sent.Deps[i+1] = Row{ID: i + 1, Form: m.Form, Head: 3, DepRel: "dep", Misc: m.Range.Misc(), TokenID: 1}
+	}
+	var buf bytes.Buffer
+	Write(&buf, []interface{}{*sent})
+	lines := strings.Split(buf.String(), "\n")
+	if expected := "1-3\tבבית\t_\t_\t_\t_\t_\t_\t_\tTokenRange=0:4"; lines[0] != expected {
+		t.Errorf("got token line %q, expected %q", lines[0], expected)
+	}
+	if fields := strings.Split(lines[2], "\t"); fields[9] != "TokenRange=1:1" {
+		t.Errorf("got MISC %q of the second morpheme", fields[9])
+	}
+}
+
+func TestMorphGraph2ConllUPartialMWE(t *testing.T) {
+	morph := func(form, lemma string, token int, mwe string) *nlp.EMorpheme {
+		m := &nlp.EMorpheme{Morpheme: nlp.Morpheme{Form: form, Lemma: lemma, CPOS: "NN", TokenID: token, Features: map[string]string{}}}
+		if len(mwe) > 0 {
+			m.Features[nlp.MWE_FEATURE] = mwe
+			m.FeatureStr = nlp.MWE_FEATURE + "=" + mwe
+		}
+		return m
+	}
+	tests := []struct {
+		nodes    []*nlp.EMorpheme
+		expected []string // MISC of the nodes
+	}{
+		{[]*nlp.EMorpheme{morph("בית", "בית ספר", 1, "1/2"), morph("ספר", "בית ספר", 2, "2/2")}, []string{"MWE=1-2", "MWE=1-2"}},
+		{[]*nlp.EMorpheme{morph("בית", "בית ספר", 1, "1/2"), morph("ספר", "ספר", 2, "")}, []string{"", ""}},
+		{[]*nlp.EMorpheme{morph("בית", "בית", 1, ""), morph("ספר", "בית ספר", 2, "2/2")}, []string{"", ""}},
+	}
+	for i, test := range tests {
+		graph := &morphtypes.BasicMorphGraph{}
+		for _, node := range test.nodes {
+			graph.Nodes = append(graph.Nodes, node)
+		}
+		sent := MorphGraph2ConllU(graph)
+		for j, expected := range test.expected {
+			row := sent.Deps[j+1]
+			if row.Misc != expected {
+				t.Errorf("test %d: got MISC %q of node %d, expected %q", i, row.Misc, j, expected)
+			}
+			if _, exists := row.Feats[nlp.MWE_FEATURE]; exists != (len(expected) > 0) {
+				t.Errorf("test %d: got FEATS %v of node %d", i, row.Feats, j)
+			}
+		}
+		if _, exists := test.nodes[0].Features[nlp.MWE_FEATURE]; i == 1 && !exists {
+			t.Errorf("test %d: the graph morpheme lost its mwe feature", i)
+		}
+	}
+}
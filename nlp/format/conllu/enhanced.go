@@ -0,0 +1,183 @@
+package conllu
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Enhanced UD relations
+const (
+	ENHANCED_CONJ  = "conj"
+	ENHANCED_XCOMP = "xcomp"
+	ENHANCED_CASE  = "case"
+	ENHANCED_ROOT  = "root"
+	ENHANCED_XSUBJ = "nsubj:xsubj"
+)
+
+var (
+	// write enhanced dependencies to the DEPS column
+	ENHANCED bool
+
+	// relations augmented with the forms of their case markers
+	CASE_AUGMENTED = map[string]bool{"obl": true, "nmod": true}
+	// subject relations propagated to conjuncts without a subject
+	SUBJECT_RELATIONS = map[string]bool{"nsubj": true, "nsubj:pass": true, "csubj": true, "csubj:pass": true}
+	// relations of controllers of xcomp subjects, in order of preference
+	CONTROLLER_RELATIONS = []string{"obj", "iobj", "nsubj"}
+)
+
+type enhancedArc struct {
+	Head int
+	Rel  string
+}
+
+// universal returns a relation without its subtype
+func universal(rel string) string {
+	if i := strings.Index(rel, ":"); i >= 0 {
+		return rel[:i]
+	}
+	return rel
+}
+
+// Enhance sets the DEPS column of the rows of a sentence to its enhanced
+// dependencies, derived from the basic tree:
+//
+//   - obl and nmod relations without a subtype are augmented with the
+//     (lower cased) forms of their case markers, e.g. obl:ב
+//   - conjuncts are attached to the head of the first conjunct, with its
+//     relation augmented with their own case markers (or those of the first
+//     conjunct if they have none), and conjuncts without a subject share
+//     the subjects of the first conjunct
+//   - xcomp clauses get the object (or subject) of their head as an
+//     nsubj:xsubj subject
+//
+// The rows of the sentence are modified in place.
+func Enhance(sent Sentence) Sentence {
+	var (
+		n        = len(sent.Deps)
+		children = make(map[int][]int, n)
+		enhanced = make(map[int][]enhancedArc, n)
+		add      = func(dep, head int, rel string) {
+			for _, arc := range enhanced[dep] {
+				if arc.Head == head && arc.Rel == rel {
+					return
+				}
+			}
+			enhanced[dep] = append(enhanced[dep], enhancedArc{head, rel})
+		}
+	)
+	for id := 1; id <= n; id++ {
+		children[sent.Deps[id].Head] = append(children[sent.Deps[id].Head], id)
+	}
+	caseMarkers := func(id int) []string {
+		var markers []string
+		for _, child := range children[id] {
+			if universal(sent.Deps[child].DepRel) == ENHANCED_CASE {
+				markers = append(markers, strings.ToLower(sent.Deps[child].Form))
+			}
+		}
+		return markers
+	}
+	augmented := func(id int) string {
+		rel := sent.Deps[id].DepRel
+		if !CASE_AUGMENTED[rel] {
+			return rel
+		}
+		markers := caseMarkers(id)
+		if len(markers) == 0 {
+			return rel
+		}
+		return rel + ":" + strings.Join(markers, "_")
+	}
+	basicRel := func(id int) string {
+		if sent.Deps[id].Head == 0 {
+			return ENHANCED_ROOT
+		}
+		return augmented(id)
+	}
+	// conjunctRel returns the relation of a conjunct to the head of the
+	// first conjunct: the relation of the first conjunct, augmented with the
+	// case markers of the conjunct, or of the first conjunct if it has none
+	conjunctRel := func(id, first int) string {
+		rel := sent.Deps[first].DepRel
+		if sent.Deps[first].Head == 0 || !CASE_AUGMENTED[rel] {
+			return basicRel(first)
+		}
+		if markers := caseMarkers(id); len(markers) > 0 {
+			return rel + ":" + strings.Join(markers, "_")
+		}
+		return basicRel(first)
+	}
+
+	for id := 1; id <= n; id++ {
+		add(id, sent.Deps[id].Head, basicRel(id))
+	}
+	for id := 1; id <= n; id++ {
+		if universal(sent.Deps[id].DepRel) != ENHANCED_CONJ {
+			continue
+		}
+		first := sent.Deps[id].Head
+		for steps := 0; first > 0 && universal(sent.Deps[first].DepRel) == ENHANCED_CONJ && steps < n; steps++ {
+			first = sent.Deps[first].Head
+		}
+		if first <= 0 {
+			continue
+		}
+		add(id, sent.Deps[first].Head, conjunctRel(id, first))
+		hasSubject := false
+		for _, child := range children[id] {
+			if SUBJECT_RELATIONS[sent.Deps[child].DepRel] {
+				hasSubject = true
+				break
+			}
+		}
+		if hasSubject {
+			continue
+		}
+		for _, child := range children[first] {
+			if SUBJECT_RELATIONS[sent.Deps[child].DepRel] {
+				add(child, id, sent.Deps[child].DepRel)
+			}
+		}
+	}
+	for id := 1; id <= n; id++ {
+		// xcomp conjuncts are controlled by the head of the first conjunct
+		for _, xcomp := range append([]enhancedArc{}, enhanced[id]...) {
+			if universal(xcomp.Rel) != ENHANCED_XCOMP {
+				continue
+			}
+			if controller := controllerOf(enhanced, n, xcomp.Head); controller > 0 {
+				add(controller, id, ENHANCED_XSUBJ)
+			}
+		}
+	}
+
+	for id := 1; id <= n; id++ {
+		arcs := enhanced[id]
+		sort.SliceStable(arcs, func(i, j int) bool { return arcs[i].Head < arcs[j].Head })
+		row := sent.Deps[id]
+		row.Deps = make([]string, len(arcs))
+		for i, arc := range arcs {
+			row.Deps[i] = fmt.Sprintf("%d:%s", arc.Head, arc.Rel)
+		}
+		sent.Deps[id] = row
+	}
+	return sent
+}
+
+// controllerOf returns the dependent of head (in enhanced arcs, which
+// include subjects shared with a conjunct) that controls the subject of its
+// xcomp clauses, or -1
+func controllerOf(enhanced map[int][]enhancedArc, n, head int) int {
+	for _, rel := range CONTROLLER_RELATIONS {
+		for dep := 1; dep <= n; dep++ {
+			for _, arc := range enhanced[dep] {
+				if arc.Head == head && universal(arc.Rel) == rel {
+					return dep
+				}
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,57 @@
+package conllu
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEnhance(t *testing.T) {
+	tests := []struct {
+		name string
+		// form, head and relation of each row
+		rows     [][3]string
+		expected []string
+	}{
+		{"xcomp, conj and case",
+			[][3]string{
+				{"Mary", "2", "nsubj"}, {"persuaded", "0", "root"}, {"John", "2", "obj"}, {"to", "5", "mark"},
+				{"leave", "2", "xcomp"}, {"and", "7", "cc"}, {"sleep", "5", "conj"}, {"In", "10", "case"},
+				{"the", "10", "det"}, {"house", "7", "obl"},
+			},
+			[]string{"2:nsubj", "0:root", "2:obj|5:nsubj:xsubj|7:nsubj:xsubj", "5:mark",
+				"2:xcomp", "7:cc", "2:xcomp|5:conj", "10:case", "10:det", "7:obl:in"},
+		},
+		{"shared subject",
+			[][3]string{{"She", "2", "nsubj"}, {"sang", "0", "root"}, {"and", "4", "cc"}, {"danced", "2", "conj"}},
+			[]string{"2:nsubj|4:nsubj", "0:root", "4:cc", "0:root|2:conj"},
+		},
+		{"own subject",
+			[][3]string{{"She", "2", "nsubj"}, {"sang", "0", "root"}, {"he", "4", "nsubj"}, {"danced", "2", "conj"}},
+			[]string{"2:nsubj", "0:root", "4:nsubj", "0:root|2:conj"},
+		},
+		{"conjuncts with their own case markers",
+			[][3]string{
+				{"הלך", "0", "root"}, {"ל", "3", "case"}, {"בית", "1", "obl"}, {"ו", "6", "cc"},
+				{"מ", "6", "case"}, {"עיר", "3", "conj"}, {"ו", "8", "cc"}, {"שוק", "3", "conj"},
+			},
+			[]string{"0:root", "3:case", "1:obl:ל", "6:cc", "6:case", "1:obl:מ|3:conj", "8:cc", "1:obl:ל|3:conj"},
+		},
+	}
+	for _, test := range tests {
+		sent := NewSentence()
+		for i, fields := range test.rows {
+			row, err := ParseRow([]string{"0", fields[0], "_", "_", "_", "_", fields[1], fields[2], "_", "_"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			row.ID = i + 1
+			sent.Deps[row.ID] = row
+		}
+		Enhance(*sent)
+		for i, expected := range test.expected {
+			if deps := strings.Join(sent.Deps[i+1].Deps, FEATURES_SEPARATOR); deps != expected {
+				t.Errorf("%s: row %d got DEPS %s, expected %s", test.name, i+1, deps, expected)
+			}
+		}
+	}
+}
@@ -114,6 +114,8 @@ type Edge struct {
 	Token    int
 	Id       int
 	TokenStr string
+	// characters of the original text of the edge, if known
+	Range *nlp.TextRange
 }
 
 type EdgeSlice []Edge
@@ -179,6 +181,12 @@ func (e Edge) UDString() string {
 	if len(e.Lemma) == 0 {
 		fields[3] = "_"
 	}
+	if e.Range != nil {
+		fields[7] = e.Range.Misc()
+	}
+	if mwe := nlp.MWEMisc(e.Feats, e.Token); len(mwe) > 0 {
+		fields[7] = nlp.AddMisc(fields[7], mwe)
+	}
 	return strings.Join(fields, "\t")
 }
 
@@ -271,6 +279,20 @@ func ParseFeatures(featuresStr string) (Features, error) {
 	return featureMap, nil
 }
 
+// parseMiscRange sets the range of an edge from the TokenRange attribute of
+// its MISC field, if it has one
+func parseMiscRange(row *Edge, record []string) error {
+	if len(record) <= 7 {
+		return nil
+	}
+	r, err := nlp.MiscTextRange(record[7])
+	if err != nil {
+		return errors.New(fmt.Sprintf("Error parsing MISC field (%s): %s", record[7], err.Error()))
+	}
+	row.Range = r
+	return nil
+}
+
 func ParseULEdge(record []string) (*Edge, error) {
 	row := &Edge{}
 	start, err := ParseInt(record[0])
@@ -318,7 +340,7 @@ func ParseULEdge(record []string) (*Edge, error) {
 	}
 	row.Feats = features
 	row.FeatStr = ParseString(record[6])
-	return row, nil
+	return row, parseMiscRange(row, record)
 }
 
 func ParseUDEdge(record []string) (*Edge, error) {
@@ -374,7 +396,7 @@ func ParseUDEdge(record []string) (*Edge, error) {
 	}
 	row.Feats = features
 	row.FeatStr = ParseString(record[6])
-	return row, nil
+	return row, parseMiscRange(row, record)
 }
 
 func ParseEdge(record []string) (*Edge, error) {
@@ -849,6 +871,25 @@ func UDRead(r io.Reader, limit int) ([]Lattice, error) {
 	return sentences, nil
 }
 
+// unionRange returns the range spanning two ranges, either of which may be
+// unknown (nil)
+func unionRange(r, other *nlp.TextRange) *nlp.TextRange {
+	if r == nil || other == nil {
+		if r == nil {
+			return other
+		}
+		return r
+	}
+	union := *r
+	if other.Start < union.Start {
+		union.Start = other.Start
+	}
+	if other.End > union.End {
+		union.End = other.End
+	}
+	return &union
+}
+
 func UDWrite(writer io.Writer, lattices []Lattice, comments [][]string, oovVectors []nlp.BasicSentence) error {
 	var (
 		lastToken    int
@@ -873,6 +914,7 @@ func UDWrite(writer io.Writer, lattices []Lattice, comments [][]string, oovVecto
 					if edge.Token > lastToken {
 						// run forward and find the bottom and top of the current token
 						bottom, top := edge.Start, edge.End
+						tokenRange := edge.Range
 					outerLoop:
 						for j := i; j <= max; j++ {
 							if otherRow, otherExists := lattice[j]; otherExists {
@@ -886,6 +928,7 @@ func UDWrite(writer io.Writer, lattices []Lattice, comments [][]string, oovVecto
 									if otherEdge.End > top {
 										top = otherEdge.End
 									}
+									tokenRange = unionRange(tokenRange, otherEdge.Range)
 								}
 							}
 						}
@@ -894,6 +937,9 @@ func UDWrite(writer io.Writer, lattices []Lattice, comments [][]string, oovVecto
 						} else {
 							tokenComment = "_"
 						}
+						if tokenRange != nil {
+							tokenComment = nlp.AddMisc(tokenComment, tokenRange.Misc())
+						}
 						fmt.Fprintf(writer, "%d-%d\t%s\t%s\n", bottom, top, edge.TokenStr, tokenComment)
 						lastToken = edge.Token
 					}
@@ -948,6 +994,9 @@ func UDWriteJSON(writer io.Writer, lattices []Lattice) error {
 					if edge.PosTag != "_" {
 						jsonEdge.XPOSTag = edge.PosTag
 					}
+					if edge.Range != nil {
+						jsonEdge.Misc = edge.Range.Misc()
+					}
 					startStr := fmt.Sprint(edge.Start)
 					if outEdges, edgesExist := jsonLat[startStr]; edgesExist {
 						outEdges = append(outEdges, *jsonEdge)
@@ -1250,7 +1299,9 @@ func Lattice2Sentence(lattice Lattice, eWord, ePOS, eWPOS, eMorphFeat, eMHost, e
 					edge.Token,
 					edge.FeatStr,
 				},
+				Range: edge.Range,
 			}
+			lat.Range = unionRange(lat.Range, edge.Range)
 			switch WORD_TYPE {
 			case "form":
 				newMorpheme.EForm, _ = eWord.Add(edge.Word)
@@ -1339,12 +1390,13 @@ func Sentence2Lattice(lattice nlp.LatticeSentence, xliter8or xliter8.Interface)
 				outLemma,
 				m.CPOS,
 				m.POS,
-				nil,
+				m.Features,
 				m.FeatureStr,
 				// should be m.TokenID+1
 				m.TokenID,
 				m.ID(),
 				string(sentlat.Token),
+				m.Range,
 			}
 			if len(m.FeatureStr) == 0 {
 				e.FeatStr = "_"
@@ -41,3 +41,21 @@ func TestParseEdgeWithRepeatingParams(t *testing.T) {
 		t.Error("Failure concatenating multiple features: should be F,M got " + parsed.Feats["suf_gen"])
 	}
 }
+
+func TestParseULEdgeRange(t *testing.T) {
+	row := strings.Split("3\t4\tספר_\tספר\tNOUN\tNOUN\tGender=Masc|Number=Sing\tTokenRange=5:8\t_", string(FIELD_SEPARATOR))
+	parsed, err := ParseULEdge(row)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Range == nil || parsed.Range.Start != 5 || parsed.Range.End != 8 {
+		t.Errorf("got range %v, expected 5:8", parsed.Range)
+	}
+	if udString := parsed.UDString(); udString != strings.Join(row, "\t") {
+		t.Errorf("got %q, expected %q", udString, strings.Join(row, "\t"))
+	}
+	row[7] = "TokenRange=8:5"
+	if _, err := ParseULEdge(row); err == nil {
+		t.Errorf("expected an error for an invalid range")
+	}
+}
@@ -0,0 +1,292 @@
+package lex
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"yap/alg/graph"
+	"yap/nlp/types"
+)
+
+const (
+	// COMPILED_MAGIC starts a compiled lexicon file
+	COMPILED_MAGIC   = "YAPLEX"
+	COMPILED_VERSION = 1
+)
+
+// Options are the reading options that change the analyses of lexicon
+// files; a compiled lexicon is only valid with the options it was
+// compiled with
+type Options struct {
+	AddNNPNoFeats, StripAllNNPOfFeats  bool
+	SkipBinyan, SkipPolar, SkipAllType bool
+	SkipTypes                          string // sorted, comma separated
+}
+
+func (o Options) String() string {
+	return fmt.Sprintf("addnnpnofeats=%v stripnnpfeats=%v skipbinyan=%v skippolar=%v skipalltype=%v skiptypes=%v",
+		o.AddNNPNoFeats, o.StripAllNNPOfFeats, o.SkipBinyan, o.SkipPolar, o.SkipAllType, o.SkipTypes)
+}
+
+// CurrentOptions returns the reading options currently set
+func CurrentOptions() Options {
+	skipTypes := make([]string, 0, len(SKIP_TYPES))
+	for skipType, skip := range SKIP_TYPES {
+		if skip {
+			skipTypes = append(skipTypes, skipType)
+		}
+	}
+	sort.Strings(skipTypes)
+	return Options{ADD_NNP_NO_FEATS, STRIP_ALL_NNP_OF_FEATS, SKIP_BINYAN, SKIP_POLAR, SKIP_ALL_TYPE, strings.Join(skipTypes, ",")}
+}
+
+// CompiledMorpheme is a morpheme with its strings replaced by their index
+// in the interned strings of a compiled lexicon
+type CompiledMorpheme struct {
+	ID, From, To                       int
+	Form, Lemma, CPOS, POS, FeatureStr uint32
+	TokenID                            int
+	HasFeatures                        bool
+	Features                           []uint32 // key, value pairs sorted by key
+}
+
+// CompiledToken holds the analyses of a token of a compiled lexicon
+type CompiledToken struct {
+	Token    uint32
+	Analyses [][]CompiledMorpheme
+}
+
+// Compiled is a binary lexicon of prefix and lexicon analyses, as loaded
+// from lexicon files, for fast loading
+type Compiled struct {
+	Version  int
+	MAType   string
+	Options  Options
+	Sources  []string
+	Strings  []string
+	Prefixes []CompiledToken
+	Lexicon  []CompiledToken
+}
+
+type stringInterner struct {
+	strings []string
+	index   map[string]uint32
+}
+
+func (s *stringInterner) intern(str string) uint32 {
+	if i, exists := s.index[str]; exists {
+		return i
+	}
+	i := uint32(len(s.strings))
+	s.strings = append(s.strings, str)
+	s.index[str] = i
+	return i
+}
+
+func (s *stringInterner) compile(dict map[string][]types.BasicMorphemes) []CompiledToken {
+	tokens := make([]string, 0, len(dict))
+	for token := range dict {
+		tokens = append(tokens, token)
+	}
+	sort.Strings(tokens)
+	compiled := make([]CompiledToken, len(tokens))
+	for i, token := range tokens {
+		analyses := dict[token]
+		compiled[i] = CompiledToken{s.intern(token), make([][]CompiledMorpheme, len(analyses))}
+		for j, analysis := range analyses {
+			morphs := make([]CompiledMorpheme, len(analysis))
+			for k, m := range analysis {
+				morphs[k] = CompiledMorpheme{
+					ID: m.ID(), From: m.From(), To: m.To(),
+					Form: s.intern(m.Form), Lemma: s.intern(m.Lemma),
+					CPOS: s.intern(m.CPOS), POS: s.intern(m.POS),
+					FeatureStr:  s.intern(m.FeatureStr),
+					TokenID:     m.TokenID,
+					HasFeatures: m.Features != nil,
+				}
+				keys := make([]string, 0, len(m.Features))
+				for key := range m.Features {
+					keys = append(keys, key)
+				}
+				sort.Strings(keys)
+				for _, key := range keys {
+					morphs[k].Features = append(morphs[k].Features, s.intern(key), s.intern(m.Features[key]))
+				}
+			}
+			compiled[i].Analyses[j] = morphs
+		}
+	}
+	return compiled
+}
+
+// Compile compiles the analyses of prefixes and lexicon tokens, read with
+// the current options
+func Compile(prefixes, lexicon map[string][]types.BasicMorphemes, maType string, sources []string) *Compiled {
+	interner := &stringInterner{index: make(map[string]uint32)}
+	c := &Compiled{
+		Version: COMPILED_VERSION,
+		MAType:  maType,
+		Options: CurrentOptions(),
+		Sources: sources,
+	}
+	c.Prefixes = interner.compile(prefixes)
+	c.Lexicon = interner.compile(lexicon)
+	c.Strings = interner.strings
+	return c
+}
+
+func (c *Compiled) expand(compiled []CompiledToken) map[string][]types.BasicMorphemes {
+	dict := make(map[string][]types.BasicMorphemes, len(compiled))
+	for _, token := range compiled {
+		analyses := make([]types.BasicMorphemes, len(token.Analyses))
+		for i, analysis := range token.Analyses {
+			morphs := make(types.BasicMorphemes, len(analysis))
+			for j, m := range analysis {
+				morph := &types.Morpheme{
+					BasicDirectedEdge: graph.BasicDirectedEdge{m.ID, m.From, m.To},
+					Form:              c.Strings[m.Form],
+					Lemma:             c.Strings[m.Lemma],
+					CPOS:              c.Strings[m.CPOS],
+					POS:               c.Strings[m.POS],
+					TokenID:           m.TokenID,
+					FeatureStr:        c.Strings[m.FeatureStr],
+				}
+				if m.HasFeatures {
+					morph.Features = make(map[string]string, len(m.Features)/2)
+					for k := 0; k+1 < len(m.Features); k += 2 {
+						morph.Features[c.Strings[m.Features[k]]] = c.Strings[m.Features[k+1]]
+					}
+				}
+				morphs[j] = morph
+			}
+			analyses[i] = morphs
+		}
+		dict[c.Strings[token.Token]] = analyses
+	}
+	return dict
+}
+
+// Expand returns the analyses of the prefixes and lexicon tokens
+func (c *Compiled) Expand() (prefixes, lexicon map[string][]types.BasicMorphemes) {
+	return c.expand(c.Prefixes), c.expand(c.Lexicon)
+}
+
+// Verify returns an error if the compiled lexicon can not be used as a
+// lexicon of an MA type read with the current options
+func (c *Compiled) Verify(maType string) error {
+	if c.MAType != maType {
+		return fmt.Errorf("compiled lexicon is of MA type %v, expected %v", c.MAType, maType)
+	}
+	if options := CurrentOptions(); c.Options != options {
+		return fmt.Errorf("compiled lexicon options (%v) differ from the current options (%v), recompile it", c.Options, options)
+	}
+	return nil
+}
+
+func WriteCompiled(writer io.Writer, c *Compiled) error {
+	if _, err := fmt.Fprintf(writer, "%s%d\n", COMPILED_MAGIC, COMPILED_VERSION); err != nil {
+		return err
+	}
+	return gob.NewEncoder(writer).Encode(c)
+}
+
+func ReadCompiled(reader io.Reader) (*Compiled, error) {
+	bufReader := bufio.NewReader(reader)
+	header, err := bufReader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if header != fmt.Sprintf("%s%d\n", COMPILED_MAGIC, COMPILED_VERSION) {
+		return nil, errors.New("not a compiled lexicon of version " + fmt.Sprint(COMPILED_VERSION))
+	}
+	c := &Compiled{}
+	if err := gob.NewDecoder(bufReader).Decode(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func WriteCompiledFile(filename string, c *Compiled) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(file)
+	if err := WriteCompiled(writer, c); err != nil {
+		file.Close()
+		return err
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func ReadCompiledFile(filename string) (*Compiled, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadCompiled(file)
+}
+
+// IsCompiledFile returns true if a file is a compiled lexicon
+func IsCompiledFile(filename string) bool {
+	file, err := os.Open(filename)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	header := make([]byte, len(COMPILED_MAGIC))
+	if _, err := io.ReadFull(file, header); err != nil {
+		return false
+	}
+	return bytes.Equal(header, []byte(COMPILED_MAGIC))
+}
+
+// CompareAnalyses returns the tokens whose analyses differ between two
+// dictionaries, including tokens missing from either; morphemes are
+// compared by all their fields
+func CompareAnalyses(a, b map[string][]types.BasicMorphemes) []string {
+	var differ []string
+	for token, analyses := range a {
+		if other, exists := b[token]; !exists || !equalAnalyses(analyses, other) {
+			differ = append(differ, token)
+		}
+	}
+	for token := range b {
+		if _, exists := a[token]; !exists {
+			differ = append(differ, token)
+		}
+	}
+	sort.Strings(differ)
+	return differ
+}
+
+func equalAnalyses(a, b []types.BasicMorphemes) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if len(a[i]) != len(b[i]) {
+			return false
+		}
+		for j, m := range a[i] {
+			other := b[i][j]
+			if m.BasicDirectedEdge != other.BasicDirectedEdge || m.TokenID != other.TokenID ||
+				(m.Features == nil) != (other.Features == nil) || !m.Equal(other) {
+				return false
+			}
+		}
+	}
+	return true
+}
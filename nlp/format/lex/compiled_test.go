@@ -0,0 +1,75 @@
+package lex
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"yap/nlp/types"
+)
+
+func testDict(t *testing.T, input string, reader LexReader) map[string][]types.BasicMorphemes {
+	dict := make(map[string][]types.BasicMorphemes)
+	for _, line := range strings.Split(strings.TrimSpace(input), "\n") {
+		token, err := reader(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dict[token.Token] = append(dict[token.Token], token.Morphemes...)
+	}
+	return dict
+}
+
+func TestCompiled(t *testing.T) {
+	prefixes := testDict(t, "ב ב PREPOSITION:: ב^ה PREPOSITION+DEF::\nוב ו^ב CONJ+PREPOSITION::", ProcessAnalyzedPrefix)
+	lexicon := testDict(t, "בית :NN-M-S: בית :NNT-M-S: בית\nאכלו :VB-M-S-3-PAST-PAAL:S-M-S-3 אכל", ProcessAnalyzedToken)
+	user, err := ReadUser(strings.NewReader(testUserLex), "spmrl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, token := range user {
+		lexicon[token.Token] = append(lexicon[token.Token], token.Morphemes...)
+	}
+
+	var first, second bytes.Buffer
+	for _, buf := range []*bytes.Buffer{&first, &second} {
+		if err := WriteCompiled(buf, Compile(prefixes, lexicon, "spmrl", []string{"test"})); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Error("Expected identical compiled lexicons of the same analyses")
+	}
+	compiled, err := ReadCompiled(&first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := compiled.Verify("spmrl"); err != nil {
+		t.Error(err)
+	}
+	if err := compiled.Verify("ud"); err == nil {
+		t.Error("Expected an MA type error")
+	}
+	compiledPrefixes, compiledLexicon := compiled.Expand()
+	if differ := CompareAnalyses(prefixes, compiledPrefixes); len(differ) > 0 {
+		t.Errorf("Prefixes differ: %v", differ)
+	}
+	if differ := CompareAnalyses(lexicon, compiledLexicon); len(differ) > 0 {
+		t.Errorf("Lexicon tokens differ: %v", differ)
+	}
+	if len(compiledLexicon["אכלו"][0]) != 3 {
+		t.Errorf("Expected 3 morphemes of אכלו, got %v", compiledLexicon["אכלו"][0])
+	}
+
+	compiledLexicon["בית"][1][0].FeatureStr = "gen=F"
+	delete(compiledLexicon, "גוגל")
+	if differ := CompareAnalyses(lexicon, compiledLexicon); len(differ) != 2 || differ[0] != "בית" || differ[1] != "גוגל" {
+		t.Errorf("Expected בית and גוגל to differ, got %v", differ)
+	}
+}
+
+func TestReadCompiledHeader(t *testing.T) {
+	if _, err := ReadCompiled(strings.NewReader("בית :NN-M-S: בית\n")); err == nil {
+		t.Error("Expected an error reading a text lexicon")
+	}
+}
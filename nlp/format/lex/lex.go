@@ -8,6 +8,7 @@ import (
 	"log"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
 
 	"yap/alg/graph"
@@ -25,6 +26,8 @@ const (
 	PREFIX_MSR_SEPARATOR    = "+"
 	FEATURE_PAIR_SEPARATOR  = "|"
 	FEATURE_VALUE_SEPARATOR = "="
+	USER_SEPARATOR          = "\t"
+	USER_COMMENT            = "#"
 )
 
 var (
@@ -258,6 +261,7 @@ func ProcessUDAnalyzedToken(analysis string) (*AnalyzedToken, error) {
 		curNode, curID        int
 		lemma                 string
 		def, noMerge          bool
+		skippedDef            bool
 		UDMSR, UDPOS, UDFeats string
 		udPOSExists           bool
 	)
@@ -299,6 +303,8 @@ func ProcessUDAnalyzedToken(analysis string) (*AnalyzedToken, error) {
 			if msrs[0] == "DEF" {
 				hebanalysis := HEBREW_XLITER8.To(analysis)
 				if hebanalysis[0] == 'H' {
+					// the definite article is analyzed as a prefix
+					skippedDef = true
 					continue
 				}
 				def = true
@@ -451,10 +457,17 @@ func ProcessUDAnalyzedToken(analysis string) (*AnalyzedToken, error) {
 				curNode++
 			}
 		}
+		for _, morph := range morphs {
+			if len(morph.FeatureStr) > 0 {
+				morph.FeatureStr, morph.Features = util.MergeUDFeatureValues(strings.Split(morph.FeatureStr, FEATURE_PAIR_SEPARATOR))
+			}
+		}
 		curToken.Morphemes = append(curToken.Morphemes, morphs)
 	}
 	log.SetPrefix(prefix)
-	if len(curToken.Morphemes) == 0 {
+	// a token of only definite analyses is known, with no analyses of its
+	// own (see ProcessUDAnalyzedPrefix)
+	if len(curToken.Morphemes) == 0 && !skippedDef {
 		return nil, nil
 	}
 	return curToken, nil
@@ -780,3 +793,136 @@ func ReadFile(filename string, format string, maType string) ([]*AnalyzedToken,
 
 	return Read(file, format, maType)
 }
+
+// ProcessUserAnalyzedToken reads a single morpheme analysis of a user
+// lexicon line of tab separated token, lemma, POS and features
+// (e.g. gen=M|num=S, or _ for none); an empty or _ lemma is the token
+// (with single spaces between the tokens of a multi-token expression).
+// A token of several space separated tokens is a multi-token expression
+// (see types.MWEPart), whose head token (1-based) may be given in a fifth
+// field; its tokens are joined by types.MWE_SEPARATOR.
+func ProcessUserAnalyzedToken(analysis string) (*AnalyzedToken, error) {
+	split := strings.Split(analysis, USER_SEPARATOR)
+	if len(split) < 3 || len(split) > 5 {
+		return nil, errors.New("Wrong number of fields, expected token, lemma, POS, features and an optional head (" + analysis + ")")
+	}
+	token, lemma, POS := strings.TrimSpace(split[0]), strings.TrimSpace(split[1]), strings.TrimSpace(split[2])
+	if len(token) == 0 || len(POS) == 0 {
+		return nil, errors.New("Empty token or POS (" + analysis + ")")
+	}
+	parts := strings.Fields(token)
+	token = strings.Join(parts, types.MWE_SEPARATOR)
+	if len(lemma) == 0 || lemma == "_" {
+		lemma = strings.Join(parts, " ")
+	}
+	var features []string
+	if len(split) >= 4 {
+		if featureStr := strings.TrimSpace(split[3]); len(featureStr) > 0 && featureStr != "_" {
+			features = strings.Split(featureStr, FEATURE_PAIR_SEPARATOR)
+		}
+	}
+	if len(split) == 5 {
+		head, err := strconv.Atoi(strings.TrimSpace(split[4]))
+		if err != nil || head < 1 || head > len(parts) || len(parts) < 2 {
+			return nil, errors.New("Head " + split[4] + " is not a token of a multi-token expression (" + analysis + ")")
+		}
+		features = append(features, types.MWE_HEAD_FEATURE+FEATURE_VALUE_SEPARATOR+strconv.Itoa(head))
+	}
+	sort.Strings(features)
+	featureMap := make(map[string]string, len(features))
+	for _, feature := range features {
+		pair := strings.Split(feature, FEATURE_VALUE_SEPARATOR)
+		if len(pair) != 2 || len(pair[0]) == 0 || len(pair[1]) == 0 {
+			return nil, errors.New("Malformed feature " + feature + " (" + analysis + ")")
+		}
+		featureMap[pair[0]] = pair[1]
+	}
+	return &AnalyzedToken{
+		Token: token,
+		Morphemes: []types.BasicMorphemes{{&types.Morpheme{
+			BasicDirectedEdge: graph.BasicDirectedEdge{0, 0, 1},
+			Form:              token,
+			Lemma:             lemma,
+			CPOS:              POS,
+			POS:               POS,
+			Features:          featureMap,
+			TokenID:           0,
+			FeatureStr:        strings.Join(features, FEATURE_PAIR_SEPARATOR),
+		}}},
+	}, nil
+}
+
+// UDMorpheme converts the POS and features of a morpheme of the Hebrew
+// lexicon to UD (see util.Heb2UDMSR); the features of proper nouns are
+// stripped with STRIP_ALL_NNP_OF_FEATS, except for the head of a
+// multi-token expression
+func UDMorpheme(morph *types.Morpheme) {
+	morph.CPOS, morph.FeatureStr, morph.Features = util.Heb2UDMSR(morph.CPOS, morph.FeatureStr)
+	if STRIP_ALL_NNP_OF_FEATS && morph.CPOS == "PROPN" {
+		head, exists := morph.Features[types.MWE_HEAD_FEATURE]
+		morph.FeatureStr, morph.Features = "", nil
+		if exists {
+			morph.FeatureStr = types.MWE_HEAD_FEATURE + FEATURE_VALUE_SEPARATOR + head
+			morph.Features = map[string]string{types.MWE_HEAD_FEATURE: head}
+		}
+	}
+}
+
+// ReadUser reads a user lexicon; lines with tabs are read as user
+// analyses (see ProcessUserAnalyzedToken), converted to UD for the ud MA
+// type (see UDMorpheme), other lines as lexicon lines of the MA type.
+// Empty lines and lines starting with # are skipped.
+func ReadUser(input io.Reader, maType string) ([]*AnalyzedToken, error) {
+	var (
+		tokens []*AnalyzedToken
+		reader LexReader
+		lineNo int
+	)
+	switch maType {
+	case "spmrl":
+		reader = ProcessAnalyzedToken
+	case "ud":
+		reader = ProcessUDAnalyzedToken
+	default:
+		return nil, fmt.Errorf("Unknown MA type %v", maType)
+	}
+	scan := bufio.NewScanner(input)
+	for scan.Scan() {
+		lineNo++
+		line := strings.TrimRight(scan.Text(), "\r")
+		if len(strings.TrimSpace(line)) == 0 || strings.HasPrefix(line, USER_COMMENT) {
+			continue
+		}
+		var (
+			token *AnalyzedToken
+			err   error
+		)
+		if strings.Contains(line, USER_SEPARATOR) {
+			token, err = ProcessUserAnalyzedToken(line)
+			if err == nil && maType == "ud" {
+				for _, morph := range token.Morphemes[0] {
+					UDMorpheme(morph)
+					morph.POS = "_"
+				}
+			}
+		} else {
+			token, err = reader(line)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNo, err)
+		}
+		if token != nil {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, scan.Err()
+}
+
+func ReadUserFile(filename string, maType string) ([]*AnalyzedToken, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadUser(file, maType)
+}
@@ -0,0 +1,91 @@
+package lex
+
+import (
+	"strings"
+	"testing"
+
+	"yap/nlp/types"
+)
+
+const testUserLex = "# drugs\n" +
+	"אקמול\t_\tNN\tgen=M|num=S\n" +
+	"\n" +
+	"גוגל\tגוגל\tNNP\n" +
+	"גוגל\t_\tNNP\t_\n"
+
+func TestReadUser(t *testing.T) {
+	tokens, err := ReadUser(strings.NewReader(testUserLex), "spmrl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 3 {
+		t.Fatalf("Expected 3 tokens, got %d", len(tokens))
+	}
+	morph := tokens[0].Morphemes[0][0]
+	if tokens[0].Token != "אקמול" || morph.Form != "אקמול" || morph.Lemma != "אקמול" || morph.CPOS != "NN" || morph.POS != "NN" {
+		t.Errorf("Got token %v morpheme %v", tokens[0].Token, morph)
+	}
+	if morph.FeatureStr != "gen=M|num=S" || morph.Features["gen"] != "M" || morph.Features["num"] != "S" {
+		t.Errorf("Got features %v (%v), expected gen=M|num=S", morph.FeatureStr, morph.Features)
+	}
+	for _, token := range tokens[1:] {
+		if morph := token.Morphemes[0][0]; morph.FeatureStr != "" || len(morph.Features) != 0 {
+			t.Errorf("Expected no features, got %v", morph.FeatureStr)
+		}
+	}
+}
+
+func TestReadUserErrors(t *testing.T) {
+	for _, input := range []string{
+		"אקמול\tNN\n",
+		"\t_\tNN\n",
+		"אקמול\t_\tNN\tgen\n",
+		"אקמול\t_\tNN\t_\t1\n",
+		"בית ספר\t_\tNN\t_\t3\n",
+	} {
+		if _, err := ReadUser(strings.NewReader(input), "spmrl"); err == nil || !strings.Contains(err.Error(), "line 1") {
+			t.Errorf("Expected a line 1 error reading %q, got %v", input, err)
+		}
+	}
+}
+
+func TestReadUserMWE(t *testing.T) {
+	tokens, err := ReadUser(strings.NewReader("בית  ספר\t_\tNN\tgen=M|num=S\t1\n"), "spmrl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	morph := tokens[0].Morphemes[0][0]
+	if tokens[0].Token != "בית"+types.MWE_SEPARATOR+"ספר" || morph.Lemma != "בית ספר" {
+		t.Errorf("Got token %q lemma %v, expected the joined tokens, בית ספר", tokens[0].Token, morph.Lemma)
+	}
+	if morph.FeatureStr != "gen=M|mwehead=1|num=S" || morph.Features["mwehead"] != "1" {
+		t.Errorf("Got features %v, expected gen=M|mwehead=1|num=S", morph.FeatureStr)
+	}
+}
+
+func TestReadUserUD(t *testing.T) {
+	tokens, err := ReadUser(strings.NewReader("אקמול\t_\tNNT\tgen=M|num=P|num=S\nגוגל\t_\tPROPN\t_\n"), "ud")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"NOUN _ Definite=Cons|Gender=Masc|Number=Plur,Sing", "PROPN _ "}
+	for i, token := range tokens {
+		morph := token.Morphemes[0][0]
+		if got := morph.CPOS + " " + morph.POS + " " + morph.FeatureStr; got != expected[i] {
+			t.Errorf("%s: got %s, expected %s", token.Token, got, expected[i])
+		}
+	}
+	if morph := tokens[0].Morphemes[0][0]; morph.Features["Number"] != "Plur,Sing" {
+		t.Errorf("Got features %v, expected Number=Plur,Sing", morph.Features)
+	}
+}
+
+func TestProcessUDAnalyzedTokenDefinite(t *testing.T) {
+	token, err := ProcessUDAnalyzedToken("הבית DEF:NN-M-S: בית")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token == nil || token.Token != "הבית" || len(token.Morphemes) != 0 {
+		t.Errorf("Got %v, expected a known token with no analyses", token)
+	}
+}
@@ -28,10 +28,17 @@ func UDWriteMorph(writer io.Writer, morph *nlp.EMorpheme, curMorph int) {
 	} else {
 		writer.Write([]byte(morph.FeatureStr))
 	}
-	for j := 0; j < 4; j++ {
+	for j := 0; j < 3; j++ {
 		writer.Write([]byte("\t_"))
 	}
-	writer.Write([]byte{'\n'})
+	misc := "_"
+	if morph.Range != nil {
+		misc = morph.Range.Misc()
+	}
+	if mwe := nlp.MWEMisc(morph.Features, morph.TokenID); len(mwe) > 0 {
+		misc = nlp.AddMisc(misc, mwe)
+	}
+	writer.Write([]byte("\t" + misc + "\n"))
 }
 
 func WriteMorph(writer io.Writer, morph *nlp.EMorpheme, curMorph, curToken int) {
@@ -66,19 +73,28 @@ func UDWrite(writer io.Writer, mappedSents []interface{}, conllul []conllul.Conl
 			writer.Write([]byte(comment))
 			writer.Write([]byte("\n"))
 		}
-		for _, mapping := range mappedSent.(*disambig.MDConfig).Mappings {
+		mappings := mappedSent.(*disambig.MDConfig).Mappings
+		partialMWEs := nlp.MappingsPartialMWEs(mappings)
+		for _, mapping := range mappings {
 			if len(mapping.Spellout) > 1 {
 				writer.Write([]byte(fmt.Sprintf("%d-%d\t%s", curMorph, curMorph+len(mapping.Spellout)-1, mapping.Token)))
-				for j := 0; j < 8; j++ {
+				for j := 0; j < 7; j++ {
 					writer.Write([]byte("\t_"))
 				}
-				writer.Write([]byte("\n"))
+				misc := "_"
+				if tokenRange := mapping.Spellout.Range(); tokenRange != nil {
+					misc = tokenRange.Misc()
+				}
+				writer.Write([]byte("\t" + misc + "\n"))
 			}
 			for _, morph := range mapping.Spellout {
 				if morph == nil {
 					// log.Println("\t", "Morph is nil, continuing")
 					continue
 				}
+				if partialMWEs[morph] {
+					morph = nlp.WithoutMWE(morph)
+				}
 				UDWriteMorph(writer, morph, curMorph)
 				curMorph++
 			}
@@ -91,7 +107,9 @@ func Write(writer io.Writer, mappedSents []interface{}) {
 	var curMorph int
 	for _, mappedSent := range mappedSents {
 		curMorph = 0
-		for i, mapping := range mappedSent.(*disambig.MDConfig).Mappings {
+		mappings := mappedSent.(*disambig.MDConfig).Mappings
+		partialMWEs := nlp.MappingsPartialMWEs(mappings)
+		for i, mapping := range mappings {
 			// log.Println("At token", i, mapping.Token)
 			if mapping.Token == nlp.ROOT_TOKEN {
 				continue
@@ -106,6 +124,9 @@ func Write(writer io.Writer, mappedSents []interface{}) {
 					// log.Println("\t", "Morph is nil, continuing")
 					continue
 				}
+				if partialMWEs[morph] {
+					morph = nlp.WithoutMWE(morph)
+				}
 				WriteMorph(writer, morph, curMorph, i)
 				// log.Println("\t", "At morph", j, morph.Form)
 				curMorph++
@@ -120,7 +141,9 @@ func WriteStream(writer *os.File, mappedSents chan interface{}) {
 	var i int
 	for mappedSent := range mappedSents {
 		curMorph = 0
-		for i, mapping := range mappedSent.(*disambig.MDConfig).Mappings {
+		mappings := mappedSent.(*disambig.MDConfig).Mappings
+		partialMWEs := nlp.MappingsPartialMWEs(mappings)
+		for i, mapping := range mappings {
 			// log.Println("At token", i, mapping.Token)
 			if mapping.Token == nlp.ROOT_TOKEN {
 				continue
@@ -135,6 +158,9 @@ func WriteStream(writer *os.File, mappedSents chan interface{}) {
 					// log.Println("\t", "Morph is nil, continuing")
 					continue
 				}
+				if partialMWEs[morph] {
+					morph = nlp.WithoutMWE(morph)
+				}
 				WriteMorph(writer, morph, curMorph, i)
 				// log.Println("\t", "At morph", j, morph.Form)
 				curMorph++
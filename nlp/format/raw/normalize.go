@@ -0,0 +1,299 @@
+package raw
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Normalization steps, applied in the order of NORMALIZATION_STEPS
+const (
+	NORM_BOM          = "bom"          // strip byte order marks
+	NORM_BIDI         = "bidi"         // strip RTL/LTR marks, embeddings and isolates
+	NORM_CRLF         = "crlf"         // map CRLF and CR line endings to LF
+	NORM_PRESENTATION = "presentation" // decompose Hebrew presentation forms
+	NORM_CANTILLATION = "cantillation" // strip cantillation marks (teamim)
+	NORM_NIQQUD       = "niqqud"       // strip vowel points, dagesh and shin/sin dots
+	NORM_QUOTES       = "quotes"       // map look-alike quotes (geresh, gershayim, curly) to ASCII
+	NORM_VARIANTS     = "variants"     // replace spelling variants of words (e.g. ktiv haser) by their standard form
+)
+
+var (
+	NORMALIZATION_STEPS = []string{NORM_BOM, NORM_BIDI, NORM_CRLF, NORM_PRESENTATION, NORM_CANTILLATION, NORM_NIQQUD, NORM_QUOTES, NORM_VARIANTS}
+	// DEFAULT_NORMALIZATION steps only remove encoding artifacts
+	DEFAULT_NORMALIZATION = strings.Join([]string{NORM_BOM, NORM_BIDI, NORM_CRLF}, ",")
+
+	// HEBREW_PRESENTATION are the Hebrew presentation forms and their
+	// canonical (or compatibility) decomposition
+	HEBREW_PRESENTATION = map[rune]string{
+		'\uFB1D': "\u05D9\u05B4", '\uFB1F': "\u05F2\u05B7", '\uFB20': "\u05E2",
+		'\uFB21': "\u05D0", '\uFB22': "\u05D3", '\uFB23': "\u05D4",
+		'\uFB24': "\u05DB", '\uFB25': "\u05DC", '\uFB26': "\u05DD",
+		'\uFB27': "\u05E8", '\uFB28': "\u05EA", '\uFB29': "+",
+		'\uFB2A': "\u05E9\u05C1", '\uFB2B': "\u05E9\u05C2", '\uFB2C': "\u05E9\u05BC\u05C1",
+		'\uFB2D': "\u05E9\u05BC\u05C2", '\uFB2E': "\u05D0\u05B7", '\uFB2F': "\u05D0\u05B8",
+		'\uFB30': "\u05D0\u05BC", '\uFB31': "\u05D1\u05BC", '\uFB32': "\u05D2\u05BC",
+		'\uFB33': "\u05D3\u05BC", '\uFB34': "\u05D4\u05BC", '\uFB35': "\u05D5\u05BC",
+		'\uFB36': "\u05D6\u05BC", '\uFB38': "\u05D8\u05BC", '\uFB39': "\u05D9\u05BC",
+		'\uFB3A': "\u05DA\u05BC", '\uFB3B': "\u05DB\u05BC", '\uFB3C': "\u05DC\u05BC",
+		'\uFB3E': "\u05DE\u05BC", '\uFB40': "\u05E0\u05BC", '\uFB41': "\u05E1\u05BC",
+		'\uFB43': "\u05E3\u05BC", '\uFB44': "\u05E4\u05BC", '\uFB46': "\u05E6\u05BC",
+		'\uFB47': "\u05E7\u05BC", '\uFB48': "\u05E8\u05BC", '\uFB49': "\u05E9\u05BC",
+		'\uFB4A': "\u05EA\u05BC", '\uFB4B': "\u05D5\u05B9", '\uFB4C': "\u05D1\u05BF",
+		'\uFB4D': "\u05DB\u05BF", '\uFB4E': "\u05E4\u05BF", '\uFB4F': "\u05D0\u05DC",
+	}
+
+	// QUOTES are look-alike quotes and their ASCII quote
+	QUOTES = map[rune]rune{
+		'\u05F3': '\'', // geresh
+		'\u2018': '\'',
+		'\u2019': '\'',
+		'\u201A': '\'',
+		'\u2032': '\'', // prime
+		'\u0060': '\'',
+		'\u00B4': '\'',
+		'\u05F4': '"', // gershayim
+		'\u201C': '"',
+		'\u201D': '"',
+		'\u201E': '"',
+		'\u2033': '"', // double prime
+	}
+)
+
+// normStep maps a rune, followed by next (-1 at the end of the text), to
+// its normalized runes; nil strips it
+type normStep func(r, next rune) []rune
+
+func isBidiControl(r rune) bool {
+	return r == '\u200E' || r == '\u200F' || r == '\u061C' ||
+		(r >= '\u202A' && r <= '\u202E') || (r >= '\u2066' && r <= '\u2069')
+}
+
+func isNiqqud(r rune) bool {
+	return (r >= '\u05B0' && r <= '\u05BD') || r == '\u05BF' || r == '\u05C1' || r == '\u05C2' ||
+		r == '\u05C4' || r == '\u05C5' || r == '\u05C7'
+}
+
+func isCantillation(r rune) bool {
+	return r >= '\u0591' && r <= '\u05AF'
+}
+
+func strip(drop func(rune) bool) normStep {
+	return func(r, next rune) []rune {
+		if drop(r) {
+			return nil
+		}
+		return []rune{r}
+	}
+}
+
+var normSteps = map[string]normStep{
+	NORM_BOM:          strip(func(r rune) bool { return r == '\uFEFF' }),
+	NORM_BIDI:         strip(isBidiControl),
+	NORM_CANTILLATION: strip(isCantillation),
+	NORM_NIQQUD:       strip(isNiqqud),
+	NORM_CRLF: func(r, next rune) []rune {
+		if r != '\r' {
+			return []rune{r}
+		}
+		if next == '\n' {
+			return nil
+		}
+		return []rune{'\n'}
+	},
+	NORM_PRESENTATION: func(r, next rune) []rune {
+		if decomposed, exists := HEBREW_PRESENTATION[r]; exists {
+			return []rune(decomposed)
+		}
+		return []rune{r}
+	},
+	NORM_QUOTES: func(r, next rune) []rune {
+		if quote, exists := QUOTES[r]; exists {
+			return []rune{quote}
+		}
+		return []rune{r}
+	},
+}
+
+// Normalizer strips or maps characters (and words) of text before it is
+// analyzed, keeping the offsets of the normalized text in the original
+type Normalizer struct {
+	steps    []normStep
+	variants bool
+	// spelling variants of words and their standard form, replaced when
+	// the variants step is set
+	Variants map[string]string
+}
+
+// NewNormalizer returns a normalizer of comma separated steps, in their
+// order in NORMALIZATION_STEPS; "all" for all steps, "none" for none
+func NewNormalizer(steps string) (*Normalizer, error) {
+	n := &Normalizer{Variants: make(map[string]string)}
+	selected := make(map[string]bool)
+	switch steps {
+	case "none", "":
+	case "all":
+		for _, step := range NORMALIZATION_STEPS {
+			selected[step] = true
+		}
+	default:
+		for _, step := range strings.Split(steps, ",") {
+			step = strings.TrimSpace(step)
+			if _, exists := normSteps[step]; !exists && step != NORM_VARIANTS {
+				return nil, fmt.Errorf("Unknown normalization step %v, expected one of %v", step, strings.Join(NORMALIZATION_STEPS, ", "))
+			}
+			selected[step] = true
+		}
+	}
+	for _, step := range NORMALIZATION_STEPS {
+		if !selected[step] {
+			continue
+		}
+		if step == NORM_VARIANTS {
+			n.variants = true
+		} else {
+			n.steps = append(n.steps, normSteps[step])
+		}
+	}
+	return n, nil
+}
+
+// ReadVariants reads spelling variants, a tab separated variant and standard
+// form per line; lines starting with # are comments
+func (n *Normalizer) ReadVariants(reader io.Reader) error {
+	scanner := bufio.NewScanner(reader)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if len(text) == 0 || strings.HasPrefix(text, "#") {
+			continue
+		}
+		split := strings.Split(text, "\t")
+		if len(split) != 2 || len(split[0]) == 0 || len(split[1]) == 0 {
+			return fmt.Errorf("line %d: expected a variant and its standard form separated by a tab, got %q", line, text)
+		}
+		n.Variants[split[0]] = split[1]
+	}
+	return scanner.Err()
+}
+
+func (n *Normalizer) ReadVariantsFile(filename string) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := n.ReadVariants(file); err != nil {
+		return fmt.Errorf("%s: %v", filename, err)
+	}
+	return nil
+}
+
+// Normalized is a normalized text with the range of each of its bytes in
+// the original text
+type Normalized struct {
+	Text, Original string
+	starts, ends   []int
+}
+
+func (t *Normalized) add(runes []rune, start, end int) {
+	for _, r := range runes {
+		size := utf8.RuneLen(r)
+		if size < 0 {
+			// invalid runes are written as the replacement character
+			size = utf8.RuneLen(utf8.RuneError)
+		}
+		for i := 0; i < size; i++ {
+			t.starts = append(t.starts, start)
+			t.ends = append(t.ends, end)
+		}
+	}
+}
+
+// OriginalRange returns the byte range in the original text of a byte range
+// of the normalized text
+func (t *Normalized) OriginalRange(start, end int) (int, int) {
+	if start >= len(t.Text) {
+		return len(t.Original), len(t.Original)
+	}
+	if end <= start {
+		return t.starts[start], t.starts[start]
+	}
+	return t.starts[start], t.ends[end-1]
+}
+
+// unnormalized returns a text as is, each byte at its own offset
+func unnormalized(text string) *Normalized {
+	t := &Normalized{Text: text, Original: text, starts: make([]int, len(text)), ends: make([]int, len(text))}
+	for i := range t.starts {
+		t.starts[i], t.ends[i] = i, i+1
+	}
+	return t
+}
+
+// Normalize returns the normalized text and its offsets in the text
+func (n *Normalizer) Normalize(text string) *Normalized {
+	t := &Normalized{Original: text}
+	var out strings.Builder
+	for i, r := range text {
+		size := utf8.RuneLen(r)
+		if r == utf8.RuneError {
+			_, size = utf8.DecodeRuneInString(text[i:])
+		}
+		next := rune(-1)
+		if i+size < len(text) {
+			next, _ = utf8.DecodeRuneInString(text[i+size:])
+		}
+		runes := []rune{r}
+		for _, step := range n.steps {
+			var mapped []rune
+			for _, cur := range runes {
+				mapped = append(mapped, step(cur, next)...)
+			}
+			runes = mapped
+		}
+		out.WriteString(string(runes))
+		t.add(runes, i, i+size)
+	}
+	t.Text = out.String()
+	if n.variants && len(n.Variants) > 0 {
+		n.replaceVariants(t)
+	}
+	return t
+}
+
+// replaceVariants replaces the variant words of a normalized text; the
+// characters of a standard form have the range of the whole variant
+func (n *Normalizer) replaceVariants(t *Normalized) {
+	var (
+		out          strings.Builder
+		starts, ends []int
+	)
+	text := t.Text
+	for i := 0; i < len(text); {
+		end := i + strings.IndexFunc(text[i:], unicode.IsSpace)
+		if end < i {
+			end = len(text)
+		}
+		if end == i {
+			_, size := utf8.DecodeRuneInString(text[i:])
+			end = i + size
+		}
+		if standard, exists := n.Variants[text[i:end]]; exists {
+			out.WriteString(standard)
+			for j := 0; j < len(standard); j++ {
+				starts = append(starts, t.starts[i])
+				ends = append(ends, t.ends[end-1])
+			}
+		} else {
+			out.WriteString(text[i:end])
+			starts = append(starts, t.starts[i:end]...)
+			ends = append(ends, t.ends[i:end]...)
+		}
+		i = end
+	}
+	t.Text, t.starts, t.ends = out.String(), starts, ends
+}
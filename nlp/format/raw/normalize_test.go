@@ -0,0 +1,107 @@
+package raw
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		steps, input, expected string
+	}{
+		{"", "\uFEFFשלום", "\uFEFFשלום"},
+		{DEFAULT_NORMALIZATION, "\uFEFFשלום\r\nעולם\r", "שלום\nעולם\n"},
+		{DEFAULT_NORMALIZATION, "\u200Fשלום\u202B עולם\u202C", "שלום עולם"},
+		{NORM_NIQQUD, "ש\u05B8\u05C1לו\u05B9ם", "שלום"},
+		{NORM_CANTILLATION, "ב\u05B0\u05BCר\u05B5אש\u05B4\u05C1\u0596ית", "ב\u05B0\u05BCר\u05B5אש\u05B4\u05C1ית"},
+		{"cantillation,niqqud", "ב\u05B0\u05BCר\u05B5אש\u05B4\u05C1\u0596ית", "בראשית"},
+		{NORM_PRESENTATION, "\uFB2Aלום", "ש\u05C1לום"},
+		{"presentation,niqqud", "\uFB31\uFB2Fת", "באת"},
+		{NORM_QUOTES, "צה״ל ג׳ “ציטוט”", "צה\"ל ג' \"ציטוט\""},
+		{"all", "\uFEFFש\u05B8\u05C1לו\u05B9ם\r\n", "שלום\n"},
+	}
+	for _, test := range tests {
+		n, err := NewNormalizer(test.steps)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if normalized := n.Normalize(test.input).Text; normalized != test.expected {
+			t.Errorf("%q with %q: got %q, expected %q", test.input, test.steps, normalized, test.expected)
+		}
+	}
+	if _, err := NewNormalizer("bom,nikud"); err == nil {
+		t.Errorf("expected an error for an unknown step")
+	}
+}
+
+func TestNormalizedOriginalRange(t *testing.T) {
+	n, _ := NewNormalizer("all")
+	input := "\uFEFFש\u05B8\u05C1לו\u05B9ם עולם"
+	normalized := n.Normalize(input)
+	if normalized.Text != "שלום עולם" {
+		t.Fatalf("got %q", normalized.Text)
+	}
+	tests := []struct {
+		word, original string
+	}{
+		{"שלום", "ש\u05B8\u05C1לו\u05B9ם"},
+		{"עולם", "עולם"},
+	}
+	for _, test := range tests {
+		at := strings.Index(normalized.Text, test.word)
+		start, end := normalized.OriginalRange(at, at+len(test.word))
+		if input[start:end] != test.original {
+			t.Errorf("%s: got original %q, expected %q", test.word, input[start:end], test.original)
+		}
+	}
+	if start, end := normalized.OriginalRange(len(normalized.Text), len(normalized.Text)); start != len(input) || end != len(input) {
+		t.Errorf("got end range %d-%d, expected %d", start, end, len(input))
+	}
+}
+
+func TestNormalizeVariants(t *testing.T) {
+	n, _ := NewNormalizer("niqqud,variants")
+	if err := n.ReadVariants(strings.NewReader("# haser\tmale\nשמרה\tשומרה\nתכנית\tתוכנית\n")); err != nil {
+		t.Fatal(err)
+	}
+	input := "התכנית שמרה תכנית"
+	normalized := n.Normalize(input)
+	if normalized.Text != "התכנית שומרה תוכנית" {
+		t.Fatalf("got %q", normalized.Text)
+	}
+	at := strings.Index(normalized.Text, "תוכנית")
+	if start, end := normalized.OriginalRange(at, at+len("תוכנית")); input[start:end] != "תכנית" {
+		t.Errorf("got original %q of the variant", input[start:end])
+	}
+	if err := n.ReadVariants(strings.NewReader("שמרה שומרה\n")); err == nil {
+		t.Errorf("expected an error for a line without a tab")
+	}
+}
+
+func TestReadNormalized(t *testing.T) {
+	normalizer, _ := NewNormalizer(DEFAULT_NORMALIZATION)
+	input := "\uFEFFגנן\r\nגידל\r\n\r\nדגן\r\n\r\n"
+	sents, err := ReadNormalized(strings.NewReader(input), 0, normalizer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sents) != 2 || len(sents[0]) != 2 || sents[0][0] != "גנן" || sents[1][0] != "דגן" {
+		t.Errorf("got %v", sents)
+	}
+	// lines normalized to nothing are dropped, and don't end the sentence
+	sents, err = ReadNormalized(strings.NewReader("גנן\n\u200F\nגידל\n\uFEFF\n\nדגן\n\n"), 0, normalizer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sents) != 2 || len(sents[0]) != 2 || sents[0][1] != "גידל" || len(sents[1]) != 1 {
+		t.Errorf("got %v", sents)
+	}
+	// Read doesn't normalize
+	sents, err = Read(strings.NewReader(input), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sents) != 2 || sents[0][0] != "\uFEFFגנן" {
+		t.Errorf("got unnormalized %q", sents)
+	}
+}
@@ -8,40 +8,102 @@ import (
 	nlp "yap/nlp/types"
 
 	"bufio"
-	"bytes"
 	"io"
 	// "log"
 	"os"
+	"strings"
+	"unicode/utf8"
 )
 
 func Read(reader io.Reader, limit int) ([]nlp.BasicSentence, error) {
-	var sentences []nlp.BasicSentence
-	bufReader := bufio.NewReader(reader)
+	sentences, _, err := ReadWithOffsets(reader, limit, nil)
+	return sentences, err
+}
+
+// ReadNormalized reads sentences as Read, normalizing each token
+func ReadNormalized(reader io.Reader, limit int, normalizer *Normalizer) ([]nlp.BasicSentence, error) {
+	sentences, _, err := ReadWithOffsets(reader, limit, normalizer)
+	return sentences, err
+}
+
+// TokenOffsets maps ranges of characters of a token read by ReadWithOffsets,
+// after normalization, to ranges of characters of the input
+type TokenOffsets struct {
+	normalized *Normalized
+	// characters of the input before the line of the token
+	lineStart int
+}
+
+// Range returns the characters of the input a range of characters of the
+// token was read from
+func (o *TokenOffsets) Range(r nlp.TextRange) nlp.TextRange {
+	text, original := o.normalized.Text, o.normalized.Original
+	start, end := runeIndex(text, r.Start), runeIndex(text, r.End)
+	start, end = o.normalized.OriginalRange(start, end)
+	return nlp.TextRange{
+		o.lineStart + utf8.RuneCountInString(original[:start]),
+		o.lineStart + utf8.RuneCountInString(original[:end]),
+	}
+}
+
+// runeIndex returns the byte index of the i-th rune of text
+func runeIndex(text string, i int) int {
+	for index := range text {
+		if i == 0 {
+			return index
+		}
+		i--
+	}
+	return len(text)
+}
 
+// ReadWithOffsets reads sentences as Read, normalizing each token if a
+// normalizer is given, with the offsets of each token in the input.
+// Tokens normalized to nothing (e.g. a lone RTL mark) are dropped.
+func ReadWithOffsets(reader io.Reader, limit int, normalizer *Normalizer) ([]nlp.BasicSentence, [][]*TokenOffsets, error) {
 	var (
-		i int
+		sentences []nlp.BasicSentence
+		offsets   [][]*TokenOffsets
+		lineStart int
 	)
+	bufReader := bufio.NewReader(reader)
 	currentSent := make(nlp.BasicSentence, 0, 10)
-	for curLine, isPrefix, err := bufReader.ReadLine(); err == nil; curLine, isPrefix, err = bufReader.ReadLine() {
-		if isPrefix {
-			panic("Buffer not large enough, fix me :(")
+	currentOffsets := make([]*TokenOffsets, 0, 10)
+	for {
+		line, err := bufReader.ReadString('\n')
+		if len(line) == 0 && err != nil {
+			break
 		}
-		buf := bytes.NewBuffer(curLine)
-		// log.Println("At record", i)
+		lineLen := utf8.RuneCountInString(line)
+		line = strings.TrimSuffix(line, "\n")
+		line = strings.TrimSuffix(line, "\r")
 		// an empty line indicates a new record
-		if len(curLine) == 0 {
+		if len(line) == 0 {
 			sentences = append(sentences, currentSent)
+			offsets = append(offsets, currentOffsets)
 			if limit > 0 && len(sentences) >= limit {
 				break
 			}
 			currentSent = make(nlp.BasicSentence, 0, 10)
+			currentOffsets = make([]*TokenOffsets, 0, 10)
 		} else {
-			currentSent = append(currentSent, nlp.Token(buf.String()))
+			var normalized *Normalized
+			if normalizer != nil {
+				normalized = normalizer.Normalize(line)
+			} else {
+				normalized = unnormalized(line)
+			}
+			if len(normalized.Text) > 0 {
+				currentSent = append(currentSent, nlp.Token(normalized.Text))
+				currentOffsets = append(currentOffsets, &TokenOffsets{normalized, lineStart})
+			}
+		}
+		lineStart += lineLen
+		if err != nil {
+			break
 		}
-
-		i++
 	}
-	return sentences, nil
+	return sentences, offsets, nil
 }
 
 func ReadFile(filename string, limit int) ([]nlp.BasicSentence, error) {
@@ -54,6 +116,25 @@ func ReadFile(filename string, limit int) ([]nlp.BasicSentence, error) {
 	return Read(file, limit)
 }
 
+// ReadFileNormalized reads a file as ReadNormalized
+func ReadFileNormalized(filename string, limit int, normalizer *Normalizer) ([]nlp.BasicSentence, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadNormalized(file, limit, normalizer)
+}
+
+func ReadFileWithOffsets(filename string, limit int, normalizer *Normalizer) ([]nlp.BasicSentence, [][]*TokenOffsets, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+	return ReadWithOffsets(file, limit, normalizer)
+}
+
 func Write(writer io.Writer, sents []interface{}) {
 	for _, sent := range sents {
 		for _, token := range sent.(nlp.BasicSentence) {
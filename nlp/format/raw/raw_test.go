@@ -0,0 +1,65 @@
+package raw
+
+import (
+	"strings"
+	"testing"
+
+	"yap/alg/graph"
+	nlp "yap/nlp/types"
+)
+
+func TestReadWithOffsets(t *testing.T) {
+	normalizer, _ := NewNormalizer("bom,crlf,niqqud")
+	input := "\uFEFFבבית\r\nש\u05B8\u05C1לו\u05B9ם\r\n\r\nספרו\n\n"
+	sents, offsets, err := ReadWithOffsets(strings.NewReader(input), 0, normalizer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sents) != 2 || len(offsets) != 2 || len(offsets[0]) != len(sents[0]) {
+		t.Fatalf("got %v with offsets %v", sents, offsets)
+	}
+	runes := []rune(input)
+	tests := []struct {
+		sent, token int
+		original    string
+	}{
+		{0, 0, "בבית"},
+		{0, 1, "ש\u05B8\u05C1לו\u05B9ם"},
+		{1, 0, "ספרו"},
+	}
+	for _, test := range tests {
+		token := sents[test.sent][test.token]
+		r := offsets[test.sent][test.token].Range(nlp.TextRange{0, len([]rune(string(token)))})
+		if original := string(runes[r.Start:r.End]); original != test.original {
+			t.Errorf("%s: got range %v of %q, expected %q", token, r, original, test.original)
+		}
+	}
+	// the second letter of שלום, after the stripped points of the first
+	if r := offsets[0][1].Range(nlp.TextRange{1, 2}); string(runes[r.Start:r.End]) != "ל" {
+		t.Errorf("got range %v of %q", r, string(runes[r.Start:r.End]))
+	}
+}
+
+func TestSetRanges(t *testing.T) {
+	sents, offsets, _ := ReadWithOffsets(strings.NewReader("שלום\nבבית\n\n"), 0, nil)
+	morph := func(id, from, to int, form string) *nlp.EMorpheme {
+		return &nlp.EMorpheme{Morpheme: nlp.Morpheme{BasicDirectedEdge: graph.BasicDirectedEdge{id, from, to}, Form: form}}
+	}
+	// ב + ה + בית, ב + בית and בבית
+	lat := &nlp.Lattice{
+		Token:     sents[0][1],
+		Morphemes: nlp.Morphemes{morph(0, 0, 1, "ב"), morph(1, 1, 2, "ה"), morph(2, 2, 3, "בית"), morph(3, 1, 3, "בית"), morph(4, 0, 3, "בבית")},
+		BottomId:  0,
+		TopId:     3,
+	}
+	lat.SetRanges(offsets[0][1].Range)
+	if *lat.Range != (nlp.TextRange{5, 9}) {
+		t.Errorf("got token range %v", lat.Range)
+	}
+	expected := []nlp.TextRange{{5, 6}, {6, 6}, {6, 9}, {6, 9}, {5, 9}}
+	for i, m := range lat.Morphemes {
+		if m.Range == nil || *m.Range != expected[i] {
+			t.Errorf("%s: got range %v, expected %v", m.Form, m.Range, expected[i])
+		}
+	}
+}
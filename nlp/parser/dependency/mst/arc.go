@@ -0,0 +1,191 @@
+package mst
+
+import (
+	"yap/alg/transition"
+	"yap/nlp/format/clusters"
+	nlp "yap/nlp/types"
+	"yap/util"
+
+	"fmt"
+)
+
+// ROOT_ATTRIBUTE is the value of all node attributes of the root
+const ROOT_ATTRIBUTE int = -1
+
+// Arc is a candidate arc of a sentence, scored by the features of a
+// transition.GenericExtractor. It addresses the sentence like a
+// configuration with the sources:
+//
+//	H	the head (the root is at position -1)
+//	D	the modifier
+//	B	generates the nodes between the head and the modifier
+//
+// where offsets are relative sentence positions (e.g. H1 is the word
+// following the head), the node attributes w, p, wp, h (morph host) and
+// x (morph suffix), and the arc attributes d (distance) and o (orientation).
+// Feature templates use the same syntax as transition features, e.g.
+//
+//	H0|w|p+D0|p+D0|d,H0|w
+//
+// and as with generated transition features, B must be the first element
+// of its templates.
+type Arc struct {
+	Tokens         []nlp.EnumTaggedToken
+	Head, Modifier int
+	Clusters       *clusters.Set
+}
+
+// Verify that Arc is a Configuration for the feature extractor
+var _ transition.Configuration = &Arc{}
+
+func (a *Arc) Init(abstractSentence interface{}) {
+	a.Tokens = abstractSentence.(nlp.EnumTaggedSentence).EnumTaggedTokens()
+	a.Head, a.Modifier = -1, 0
+}
+
+func (a *Arc) Terminal() bool {
+	return true
+}
+
+func (a *Arc) Copy() transition.Configuration {
+	copied := *a
+	return &copied
+}
+
+func (a *Arc) CopyTo(target transition.Configuration) {
+	*(target.(*Arc)) = *a
+}
+
+func (a *Arc) Clear() {
+	a.Tokens = nil
+}
+
+func (a *Arc) Len() int {
+	return 1
+}
+
+func (a *Arc) Previous() transition.Configuration {
+	return nil
+}
+
+func (a *Arc) SetPrevious(transition.Configuration) {
+}
+
+func (a *Arc) GetSequence() transition.ConfigurationSequence {
+	return transition.ConfigurationSequence{a}
+}
+
+func (a *Arc) SetLastTransition(transition.Transition) {
+}
+
+func (a *Arc) GetLastTransition() transition.Transition {
+	return transition.ConstTransition(0)
+}
+
+func (a *Arc) String() string {
+	return fmt.Sprintf("(%d,%d)", a.Head, a.Modifier)
+}
+
+func (a *Arc) Equal(otherEq util.Equaler) bool {
+	other, ok := otherEq.(*Arc)
+	return ok && a.Head == other.Head && a.Modifier == other.Modifier
+}
+
+func (a *Arc) Assignment() uint16 {
+	return 0
+}
+
+func (a *Arc) State() byte {
+	return 'A'
+}
+
+func (a *Arc) Address(location []byte, offset int) (int, bool, bool) {
+	switch location[0] {
+	case 'H':
+		return a.position(a.Head + offset)
+	case 'D':
+		return a.position(a.Modifier + offset)
+	case 'B':
+		from, to := a.between()
+		if from >= to {
+			return 0, false, false
+		}
+		return from, true, true
+	}
+	return 0, false, false
+}
+
+func (a *Arc) position(pos int) (int, bool, bool) {
+	if pos < -1 || pos >= len(a.Tokens) {
+		return 0, false, false
+	}
+	return pos, true, false
+}
+
+// between returns the range of positions strictly between the head and modifier
+func (a *Arc) between() (int, int) {
+	if a.Head < a.Modifier {
+		return a.Head + 1, a.Modifier
+	}
+	return a.Modifier + 1, a.Head
+}
+
+func (a *Arc) GenerateAddresses(nodeID int, location []byte) []int {
+	if location[0] != 'B' {
+		return nil
+	}
+	from, to := a.between()
+	return util.RangeInt(to)[from:]
+}
+
+func (a *Arc) Attribute(source byte, nodeID int, attribute []byte, transitions []int) (interface{}, bool, bool) {
+	switch attribute[0] {
+	case 'd':
+		return a.distance(), true, false
+	case 'o':
+		if a.Head < a.Modifier {
+			return 0, true, false
+		}
+		return 1, true, false
+	}
+	if nodeID < -1 || nodeID >= len(a.Tokens) {
+		return 0, false, false
+	}
+	if nodeID == -1 {
+		return ROOT_ATTRIBUTE, true, false
+	}
+	token := a.Tokens[nodeID]
+	switch attribute[0] {
+	case 'w':
+		if len(attribute) > 1 && attribute[1] == 'p' {
+			return token.ETPOS, true, false
+		}
+		return token.EToken, true, false
+	case 'p':
+		return token.EPOS, true, false
+	case 'h':
+		return token.EMHost, true, false
+	case 'x':
+		return token.EMSuffix, true, false
+	case 'c', 'k':
+		att, exists := a.Clusters.Attribute(token.Token, attribute)
+		return att, exists, false
+	}
+	return 0, false, false
+}
+
+// distance buckets the arc length like the transition configuration distance
+func (a *Arc) distance() int {
+	dist := a.Head - a.Modifier
+	if dist < 0 {
+		dist = -dist
+	}
+	switch {
+	case dist > 10:
+		return 6
+	case dist > 5:
+		return 5
+	default:
+		return dist
+	}
+}
@@ -0,0 +1,276 @@
+package mst
+
+import (
+	"math"
+)
+
+// NO_ARC marks a missing arc in a score matrix
+const NO_ARC int64 = math.MinInt64
+
+// Score matrices are indexed by [head][modifier], where node 0 is the
+// artificial root and node i is word i-1 of the sentence. Decoders return
+// the head of every node, with -1 as the head of the root.
+
+// ChuLiuEdmonds returns the heads of the maximum spanning (possibly
+// non-projective) tree of a score matrix, with a single modifier of the root
+func ChuLiuEdmonds(scores [][]int64) []int {
+	return chuLiuEdmonds(singleRoot(scores))
+}
+
+// Eisner returns the heads of the maximum projective tree of a score
+// matrix, with a single modifier of the root
+func Eisner(scores [][]int64) []int {
+	return eisner(singleRoot(scores))
+}
+
+// singleRoot returns a copy of the scores where every arc from the root is
+// penalized by more than the score difference of any two trees, so the best
+// tree has a single modifier of the root (a tree with a single root modifier
+// always exists as all arcs are allowed)
+func singleRoot(scores [][]int64) [][]int64 {
+	var (
+		min, max int64 = math.MaxInt64, math.MinInt64
+	)
+	n := len(scores)
+	copied := make([][]int64, n)
+	for h, row := range scores {
+		copied[h] = make([]int64, n)
+		copy(copied[h], row)
+		for d, score := range row {
+			if score == NO_ARC || h == d || d == 0 {
+				continue
+			}
+			if score < min {
+				min = score
+			}
+			if score > max {
+				max = score
+			}
+		}
+	}
+	if n < 3 || max < min {
+		return copied
+	}
+	penalty := int64(n)*(max-min) + 1
+	for d := 1; d < n; d++ {
+		if copied[0][d] != NO_ARC {
+			copied[0][d] -= penalty
+		}
+	}
+	return copied
+}
+
+func chuLiuEdmonds(scores [][]int64) []int {
+	n := len(scores)
+	heads := make([]int, n)
+	heads[0] = -1
+	// greedily choose the best head of every node
+	for d := 1; d < n; d++ {
+		heads[d] = -1
+		for h := 0; h < n; h++ {
+			if h == d || scores[h][d] == NO_ARC {
+				continue
+			}
+			if heads[d] < 0 || scores[h][d] > scores[heads[d]][d] {
+				heads[d] = h
+			}
+		}
+	}
+	cycle := findCycle(heads)
+	if cycle == nil {
+		return heads
+	}
+
+	// contract the cycle into a single node, the last of the contracted graph
+	inCycle := make([]bool, n)
+	for _, node := range cycle {
+		inCycle[node] = true
+	}
+	contracted := make([]int, n) // node -> contracted node
+	original := make([]int, 0, n-len(cycle)+1)
+	for node := 0; node < n; node++ {
+		if !inCycle[node] {
+			contracted[node] = len(original)
+			original = append(original, node)
+		}
+	}
+	c := len(original)
+	for _, node := range cycle {
+		contracted[node] = c
+	}
+	m := c + 1
+	cScores := make([][]int64, m)
+	for i := range cScores {
+		cScores[i] = make([]int64, m)
+		for j := range cScores[i] {
+			cScores[i][j] = NO_ARC
+		}
+	}
+	enters := make([]int, m) // contracted head -> cycle node it enters
+	leaves := make([]int, m) // contracted modifier -> cycle node it leaves
+	for h := 0; h < n; h++ {
+		for d := 1; d < n; d++ {
+			score := scores[h][d]
+			if h == d || score == NO_ARC {
+				continue
+			}
+			ch, cd := contracted[h], contracted[d]
+			switch {
+			case !inCycle[h] && !inCycle[d]:
+				cScores[ch][cd] = score
+			case !inCycle[h] && inCycle[d]:
+				// entering the cycle at d breaks the cycle arc of d
+				score -= scores[heads[d]][d]
+				if cScores[ch][c] == NO_ARC || score > cScores[ch][c] {
+					cScores[ch][c] = score
+					enters[ch] = d
+				}
+			case inCycle[h] && !inCycle[d]:
+				if cScores[c][cd] == NO_ARC || score > cScores[c][cd] {
+					cScores[c][cd] = score
+					leaves[cd] = h
+				}
+			}
+		}
+	}
+
+	// expand the tree of the contracted graph
+	cHeads := chuLiuEdmonds(cScores)
+	for cd := 1; cd < c; cd++ {
+		if cHeads[cd] == c {
+			heads[original[cd]] = leaves[cd]
+		} else {
+			heads[original[cd]] = original[cHeads[cd]]
+		}
+	}
+	ch := cHeads[c]
+	heads[enters[ch]] = original[ch]
+	return heads
+}
+
+// findCycle returns the nodes of a cycle in the graph of heads, or nil
+func findCycle(heads []int) []int {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(heads))
+	for start := range heads {
+		node := start
+		for node >= 0 && state[node] == unvisited {
+			state[node] = visiting
+			node = heads[node]
+		}
+		if node >= 0 && state[node] == visiting {
+			cycle := []int{node}
+			for next := heads[node]; next != node; next = heads[next] {
+				cycle = append(cycle, next)
+			}
+			return cycle
+		}
+		for node = start; node >= 0 && state[node] == visiting; node = heads[node] {
+			state[node] = visited
+		}
+	}
+	return nil
+}
+
+const (
+	left = iota
+	right
+)
+
+// eisner chart items span [s,t] and are indexed by [s][t][direction], where
+// left items are headed by t and right items by s
+type eisnerChart [][][2]int64
+
+func newEisnerChart(n int) eisnerChart {
+	chart := make(eisnerChart, n)
+	for s := range chart {
+		chart[s] = make([][2]int64, n)
+		for t := range chart[s] {
+			if s != t {
+				chart[s][t] = [2]int64{NO_ARC, NO_ARC}
+			}
+		}
+	}
+	return chart
+}
+
+func addScores(a, b int64) int64 {
+	if a == NO_ARC || b == NO_ARC {
+		return NO_ARC
+	}
+	return a + b
+}
+
+func eisner(scores [][]int64) []int {
+	n := len(scores)
+	complete, incomplete := newEisnerChart(n), newEisnerChart(n)
+	completeSplit, incompleteSplit := make([][][2]int, n), make([][][2]int, n)
+	for s := range completeSplit {
+		completeSplit[s], incompleteSplit[s] = make([][2]int, n), make([][2]int, n)
+	}
+	for k := 1; k < n; k++ {
+		for s := 0; s+k < n; s++ {
+			t := s + k
+			// incomplete items add an arc between s and t
+			for r := s; r < t; r++ {
+				span := addScores(complete[s][r][right], complete[r+1][t][left])
+				if s > 0 {
+					if score := addScores(span, scores[t][s]); score != NO_ARC && (incomplete[s][t][left] == NO_ARC || score > incomplete[s][t][left]) {
+						incomplete[s][t][left] = score
+						incompleteSplit[s][t][left] = r
+					}
+				}
+				if score := addScores(span, scores[s][t]); score != NO_ARC && (incomplete[s][t][right] == NO_ARC || score > incomplete[s][t][right]) {
+					incomplete[s][t][right] = score
+					incompleteSplit[s][t][right] = r
+				}
+			}
+			// complete items combine an incomplete item with a complete one
+			for r := s; r < t; r++ {
+				if score := addScores(complete[s][r][left], incomplete[r][t][left]); score != NO_ARC && (complete[s][t][left] == NO_ARC || score > complete[s][t][left]) {
+					complete[s][t][left] = score
+					completeSplit[s][t][left] = r
+				}
+			}
+			for r := s + 1; r <= t; r++ {
+				if score := addScores(incomplete[s][r][right], complete[r][t][right]); score != NO_ARC && (complete[s][t][right] == NO_ARC || score > complete[s][t][right]) {
+					complete[s][t][right] = score
+					completeSplit[s][t][right] = r
+				}
+			}
+		}
+	}
+	heads := make([]int, n)
+	heads[0] = -1
+	var backtrack func(s, t, direction int, isComplete bool)
+	backtrack = func(s, t, direction int, isComplete bool) {
+		if s == t {
+			return
+		}
+		if isComplete {
+			r := completeSplit[s][t][direction]
+			if direction == left {
+				backtrack(s, r, left, true)
+				backtrack(r, t, left, false)
+			} else {
+				backtrack(s, r, right, false)
+				backtrack(r, t, right, true)
+			}
+			return
+		}
+		r := incompleteSplit[s][t][direction]
+		if direction == left {
+			heads[s] = t
+		} else {
+			heads[t] = s
+		}
+		backtrack(s, r, right, true)
+		backtrack(r+1, t, left, true)
+	}
+	backtrack(0, n-1, right, true)
+	return heads
+}
@@ -0,0 +1,128 @@
+package mst
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func randomScores(r *rand.Rand, n int) [][]int64 {
+	scores := make([][]int64, n)
+	for h := range scores {
+		scores[h] = make([]int64, n)
+		for d := range scores[h] {
+			if h == d || d == 0 {
+				scores[h][d] = NO_ARC
+			} else {
+				scores[h][d] = r.Int63n(20) - 10
+			}
+		}
+	}
+	return scores
+}
+
+func isTree(heads []int) bool {
+	rootMods := 0
+	for d := 1; d < len(heads); d++ {
+		if heads[d] == 0 {
+			rootMods++
+		}
+	}
+	return rootMods == 1 && findCycle(heads) == nil
+}
+
+func isProjective(heads []int) bool {
+	for d := 1; d < len(heads); d++ {
+		from, to := heads[d], d
+		if from > to {
+			from, to = to, from
+		}
+		for between := from + 1; between < to; between++ {
+			// every node between the head and modifier descends from the head
+			node := between
+			for node != heads[d] && node > 0 {
+				node = heads[node]
+			}
+			if node != heads[d] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func treeScore(scores [][]int64, heads []int) int64 {
+	var score int64
+	for d := 1; d < len(heads); d++ {
+		score += scores[heads[d]][d]
+	}
+	return score
+}
+
+// bestTreeScore enumerates all trees to find the best (projective) score
+func bestTreeScore(scores [][]int64, projective bool) int64 {
+	n := len(scores)
+	heads := make([]int, n)
+	heads[0] = -1
+	var (
+		best  int64
+		found bool
+		visit func(d int)
+	)
+	visit = func(d int) {
+		if d == n {
+			if isTree(heads) && (!projective || isProjective(heads)) {
+				if score := treeScore(scores, heads); !found || score > best {
+					best, found = score, true
+				}
+			}
+			return
+		}
+		for h := 0; h < n; h++ {
+			if h != d {
+				heads[d] = h
+				visit(d + 1)
+			}
+		}
+	}
+	visit(1)
+	return best
+}
+
+func TestDecoders(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		scores := randomScores(r, 2+r.Intn(5))
+		heads := ChuLiuEdmonds(scores)
+		if !isTree(heads) {
+			t.Fatalf("Chu-Liu/Edmonds decoded a non tree %v for %v", heads, scores)
+		}
+		if score, best := treeScore(scores, heads), bestTreeScore(scores, false); score != best {
+			t.Fatalf("Chu-Liu/Edmonds decoded %v with score %d, expected %d for %v", heads, score, best, scores)
+		}
+		heads = Eisner(scores)
+		if !isTree(heads) || !isProjective(heads) {
+			t.Fatalf("Eisner decoded a non projective tree %v for %v", heads, scores)
+		}
+		if score, best := treeScore(scores, heads), bestTreeScore(scores, true); score != best {
+			t.Fatalf("Eisner decoded %v with score %d, expected %d for %v", heads, score, best, scores)
+		}
+	}
+}
+
+func TestChuLiuEdmondsNonProjective(t *testing.T) {
+	// root -> 2, 2 -> 1, 1 -> 3, 2 -> 4 crosses 1 -> 3
+	expected := []int{-1, 2, 0, 1, 2}
+	scores := randomScores(rand.New(rand.NewSource(2)), len(expected))
+	for d := 1; d < len(expected); d++ {
+		scores[expected[d]][d] = 100
+	}
+	heads := ChuLiuEdmonds(scores)
+	for d := range expected {
+		if heads[d] != expected[d] {
+			t.Fatal("Decoded", heads, "expected", expected)
+		}
+	}
+	if !isProjective(Eisner(scores)) {
+		t.Error("Eisner decoded a non projective tree")
+	}
+}
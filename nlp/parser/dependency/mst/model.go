@@ -0,0 +1,134 @@
+package mst
+
+import (
+	"yap/alg/featurevector"
+	"yap/alg/perceptron"
+
+	"encoding/gob"
+)
+
+func init() {
+	gob.Register(ArcFeature{})
+}
+
+// UNLABELED is the label of arc features that score the arc regardless of
+// its relation
+const UNLABELED int = -1
+
+// ArcFeature is the key of a feature value of a template, scored for a
+// relation (or UNLABELED)
+type ArcFeature struct {
+	Template, Label int
+	Value           interface{}
+}
+
+// Model is an arc factored linear model. Weights are averaged over
+// training generations lazily, see AveragedStrategy.
+type Model struct {
+	Weights    featurevector.Sparse
+	Generation int
+
+	totals featurevector.Sparse
+	stamps map[featurevector.Feature]int
+}
+
+var _ perceptron.Model = &Model{}
+
+func NewModel() *Model {
+	return &Model{
+		Weights: featurevector.NewSparse(),
+		totals:  featurevector.NewSparse(),
+		stamps:  make(map[featurevector.Feature]int),
+	}
+}
+
+// Score returns the sum of weights of a list of features
+func (m *Model) Score(features interface{}) int64 {
+	return m.Weights.DotProductFeatures(features.([]featurevector.Feature))
+}
+
+func (m *Model) Add(features interface{}) perceptron.Model {
+	m.apply(features, 1)
+	return m
+}
+
+func (m *Model) Subtract(features interface{}) perceptron.Model {
+	m.apply(features, -1)
+	return m
+}
+
+// AddSubtract adds amount to the weights of the gold features, see
+// perceptron.LinearPerceptron
+func (m *Model) AddSubtract(goldFeatures, decodedFeatures interface{}, amount int64) {
+	m.apply(goldFeatures, amount)
+}
+
+func (m *Model) apply(features interface{}, amount int64) {
+	if m.totals == nil {
+		m.totals, m.stamps = featurevector.NewSparse(), make(map[featurevector.Feature]int)
+	}
+	for _, feature := range features.([]featurevector.Feature) {
+		m.totals[feature] += int64(m.Generation-m.stamps[feature]) * m.Weights[feature]
+		m.stamps[feature] = m.Generation
+		if value := m.Weights[feature] + amount; value != 0 {
+			m.Weights[feature] = value
+		} else {
+			delete(m.Weights, feature)
+		}
+	}
+}
+
+// Integrate sets the weights to their sum over all generations
+func (m *Model) Integrate() {
+	for feature, weight := range m.Weights {
+		m.totals[feature] += int64(m.Generation-m.stamps[feature]) * weight
+	}
+	for feature, total := range m.totals {
+		if total == 0 {
+			delete(m.totals, feature)
+		}
+	}
+	m.Weights, m.totals, m.stamps = m.totals, nil, nil
+}
+
+func (m *Model) ScalarDivide(value int64) {
+	m.Weights.UpdateScalarDivide(value)
+}
+
+func (m *Model) Copy() perceptron.Model {
+	return &Model{Weights: m.Weights.Copy(), Generation: m.Generation}
+}
+
+func (m *Model) AddModel(other perceptron.Model) {
+	m.Weights.UpdateAdd(other.(*Model).Weights)
+}
+
+func (m *Model) New() perceptron.Model {
+	return NewModel()
+}
+
+// AveragedStrategy averages the weights of a Model over all training
+// instances. As with the transition models, the sum of the weights is kept
+// rather than their mean, which scores the same.
+type AveragedStrategy struct {
+	model *Model
+}
+
+var _ perceptron.UpdateStrategy = &AveragedStrategy{}
+
+func (u *AveragedStrategy) Init(m perceptron.Model, iterations int) {
+	model, ok := m.(*Model)
+	if !ok {
+		panic("AveragedStrategy requires an mst Model")
+	}
+	u.model = model
+}
+
+func (u *AveragedStrategy) Update(m perceptron.Model) {
+	u.model.Generation++
+}
+
+func (u *AveragedStrategy) Finalize(m perceptron.Model) perceptron.Model {
+	u.model.Integrate()
+	return u.model
+}
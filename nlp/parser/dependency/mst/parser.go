@@ -0,0 +1,217 @@
+package mst
+
+import (
+	"yap/alg/featurevector"
+	"yap/alg/perceptron"
+	"yap/nlp/format/clusters"
+	dep "yap/nlp/parser/dependency/transition"
+	nlp "yap/nlp/types"
+	"yap/util"
+
+	"fmt"
+)
+
+// ARC_TRANSITION_TYPE is the transition type of the feature group of arc
+// features (a "transition: Arc" feature group)
+const ARC_TRANSITION_TYPE byte = 'A'
+
+// Parser is a first-order arc factored graph-based dependency parser.
+// It decodes the maximum spanning tree of the arc scores with Chu-Liu/Edmonds,
+// or the maximum projective tree with Eisner, and labels every arc with its
+// best scoring relation.
+type Parser struct {
+	Extractor  perceptron.FeatureExtractor
+	Relations  *util.EnumSet
+	Projective bool
+	Model      *Model
+	// clusters of the cluster attributes, nil if the model has none
+	Clusters *clusters.Set
+}
+
+var _ perceptron.InstanceDecoder = &Parser{}
+var _ perceptron.EarlyUpdateInstanceDecoder = &Parser{}
+
+func (p *Parser) Name() string {
+	if p.Projective {
+		return "MST (Eisner)"
+	}
+	return "MST (Chu-Liu/Edmonds)"
+}
+
+// templateValues returns the feature values of each template for an arc
+func (p *Parser) templateValues(tokens []nlp.EnumTaggedToken, head, modifier int) []featurevector.Feature {
+	return p.Extractor.Features(&Arc{tokens, head, modifier, p.Clusters}, false, ARC_TRANSITION_TYPE, nil)
+}
+
+// appendFeatures appends the arc features of template values for a label
+func appendFeatures(features []featurevector.Feature, values []featurevector.Feature, label int) []featurevector.Feature {
+	for template, value := range values {
+		switch typed := value.(type) {
+		case nil:
+		case []interface{}:
+			for _, generated := range typed {
+				features = append(features, ArcFeature{template, label, generated})
+			}
+		default:
+			features = append(features, ArcFeature{template, label, value})
+		}
+	}
+	return features
+}
+
+// Features returns the features of a labeled tree, given by the head
+// (-1 for the root) and relation index of every token
+func (p *Parser) Features(tokens []nlp.EnumTaggedToken, heads, relations []int) []featurevector.Feature {
+	features := make([]featurevector.Feature, 0, len(tokens)*p.Extractor.EstimatedNumberOfFeatures()*2)
+	for modifier, head := range heads {
+		values := p.templateValues(tokens, head, modifier)
+		features = appendFeatures(features, values, UNLABELED)
+		if relations[modifier] >= 0 {
+			features = appendFeatures(features, values, relations[modifier])
+		}
+	}
+	return features
+}
+
+// scores returns the score matrix of all arcs of a sentence, and the best
+// relation of each arc
+func (p *Parser) scores(tokens []nlp.EnumTaggedToken, model *Model) ([][]int64, [][]int) {
+	n := len(tokens) + 1
+	scores, relations := make([][]int64, n), make([][]int, n)
+	features := make([]featurevector.Feature, 0, p.Extractor.EstimatedNumberOfFeatures())
+	for h := 0; h < n; h++ {
+		scores[h], relations[h] = make([]int64, n), make([]int, n)
+		for d := 1; d < n; d++ {
+			if h == d {
+				scores[h][d] = NO_ARC
+				continue
+			}
+			values := p.templateValues(tokens, h-1, d-1)
+			features = appendFeatures(features[0:0], values, UNLABELED)
+			scores[h][d] = model.Score(features)
+			var best int64
+			relations[h][d] = -1
+			for rel := 0; rel < p.Relations.Len(); rel++ {
+				features = appendFeatures(features[0:0], values, rel)
+				if score := model.Score(features); relations[h][d] < 0 || score > best {
+					best, relations[h][d] = score, rel
+				}
+			}
+			scores[h][d] += best
+		}
+		scores[h][0] = NO_ARC
+	}
+	return scores, relations
+}
+
+// ArcScores is the score matrix of the arcs of a sentence, indexed by
+// [head][modifier] where node 0 is the root and node i is word i-1
+type ArcScores [][]int64
+
+// RootScore returns the score of attaching a word to the root
+func (s ArcScores) RootScore(word int) (float64, bool) {
+	if word < 0 || word+1 >= len(s) || s[0][word+1] == NO_ARC {
+		return 0, false
+	}
+	return float64(s[0][word+1]), true
+}
+
+// ArcScores returns the scores of all arcs of a sentence by the model of
+// the parser, with the best relation of each arc
+func (p *Parser) ArcScores(sent nlp.EnumTaggedSentence) ArcScores {
+	scores, _ := p.scores(sent.EnumTaggedTokens(), p.Model)
+	return scores
+}
+
+// ParseTokens returns the head and relation index of every token, and the
+// score of the tree
+func (p *Parser) ParseTokens(tokens []nlp.EnumTaggedToken, model *Model) ([]int, []int, int64) {
+	heads, relations := make([]int, len(tokens)), make([]int, len(tokens))
+	if len(tokens) == 0 {
+		return heads, relations, 0
+	}
+	scores, bestRelations := p.scores(tokens, model)
+	var nodeHeads []int
+	if p.Projective {
+		nodeHeads = Eisner(scores)
+	} else {
+		nodeHeads = ChuLiuEdmonds(scores)
+	}
+	var score int64
+	for d, h := range nodeHeads[1:] {
+		heads[d], relations[d] = h-1, bestRelations[h][d+1]
+		score += scores[h][d+1]
+	}
+	return heads, relations, score
+}
+
+// Parse returns the dependency graph of a sentence
+func (p *Parser) Parse(sent nlp.EnumTaggedSentence) nlp.LabeledDependencyGraph {
+	tokens := sent.EnumTaggedTokens()
+	heads, relations, _ := p.ParseTokens(tokens, p.Model)
+	return p.graph(tokens, heads, relations)
+}
+
+func (p *Parser) graph(tokens []nlp.EnumTaggedToken, heads, relations []int) *dep.BasicDepGraph {
+	g := &dep.BasicDepGraph{
+		Nodes: make([]nlp.DepNode, len(tokens)),
+		Arcs:  make([]*dep.BasicDepArc, len(tokens)),
+	}
+	for i, token := range tokens {
+		g.Nodes[i] = &dep.TaggedDepNode{
+			Id:       i,
+			Token:    token.EToken,
+			POS:      token.EPOS,
+			TokenPOS: token.ETPOS,
+			MHost:    token.EMHost,
+			MSuffix:  token.EMSuffix,
+			RawToken: token.Token,
+			RawLemma: token.Lemma,
+			RawPOS:   token.POS,
+		}
+		g.Arcs[i] = &dep.BasicDepArc{heads[i], relations[i], i, p.Relations.ValueOf(relations[i]).(nlp.DepRel)}
+	}
+	return g
+}
+
+// goldTree returns the heads and relation indices of a gold graph
+func (p *Parser) goldTree(g nlp.LabeledDependencyGraph) ([]int, []int) {
+	heads, relations := make([]int, g.NumberOfNodes()), make([]int, g.NumberOfNodes())
+	for i := range heads {
+		arc := g.GetLabeledArc(i)
+		if arc == nil {
+			panic(fmt.Sprintf("Gold graph has no arc for node %d", i))
+		}
+		heads[i] = arc.GetHead()
+		if rel, exists := p.Relations.IndexOf(arc.GetRelation()); exists {
+			relations[i] = rel
+		} else {
+			relations[i] = -1
+		}
+	}
+	return heads, relations
+}
+
+func (p *Parser) Decode(i perceptron.Instance, m perceptron.Model) (perceptron.DecodedInstance, interface{}) {
+	sent := i.(nlp.EnumTaggedSentence)
+	tokens := sent.EnumTaggedTokens()
+	heads, relations, _ := p.ParseTokens(tokens, m.(*Model))
+	return &perceptron.Decoded{i, p.graph(tokens, heads, relations)}, p.Features(tokens, heads, relations)
+}
+
+func (p *Parser) DecodeGold(goldInstance perceptron.DecodedInstance, m perceptron.Model) (perceptron.DecodedInstance, interface{}) {
+	sent := goldInstance.Instance().(nlp.EnumTaggedSentence)
+	heads, relations := p.goldTree(goldInstance.Decoded().(nlp.LabeledDependencyGraph))
+	return goldInstance, p.Features(sent.EnumTaggedTokens(), heads, relations)
+}
+
+// DecodeEarlyUpdate decodes the best tree of a gold instance; an arc factored
+// parser has no search errors, so the update is always over the full tree
+func (p *Parser) DecodeEarlyUpdate(goldInstance perceptron.DecodedInstance, m perceptron.Model) (perceptron.DecodedInstance, interface{}, interface{}, int, int, float64) {
+	sent := goldInstance.Instance().(nlp.EnumTaggedSentence)
+	tokens := sent.EnumTaggedTokens()
+	heads, relations, score := p.ParseTokens(tokens, m.(*Model))
+	goldHeads, goldRelations := p.goldTree(goldInstance.Decoded().(nlp.LabeledDependencyGraph))
+	decoded := &perceptron.Decoded{sent, p.graph(tokens, heads, relations)}
+	return decoded, p.Features(tokens, heads, relations), p.Features(tokens, goldHeads, goldRelations), -1, len(tokens), float64(score)
+}
@@ -0,0 +1,130 @@
+package mst
+
+import (
+	"yap/alg/perceptron"
+	"yap/alg/transition"
+	"yap/nlp/format/clusters"
+	dep "yap/nlp/parser/dependency/transition"
+	nlp "yap/nlp/types"
+	"yap/util"
+
+	"testing"
+)
+
+var testFeatures = []byte(`
+feature groups:
+ - group: Arc
+   transition: Arc
+   features:
+   - H0|p,H0|p
+   - D0|p,D0|p
+   - H0|w|p+D0|w|p,H0|p
+   - H0|p+D0|p+D0|d+D0|o,H0|p
+   - B0|p+H0|p+D0|p,B0|p
+   - H0|p+H1|p+D-1|p+D0|p,D-1|p
+`)
+
+// word/POS head relation, heads are 0 for the root as in CoNLL
+var testSents = [][][3]string{
+	{{"A", "DT", "2/det"}, {"hearing", "NN", "3/subj"}, {"is", "VB", "0/ROOT"}, {"scheduled", "VBN", "3/xcomp"},
+		{"on", "IN", "2/prep"}, {"the", "DT", "7/det"}, {"issue", "NN", "5/pobj"}, {"today", "NN", "4/tmod"}},
+	{{"The", "DT", "2/det"}, {"dog", "NN", "3/subj"}, {"barked", "VB", "0/ROOT"}, {"today", "NN", "3/tmod"}},
+	{{"A", "DT", "2/det"}, {"man", "NN", "3/subj"}, {"is", "VB", "0/ROOT"}, {"on", "IN", "3/prep"}, {"the", "DT", "6/det"}, {"issue", "NN", "4/pobj"}},
+}
+
+var testRelations = []string{"det", "subj", nlp.ROOT_LABEL, "xcomp", "prep", "pobj", "tmod"}
+
+func testGraphs(eWord, ePOS, eWPOS, eRel *util.EnumSet) []interface{} {
+	graphs := make([]interface{}, len(testSents))
+	for i, sent := range testSents {
+		g := &dep.BasicDepGraph{make([]nlp.DepNode, len(sent)), make([]*dep.BasicDepArc, len(sent))}
+		for j, token := range sent {
+			node := &dep.TaggedDepNode{Id: j, RawToken: token[0], RawPOS: token[1]}
+			node.Token, _ = eWord.Add(token[0])
+			node.POS, _ = ePOS.Add(token[1])
+			node.TokenPOS, _ = eWPOS.Add([2]string{token[0], token[1]})
+			head := int(token[2][0]-'0') - 1
+			rel := nlp.DepRel(token[2][2:])
+			relIndex, _ := eRel.IndexOf(rel)
+			g.Nodes[j], g.Arcs[j] = node, &dep.BasicDepArc{head, relIndex, j, rel}
+		}
+		graphs[i] = g
+	}
+	return graphs
+}
+
+func TestParserTraining(t *testing.T) {
+	eWord, ePOS, eWPOS, eRel := util.NewEnumSet(10), util.NewEnumSet(10), util.NewEnumSet(10), util.NewEnumSet(10)
+	for _, rel := range testRelations {
+		eRel.Add(nlp.DepRel(rel))
+	}
+	graphs := testGraphs(eWord, ePOS, eWPOS, eRel)
+	extractor := &transition.GenericExtractor{
+		EFeatures: util.NewEnumSet(10),
+		EWord:     eWord, EPOS: ePOS, EWPOS: eWPOS, ERel: eRel,
+	}
+	extractor.InitTypes([]byte{ARC_TRANSITION_TYPE})
+	extractor.LoadFeatureSetup(transition.LoadFeatureConf(testFeatures))
+
+	for _, projective := range []bool{false, true} {
+		parser := &Parser{Extractor: extractor, Relations: eRel, Projective: projective}
+		instances := make([]perceptron.DecodedInstance, len(graphs))
+		for i, g := range graphs {
+			graph := g.(nlp.LabeledDependencyGraph)
+			instances[i] = &perceptron.Decoded{graph.TaggedSentence(), graph}
+		}
+		trainer := &perceptron.LinearPerceptron{
+			Decoder:     parser,
+			GoldDecoder: parser,
+			Updater:     &AveragedStrategy{},
+			Iterations:  10,
+		}
+		trainer.Init(NewModel())
+		trainer.Train(instances)
+		parser.Model = trainer.Model.(*Model)
+
+		for i, g := range graphs {
+			gold := g.(nlp.LabeledDependencyGraph)
+			parsed := parser.Parse(gold.TaggedSentence().(nlp.EnumTaggedSentence))
+			// the root (word 2 of all sentences) has the best root score
+			rootScores := parser.ArcScores(gold.TaggedSentence().(nlp.EnumTaggedSentence))
+			best, _ := rootScores.RootScore(2)
+			for d := 0; d < gold.NumberOfNodes(); d++ {
+				if score, exists := rootScores.RootScore(d); !exists || (d != 2 && score >= best) {
+					t.Errorf("%s: got root score %v (exists %v) of %d in sentence %d, root score %v", parser.Name(), score, exists, d, i, best)
+				}
+			}
+			if _, exists := rootScores.RootScore(gold.NumberOfNodes()); exists {
+				t.Errorf("%s: got a root score of a word out of sentence %d", parser.Name(), i)
+			}
+			if projective && i == 0 {
+				// the first sentence is non-projective, check the projective arcs
+				for _, d := range []int{0, 1, 2, 3, 5, 6} {
+					if parsed.GetLabeledArc(d).GetHead() != gold.GetLabeledArc(d).GetHead() {
+						t.Errorf("%s: wrong head of %d, got %d expected %d", parser.Name(), d, parsed.GetLabeledArc(d).GetHead(), gold.GetLabeledArc(d).GetHead())
+					}
+				}
+				continue
+			}
+			if !gold.Equal(parsed) {
+				t.Errorf("%s: parsed sentence %d as %v, expected %v", parser.Name(), i, parsed.(*dep.BasicDepGraph).StringEdges(), gold.(*dep.BasicDepGraph).StringEdges())
+			}
+		}
+	}
+}
+
+func TestArcClusters(t *testing.T) {
+	tokens := []nlp.EnumTaggedToken{{TaggedToken: nlp.TaggedToken{Token: "dog"}}, {TaggedToken: nlp.TaggedToken{Token: "barked"}}}
+	set := &clusters.Set{Brown: &clusters.Clusters{Words: map[string]string{"dog": "0010"}}}
+	// the clusters of each parser are independent
+	for _, test := range []struct {
+		set    *clusters.Set
+		exists bool
+	}{{set, true}, {nil, false}} {
+		arc := &Arc{tokens, 1, 0, test.set}
+		value, exists, _ := arc.Attribute('D', 0, []byte("c2"), nil)
+		if exists != test.exists || (exists && value != "00") {
+			t.Errorf("Got cluster attribute %v %v with clusters %v, expected 00 %v", value, exists, test.set, test.exists)
+		}
+	}
+}
@@ -0,0 +1,457 @@
+// Package pseudoproj implements pseudo-projective dependency parsing
+// (Nivre & Nilsson 2005, "Pseudo-Projective Dependency Parsing"):
+// non-projective arcs of training trees are lifted until the trees are
+// projective, with the lifts encoded in the arc labels, and the encoded
+// labels of parsed trees are used to restore non-projective arcs.
+package pseudoproj
+
+import (
+	"yap/alg/graph"
+	"yap/nlp/parser/dependency/transition"
+	nlp "yap/nlp/types"
+	"yap/util"
+
+	"fmt"
+	"strings"
+)
+
+type Encoding int
+
+const (
+	// HEAD encodes the label of the syntactic head in the lifted arc label
+	HEAD Encoding = iota
+	// PATH marks the lifted arc and the arcs along the lifting path
+	PATH
+	// HEAD_PATH combines the HEAD and PATH encodings
+	HEAD_PATH
+)
+
+const (
+	LIFT_MARK = "↑"
+	PATH_MARK = "↓"
+)
+
+var encodingNames = map[string]Encoding{
+	"head":     HEAD,
+	"path":     PATH,
+	"headpath": HEAD_PATH,
+}
+
+func ParseEncoding(name string) (Encoding, error) {
+	enc, exists := encodingNames[name]
+	if !exists {
+		return HEAD, fmt.Errorf("Unknown pseudo-projective encoding %s (expected head, path or headpath)", name)
+	}
+	return enc, nil
+}
+
+func (e Encoding) String() string {
+	for name, enc := range encodingNames {
+		if enc == e {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// label is a dependency label with its pseudo-projective encoding
+type label struct {
+	Rel, Head string
+	Lifted    bool
+	Path      bool
+}
+
+func parseLabel(raw string) *label {
+	l := &label{Rel: raw}
+	if strings.HasSuffix(l.Rel, PATH_MARK) {
+		l.Path = true
+		l.Rel = strings.TrimSuffix(l.Rel, PATH_MARK)
+	}
+	if i := strings.Index(l.Rel, LIFT_MARK); i >= 0 {
+		l.Lifted = true
+		l.Rel, l.Head = l.Rel[:i], l.Rel[i+len(LIFT_MARK):]
+	}
+	return l
+}
+
+func (l *label) String() string {
+	str := l.Rel
+	if l.Lifted {
+		str += LIFT_MARK + l.Head
+	}
+	if l.Path {
+		str += PATH_MARK
+	}
+	return str
+}
+
+// BaseLabel returns a label without its pseudo-projective encoding
+func BaseLabel(raw string) string {
+	return parseLabel(raw).Rel
+}
+
+// tree is a mutable copy of the arcs of a dependency graph
+type tree struct {
+	heads  []int
+	labels []*label
+	hasArc []bool
+}
+
+func newTree(g nlp.LabeledDependencyGraph) *tree {
+	n := g.NumberOfNodes()
+	t := &tree{make([]int, n), make([]*label, n), make([]bool, n)}
+	for i := 0; i < n; i++ {
+		t.heads[i] = -1
+		t.labels[i] = &label{}
+		arc := g.GetLabeledArc(i)
+		if arc == nil || arc.GetModifier() != i {
+			continue
+		}
+		t.heads[i] = arc.GetHead()
+		t.labels[i] = parseLabel(string(arc.GetRelation()))
+		t.hasArc[i] = true
+	}
+	return t
+}
+
+// dominates returns true if h is an ancestor of (or equal to) n
+func (t *tree) dominates(h, n int) bool {
+	for steps := 0; n >= 0 && steps <= len(t.heads); steps++ {
+		if n == h {
+			return true
+		}
+		n = t.heads[n]
+	}
+	return false
+}
+
+// nonProjective returns true if the arc of a node spans a node that is
+// not dominated by its head
+func (t *tree) nonProjective(d int) bool {
+	h := t.heads[d]
+	if h < 0 {
+		return false
+	}
+	from, to := h, d
+	if from > to {
+		from, to = to, from
+	}
+	for k := from + 1; k < to; k++ {
+		if !t.dominates(h, k) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *tree) children(h int) []int {
+	var children []int
+	for d, head := range t.heads {
+		if head == h {
+			children = append(children, d)
+		}
+	}
+	return children
+}
+
+// breadthFirst returns the nodes under h (excluding h itself and the
+// subtree of exclude) in breadth first, left to right order. If follow is
+// not nil, only nodes it accepts are visited.
+func (t *tree) breadthFirst(h, exclude int, follow func(int) bool) []int {
+	var (
+		result []int
+		agenda = []int{h}
+	)
+	for len(agenda) > 0 {
+		cur := agenda[0]
+		agenda = agenda[1:]
+		for _, child := range t.children(cur) {
+			if child == exclude || (follow != nil && !follow(child)) {
+				continue
+			}
+			result = append(result, child)
+			agenda = append(agenda, child)
+		}
+	}
+	return result
+}
+
+// graph overlays the arcs of the tree over the graph it was created from
+func (t *tree) graph(g nlp.LabeledDependencyGraph, eRel *util.EnumSet) nlp.LabeledDependencyGraph {
+	arcs := make([]*transition.BasicDepArc, len(t.heads))
+	for i, head := range t.heads {
+		if !t.hasArc[i] {
+			continue
+		}
+		rel := nlp.DepRel(t.labels[i].String())
+		arcs[i] = &transition.BasicDepArc{Head: head, Relation: -1, Modifier: i, RawRelation: rel}
+	}
+	result := &Graph{g, arcs}
+	if eRel != nil {
+		result.SetRelations(eRel)
+	}
+	if morphGraph, ok := g.(nlp.MorphDependencyGraph); ok {
+		return &MorphGraph{result, morphGraph}
+	}
+	return result
+}
+
+// Projectivize lifts non-projective arcs of a graph, shortest arcs first, to
+// the head of their head until the graph is projective. The lifts are encoded
+// in the labels of the resulting graph; relation indices are set from eRel if
+// it is not nil (see Graph.SetRelations). Returns the projective graph and the
+// number of lifted arcs.
+func Projectivize(g nlp.LabeledDependencyGraph, enc Encoding, eRel *util.EnumSet) (nlp.LabeledDependencyGraph, int) {
+	var (
+		t        = newTree(g)
+		lifted   = make([]bool, len(t.heads))
+		stuck    = make([]bool, len(t.heads))
+		length   = func(d int) int { return util.AbsInt(t.heads[d] - d) }
+		numLifts int
+	)
+	for {
+		lift := -1
+		for d := range t.heads {
+			if !stuck[d] && t.nonProjective(d) && (lift < 0 || length(d) < length(lift)) {
+				lift = d
+			}
+		}
+		if lift < 0 {
+			break
+		}
+		h := t.heads[lift]
+		if t.heads[h] < 0 {
+			// can't lift above a root (the graph has more than one root)
+			stuck[lift] = true
+			continue
+		}
+		if !lifted[lift] {
+			lifted[lift] = true
+			numLifts++
+			dep := t.labels[lift]
+			dep.Lifted = true
+			if enc != PATH {
+				dep.Head = t.labels[h].Rel
+			}
+		}
+		if enc != HEAD {
+			t.labels[h].Path = true
+		}
+		t.heads[lift] = t.heads[h]
+	}
+	return t.graph(g, eRel), numLifts
+}
+
+// Deprojectivize reattaches lifted arcs of a parsed graph to the head
+// indicated by their encoded label, searching breadth first under their
+// current head, and removes the encoding from all labels. Relation indices
+// are set from eRel if it is not nil.
+func Deprojectivize(g nlp.LabeledDependencyGraph, enc Encoding, eRel *util.EnumSet) nlp.LabeledDependencyGraph {
+	t := newTree(g)
+	var roots []int
+	for d, head := range t.heads {
+		if head < 0 {
+			roots = append(roots, d)
+		}
+	}
+	// process lifted arcs top down, so that arcs lifted over other lifted
+	// arcs are reattached under their restored heads
+	var order []int
+	for _, root := range roots {
+		order = append(order, root)
+		order = append(order, t.breadthFirst(root, -1, nil)...)
+	}
+	isPath := func(n int) bool { return t.labels[n].Path }
+	for _, d := range order {
+		dep := t.labels[d]
+		if !dep.Lifted || t.heads[d] < 0 {
+			continue
+		}
+		target := -1
+		switch enc {
+		case HEAD:
+			for _, n := range t.breadthFirst(t.heads[d], d, nil) {
+				if t.labels[n].Rel == dep.Head {
+					target = n
+					break
+				}
+			}
+		case PATH, HEAD_PATH:
+			for _, n := range t.breadthFirst(t.heads[d], d, isPath) {
+				if enc == HEAD_PATH && t.labels[n].Rel == dep.Head {
+					target = n
+					break
+				}
+				if len(t.breadthFirst(n, d, isPath)) == 0 {
+					target = n
+					if enc == PATH {
+						break
+					}
+				}
+			}
+		}
+		if target >= 0 {
+			t.heads[d] = target
+		}
+	}
+	for _, l := range t.labels {
+		l.Lifted, l.Head, l.Path = false, "", false
+	}
+	return t.graph(g, eRel)
+}
+
+// Report counts the arcs lifted when projectivizing a corpus
+type Report struct {
+	Sentences, NonProjective int
+	Arcs, Lifted             int
+}
+
+func (r *Report) String() string {
+	return fmt.Sprintf("lifted %d of %d arcs in %d of %d sentences", r.Lifted, r.Arcs, r.NonProjective, r.Sentences)
+}
+
+// ProjectivizeCorpus projectivizes a slice of graphs
+func ProjectivizeCorpus(corpus []interface{}, enc Encoding, eRel *util.EnumSet) ([]interface{}, *Report) {
+	report := &Report{Sentences: len(corpus)}
+	result := make([]interface{}, len(corpus))
+	for i, instance := range corpus {
+		g := instance.(nlp.LabeledDependencyGraph)
+		var lifted int
+		result[i], lifted = Projectivize(g, enc, eRel)
+		report.Arcs += g.NumberOfArcs()
+		report.Lifted += lifted
+		if lifted > 0 {
+			report.NonProjective++
+		}
+	}
+	return result, report
+}
+
+// DeprojectivizeCorpus deprojectivizes a slice of graphs
+func DeprojectivizeCorpus(corpus []interface{}, enc Encoding, eRel *util.EnumSet) []interface{} {
+	result := make([]interface{}, len(corpus))
+	for i, instance := range corpus {
+		result[i] = Deprojectivize(instance.(nlp.LabeledDependencyGraph), enc, eRel)
+	}
+	return result
+}
+
+// DeprojectivizeStream deprojectivizes a stream of graphs
+func DeprojectivizeStream(corpus chan interface{}, enc Encoding, eRel *util.EnumSet) chan interface{} {
+	result := make(chan interface{}, 2)
+	go func() {
+		for instance := range corpus {
+			result <- Deprojectivize(instance.(nlp.LabeledDependencyGraph), enc, eRel)
+		}
+		close(result)
+	}()
+	return result
+}
+
+// Labels returns the labels of a corpus of graphs, in order of appearance
+func Labels(corpus []interface{}) []string {
+	var (
+		labels []string
+		seen   = make(map[string]bool)
+	)
+	for _, instance := range corpus {
+		g := instance.(nlp.LabeledDependencyGraph)
+		for _, edge := range g.GetEdges() {
+			arc := g.GetLabeledArc(edge)
+			if arc == nil {
+				continue
+			}
+			rel := string(arc.GetRelation())
+			if !seen[rel] {
+				seen[rel] = true
+				labels = append(labels, rel)
+			}
+		}
+	}
+	return labels
+}
+
+// Graph replaces the arcs of a dependency graph; arcs are indexed by
+// their modifier
+type Graph struct {
+	nlp.LabeledDependencyGraph
+	Arcs []*transition.BasicDepArc
+}
+
+var _ nlp.LabeledDependencyGraph = &Graph{}
+
+// SetRelations sets the relation indices of the arcs from their labels
+func (g *Graph) SetRelations(eRel *util.EnumSet) {
+	for _, arc := range g.Arcs {
+		if arc == nil {
+			continue
+		}
+		if index, exists := eRel.IndexOf(arc.RawRelation); exists {
+			arc.Relation = index
+		} else {
+			arc.Relation = -1
+		}
+	}
+}
+
+func (g *Graph) GetEdges() []int {
+	edges := make([]int, 0, len(g.Arcs))
+	for i, arc := range g.Arcs {
+		if arc != nil {
+			edges = append(edges, i)
+		}
+	}
+	return edges
+}
+
+func (g *Graph) GetEdge(n int) graph.Edge {
+	if arc := g.GetLabeledArc(n); arc != nil {
+		return graph.Edge(arc)
+	}
+	return nil
+}
+
+func (g *Graph) GetDirectedEdge(n int) graph.DirectedEdge {
+	if arc := g.GetLabeledArc(n); arc != nil {
+		return graph.DirectedEdge(arc)
+	}
+	return nil
+}
+
+func (g *Graph) NumberOfEdges() int {
+	return len(g.GetEdges())
+}
+
+func (g *Graph) NumberOfArcs() int {
+	return g.NumberOfEdges()
+}
+
+func (g *Graph) GetArc(n int) nlp.DepArc {
+	if arc := g.GetLabeledArc(n); arc != nil {
+		return nlp.DepArc(arc)
+	}
+	return nil
+}
+
+func (g *Graph) GetLabeledArc(n int) nlp.LabeledDepArc {
+	if n < 0 || n >= len(g.Arcs) || g.Arcs[n] == nil {
+		return nil
+	}
+	return g.Arcs[n]
+}
+
+// MorphGraph replaces the arcs of a morphological dependency graph
+type MorphGraph struct {
+	*Graph
+	Morph nlp.MorphDependencyGraph
+}
+
+var _ nlp.MorphDependencyGraph = &MorphGraph{}
+
+func (g *MorphGraph) GetMappings() nlp.Mappings {
+	return g.Morph.GetMappings()
+}
+
+func (g *MorphGraph) GetMorpheme(n int) *nlp.EMorpheme {
+	return g.Morph.GetMorpheme(n)
+}
@@ -0,0 +1,125 @@
+package pseudoproj
+
+import (
+	"yap/nlp/parser/dependency/transition"
+	nlp "yap/nlp/types"
+
+	"reflect"
+	"testing"
+)
+
+// A hearing is scheduled on the issue today
+var (
+	testHeads  = []int{1, 2, -1, 2, 1, 6, 4, 3}
+	testLabels = []string{"det", "subj", nlp.ROOT_LABEL, "xcomp", "prep", "det", "pobj", "tmod"}
+	// A hearing on the issue is scheduled today (with on attached to is)
+	testSingleHeads = []int{1, 2, -1, 2, 1, 6, 4, 2}
+)
+
+func testGraph(heads []int, labels []string) nlp.LabeledDependencyGraph {
+	nodes := make([]nlp.DepNode, len(heads))
+	arcs := make([]*transition.BasicDepArc, len(heads))
+	for i, head := range heads {
+		nodes[i] = &transition.TaggedDepNode{Id: i}
+		arcs[i] = &transition.BasicDepArc{Head: head, Relation: -1, Modifier: i, RawRelation: nlp.DepRel(labels[i])}
+	}
+	return &transition.BasicDepGraph{nodes, arcs}
+}
+
+func graphArcs(g nlp.LabeledDependencyGraph) ([]int, []string) {
+	heads := make([]int, g.NumberOfNodes())
+	labels := make([]string, g.NumberOfNodes())
+	for i := range heads {
+		arc := g.GetLabeledArc(i)
+		heads[i], labels[i] = arc.GetHead(), string(arc.GetRelation())
+	}
+	return heads, labels
+}
+
+func TestProjectivize(t *testing.T) {
+	tests := []struct {
+		name          string
+		heads         []int
+		enc           Encoding
+		lifted        int
+		liftedHeads   []int
+		liftedLabels  []string
+		restoresGraph bool
+	}{
+		{"head", testHeads, HEAD, 2,
+			[]int{1, 2, -1, 2, 2, 6, 4, 2},
+			[]string{"det", "subj", nlp.ROOT_LABEL, "xcomp", "prep↑subj", "det", "pobj", "tmod↑xcomp"},
+			true},
+		{"path", testHeads, PATH, 2,
+			[]int{1, 2, -1, 2, 2, 6, 4, 2},
+			[]string{"det", "subj↓", nlp.ROOT_LABEL, "xcomp↓", "prep↑", "det", "pobj", "tmod↑"},
+			// both lifts are under the root, path encoding can't tell them apart
+			false},
+		{"headpath", testHeads, HEAD_PATH, 2,
+			[]int{1, 2, -1, 2, 2, 6, 4, 2},
+			[]string{"det", "subj↓", nlp.ROOT_LABEL, "xcomp↓", "prep↑subj", "det", "pobj", "tmod↑xcomp"},
+			true},
+		{"path single lift", testSingleHeads, PATH, 1,
+			[]int{1, 2, -1, 2, 2, 6, 4, 2},
+			[]string{"det", "subj↓", nlp.ROOT_LABEL, "xcomp", "prep↑", "det", "pobj", "tmod"},
+			true},
+		{"projective", []int{1, 2, -1, 2, 2, 6, 4, 2}, HEAD, 0,
+			[]int{1, 2, -1, 2, 2, 6, 4, 2},
+			testLabels,
+			true},
+	}
+	for _, test := range tests {
+		g := testGraph(test.heads, testLabels)
+		proj, lifted := Projectivize(g, test.enc, nil)
+		if lifted != test.lifted {
+			t.Errorf("%s: lifted %d arcs, expected %d", test.name, lifted, test.lifted)
+		}
+		heads, labels := graphArcs(proj)
+		if !reflect.DeepEqual(heads, test.liftedHeads) || !reflect.DeepEqual(labels, test.liftedLabels) {
+			t.Errorf("%s: projectivized to %v %v, expected %v %v", test.name, heads, labels, test.liftedHeads, test.liftedLabels)
+		}
+		projTree := newTree(proj)
+		for d := range projTree.heads {
+			if projTree.nonProjective(d) {
+				t.Errorf("%s: arc of %d is not projective after projectivizing", test.name, d)
+			}
+		}
+		heads, labels = graphArcs(Deprojectivize(proj, test.enc, nil))
+		restored := reflect.DeepEqual(heads, test.heads) && reflect.DeepEqual(labels, testLabels)
+		if test.restoresGraph && !restored {
+			t.Errorf("%s: deprojectivized to %v %v, expected %v %v", test.name, heads, labels, test.heads, testLabels)
+		}
+		for _, label := range labels {
+			if label != BaseLabel(label) {
+				t.Errorf("%s: deprojectivized label %s is still encoded", test.name, label)
+			}
+		}
+	}
+}
+
+func TestProjectivizeCorpus(t *testing.T) {
+	corpus := []interface{}{
+		testGraph(testHeads, testLabels),
+		testGraph([]int{1, 2, -1, 2, 2, 6, 4, 2}, testLabels),
+	}
+	proj, report := ProjectivizeCorpus(corpus, HEAD, nil)
+	if report.Sentences != 2 || report.NonProjective != 1 || report.Arcs != 16 || report.Lifted != 2 {
+		t.Error("Got wrong report", report)
+	}
+	labels := Labels(proj)
+	expected := []string{"det", "subj", nlp.ROOT_LABEL, "xcomp", "prep↑subj", "pobj", "tmod↑xcomp", "prep", "tmod"}
+	if !reflect.DeepEqual(labels, expected) {
+		t.Error("Got labels", labels, "expected", expected)
+	}
+}
+
+func TestParseEncoding(t *testing.T) {
+	for name, expected := range encodingNames {
+		if enc, err := ParseEncoding(name); err != nil || enc != expected || enc.String() != name {
+			t.Error("Failed parsing encoding", name)
+		}
+	}
+	if _, err := ParseEncoding("lift"); err == nil {
+		t.Error("Expected error parsing unknown encoding")
+	}
+}
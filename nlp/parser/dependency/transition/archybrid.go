@@ -0,0 +1,175 @@
+package transition
+
+import (
+	. "yap/alg/transition"
+	. "yap/nlp/types"
+
+	"fmt"
+)
+
+// ArcHybrid is the arc hybrid transition system (Kuhlmann et al. 2011):
+// left arcs are created between the stack top and the buffer head as in
+// arc eager, right arcs between the two topmost stack elements as in
+// arc standard.
+type ArcHybrid struct {
+	ArcStandard
+}
+
+// Verify that ArcHybrid is a TransitionSystem
+var _ TransitionSystem = &ArcHybrid{}
+
+func (a *ArcHybrid) Transition(from Configuration, rawTransition Transition) Configuration {
+	conf, ok := from.Copy().(*SimpleConfiguration)
+	if !ok {
+		panic("Got wrong configuration type")
+	}
+	transition := rawTransition.Value()
+	// Transition System:
+	// LA-r	(S|wi,	wj|B,	A) => (S   ,	wj|B,	A+{(wj,r,wi)})
+	// RA-r	(S|wi|wj,	B,	A) => (S|wi,	   B,	A+{(wi,r,wj)})
+	// SH	(S   ,	wi|B, 	A) => (S|wi,	   B,	A)
+	switch {
+	case transition >= a.LEFT && transition < a.RIGHT:
+		wi, wiExists := conf.Stack().Pop()
+		wj, wjExists := conf.Queue().Peek()
+		if !(wiExists && wjExists) {
+			panic(fmt.Sprintf("Can't LA, Stack and/or Queue are/is empty: %v", conf))
+		}
+		relation := int(transition - a.LEFT)
+		relationValue := a.Relations.ValueOf(relation).(DepRel)
+		newArc := &BasicDepArc{wj, relation, wi, relationValue}
+		conf.AddArc(newArc)
+		conf.Assign(uint16(conf.Nodes[newArc.Modifier].ID()))
+		conf.NumHeadStack--
+	case transition >= a.RIGHT:
+		wj, wjExists := conf.Stack().Pop()
+		wi, wiExists := conf.Stack().Peek()
+		if !(wiExists && wjExists) {
+			panic(fmt.Sprintf("Can't RA, Stack has less than 2 elements: %v", conf))
+		}
+		rel := int(transition - a.RIGHT)
+		relValue := a.Relations.ValueOf(rel).(DepRel)
+		newArc := &BasicDepArc{wi, rel, wj, relValue}
+		conf.AddArc(newArc)
+		conf.Assign(uint16(conf.Nodes[newArc.Modifier].ID()))
+		conf.NumHeadStack--
+	case transition == a.SHIFT:
+		wi, wiExists := conf.Queue().Pop()
+		if !wiExists {
+			panic("Can't shift, queue is empty")
+		}
+		conf.Assign(uint16(conf.Nodes[wi].ID()))
+		conf.Stack().Push(wi)
+		conf.NumHeadStack++
+	default:
+		panic(fmt.Sprintf("Unknown transition %v SHIFT is %v", transition, a.SHIFT))
+	}
+	conf.SetLastTransition(rawTransition)
+	return conf
+}
+
+func (a *ArcHybrid) possibleTransitions(from Configuration, transitions chan int) {
+	conf, ok := from.(*SimpleConfiguration)
+	if !ok {
+		panic("Got wrong configuration type")
+	}
+	_, qExists := conf.Queue().Peek()
+	_, sExists := conf.Stack().Peek()
+	if qExists {
+		transitions <- a.SHIFT
+	}
+	if sExists && qExists {
+		for rel, _ := range a.Relations.Index {
+			transitions <- a.LEFT + rel
+		}
+	}
+	if conf.Stack().Size() > 1 {
+		for rel, _ := range a.Relations.Index {
+			transitions <- a.RIGHT + rel
+		}
+	}
+	close(transitions)
+}
+
+func (a *ArcHybrid) GetTransitions(from Configuration) (byte, []int) {
+	retval := make([]int, 0, 10)
+	tType, transitions := a.YieldTransitions(from)
+	for transition := range transitions {
+		retval = append(retval, int(transition))
+	}
+	return tType, retval
+}
+
+func (a *ArcHybrid) YieldTransitions(from Configuration) (byte, chan int) {
+	transitions := make(chan int)
+	go a.possibleTransitions(from, transitions)
+	return TransitionType, transitions
+}
+
+func (a *ArcHybrid) Oracle() Oracle {
+	return a.oracle
+}
+
+func (a *ArcHybrid) AddDefaultOracle() {
+	a.oracle = Oracle(&ArcHybridOracle{ArcStandardOracle{Transitions: a.Transitions, LA: int(a.LEFT), RA: int(a.RIGHT)}})
+}
+
+func (a *ArcHybrid) Name() string {
+	return "Arc Hybrid"
+}
+
+type ArcHybridOracle struct {
+	ArcStandardOracle
+}
+
+var _ Decision = &ArcHybridOracle{}
+
+func (o *ArcHybridOracle) Transition(conf Configuration) Transition {
+	c := conf.(*SimpleConfiguration)
+
+	if o.gold == nil {
+		panic("Oracle needs gold reference, use SetGold")
+	}
+	// Given Gd=(Vd,Ad) # gold dependencies
+	// o(c = (S,B,A)) =
+	// LA-r	if	(B[0],r,S[0]) in Ad
+	// RA-r	if	(S[1],r,S[0]) in Ad; and for all w,r', if (S[0],r',w) in Ad then (S[0],r',w) in A
+	// SH	otherwise
+	var index int
+	bTop, bExists := c.Queue().Peek()
+	s0, s0Exists := c.Stack().Index(0)
+	s1, s1Exists := c.Stack().Index(1)
+	if s0Exists && bExists {
+		arcs := o.arcSet.Get(&BasicDepArc{bTop, -1, s0, DepRel("")})
+		if len(arcs) > 0 {
+			index, _ = o.Transitions.IndexOf("LA-" + string(arcs[0].GetRelation()))
+			return &TypedTransition{TransitionType, index}
+		}
+	}
+	if s0Exists && s1Exists {
+		arcs := o.arcSet.Get(&BasicDepArc{s1, -1, s0, DepRel("")})
+		if len(arcs) > 0 && o.complete(c, s0) {
+			index, _ = o.Transitions.IndexOf("RA-" + string(arcs[0].GetRelation()))
+			return &TypedTransition{TransitionType, index}
+		}
+	}
+	if bExists {
+		index, _ = o.Transitions.IndexOf("SH")
+		return &TypedTransition{TransitionType, index}
+	}
+	panic(fmt.Sprintf("No oracle transition for configuration %v", c))
+}
+
+// complete returns true if all gold modifiers of a node are attached to it
+func (o *ArcHybridOracle) complete(c *SimpleConfiguration, node int) bool {
+	for _, arc := range o.arcSet.Get(&BasicDepArc{node, -1, -1, DepRel("")}) {
+		if len(c.Arcs().Get(arc)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (o *ArcHybridOracle) Name() string {
+	return "Arc Hybrid"
+}
@@ -0,0 +1,184 @@
+package transition
+
+import (
+	. "yap/alg/transition"
+	nlp "yap/nlp/types"
+
+	"reflect"
+	"testing"
+)
+
+var (
+	TEST_HYBRID_TRANSITIONS []nlp.DepRel = []nlp.DepRel{
+		"SH",
+		"LA-ATT",
+		"SH",
+		"LA-SBJ",
+		"SH",
+		"SH",
+		"LA-ATT",
+		"SH",
+		"SH",
+		"SH",
+		"LA-ATT",
+		"SH",
+		"RA-PC",
+		"RA-ATT",
+		"RA-OBJ",
+		"SH",
+		"RA-PU"}
+	TEST_HYBRID_ENUM_TRANSITIONS []Transition
+)
+
+func SetupHybridEnum() {
+	SetupTestEnum()
+	SetupEagerTransEnum()
+	TEST_HYBRID_ENUM_TRANSITIONS = make([]Transition, len(TEST_HYBRID_TRANSITIONS))
+	for i, transition := range TEST_HYBRID_TRANSITIONS {
+		index, _ := TRANSITIONS_ENUM.IndexOf(string(transition))
+		TEST_HYBRID_ENUM_TRANSITIONS[i] = ConstTransition(index)
+	}
+}
+
+func newTestArcHybrid() *ArcHybrid {
+	arcHyb := &ArcHybrid{
+		ArcStandard: ArcStandard{
+			SHIFT:       SH.Value(),
+			LEFT:        LA.Value(),
+			RIGHT:       RA.Value(),
+			Relations:   TEST_ENUM_RELATIONS,
+			Transitions: TRANSITIONS_ENUM,
+		},
+	}
+	arcHyb.AddDefaultOracle()
+	return arcHyb
+}
+
+func newTestHybridConf() *SimpleConfiguration {
+	conf := &SimpleConfiguration{
+		EWord:         EWord,
+		EPOS:          EPOS,
+		EWPOS:         EWPOS,
+		ERel:          TEST_ENUM_RELATIONS,
+		ETrans:        TRANSITIONS_ENUM,
+		TerminalStack: 1,
+	}
+	conf.Init(TEST_SENT)
+	return conf
+}
+
+func TestArcHybridTransitions(t *testing.T) {
+	SetupHybridEnum()
+	arcHyb := newTestArcHybrid()
+	conf := newTestHybridConf()
+
+	// SHIFT
+	shConf := arcHyb.Transition(conf, SH).(*SimpleConfiguration)
+	if qPeek, qPeekExists := shConf.Queue().Peek(); !qPeekExists || qPeek != 1 {
+		t.Error("Expected N0 = 1, got", qPeek)
+	}
+	if sPeek, sPeekExists := shConf.Stack().Peek(); !sPeekExists || sPeek != 0 {
+		t.Error("Expected S0 = 0, got", sPeek)
+	}
+	// LA attaches the stack head to the buffer head
+	transition, exists := TRANSITIONS_ENUM.IndexOf("LA-ATT")
+	if !exists {
+		t.Fatal("Can't find transition LA-ATT")
+	}
+	laConf := arcHyb.Transition(shConf, ConstTransition(transition)).(*SimpleConfiguration)
+	if laConf.Stack().Size() != 0 {
+		t.Error("Expected empty stack after LA, got size", laConf.Stack().Size())
+	}
+	if qPeek, qPeekExists := laConf.Queue().Peek(); !qPeekExists || qPeek != 1 {
+		t.Error("Expected N0 = 1 after LA, got", qPeek)
+	}
+	label, _ := TEST_ENUM_RELATIONS.IndexOf(nlp.DepRel("ATT"))
+	if arcs := laConf.Arcs().Get(&BasicDepArc{1, label, 0, nlp.DepRel("ATT")}); len(arcs) != 1 {
+		t.Error("Left arc not found, arcs: ", laConf.StringArcs())
+	}
+
+	// RA attaches the stack head to the element below it
+	c := Configuration(laConf)
+	c = arcHyb.Transition(c, SH)
+	c = arcHyb.Transition(c, SH)
+	transition, exists = TRANSITIONS_ENUM.IndexOf("RA-OBJ")
+	if !exists {
+		t.Fatal("Can't find transition RA-OBJ")
+	}
+	raConf := arcHyb.Transition(c, ConstTransition(transition)).(*SimpleConfiguration)
+	if sPeek, sPeekExists := raConf.Stack().Peek(); !sPeekExists || sPeek != 1 || raConf.Stack().Size() != 1 {
+		t.Error("Expected S0 = 1 as the only stack element after RA, got", sPeek)
+	}
+	if qPeek, qPeekExists := raConf.Queue().Peek(); !qPeekExists || qPeek != 3 {
+		t.Error("Expected N0 = 3 after RA, got", qPeek)
+	}
+	label, _ = TEST_ENUM_RELATIONS.IndexOf(nlp.DepRel("OBJ"))
+	if arcs := raConf.Arcs().Get(&BasicDepArc{1, label, 2, nlp.DepRel("OBJ")}); len(arcs) != 1 {
+		t.Error("Right arc not found, arcs: ", raConf.StringArcs())
+	}
+
+	// RA checks conditions
+	recovered := false
+	panicFunc := func() {
+		defer func() {
+			r := recover()
+			recovered = r != nil
+		}()
+		_ = arcHyb.Transition(raConf, RA)
+	}
+	panicFunc()
+	if !recovered {
+		t.Error("Did not panic when trying to Right-Arc with a single stack element")
+	}
+	_, possible := arcHyb.GetTransitions(raConf)
+	for _, p := range possible {
+		if p >= RA.Value() {
+			t.Error("Got possible RA transition with a single stack element")
+		}
+	}
+}
+
+func TestArcHybridOracle(t *testing.T) {
+	SetupHybridEnum()
+	arcHyb := newTestArcHybrid()
+	goldGraph := GetTestDepGraph()
+	conf := Configuration(newTestHybridConf())
+
+	oracle := arcHyb.Oracle()
+	oracle.SetGold(goldGraph)
+	for i, expected := range TEST_HYBRID_ENUM_TRANSITIONS {
+		transition := oracle.Transition(conf)
+		if transition.Value() != expected.Value() {
+			t.Fatal("Oracle failed at transition", i, "expected", TRANSITIONS_ENUM.ValueOf(expected.Value()), "got", TRANSITIONS_ENUM.ValueOf(transition.Value()))
+		}
+		conf = arcHyb.Transition(conf, transition)
+	}
+	if !conf.Terminal() {
+		t.Error("Configuration should be terminal at end of expected transition sequence")
+	}
+
+	arcs := conf.(*SimpleConfiguration).Arcs()
+	for i := 0; i < goldGraph.NumberOfNodes(); i++ {
+		goldArc := goldGraph.GetLabeledArc(i)
+		if goldArc.GetHead() < 0 {
+			continue
+		}
+		if len(arcs.Get(goldArc)) != 1 {
+			t.Error("Oracle/Gold parsing is missing arc", goldArc)
+		}
+	}
+	if arcs.Size() != goldGraph.NumberOfNodes()-1 {
+		t.Error("Oracle/Gold parsing resulted in", arcs.Size(), "arcs, expected", goldGraph.NumberOfNodes()-1)
+	}
+}
+
+func TestArcHybridEsotericFunctions(t *testing.T) {
+	arcHyb := new(ArcHybrid)
+	transitions := arcHyb.TransitionTypes()
+	if !reflect.DeepEqual(transitions, []string{"LA-*", "RA-*", "SH"}) {
+		t.Error("Wrong transition types")
+	}
+	if !arcHyb.Projective() || !arcHyb.Labeled() {
+		t.Error("Arc hybrid should be projective and labeled")
+	}
+}
@@ -0,0 +1,308 @@
+package transition
+
+import (
+	. "yap/alg/transition"
+	. "yap/nlp/types"
+
+	"fmt"
+	"sort"
+)
+
+// ArcSwap is the arc standard transition system extended with a SWAP
+// transition, which reorders the input and allows deriving non-projective
+// trees (Nivre 2009, "Non-Projective Dependency Parsing in Expected
+// Linear Time"). Unlike ArcStandard, arcs are created between the two
+// topmost stack elements.
+type ArcSwap struct {
+	ArcStandard
+	SWAP int
+}
+
+// Verify that ArcSwap is a TransitionSystem
+var _ TransitionSystem = &ArcSwap{}
+
+func (a *ArcSwap) Transition(from Configuration, rawTransition Transition) Configuration {
+	conf, ok := from.Copy().(*SimpleConfiguration)
+	if !ok {
+		panic("Got wrong configuration type")
+	}
+	transition := rawTransition.Value()
+	// Transition System:
+	// LA-r	(S|wi|wj,	B,	A) => (S|wj,	   B,	A+{(wj,r,wi)})
+	// RA-r	(S|wi|wj,	B,	A) => (S|wi,	   B,	A+{(wi,r,wj)})
+	// SW	(S|wi|wj,	B,	A) => (S|wj,	wi|B,	A)	if: i < j
+	// SH	(S   ,	wi|B, 	A) => (S|wi,	   B,	A)
+	// SWAP is enumerated after RA-*, so it must be tested first
+	switch {
+	case transition == a.SWAP:
+		wj, wjExists := conf.Stack().Pop()
+		wi, wiExists := conf.Stack().Pop()
+		if !(wiExists && wjExists) {
+			panic(fmt.Sprintf("Can't SW, Stack has less than 2 elements: %v", conf))
+		}
+		conf.Stack().Push(wj)
+		conf.Queue().Push(wi)
+		conf.Assign(uint16(conf.Nodes[wi].ID()))
+		// a head-less element is moved back to the buffer
+		conf.NumHeadStack--
+	case transition >= a.LEFT && transition < a.RIGHT:
+		wj, wjExists := conf.Stack().Pop()
+		wi, wiExists := conf.Stack().Pop()
+		if !(wiExists && wjExists) {
+			panic(fmt.Sprintf("Can't LA, Stack has less than 2 elements: %v", conf))
+		}
+		relation := int(transition - a.LEFT)
+		relationValue := a.Relations.ValueOf(relation).(DepRel)
+		newArc := &BasicDepArc{wj, relation, wi, relationValue}
+		conf.Stack().Push(wj)
+		conf.AddArc(newArc)
+		conf.Assign(uint16(conf.Nodes[newArc.Modifier].ID()))
+		conf.NumHeadStack--
+	case transition >= a.RIGHT:
+		wj, wjExists := conf.Stack().Pop()
+		wi, wiExists := conf.Stack().Peek()
+		if !(wiExists && wjExists) {
+			panic(fmt.Sprintf("Can't RA, Stack has less than 2 elements: %v", conf))
+		}
+		rel := int(transition - a.RIGHT)
+		relValue := a.Relations.ValueOf(rel).(DepRel)
+		newArc := &BasicDepArc{wi, rel, wj, relValue}
+		conf.AddArc(newArc)
+		conf.Assign(uint16(conf.Nodes[newArc.Modifier].ID()))
+		conf.NumHeadStack--
+	case transition == a.SHIFT:
+		wi, wiExists := conf.Queue().Pop()
+		if !wiExists {
+			panic("Can't shift, queue is empty")
+		}
+		conf.Assign(uint16(conf.Nodes[wi].ID()))
+		conf.Stack().Push(wi)
+		conf.NumHeadStack++
+	default:
+		panic(fmt.Sprintf("Unknown transition %v SHIFT is %v", transition, a.SHIFT))
+	}
+	conf.SetLastTransition(rawTransition)
+	return conf
+}
+
+func (a *ArcSwap) possibleTransitions(from Configuration, transitions chan int) {
+	conf, ok := from.(*SimpleConfiguration)
+	if !ok {
+		panic("Got wrong configuration type")
+	}
+	_, qExists := conf.Queue().Peek()
+	s0, s0Exists := conf.Stack().Index(0)
+	s1, s1Exists := conf.Stack().Index(1)
+	if qExists {
+		transitions <- a.SHIFT
+	}
+	if s0Exists && s1Exists {
+		for rel, _ := range a.Relations.Index {
+			transitions <- a.LEFT + rel
+		}
+		for rel, _ := range a.Relations.Index {
+			transitions <- a.RIGHT + rel
+		}
+		// only swap elements in their original order, otherwise
+		// the parser could swap indefinitely
+		if s1 < s0 {
+			transitions <- a.SWAP
+		}
+	}
+	close(transitions)
+}
+
+func (a *ArcSwap) GetTransitions(from Configuration) (byte, []int) {
+	retval := make([]int, 0, 10)
+	tType, transitions := a.YieldTransitions(from)
+	for transition := range transitions {
+		retval = append(retval, int(transition))
+	}
+	return tType, retval
+}
+
+func (a *ArcSwap) YieldTransitions(from Configuration) (byte, chan int) {
+	transitions := make(chan int)
+	go a.possibleTransitions(from, transitions)
+	return TransitionType, transitions
+}
+
+func (a *ArcSwap) TransitionTypes() []string {
+	return []string{"LA-*", "RA-*", "SH", "SW"}
+}
+
+func (a *ArcSwap) Projective() bool {
+	return false
+}
+
+func (a *ArcSwap) Oracle() Oracle {
+	return a.oracle
+}
+
+func (a *ArcSwap) AddDefaultOracle() {
+	a.oracle = Oracle(&ArcSwapOracle{ArcStandardOracle: ArcStandardOracle{Transitions: a.Transitions, LA: int(a.LEFT), RA: int(a.RIGHT)}})
+}
+
+func (a *ArcSwap) Name() string {
+	return "Arc Standard with Swap"
+}
+
+// ArcSwapOracle is the static oracle with lazy swap ordering of Nivre,
+// Kuhlmann and Hall 2009 ("An Improved Oracle for Dependency Parsing with
+// Online Reordering"): elements are swapped only when they are out of
+// projective order and the next buffer element is not part of the same
+// maximal projective component.
+type ArcSwapOracle struct {
+	ArcStandardOracle
+	heads     []int
+	rels      []DepRel
+	numChild  []int
+	projOrder []int
+	mpc       []int
+}
+
+var _ Decision = &ArcSwapOracle{}
+
+func (o *ArcSwapOracle) SetGold(g interface{}) {
+	o.ArcStandardOracle.SetGold(g)
+	numNodes := o.gold.NumberOfNodes()
+	o.heads = make([]int, numNodes)
+	o.rels = make([]DepRel, numNodes)
+	o.numChild = make([]int, numNodes)
+	for i := range o.heads {
+		o.heads[i] = -1
+	}
+	for i := 0; i < numNodes; i++ {
+		arc := o.gold.GetLabeledArc(i)
+		if arc == nil || arc.GetModifier() < 0 || arc.GetHead() < 0 {
+			continue
+		}
+		o.heads[arc.GetModifier()] = arc.GetHead()
+		o.rels[arc.GetModifier()] = arc.GetRelation()
+		o.numChild[arc.GetHead()]++
+	}
+	o.setProjectiveOrder()
+	o.setMPCs()
+}
+
+// setProjectiveOrder sets the position of each node in an inorder traversal
+// of the gold tree; swapping by this order yields a projective tree
+func (o *ArcSwapOracle) setProjectiveOrder() {
+	children := make([][]int, len(o.heads))
+	var roots []int
+	for mod, head := range o.heads {
+		if head < 0 {
+			roots = append(roots, mod)
+		} else {
+			children[head] = append(children[head], mod)
+		}
+	}
+	o.projOrder = make([]int, len(o.heads))
+	var (
+		pos   int
+		visit func(int)
+	)
+	visit = func(node int) {
+		for _, child := range children[node] {
+			if child < node {
+				visit(child)
+			}
+		}
+		o.projOrder[node] = pos
+		pos++
+		for _, child := range children[node] {
+			if child > node {
+				visit(child)
+			}
+		}
+	}
+	sort.Ints(roots)
+	for _, root := range roots {
+		visit(root)
+	}
+}
+
+// setMPCs sets the maximal projective component of each node, by parsing
+// the gold tree without swapping and grouping the nodes connected by the
+// arcs that were created
+func (o *ArcSwapOracle) setMPCs() {
+	o.mpc = make([]int, len(o.heads))
+	for i := range o.mpc {
+		o.mpc[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if o.mpc[i] != i {
+			o.mpc[i] = find(o.mpc[i])
+		}
+		return o.mpc[i]
+	}
+	attached := make([]int, len(o.heads))
+	stack := make([]int, 0, len(o.heads))
+	for i := range o.heads {
+		stack = append(stack, i)
+		for len(stack) > 1 {
+			s0, s1 := stack[len(stack)-1], stack[len(stack)-2]
+			if o.heads[s1] == s0 && attached[s1] == o.numChild[s1] {
+				attached[s0]++
+				o.mpc[find(s1)] = find(s0)
+				stack = append(stack[:len(stack)-2], s0)
+			} else if o.heads[s0] == s1 && attached[s0] == o.numChild[s0] {
+				attached[s1]++
+				o.mpc[find(s0)] = find(s1)
+				stack = stack[:len(stack)-1]
+			} else {
+				break
+			}
+		}
+	}
+	for i := range o.mpc {
+		o.mpc[i] = find(i)
+	}
+}
+
+// complete returns true if all gold modifiers of a node are attached to it
+func (o *ArcSwapOracle) complete(c *SimpleConfiguration, node int) bool {
+	return len(c.Arcs().Get(&BasicDepArc{node, -1, -1, DepRel("")})) == o.numChild[node]
+}
+
+func (o *ArcSwapOracle) Transition(conf Configuration) Transition {
+	c := conf.(*SimpleConfiguration)
+
+	if o.gold == nil {
+		panic("Oracle needs gold reference, use SetGold")
+	}
+	// Given Gd=(Vd,Ad) # gold dependencies
+	// o(c = (S,B,A)) =
+	// LA-r	if	(S[0],r,S[1]) in Ad and S[1] has all its modifiers
+	// RA-r	if	(S[1],r,S[0]) in Ad and S[0] has all its modifiers
+	// SW	if	proj(S[0]) < proj(S[1]) and (B is empty or mpc(S[0]) != mpc(B[0]))
+	// SH	otherwise
+	var index int
+	bTop, bExists := c.Queue().Peek()
+	s0, s0Exists := c.Stack().Index(0)
+	s1, s1Exists := c.Stack().Index(1)
+	if s0Exists && s1Exists {
+		if o.heads[s1] == s0 && o.complete(c, s1) {
+			index, _ = o.Transitions.IndexOf("LA-" + string(o.rels[s1]))
+			return &TypedTransition{TransitionType, index}
+		}
+		if o.heads[s0] == s1 && o.complete(c, s0) {
+			index, _ = o.Transitions.IndexOf("RA-" + string(o.rels[s0]))
+			return &TypedTransition{TransitionType, index}
+		}
+		if o.projOrder[s0] < o.projOrder[s1] && (!bExists || o.mpc[s0] != o.mpc[bTop]) {
+			index, _ = o.Transitions.IndexOf("SW")
+			return &TypedTransition{TransitionType, index}
+		}
+	}
+	if bExists {
+		index, _ = o.Transitions.IndexOf("SH")
+		return &TypedTransition{TransitionType, index}
+	}
+	panic(fmt.Sprintf("No oracle transition for configuration %v", c))
+}
+
+func (o *ArcSwapOracle) Name() string {
+	return "Arc Standard with Swap (Lazy)"
+}
@@ -0,0 +1,168 @@
+package transition
+
+import (
+	. "yap/alg/transition"
+	nlp "yap/nlp/types"
+	"yap/util"
+
+	"reflect"
+	"testing"
+)
+
+var SW Transition
+
+func SetupSwapEnum() {
+	SetupTestEnum()
+	TRANSITIONS_ENUM = util.NewEnumSet(len(TEST_RELATIONS)*2 + 3)
+	_, _ = TRANSITIONS_ENUM.Add("NO")
+	iSH, _ := TRANSITIONS_ENUM.Add("SH")
+	SH = ConstTransition(iSH)
+	LA = ConstTransition(TRANSITIONS_ENUM.Len())
+	for _, transition := range TEST_RELATIONS {
+		TRANSITIONS_ENUM.Add(string("LA-" + transition))
+	}
+	RA = ConstTransition(TRANSITIONS_ENUM.Len())
+	for _, transition := range TEST_RELATIONS {
+		TRANSITIONS_ENUM.Add(string("RA-" + transition))
+	}
+	iSW, _ := TRANSITIONS_ENUM.Add("SW")
+	SW = ConstTransition(iSW)
+}
+
+func newTestArcSwap() *ArcSwap {
+	arcSwap := &ArcSwap{
+		ArcStandard: ArcStandard{
+			SHIFT:       SH.Value(),
+			LEFT:        LA.Value(),
+			RIGHT:       RA.Value(),
+			Relations:   TEST_ENUM_RELATIONS,
+			Transitions: TRANSITIONS_ENUM,
+		},
+		SWAP: SW.Value(),
+	}
+	arcSwap.AddDefaultOracle()
+	return arcSwap
+}
+
+// getNonProjTestDepGraph returns the test graph with Economic attached to
+// effect, crossing the arc of news to its head had
+func getNonProjTestDepGraph() nlp.LabeledDependencyGraph {
+	graph := GetTestDepGraph()
+	graph.(*BasicDepGraph).Arcs[0].Head = 4
+	return graph
+}
+
+func TestArcSwapTransitions(t *testing.T) {
+	SetupSwapEnum()
+	arcSwap := newTestArcSwap()
+	c := Configuration(newTestHybridConf())
+
+	// SW is possible only with two stack elements in their original order
+	c = arcSwap.Transition(c, SH)
+	c = arcSwap.Transition(c, SH)
+	_, possible := arcSwap.GetTransitions(c)
+	swapPossible := false
+	for _, p := range possible {
+		swapPossible = swapPossible || p == SW.Value()
+	}
+	if !swapPossible {
+		t.Error("Expected possible SW with two stack elements in order, got", possible)
+	}
+
+	// SW moves the second stack element back to the buffer
+	swConf := arcSwap.Transition(c, SW).(*SimpleConfiguration)
+	if sPeek, sPeekExists := swConf.Stack().Peek(); !sPeekExists || sPeek != 1 || swConf.Stack().Size() != 1 {
+		t.Error("Expected S0 = 1 as the only stack element after SW, got", sPeek)
+	}
+	if qPeek, qPeekExists := swConf.Queue().Peek(); !qPeekExists || qPeek != 0 {
+		t.Error("Expected N0 = 0 after SW, got", qPeek)
+	}
+
+	// swapped elements are not swapped back
+	c = arcSwap.Transition(swConf, SH)
+	_, possible = arcSwap.GetTransitions(c)
+	for _, p := range possible {
+		if p == SW.Value() {
+			t.Error("Got possible SW of swapped stack elements")
+		}
+	}
+
+	// LA attaches the second stack element to the first
+	transition, exists := TRANSITIONS_ENUM.IndexOf("LA-ATT")
+	if !exists {
+		t.Fatal("Can't find transition LA-ATT")
+	}
+	laConf := arcSwap.Transition(c, ConstTransition(transition)).(*SimpleConfiguration)
+	if sPeek, sPeekExists := laConf.Stack().Peek(); !sPeekExists || sPeek != 0 || laConf.Stack().Size() != 1 {
+		t.Error("Expected S0 = 0 as the only stack element after LA, got", sPeek)
+	}
+	label, _ := TEST_ENUM_RELATIONS.IndexOf(nlp.DepRel("ATT"))
+	if arcs := laConf.Arcs().Get(&BasicDepArc{0, label, 1, nlp.DepRel("ATT")}); len(arcs) != 1 {
+		t.Error("Left arc not found, arcs: ", laConf.StringArcs())
+	}
+}
+
+func TestArcSwapOracle(t *testing.T) {
+	SetupSwapEnum()
+	arcSwap := newTestArcSwap()
+	tests := []struct {
+		name       string
+		goldGraph  nlp.LabeledDependencyGraph
+		expectSwap bool
+	}{
+		{"projective", GetTestDepGraph(), false},
+		{"non-projective", getNonProjTestDepGraph(), true},
+	}
+	for _, test := range tests {
+		conf := Configuration(newTestHybridConf())
+		oracle := arcSwap.Oracle()
+		oracle.SetGold(test.goldGraph)
+		swapped := false
+		// each element is shifted at most once more for each swap
+		maxTransitions := 2 * test.goldGraph.NumberOfNodes() * test.goldGraph.NumberOfNodes()
+		for i := 0; !conf.Terminal(); i++ {
+			if i == maxTransitions {
+				t.Fatal(test.name, ": oracle did not reach a terminal configuration in", maxTransitions, "transitions")
+			}
+			transition := oracle.Transition(conf)
+			_, possible := arcSwap.GetTransitions(conf)
+			legal := false
+			for _, p := range possible {
+				legal = legal || p == transition.Value()
+			}
+			if !legal {
+				t.Fatal(test.name, ": oracle returned illegal transition", TRANSITIONS_ENUM.ValueOf(transition.Value()), "at", i)
+			}
+			swapped = swapped || transition.Value() == SW.Value()
+			conf = arcSwap.Transition(conf, transition)
+		}
+		if swapped != test.expectSwap {
+			t.Error(test.name, ": got swap", swapped, "expected", test.expectSwap)
+		}
+
+		arcs := conf.(*SimpleConfiguration).Arcs()
+		for i := 0; i < test.goldGraph.NumberOfNodes(); i++ {
+			goldArc := test.goldGraph.GetLabeledArc(i)
+			if goldArc.GetHead() < 0 {
+				continue
+			}
+			if len(arcs.Get(goldArc)) != 1 {
+				t.Error(test.name, ": Oracle/Gold parsing is missing arc", goldArc)
+			}
+		}
+		if arcs.Size() != test.goldGraph.NumberOfNodes()-1 {
+			t.Error(test.name, ": Oracle/Gold parsing resulted in", arcs.Size(), "arcs, expected", test.goldGraph.NumberOfNodes()-1)
+		}
+	}
+}
+
+func TestArcSwapEsotericFunctions(t *testing.T) {
+	arcSwap := new(ArcSwap)
+	transitions := arcSwap.TransitionTypes()
+	if !reflect.DeepEqual(transitions, []string{"LA-*", "RA-*", "SH", "SW"}) {
+		t.Error("Wrong transition types")
+	}
+	if arcSwap.Projective() || !arcSwap.Labeled() {
+		t.Error("Arc swap should be non-projective and labeled")
+	}
+}
@@ -1,7 +1,9 @@
 package transition
 
 import (
+	. "yap/alg"
 	. "yap/nlp/types"
+
 	"testing"
 )
 
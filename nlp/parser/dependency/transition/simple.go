@@ -4,6 +4,7 @@ import (
 	. "yap/alg"
 	"yap/alg/graph"
 	. "yap/alg/transition"
+	"yap/nlp/format/clusters"
 	nlp "yap/nlp/types"
 	"yap/util"
 
@@ -24,6 +25,8 @@ type SimpleConfiguration struct {
 	lastOpAssignment uint16
 	// Pointers                                           int
 	EWord, EPOS, EWPOS, EMHost, EMSuffix, ERel, ETrans *util.EnumSet
+	// clusters of the cluster attributes, nil if the model has none
+	Clusters *clusters.Set
 	// test zpar parity
 	NumHeadStack  int
 	TerminalQueue int
@@ -155,6 +158,7 @@ func (c *SimpleConfiguration) CopyTo(target Configuration) {
 	newConf.InternalPrevious = c
 
 	newConf.EWord, newConf.EPOS, newConf.EWPOS, newConf.ERel, newConf.ETrans, newConf.EMHost, newConf.EMSuffix = c.EWord, c.EPOS, c.EWPOS, c.ERel, c.ETrans, c.EMHost, c.EMSuffix
+	newConf.Clusters = c.Clusters
 }
 
 func (c *SimpleConfiguration) AddArc(arc *BasicDepArc) {
@@ -184,6 +184,9 @@ func (c *SimpleConfiguration) Attribute(source byte, nodeID int, attribute []byt
 		node := c.GetRawNode(nodeID)
 		att = node.MSuffix
 		return
+	case 'c', 'k':
+		att, exists = c.Clusters.Attribute(c.GetRawNode(nodeID).RawToken, attribute)
+		return
 	}
 	return 0, false, false
 }
@@ -0,0 +1,422 @@
+// Package validate checks that parsed dependency graphs are well formed
+// trees (a single root, every node headed, no cycles and known labels)
+// and repairs the graphs that are not, before they are written out.
+package validate
+
+import (
+	"yap/nlp/parser/dependency/pseudoproj"
+	"yap/nlp/parser/dependency/transition"
+	nlp "yap/nlp/types"
+	"yap/util"
+
+	"fmt"
+	"strings"
+)
+
+// Violation is a set of well-formedness violations of a graph
+type Violation int
+
+const (
+	// MULTIPLE_ROOTS more than one node is attached to the root
+	MULTIPLE_ROOTS Violation = 1 << iota
+	// NO_ROOT no node is attached to the root
+	NO_ROOT
+	// UNATTACHED a node has no head, in addition to the root
+	UNATTACHED
+	// CYCLE some nodes are headed by each other
+	CYCLE
+	// UNKNOWN_LABEL an arc is labeled with a label not in the label set
+	UNKNOWN_LABEL
+)
+
+var violationNames = []struct {
+	v    Violation
+	name string
+}{
+	{MULTIPLE_ROOTS, "multiple roots"},
+	{NO_ROOT, "no root"},
+	{UNATTACHED, "unattached"},
+	{CYCLE, "cycle"},
+	{UNKNOWN_LABEL, "unknown label"},
+}
+
+// Violations lists all violation kinds
+func Violations() []Violation {
+	result := make([]Violation, len(violationNames))
+	for i, named := range violationNames {
+		result[i] = named.v
+	}
+	return result
+}
+
+func (v Violation) Has(other Violation) bool {
+	return v&other != 0
+}
+
+func (v Violation) String() string {
+	if v == 0 {
+		return "valid"
+	}
+	var names []string
+	for _, named := range violationNames {
+		if v.Has(named.v) {
+			names = append(names, named.name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+type Strategy int
+
+const (
+	// NONE only validates, graphs are not repaired
+	NONE Strategy = iota
+	// ROOT keeps the first root and attaches the other roots, unattached
+	// nodes and cycles to it
+	ROOT
+	// SCORE keeps the highest scored root, by the scores of the parser, and
+	// attaches the other roots, unattached nodes and cycles to it
+	SCORE
+)
+
+var strategyNames = map[string]Strategy{
+	"none":  NONE,
+	"root":  ROOT,
+	"score": SCORE,
+}
+
+func ParseStrategy(name string) (Strategy, error) {
+	strategy, exists := strategyNames[name]
+	if !exists {
+		return NONE, fmt.Errorf("Unknown repair strategy %s (expected none, root or score)", name)
+	}
+	return strategy, nil
+}
+
+func (s Strategy) String() string {
+	for name, strategy := range strategyNames {
+		if strategy == s {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// DEFAULT_LABEL is the label of repaired arcs, and replaces unknown labels
+const DEFAULT_LABEL nlp.DepRel = "dep"
+
+// RootScorer scores the candidate roots of a parsed graph for the SCORE
+// strategy, by the score the parser gave to attaching them to the root
+type RootScorer interface {
+	// RootScore returns the score of a node as the root, false if the
+	// parser did not score it
+	RootScore(node int) (float64, bool)
+}
+
+// RootScores are the scores of the nodes attached to the root of a graph
+type RootScores map[int]float64
+
+func (r RootScores) RootScore(node int) (float64, bool) {
+	score, exists := r[node]
+	return score, exists
+}
+
+// Validator validates and repairs parsed graphs
+type Validator struct {
+	// Relations is the label set, labels are not checked if nil
+	Relations *util.EnumSet
+	Strategy  Strategy
+	// Scores returns the root scores of a graph for the SCORE strategy;
+	// if nil, graphs that implement RootScorer are scored by themselves
+	Scores func(g nlp.LabeledDependencyGraph) RootScorer
+	// Label of repaired arcs, DEFAULT_LABEL if empty
+	Label nlp.DepRel
+}
+
+const (
+	// heads of nodes in the analysis of a graph
+	rootHead       = -1
+	unattachedHead = -2
+)
+
+// analysis of the arcs of a graph
+type analysis struct {
+	heads      []int
+	labels     []nlp.DepRel
+	roots      []int
+	unattached []int
+	cycles     [][]int
+	unknown    []int
+}
+
+func (v *Validator) analyze(g nlp.LabeledDependencyGraph) *analysis {
+	n := g.NumberOfNodes()
+	a := &analysis{heads: make([]int, n), labels: make([]nlp.DepRel, n)}
+	for i := 0; i < n; i++ {
+		a.heads[i] = unattachedHead
+		arc := g.GetLabeledArc(i)
+		if arc == nil || arc.GetModifier() != i {
+			a.unattached = append(a.unattached, i)
+			continue
+		}
+		a.labels[i] = arc.GetRelation()
+		head := arc.GetHead()
+		switch {
+		case head < 0 || string(a.labels[i]) == nlp.ROOT_LABEL:
+			a.heads[i] = rootHead
+			a.roots = append(a.roots, i)
+		case head >= n || head == i:
+			a.unattached = append(a.unattached, i)
+		default:
+			a.heads[i] = head
+		}
+		if a.heads[i] >= 0 && v.Relations != nil {
+			if _, exists := v.Relations.IndexOf(a.labels[i]); !exists {
+				a.unknown = append(a.unknown, i)
+			}
+		}
+	}
+	a.cycles = cycles(a.heads)
+	return a
+}
+
+func (a *analysis) violation() Violation {
+	var v Violation
+	if len(a.heads) == 0 {
+		return v
+	}
+	switch {
+	case len(a.roots) > 1:
+		v |= MULTIPLE_ROOTS
+	case len(a.roots)+len(a.unattached) == 0:
+		v |= NO_ROOT
+	}
+	// a single unattached node is the root of graphs without root arcs
+	if len(a.unattached) > 1 || (len(a.unattached) > 0 && len(a.roots) > 0) {
+		v |= UNATTACHED
+	}
+	if len(a.cycles) > 0 {
+		v |= CYCLE
+	}
+	if len(a.unknown) > 0 {
+		v |= UNKNOWN_LABEL
+	}
+	return v
+}
+
+// cycles returns the cycles of heads, each starting at its leftmost node
+func cycles(heads []int) [][]int {
+	var (
+		result [][]int
+		// 0 unvisited, 1 on the current path, 2 done
+		state = make([]int, len(heads))
+	)
+	for start := range heads {
+		var path []int
+		n := start
+		for n >= 0 && state[n] == 0 {
+			state[n] = 1
+			path = append(path, n)
+			n = heads[n]
+		}
+		if n >= 0 && state[n] == 1 {
+			var cycle []int
+			for i, node := range path {
+				if node == n {
+					cycle = path[i:]
+					break
+				}
+			}
+			first := 0
+			for i, node := range cycle {
+				if node < cycle[first] {
+					first = i
+				}
+			}
+			result = append(result, append(append([]int{}, cycle[first:]...), cycle[:first]...))
+		}
+		for _, node := range path {
+			state[node] = 2
+		}
+	}
+	return result
+}
+
+// dominates returns true if h is an ancestor of (or equal to) n
+func dominates(heads []int, h, n int) bool {
+	for steps := 0; n >= 0 && steps <= len(heads); steps++ {
+		if n == h {
+			return true
+		}
+		n = heads[n]
+	}
+	return false
+}
+
+// Validate returns the violations of a graph, 0 if it is a well formed tree
+func (v *Validator) Validate(g nlp.LabeledDependencyGraph) Violation {
+	return v.analyze(g).violation()
+}
+
+// Repair returns the violations of a graph and, if the graph has
+// violations and the strategy is not NONE, a repaired graph that replaces
+// its arcs (a pseudoproj.Graph, or MorphGraph for morphological graphs).
+// Otherwise the graph is returned as is.
+func (v *Validator) Repair(g nlp.LabeledDependencyGraph) (nlp.LabeledDependencyGraph, Violation) {
+	a := v.analyze(g)
+	violation := a.violation()
+	if violation == 0 || v.Strategy == NONE {
+		return g, violation
+	}
+	label := v.Label
+	if label == "" {
+		label = DEFAULT_LABEL
+	}
+	var onCycle []int
+	for _, cycle := range a.cycles {
+		onCycle = append(onCycle, cycle...)
+	}
+	candidates := append(append([]int{}, a.roots...), a.unattached...)
+	if len(candidates) == 0 {
+		candidates = onCycle
+	}
+	root := candidates[0]
+	if v.Strategy == SCORE {
+		root = v.bestRoot(g, candidates)
+	}
+
+	heads, labels := append([]int{}, a.heads...), append([]nlp.DepRel{}, a.labels...)
+	attach := func(n int) {
+		heads[n] = root
+		if labels[n] == "" || string(labels[n]) == nlp.ROOT_LABEL {
+			labels[n] = label
+		}
+	}
+	for _, n := range a.unknown {
+		labels[n] = label
+	}
+	for _, n := range candidates {
+		if n != root {
+			attach(n)
+		}
+	}
+	if heads[root] >= 0 {
+		// the root was chosen from a cycle
+		heads[root], labels[root] = rootHead, nlp.ROOT_LABEL
+	}
+	for _, cycle := range a.cycles {
+		if !dominates(heads, root, cycle[0]) {
+			attach(cycle[0])
+		}
+	}
+
+	arcs := make([]*transition.BasicDepArc, len(heads))
+	for i, head := range heads {
+		if head == unattachedHead {
+			// the root of graphs without root arcs
+			continue
+		}
+		arcs[i] = &transition.BasicDepArc{Head: head, Relation: -1, Modifier: i, RawRelation: labels[i]}
+	}
+	result := &pseudoproj.Graph{g, arcs}
+	if v.Relations != nil {
+		result.SetRelations(v.Relations)
+	}
+	if morphGraph, ok := g.(nlp.MorphDependencyGraph); ok {
+		return &pseudoproj.MorphGraph{result, morphGraph}, violation
+	}
+	return result, violation
+}
+
+// bestRoot returns the highest scored of the candidate roots of a graph;
+// candidates the parser did not score are only kept if none was scored
+func (v *Validator) bestRoot(g nlp.LabeledDependencyGraph, candidates []int) int {
+	var scorer RootScorer
+	if v.Scores != nil {
+		scorer = v.Scores(g)
+	} else if graphScorer, ok := g.(RootScorer); ok {
+		scorer = graphScorer
+	}
+	root := candidates[0]
+	if scorer == nil {
+		return root
+	}
+	var (
+		best   float64
+		scored bool
+	)
+	for _, candidate := range candidates {
+		if score, exists := scorer.RootScore(candidate); exists && (!scored || score > best) {
+			root, best, scored = candidate, score, true
+		}
+	}
+	return root
+}
+
+// Report counts the invalid and repaired sentences of a run
+type Report struct {
+	Sentences, Invalid, Repaired int
+	Violations                   map[Violation]int
+}
+
+func NewReport() *Report {
+	return &Report{Violations: make(map[Violation]int)}
+}
+
+func (r *Report) Add(violation Violation, repaired bool) {
+	r.Sentences++
+	if violation == 0 {
+		return
+	}
+	r.Invalid++
+	if repaired {
+		r.Repaired++
+	}
+	for _, v := range Violations() {
+		if violation.Has(v) {
+			r.Violations[v]++
+		}
+	}
+}
+
+func (r *Report) String() string {
+	str := fmt.Sprintf("%d of %d sentences invalid, repaired %d", r.Invalid, r.Sentences, r.Repaired)
+	var counts []string
+	for _, v := range Violations() {
+		if r.Violations[v] > 0 {
+			counts = append(counts, fmt.Sprintf("%s: %d", v, r.Violations[v]))
+		}
+	}
+	if len(counts) > 0 {
+		str += " (" + strings.Join(counts, ", ") + ")"
+	}
+	return str
+}
+
+// Corpus validates and repairs a slice of graphs
+func (v *Validator) Corpus(corpus []interface{}) ([]interface{}, *Report) {
+	report := NewReport()
+	result := make([]interface{}, len(corpus))
+	for i, instance := range corpus {
+		var violation Violation
+		result[i], violation = v.Repair(instance.(nlp.LabeledDependencyGraph))
+		report.Add(violation, v.Strategy != NONE)
+	}
+	return result, report
+}
+
+// Stream validates and repairs a stream of graphs; the report is complete
+// once the returned stream is closed
+func (v *Validator) Stream(corpus chan interface{}) (chan interface{}, *Report) {
+	report := NewReport()
+	result := make(chan interface{}, 2)
+	go func() {
+		for instance := range corpus {
+			repaired, violation := v.Repair(instance.(nlp.LabeledDependencyGraph))
+			report.Add(violation, v.Strategy != NONE)
+			result <- repaired
+		}
+		close(result)
+	}()
+	return result, report
+}
@@ -0,0 +1,156 @@
+package validate
+
+import (
+	"yap/nlp/parser/dependency/transition"
+	nlp "yap/nlp/types"
+	"yap/util"
+
+	"reflect"
+	"testing"
+)
+
+// NO_HEAD marks nodes without an arc in test graphs
+const NO_HEAD = -2
+
+var testRelations = []string{"det", "subj", nlp.ROOT_LABEL, "xcomp", "prep", "pobj", "tmod", string(DEFAULT_LABEL)}
+
+func testGraph(heads []int, labels []string) nlp.LabeledDependencyGraph {
+	nodes := make([]nlp.DepNode, len(heads))
+	arcs := make([]*transition.BasicDepArc, len(heads))
+	for i, head := range heads {
+		nodes[i] = &transition.TaggedDepNode{Id: i}
+		if head == NO_HEAD {
+			continue
+		}
+		arcs[i] = &transition.BasicDepArc{Head: head, Relation: -1, Modifier: i, RawRelation: nlp.DepRel(labels[i])}
+	}
+	return &transition.BasicDepGraph{nodes, arcs}
+}
+
+func graphArcs(g nlp.LabeledDependencyGraph) ([]int, []string) {
+	heads := make([]int, g.NumberOfNodes())
+	labels := make([]string, g.NumberOfNodes())
+	for i := range heads {
+		arc := g.GetLabeledArc(i)
+		if arc == nil {
+			heads[i] = NO_HEAD
+			continue
+		}
+		heads[i], labels[i] = arc.GetHead(), string(arc.GetRelation())
+	}
+	return heads, labels
+}
+
+func TestRepair(t *testing.T) {
+	eRel := util.NewEnumSet(len(testRelations))
+	for _, rel := range testRelations {
+		eRel.Add(nlp.DepRel(rel))
+	}
+	// A hearing is scheduled on the issue today
+	tests := []struct {
+		name           string
+		heads          []int
+		labels         []string
+		strategy       Strategy
+		scores         RootScores
+		violation      Violation
+		repairedHeads  []int
+		repairedLabels []string
+	}{
+		{"valid", []int{1, 2, -1, 2, 1, 6, 4, 3},
+			[]string{"det", "subj", "ROOT", "xcomp", "prep", "det", "pobj", "tmod"},
+			ROOT, nil, 0, nil, nil},
+		{"implicit root", []int{1, 2, NO_HEAD, 2, 2, 6, 4, 3},
+			[]string{"det", "subj", "", "xcomp", "prep", "det", "pobj", "tmod"},
+			ROOT, nil, 0, nil, nil},
+		{"multiple roots", []int{1, -1, -1, 2, 1, 6, 4, 3},
+			[]string{"det", "ROOT", "ROOT", "xcomp", "prep", "det", "pobj", "tmod"},
+			ROOT, nil, MULTIPLE_ROOTS,
+			[]int{1, -1, 1, 2, 1, 6, 4, 3},
+			[]string{"det", "ROOT", "dep", "xcomp", "prep", "det", "pobj", "tmod"}},
+		{"multiple roots by score", []int{1, -1, -1, 2, 2, 6, 4, 3},
+			[]string{"det", "ROOT", "ROOT", "xcomp", "prep", "det", "pobj", "tmod"},
+			SCORE, RootScores{1: 2.5, 2: 7}, MULTIPLE_ROOTS,
+			[]int{1, 2, -1, 2, 2, 6, 4, 3},
+			[]string{"det", "dep", "ROOT", "xcomp", "prep", "det", "pobj", "tmod"}},
+		{"multiple roots by score, first scored", []int{1, -1, -1, 2, 2, 6, 4, 3},
+			[]string{"det", "ROOT", "ROOT", "xcomp", "prep", "det", "pobj", "tmod"},
+			SCORE, RootScores{1: 7, 2: 2.5}, MULTIPLE_ROOTS,
+			[]int{1, -1, 1, 2, 2, 6, 4, 3},
+			[]string{"det", "ROOT", "dep", "xcomp", "prep", "det", "pobj", "tmod"}},
+		{"multiple roots without scores", []int{1, -1, -1, 2, 2, 6, 4, 3},
+			[]string{"det", "ROOT", "ROOT", "xcomp", "prep", "det", "pobj", "tmod"},
+			SCORE, RootScores{}, MULTIPLE_ROOTS,
+			[]int{1, -1, 1, 2, 2, 6, 4, 3},
+			[]string{"det", "ROOT", "dep", "xcomp", "prep", "det", "pobj", "tmod"}},
+		{"unattached", []int{1, 2, -1, 2, 1, NO_HEAD, 4, NO_HEAD},
+			[]string{"det", "subj", "ROOT", "xcomp", "prep", "", "pobj", ""},
+			ROOT, nil, UNATTACHED,
+			[]int{1, 2, -1, 2, 1, 2, 4, 2},
+			[]string{"det", "subj", "ROOT", "xcomp", "prep", "dep", "pobj", "dep"}},
+		{"unattached without root arc", []int{1, NO_HEAD, NO_HEAD, 2, 2, 6, 4, 3},
+			[]string{"det", "", "", "xcomp", "prep", "det", "pobj", "tmod"},
+			ROOT, nil, UNATTACHED,
+			[]int{1, NO_HEAD, 1, 2, 2, 6, 4, 3},
+			[]string{"det", "", "dep", "xcomp", "prep", "det", "pobj", "tmod"}},
+		{"cycle", []int{1, 2, -1, 6, 3, 6, 4, 3},
+			[]string{"det", "subj", "ROOT", "xcomp", "prep", "det", "pobj", "tmod"},
+			ROOT, nil, CYCLE,
+			[]int{1, 2, -1, 2, 3, 6, 4, 3},
+			[]string{"det", "subj", "ROOT", "xcomp", "prep", "det", "pobj", "tmod"}},
+		{"no root", []int{1, 2, 1, 2, 1, 6, 4, 3},
+			[]string{"det", "subj", "xcomp", "xcomp", "prep", "det", "pobj", "tmod"},
+			ROOT, nil, NO_ROOT | CYCLE,
+			[]int{1, -1, 1, 2, 1, 6, 4, 3},
+			[]string{"det", "ROOT", "xcomp", "xcomp", "prep", "det", "pobj", "tmod"}},
+		{"unknown label", []int{1, 2, -1, 2, 1, 6, 4, 3},
+			[]string{"det", "nsubj", "ROOT", "xcomp", "prep", "det", "pobj", "tmod"},
+			ROOT, nil, UNKNOWN_LABEL,
+			[]int{1, 2, -1, 2, 1, 6, 4, 3},
+			[]string{"det", "dep", "ROOT", "xcomp", "prep", "det", "pobj", "tmod"}},
+		{"validate only", []int{1, -1, -1, 2, 1, 6, 4, 3},
+			[]string{"det", "ROOT", "ROOT", "xcomp", "prep", "det", "pobj", "tmod"},
+			NONE, nil, MULTIPLE_ROOTS, nil, nil},
+	}
+	for _, test := range tests {
+		v := &Validator{Relations: eRel, Strategy: test.strategy}
+		if test.scores != nil {
+			scores := test.scores
+			v.Scores = func(g nlp.LabeledDependencyGraph) RootScorer { return scores }
+		}
+		g := testGraph(test.heads, test.labels)
+		if violation := v.Validate(g); violation != test.violation {
+			t.Errorf("%s: got violations %v, expected %v", test.name, violation, test.violation)
+		}
+		repaired, violation := v.Repair(g)
+		if violation != test.violation {
+			t.Errorf("%s: repair got violations %v, expected %v", test.name, violation, test.violation)
+		}
+		if test.repairedHeads == nil {
+			if repaired != g {
+				t.Errorf("%s: expected the graph to be returned as is", test.name)
+			}
+			continue
+		}
+		heads, labels := graphArcs(repaired)
+		if !reflect.DeepEqual(heads, test.repairedHeads) || !reflect.DeepEqual(labels, test.repairedLabels) {
+			t.Errorf("%s: got %v %v, expected %v %v", test.name, heads, labels, test.repairedHeads, test.repairedLabels)
+		}
+		if after := v.Validate(repaired); after != 0 {
+			t.Errorf("%s: repaired graph has violations %v", test.name, after)
+		}
+	}
+}
+
+func TestCorpus(t *testing.T) {
+	v := &Validator{Strategy: ROOT}
+	corpus := []interface{}{
+		testGraph([]int{1, -1}, []string{"det", "ROOT"}),
+		testGraph([]int{-1, -1}, []string{"ROOT", "ROOT"}),
+		testGraph([]int{1, 0}, []string{"det", "det"}),
+	}
+	_, report := v.Corpus(corpus)
+	if report.Sentences != 3 || report.Invalid != 2 || report.Repaired != 2 || report.Violations[CYCLE] != 1 {
+		t.Errorf("Got wrong report %v", report)
+	}
+}
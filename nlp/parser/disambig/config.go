@@ -5,6 +5,7 @@ import (
 	. "yap/alg"
 	"yap/alg/featurevector"
 	. "yap/alg/transition"
+	"yap/nlp/format/clusters"
 	nlp "yap/nlp/types"
 	"yap/util"
 
@@ -34,7 +35,9 @@ type MDConfig struct {
 	InternalPrevious Configuration
 	Last             Transition
 	ETokens          *util.EnumSet
-	Log              bool
+	// clusters of the cluster attributes, nil if the model has none
+	Clusters *clusters.Set
+	Log      bool
 
 	POP         Transition
 	Transitions *util.EnumSet
@@ -96,6 +99,7 @@ func (c *MDConfig) CopyTo(target Configuration) {
 		panic("Can't copy into non *MDConfig")
 	}
 	newConf.ETokens = c.ETokens
+	newConf.Clusters = c.Clusters
 	newConf.Mappings = make([]*nlp.Mapping, len(c.Mappings), util.Max(cap(c.Mappings), len(c.Lattices)))
 	copy(newConf.Mappings, c.Mappings)
 
@@ -482,6 +486,9 @@ func (c *MDConfig) Attribute(source byte, nodeID int, attribute []byte, transiti
 		case 'f':
 			att = morpheme.EFeatures
 			return
+		case 'c', 'k':
+			att, exists = c.Clusters.Attribute(morpheme.Form, attribute)
+			return
 		case 't':
 			lat := c.Lattices[morpheme.TokenID]
 			// tokId, _ := c.ETokens.Add(lat.Token)
@@ -15,6 +15,7 @@ import (
 	"yap/alg/graph"
 	"yap/nlp/format/conllu"
 	"yap/nlp/format/lattice"
+	"yap/nlp/format/lex"
 	"yap/nlp/format/raw"
 	. "yap/nlp/types"
 	"yap/util"
@@ -23,6 +24,8 @@ import (
 const (
 	MSR_SEPARATOR = "|"
 	PUNCTUATION   = ",.|?!:;-&»«\"[]()<>"
+	// MA type of compiled UD lexicons
+	COMPILED_UD_LEX = "udlex"
 )
 
 type TrainingFile struct {
@@ -42,6 +45,8 @@ type MADict struct {
 	TopPOS                   []string
 	OOVMSRs                  []string
 	POSMSRs                  map[string]MSRFreq
+	// analyzes OOV tokens instead of the OOV MSRs if learned
+	OOVGuesser *OOVGuesser
 
 	// data
 	Files []TrainingFile
@@ -89,6 +94,9 @@ func (m *MADict) LearnFromConllU(conlluFile string, limit int) (int, error) {
 			// log.Println("\tAt token", curToken)
 			m.AddAnalyses(string(mapping.Token), standalone)
 			m.AddMSRs(standalone)
+			if m.OOVGuesser != nil {
+				m.OOVGuesser.Observe(string(mapping.Token), standalone)
+			}
 		}
 	}
 	if m.Files == nil {
@@ -107,6 +115,7 @@ func (m *MADict) LearnFromConllU(conlluFile string, limit int) (int, error) {
 
 	m.ComputeTopPOS()
 	m.ComputeOOVMSRs(m.MaxMSRsPerPOS)
+	m.trainOOVGuesser()
 
 	return tokensRead, nil
 }
@@ -117,7 +126,10 @@ func (m *MADict) Init() {
 		m.TopPOSSet[pos] = true
 	}
 }
-func (m *MADict) LearnFromLat(latticeFile, rawFile string, limit int) (int, error) {
+
+// LearnFromLat learns the analyses of the tokens of a raw file, normalized
+// by normalizer if given, from the lattices of a lattice file
+func (m *MADict) LearnFromLat(latticeFile, rawFile string, limit int, normalizer *raw.Normalizer) (int, error) {
 	latmd5, err := util.MD5File(latticeFile)
 	if err != nil {
 		return 0, err
@@ -131,7 +143,7 @@ func (m *MADict) LearnFromLat(latticeFile, rawFile string, limit int) (int, erro
 		log.Println("Error reading lattice file")
 		return 0, err
 	}
-	tokens, err := raw.ReadFile(rawFile, limit)
+	tokens, err := raw.ReadFileNormalized(rawFile, limit, normalizer)
 	if err != nil {
 		log.Println("Error reading raw file")
 		return 0, err
@@ -163,6 +175,9 @@ func (m *MADict) LearnFromLat(latticeFile, rawFile string, limit int) (int, erro
 			// log.Println("\tAt token", curToken)
 			m.AddAnalyses(string(curToken), lat.Morphemes.Standalone())
 			m.AddMSRs(lat.Morphemes.Standalone())
+			if m.OOVGuesser != nil {
+				m.OOVGuesser.Observe(string(curToken), lat.Morphemes.Standalone())
+			}
 		}
 	}
 	if m.Files == nil {
@@ -181,6 +196,7 @@ func (m *MADict) LearnFromLat(latticeFile, rawFile string, limit int) (int, erro
 
 	m.ComputeTopPOS()
 	m.ComputeOOVMSRs(m.MaxMSRsPerPOS)
+	m.trainOOVGuesser()
 
 	return tokensRead, nil
 }
@@ -279,6 +295,14 @@ func (m *MADict) ComputeOOVMSRs(maxMSRs int) {
 	}
 }
 
+func (m *MADict) trainOOVGuesser() {
+	if m.OOVGuesser == nil {
+		return
+	}
+	learned := m.OOVGuesser.Train()
+	log.Println("Learned OOV guesser from", learned, "rare tokens:", m.OOVGuesser)
+}
+
 // MSR: Morpho-Syntactic Representation
 func (m *MADict) AddMSRs(morphs BasicMorphemes) {
 	for _, morph := range morphs {
@@ -424,22 +448,41 @@ func (m *MADict) oldApplyOOV(token string, lat *Lattice, curID *int, curNode, i
 
 func (m *MADict) ApplyOOV(token string, lat *Lattice, curID *int, curNode, i int) {
 	// add morphemes for Out-Of-Vocabulary
-	lat.Morphemes = make([]*EMorpheme, 0, len(m.OOVMSRs))
-	for _, msr := range m.OOVMSRs {
+	analyses := m.oovAnalyses(token)
+	lat.Morphemes = make([]*EMorpheme, 0, len(analyses))
+	for _, analysis := range analyses {
+		lat.AddAnalysis(nil, []BasicMorphemes{analysis}, i+1)
+		*curID++
+	}
+}
+
+// oovAnalyses returns the analyses of an OOV token, guessed by the OOV
+// guesser if learned, otherwise one for each OOV MSR
+func (m *MADict) oovAnalyses(token string) []BasicMorphemes {
+	if m.OOVGuesser != nil {
+		if guesses := m.OOVGuesser.Guess(token); len(guesses) > 0 {
+			analyses := make([]BasicMorphemes, len(guesses))
+			for i, guess := range guesses {
+				analyses[i] = append(append(BasicMorphemes{}, guess.Prefix...), guess.Host...)
+			}
+			return analyses
+		}
+	}
+	analyses := make([]BasicMorphemes, len(m.OOVMSRs))
+	for i, msr := range m.OOVMSRs {
 		split := strings.Split(msr, MSR_SEPARATOR)
-		lat.AddAnalysis(nil, []BasicMorphemes{BasicMorphemes{&Morpheme{
-			graph.BasicDirectedEdge{*curID, curNode, curNode + 1},
+		analyses[i] = BasicMorphemes{&Morpheme{
+			graph.BasicDirectedEdge{0, 0, 1},
 			token,
 			"_",
 			split[0],
 			split[1],
 			nil,
-			i,
+			0,
 			strings.Join(split[2:], MSR_SEPARATOR),
-		},
-		}}, i+1)
-		*curID++
+		}}
 	}
+	return analyses
 }
 
 func (m *MADict) ReadUDLex(reader io.Reader) error {
@@ -533,7 +576,20 @@ func (m *MADict) ReadUDLex(reader io.Reader) error {
 	return nil
 }
 
+// ReadUDLexFile reads a UD lexicon file, or a compiled UD lexicon file
+// (see CompileUDLex)
 func (m *MADict) ReadUDLexFile(filename string) error {
+	if lex.IsCompiledFile(filename) {
+		compiled, err := lex.ReadCompiledFile(filename)
+		if err != nil {
+			return err
+		}
+		if err := compiled.Verify(COMPILED_UD_LEX); err != nil {
+			return err
+		}
+		_, m.Data = compiled.Expand()
+		return nil
+	}
 	file, err := os.Open(filename)
 	defer file.Close()
 
@@ -543,6 +599,13 @@ func (m *MADict) ReadUDLexFile(filename string) error {
 
 	return m.ReadUDLex(file)
 }
+
+// CompileUDLex returns the compiled lexicon of the dictionary, as read
+// from a UD lexicon
+func (m *MADict) CompileUDLex(sources []string) *lex.Compiled {
+	return lex.Compile(nil, m.Data, COMPILED_UD_LEX, sources)
+}
+
 func (m *MADict) Analyze(input []string) (LatticeSentence, interface{}) {
 	retval := make(LatticeSentence, len(input))
 	oovVector := make(BasicSentence, len(input))
@@ -0,0 +1,191 @@
+package ma
+
+import (
+	. "yap/nlp/types"
+
+	"sort"
+	"strings"
+)
+
+// Sources of analyses; analyses of a host following a prefix have the
+// source of the host prefixed by SOURCE_PREFIX
+const (
+	SOURCE_PUNCT      = "punct"
+	SOURCE_LEXICON    = "lexicon"
+	SOURCE_RECOGNIZER = "recognizer:"
+	SOURCE_OOV        = "oov"
+	SOURCE_MWE        = "mwe"
+	SOURCE_PREFIX     = "prefix+"
+)
+
+// Explanation is an analysis of a token with its source
+type Explanation struct {
+	Source    string
+	Morphemes BasicMorphemes
+}
+
+// Segmentation returns the forms of the morphemes of the analysis
+func (e Explanation) Segmentation() string {
+	forms := make([]string, len(e.Morphemes))
+	for i, m := range e.Morphemes {
+		forms[i] = m.Form
+	}
+	return strings.Join(forms, " + ")
+}
+
+// Explainer analyzers give the analyses of a token with their source, and
+// search their dictionary
+type Explainer interface {
+	Explain(input string) []Explanation
+	Dictionary() map[string][]BasicMorphemes
+}
+
+var _ Explainer = &BGULex{}
+var _ Explainer = &MADict{}
+
+// GroupBySegmentation returns the segmentations of explanations in order of
+// first appearance, and the explanations of each segmentation
+func GroupBySegmentation(explanations []Explanation) ([]string, map[string][]Explanation) {
+	var segmentations []string
+	groups := make(map[string][]Explanation)
+	for _, e := range explanations {
+		segmentation := e.Segmentation()
+		if _, exists := groups[segmentation]; !exists {
+			segmentations = append(segmentations, segmentation)
+		}
+		groups[segmentation] = append(groups[segmentation], e)
+	}
+	return segmentations, groups
+}
+
+// Explain returns the analyses of a token as given by AnalyzeToken, with
+// their source; analyzer stats are not updated
+func (l *BGULex) Explain(input string) []Explanation {
+	var explanations []Explanation
+	traced := *l
+	traced.Stats, traced.LogOOV = nil, false
+	traced.trace = func(source string, prefix BasicMorphemes, hosts []BasicMorphemes) {
+		if prefix != nil {
+			source = SOURCE_PREFIX + source
+		}
+		for _, host := range hosts {
+			morphs := make(BasicMorphemes, 0, len(prefix)+len(host))
+			morphs = append(morphs, prefix...)
+			morphs = append(morphs, host...)
+			explanations = append(explanations, Explanation{source, morphs})
+		}
+	}
+	traced.AnalyzeToken(input, 0, 0)
+	return explanations
+}
+
+func (l *BGULex) Dictionary() map[string][]BasicMorphemes {
+	return l.Lex
+}
+
+// Explain returns the analyses of a token as given by Analyze, with their
+// source
+func (m *MADict) Explain(input string) []Explanation {
+	var explanations []Explanation
+	analyses, exists := m.Data[input]
+	hasOOVPOS := false
+	for _, morphs := range analyses {
+		for _, morph := range morphs {
+			hasOOVPOS = hasOOVPOS || m.TopPOSSet[morph.CPOS]
+		}
+	}
+	if !exists || (m.Dope && hasOOVPOS) {
+		for _, morphs := range m.oovAnalyses(input) {
+			explanations = append(explanations, Explanation{SOURCE_OOV, morphs})
+		}
+	}
+	for _, morphs := range analyses {
+		explanations = append(explanations, Explanation{SOURCE_LEXICON, morphs})
+	}
+	return explanations
+}
+
+func (m *MADict) Dictionary() map[string][]BasicMorphemes {
+	return m.Data
+}
+
+// MorphemeQuery matches morphemes by lemma, POS (or CPOS) and features;
+// empty fields match any morpheme
+type MorphemeQuery struct {
+	Lemma, POS string
+	// feature key=value pairs, all of which the morpheme must have
+	Features []string
+}
+
+// ParseMorphemeQuery returns a query of a lemma, POS and | separated
+// features (e.g. gen=M|num=S)
+func ParseMorphemeQuery(lemma, POS, features string) *MorphemeQuery {
+	q := &MorphemeQuery{Lemma: lemma, POS: POS}
+	if len(features) > 0 {
+		q.Features = strings.Split(features, "|")
+	}
+	return q
+}
+
+func (q *MorphemeQuery) Match(m *Morpheme) bool {
+	if len(q.Lemma) > 0 && m.Lemma != q.Lemma {
+		return false
+	}
+	if len(q.POS) > 0 && m.POS != q.POS && m.CPOS != q.POS {
+		return false
+	}
+	if len(q.Features) == 0 {
+		return true
+	}
+	features := make(map[string]bool)
+	for _, feature := range strings.Split(m.FeatureStr, "|") {
+		features[feature] = true
+	}
+	for key, value := range m.Features {
+		features[key+"="+value] = true
+	}
+	for _, feature := range q.Features {
+		if !features[feature] {
+			return false
+		}
+	}
+	return true
+}
+
+// SearchMatch is a token of a dictionary with its analyses that have a
+// morpheme matching a query
+type SearchMatch struct {
+	Token    string
+	Analyses []BasicMorphemes
+}
+
+// Search returns the tokens of a dictionary, sorted, with their analyses
+// that have a morpheme matching a query; at most limit tokens are returned
+// if limit is positive
+func Search(dict map[string][]BasicMorphemes, q *MorphemeQuery, limit int) []SearchMatch {
+	tokens := make([]string, 0, len(dict))
+	for token := range dict {
+		tokens = append(tokens, token)
+	}
+	sort.Strings(tokens)
+	var matches []SearchMatch
+	for _, token := range tokens {
+		var analyses []BasicMorphemes
+		for _, analysis := range dict[token] {
+			for _, m := range analysis {
+				if q.Match(m) {
+					analyses = append(analyses, analysis)
+					break
+				}
+			}
+		}
+		if len(analyses) > 0 {
+			// tokens of multi-token expressions are shown space separated
+			matches = append(matches, SearchMatch{strings.Replace(token, MWE_SEPARATOR, " ", -1), analyses})
+			if limit > 0 && len(matches) == limit {
+				break
+			}
+		}
+	}
+	return matches
+}
@@ -0,0 +1,106 @@
+package ma
+
+import (
+	"strings"
+	"testing"
+
+	"yap/alg/graph"
+	"yap/nlp/format/lex"
+	. "yap/nlp/types"
+)
+
+func TestExplain(t *testing.T) {
+	l := newTestLex(t)
+	l.Recognizers = testRecognizers(t)
+	tests := []struct {
+		input           string
+		expected, other []string // source:segmentation of analyses expected (not expected)
+	}{
+		{"בית", []string{"lexicon:בית"}, []string{"oov:בית"}},
+		{"בבית", []string{"oov:בבית", "prefix+lexicon:ב + בית", "prefix+lexicon:ב + ה + בית"}, []string{"lexicon:בבית"}},
+		{"ב-2020", []string{"prefix+recognizer:number:ב + 2020", "recognizer:digits:ב-2020"}, nil},
+		{"לGoogle", []string{"prefix+recognizer:latin:ל + Google"}, nil},
+		{"ואקמול", []string{"oov:ואקמול", "prefix+oov:ו + אקמול"}, nil},
+		{"—", []string{"punct:—"}, nil},
+	}
+	for _, test := range tests {
+		sources := make(map[string]bool)
+		for _, e := range l.Explain(test.input) {
+			sources[e.Source+":"+e.Segmentation()] = true
+		}
+		for _, expected := range test.expected {
+			if !sources[expected] {
+				t.Errorf("%s: expected %s in %v", test.input, expected, sources)
+			}
+		}
+		for _, other := range test.other {
+			if sources[other] {
+				t.Errorf("%s: unexpected %s in %v", test.input, other, sources)
+			}
+		}
+	}
+}
+
+func TestGroupBySegmentation(t *testing.T) {
+	l := newTestLex(t)
+	segmentations, groups := GroupBySegmentation(l.Explain("בבית"))
+	if len(segmentations) != len(groups) {
+		t.Fatalf("got %d segmentations and %d groups", len(segmentations), len(groups))
+	}
+	if segmentations[0] != "בבית" {
+		t.Errorf("got first segmentation %q, expected the whole token", segmentations[0])
+	}
+	for _, segmentation := range segmentations {
+		for _, e := range groups[segmentation] {
+			if e.Segmentation() != segmentation {
+				t.Errorf("explanation %v in group %q", e, segmentation)
+			}
+		}
+	}
+}
+
+func TestMADictExplain(t *testing.T) {
+	m := &MADict{
+		Data: TokenDictionary{
+			"בית": {{&Morpheme{BasicDirectedEdge: graph.BasicDirectedEdge{0, 0, 1}, Form: "בית", Lemma: "בית", CPOS: "NOUN", POS: "NOUN"}}},
+		},
+		OOVMSRs: []string{strings.Join([]string{"PROPN", "PROPN", "_"}, MSR_SEPARATOR)},
+	}
+	if explanations := m.Explain("בית"); len(explanations) != 1 || explanations[0].Source != SOURCE_LEXICON {
+		t.Errorf("got %v, expected one lexicon analysis", explanations)
+	}
+	if explanations := m.Explain("אקמול"); len(explanations) != 1 || explanations[0].Source != SOURCE_OOV || explanations[0].Morphemes[0].CPOS != "PROPN" {
+		t.Errorf("got %v, expected one PROPN oov analysis", explanations)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	l := newTestLex(t)
+	tokens, err := lex.ReadUser(strings.NewReader("ספר\t_\tNN\tgen=M|num=S\nספרים\tספר\tNN\tgen=M|num=P\nספרה\tספר\tVB\tgen=F|num=S\n"), l.MAType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.AddUserLex(tokens, false)
+	tests := []struct {
+		lemma, POS, features string
+		limit                int
+		expected             []string
+	}{
+		{"ספר", "", "", 0, []string{"ספר", "ספרה", "ספרים"}},
+		{"ספר", "NN", "", 0, []string{"ספר", "ספרים"}},
+		{"", "NN", "num=S", 0, []string{"בית", "ספר"}},
+		{"", "", "gen=F|num=S", 0, []string{"ספרה"}},
+		{"ספר", "", "", 2, []string{"ספר", "ספרה"}},
+		{"", "JJ", "", 0, nil},
+	}
+	for _, test := range tests {
+		matches := Search(l.Dictionary(), ParseMorphemeQuery(test.lemma, test.POS, test.features), test.limit)
+		var found []string
+		for _, match := range matches {
+			found = append(found, match.Token)
+		}
+		if strings.Join(found, ",") != strings.Join(test.expected, ",") {
+			t.Errorf("%v: got %v, expected %v", test, found, test.expected)
+		}
+	}
+}
@@ -8,8 +8,8 @@ import (
 
 	"fmt"
 	"log"
-	"regexp"
 	"strings"
+	"unicode/utf8"
 )
 
 const ESTIMATED_MORPHS_PER_TOKEN = 5
@@ -19,6 +19,9 @@ type BGULex struct {
 	Prefixes     map[string][]BasicMorphemes
 
 	Lex map[string][]BasicMorphemes
+	// most tokens of a multi-token expression of the lexicon (see
+	// MWE_SEPARATOR)
+	MaxMWELen int
 
 	Files []string
 	Stats *AnalyzeStats
@@ -26,6 +29,16 @@ type BGULex struct {
 	AlwaysNNP bool
 	LogOOV    bool
 	MAType    string
+
+	// recognizers of tokens and hosts not in the lexicon, the default
+	// recognizers if nil
+	Recognizers []Recognizer
+
+	// analyzes OOV tokens and hosts instead of OOVMSRS if set
+	OOVGuesser *OOVGuesser
+
+	// trace is called with the source of every analysis added, see Explain
+	trace func(source string, prefix BasicMorphemes, hosts []BasicMorphemes)
 }
 
 var (
@@ -53,13 +66,6 @@ var (
 		"NN-gen=M|num=P",
 		"NN-gen=F|num=P",
 	}
-	REGEX = []struct {
-		RE  *regexp.Regexp
-		POS string
-	}{
-		{regexp.MustCompile("^\\d+(\\.\\d+)?$|^\\d{1,3}(,\\d{3})*(\\.\\d+)?$"), "CD"},
-		{regexp.MustCompile("\\d"), "NCD"},
-	}
 	_ MorphologicalAnalyzer = &BGULex{}
 )
 
@@ -84,91 +90,275 @@ func (l *BGULex) loadTokens(file, format string) {
 			m[token.Token] = token.Morphemes
 		}
 	}
+	if format == "lexicon" {
+		l.setMaxMWELen()
+	}
 }
 
 func (l *BGULex) LoadPrefixes(file string) {
 	l.loadTokens(file, "prefix")
+	l.setMaxPrefixLen()
+	log.Println("Loaded", len(l.Prefixes), "prefixes from lexicon")
+}
+
+func (l *BGULex) setMaxPrefixLen() {
 	l.MaxPrefixLen = 0
 	for _, morphs := range l.Prefixes {
 		if l.MaxPrefixLen < len(morphs) {
 			l.MaxPrefixLen = len(morphs)
 		}
 	}
-	log.Println("Loaded", len(l.Prefixes), "prefixes from lexicon")
 }
 
+// LoadLex loads a lexicon file, or a compiled lexicon file (see
+// LoadCompiled)
 func (l *BGULex) LoadLex(file string, nnpnofeats bool) {
 	lex.ADD_NNP_NO_FEATS = nnpnofeats
+	if lex.IsCompiledFile(file) {
+		l.LoadCompiled(file)
+		return
+	}
 	l.loadTokens(file, "lexicon")
 	log.Println("Loaded", len(l.Lex), "tokens from lexicon")
 }
 
-func makeMorphWithPOS(input, lemma, POS string) []BasicMorphemes {
-	return []BasicMorphemes{BasicMorphemes([]*Morpheme{
-		&Morpheme{
-			BasicDirectedEdge: graph.BasicDirectedEdge{0, 0, 1},
-			Form:              input,
-			Lemma:             lemma,
-			CPOS:              POS,
-			POS:               POS,
-			FeatureStr:        "",
-		},
-	})}
+// LoadCompiled loads the lexicon, and prefixes, of a compiled lexicon
+// file; its prefixes replace prefixes loaded before
+func (l *BGULex) LoadCompiled(file string) {
+	compiled, err := lex.ReadCompiledFile(file)
+	if err == nil {
+		err = compiled.Verify(l.MAType)
+	}
+	if err != nil {
+		panic(fmt.Sprintf("Failed to load %v: %v", file, err))
+	}
+	prefixes, lexicon := compiled.Expand()
+	if len(prefixes) > 0 {
+		l.Prefixes = prefixes
+		l.setMaxPrefixLen()
+	}
+	l.Lex = lexicon
+	l.setMaxMWELen()
+	log.Println("Loaded", len(prefixes), "prefixes and", len(lexicon), "tokens from compiled lexicon file:", file)
+}
+
+// Compile returns the compiled prefixes and lexicon
+func (l *BGULex) Compile(sources []string) *lex.Compiled {
+	return lex.Compile(l.Prefixes, l.Lex, l.MAType, sources)
+}
+
+// AddUserLex adds the analyses of user lexicon tokens to the lexicon,
+// skipping analyses the lexicon already has; with override, the analyses
+// of a token in the user lexicon replace its lexicon analyses. It returns
+// the number of analyses added.
+func (l *BGULex) AddUserLex(tokens []*lex.AnalyzedToken, override bool) int {
+	if l.Lex == nil {
+		l.Lex = make(map[string][]BasicMorphemes, len(tokens))
+	}
+	var (
+		added    int
+		replaced = make(map[string]bool)
+	)
+	for _, token := range tokens {
+		if override && !replaced[token.Token] {
+			delete(l.Lex, token.Token)
+			replaced[token.Token] = true
+		}
+		cur := l.Lex[token.Token]
+		for _, morphs := range token.Morphemes {
+			if !hasAnalysis(cur, morphs) {
+				cur = append(cur, morphs)
+				added++
+			}
+		}
+		l.Lex[token.Token] = cur
+		if n := mweLen(token.Token); n > l.MaxMWELen {
+			l.MaxMWELen = n
+		}
+	}
+	return added
+}
+
+// LoadUserLex adds the analyses of a user lexicon file (see lex.ReadUser)
+// to the lexicon
+func (l *BGULex) LoadUserLex(file string, override bool) error {
+	tokens, err := lex.ReadUserFile(file, l.MAType)
+	if err != nil {
+		return fmt.Errorf("Failed to load user lexicon %v: %v", file, err)
+	}
+	added := l.AddUserLex(tokens, override)
+	l.Files = append(l.Files, file)
+	log.Println("Added", added, "analyses of", len(tokens), "entries from user lexicon file:", file)
+	return nil
+}
+
+func hasAnalysis(analyses []BasicMorphemes, morphs BasicMorphemes) bool {
+	for _, analysis := range analyses {
+		if len(analysis) != len(morphs) {
+			continue
+		}
+		equal := true
+		for i, morph := range analysis {
+			if !morph.Equal(morphs[i]) {
+				equal = false
+				break
+			}
+		}
+		if equal {
+			return true
+		}
+	}
+	return false
 }
 
 func (l *BGULex) AddOOVAnalysis(lat *Lattice, prefix BasicMorphemes, hostStr string, numToken int) {
-	var OOVPOS, featuresStr string
+	if l.OOVGuesser != nil && l.addOOVGuesses(lat, prefix, hostStr, numToken) {
+		return
+	}
 	for _, msr := range OOVMSRS {
 		// if logAnalyze {
 		// 	log.Println("Adding msr", msr)
 		// }
-		msrsplit := strings.Split(msr, "-")
-		OOVPOS, featuresStr = msrsplit[0], msrsplit[1]
-		if l.MAType == "ud" {
-			OOVPOS = util.HEB2UDPOS[OOVPOS]
-			if len(featuresStr) > 0 {
-				featuresStr = util.Heb2UDFeaturesString(featuresStr)
-			}
-		}
-		newMorph := []BasicMorphemes{BasicMorphemes([]*Morpheme{
-			&Morpheme{
-				BasicDirectedEdge: graph.BasicDirectedEdge{0, 0, 1},
-				Form:              hostStr,
-				Lemma:             hostStr,
-				CPOS:              OOVPOS,
-				POS:               OOVPOS,
-				FeatureStr:        featuresStr,
-			},
-		})}
-		lat.AddAnalysis(prefix, newMorph, numToken)
+		newMorph := []BasicMorphemes{BasicMorphemes{msrMorpheme(hostStr, msr, l.MAType)}}
+		l.addAnalysis(lat, SOURCE_OOV, prefix, newMorph, numToken)
 	}
 }
 
-func checkRegexes(input string) ([]BasicMorphemes, bool) {
-	for _, curRegex := range REGEX {
-		if curRegex.RE.MatchString(input) {
-			return makeMorphWithPOS(input, "", curRegex.POS), true
+// addOOVGuesses adds the analyses of the OOV guesser; the guesses of a host
+// following a prefix are limited to those without a prefix
+func (l *BGULex) addOOVGuesses(lat *Lattice, prefix BasicMorphemes, hostStr string, numToken int) bool {
+	var added bool
+	for _, guess := range l.OOVGuesser.Guess(hostStr) {
+		if prefix != nil && guess.Prefix != nil {
+			continue
 		}
+		// lemmas of OOV hosts are their form, as of OOVMSRS
+		guess.Host[0].Lemma = guess.Host[0].Form
+		guessPrefix := prefix
+		if guessPrefix == nil {
+			guessPrefix = guess.Prefix
+		}
+		l.addAnalysis(lat, SOURCE_OOV, guessPrefix, []BasicMorphemes{guess.Host}, numToken)
+		added = true
+	}
+	return added
+}
+
+// addAnalysis adds the analyses of a host from a source, following a
+// prefix (nil for none), to the lattice; in UD, hosts following the
+// definite article are definite (see udDefinite)
+func (l *BGULex) addAnalysis(lat *Lattice, source string, prefix BasicMorphemes, hosts []BasicMorphemes, numToken int) {
+	if l.MAType == "ud" && isUDArticle(prefix) {
+		hosts = udDefinite(hosts)
+	}
+	if l.trace != nil {
+		l.trace(source, prefix, hosts)
+	}
+	lat.AddAnalysis(prefix, hosts, numToken)
+}
+
+// lookup returns the analyses of a token or host from the lexicon, or from
+// the first recognizer of it, and their source
+func (l *BGULex) lookup(input string) ([]BasicMorphemes, string, bool) {
+	if analyses, exists := l.Lex[input]; exists {
+		return analyses, SOURCE_LEXICON, true
 	}
-	return nil, false
+	if analyses, name, exists := l.recognize(input); exists {
+		return analyses, SOURCE_RECOGNIZER + name, true
+	}
+	return nil, "", false
 }
 
 var logAnalyze bool = false
 
+// HYPHENS separate Hebrew prefixes from a foreign or numeric host, e.g.
+// ב-2020 (hyphen or maqaf)
+const HYPHENS = "-\u05BE"
+
+func isHebrewLetter(r rune) bool {
+	return r >= '\u05D0' && r <= '\u05EA'
+}
+
+func isHyphenated(host string) bool {
+	r, _ := utf8.DecodeRuneInString(host)
+	return len(host) > 0 && strings.ContainsRune(HYPHENS, r)
+}
+
+// splitPrefix splits the first prefixLen runes of a token from the rest;
+// ok is false if the token is shorter than the prefix
+func splitPrefix(input string, prefixLen int) (prefix, host string, ok bool) {
+	i := 0
+	for n := 0; n < prefixLen; n++ {
+		if i >= len(input) {
+			return "", "", false
+		}
+		_, size := utf8.DecodeRuneInString(input[i:])
+		i += size
+	}
+	return input[:i], input[i:], true
+}
+
+// splitMixedToken splits a token of Hebrew letters followed by an optional
+// hyphen and a host without Hebrew letters (e.g. ב-2020, לGoogle, ה-CEO)
+func splitMixedToken(input string) (prefix, host string, ok bool) {
+	i := 0
+	for i < len(input) {
+		r, size := utf8.DecodeRuneInString(input[i:])
+		if !isHebrewLetter(r) {
+			break
+		}
+		i += size
+	}
+	if i == 0 || i == len(input) {
+		return "", "", false
+	}
+	prefix, host = input[:i], input[i:]
+	if isHyphenated(host) {
+		_, size := utf8.DecodeRuneInString(host)
+		host = host[size:]
+	}
+	if len(host) == 0 || strings.IndexFunc(host, isHebrewLetter) >= 0 || isHyphenated(host) {
+		return "", "", false
+	}
+	return prefix, host, true
+}
+
+// analyzeMixedToken adds the analyses of a token of Hebrew prefixes and a
+// foreign or numeric host: the prefix morphemes and the lexicon analyses of
+// the host, a number, or a proper noun. The hyphen is not part of the host.
+func (l *BGULex) analyzeMixedToken(lat *Lattice, input string, numToken int) bool {
+	prefixStr, hostStr, ok := splitMixedToken(input)
+	if !ok {
+		return false
+	}
+	prefixLat, prefixExists := l.Prefixes[prefixStr]
+	if !prefixExists {
+		return false
+	}
+	hostLat, source, hostExists := l.lookup(hostStr)
+	if !hostExists {
+		hostLat, source = []BasicMorphemes{{msrMorpheme(hostStr, "NNP-", l.MAType)}}, SOURCE_OOV
+	}
+	if logAnalyze {
+		log.Println("\tMixed token prefix", prefixStr, "host", hostStr)
+	}
+	for _, prefix := range prefixLat {
+		l.addAnalysis(lat, source, prefix, hostLat, numToken)
+	}
+	return true
+}
+
 func (l *BGULex) OOVForLen(lat *Lattice, input string, startingNode, numToken, prefixLen int) bool {
-	var (
-		found   bool
-		hostStr string
-	)
-	if len(input) < prefixLen*2 {
+	var found bool
+	prefixStr, hostStr, ok := splitPrefix(input, prefixLen)
+	if !ok || isHyphenated(hostStr) {
 		return found
 	}
-	prefixLat, prefixExists := l.Prefixes[input[0:prefixLen*2]]
-	// log.Println("\tPrefixes", input[0:prefixLen*2], prefixExists)
+	prefixLat, prefixExists := l.Prefixes[prefixStr]
+	// log.Println("\tPrefixes", prefixStr, prefixExists)
 	if prefixExists {
-		hostStr = input[2*prefixLen:]
-		if len(hostStr) > 2 {
-			// Always add NNP hosts for len(hosts)>1 (unicode = 2 runes)
+		if utf8.RuneCountInString(hostStr) > 1 {
+			// Always add NNP hosts for len(hosts)>1
 			for _, prefix := range prefixLat {
 				l.AddOOVAnalysis(lat, prefix, hostStr, numToken)
 				// lat.AddAnalysis(prefix, l.OOVAnalysis(hostStr), numToken)
@@ -182,33 +372,30 @@ func (l *BGULex) analyzeTokenForLen(lat *Lattice, input string, startingNode, nu
 	var (
 		found, hostExists bool
 		hostLat           []BasicMorphemes
-		hostStr           string
+		source            string
 	)
-	if len(input) < prefixLen*2 {
+	prefixStr, hostStr, ok := splitPrefix(input, prefixLen)
+	if !ok || isHyphenated(hostStr) {
 		return found
 	}
-	prefixLat, prefixExists := l.Prefixes[input[0:prefixLen*2]]
-	// log.Println("\tPrefixes", input[0:prefixLen*2], prefixExists)
+	prefixLat, prefixExists := l.Prefixes[prefixStr]
+	// log.Println("\tPrefixes", prefixStr, prefixExists)
 	if prefixExists {
-		hostStr = input[2*prefixLen:]
 		if l.AlwaysNNP {
-			if len(hostStr) > 2 {
-				// Always add NNP hosts for len(hosts)>1 (unicode = 2 runes)
+			if utf8.RuneCountInString(hostStr) > 1 {
+				// Always add NNP hosts for len(hosts)>1
 				for _, prefix := range prefixLat {
 					l.AddOOVAnalysis(lat, prefix, hostStr, numToken)
 					// lat.AddAnalysis(prefix, l.OOVAnalysis(hostStr), numToken)
 				}
 			}
 		}
-		hostLat, hostExists = l.Lex[hostStr]
-		if !hostExists {
-			hostLat, hostExists = checkRegexes(hostStr)
-		}
+		hostLat, source, hostExists = l.lookup(hostStr)
 		// log.Println("\tHosts", input[2*prefixLen:], hostExists)
 		if hostExists {
 			for _, prefix := range prefixLat {
 				// log.Println("\t\tAdding", prefix, hostLat)
-				lat.AddAnalysis(prefix, hostLat, numToken)
+				l.addAnalysis(lat, source, prefix, hostLat, numToken)
 			}
 			found = true
 		}
@@ -217,6 +404,12 @@ func (l *BGULex) analyzeTokenForLen(lat *Lattice, input string, startingNode, nu
 }
 
 func (l *BGULex) AnalyzeToken(input string, startingNode, indexToken int) (*Lattice, interface{}) {
+	return l.analyzeToken(input, startingNode, indexToken, nil)
+}
+
+// analyzeToken analyzes a token as AnalyzeToken, adding the analyses of the
+// token as part of multi-token expressions (see mweParts)
+func (l *BGULex) analyzeToken(input string, startingNode, indexToken int, mweParts []mwePart) (*Lattice, interface{}) {
 	numToken := indexToken + 1
 	if logAnalyze {
 		log.Println("Analyzing token", numToken, "starting at", startingNode)
@@ -232,21 +425,21 @@ func (l *BGULex) AnalyzeToken(input string, startingNode, indexToken int) (*Latt
 	var (
 		hostLat               []BasicMorphemes
 		hostExists, anyExists bool
-		punctPOS              string
+		punctPOS, source      string
 	)
-	if punctVal, exists := PUNCT[input]; exists {
+	if punctVal, exists := l.punct(input); exists {
 		punctPOS = punctVal
-		if l.MAType == "ud" {
-			punctPOS = "PUNCT"
-		}
 		m := &Morpheme{
 			BasicDirectedEdge: graph.BasicDirectedEdge{0, 0, 0},
 			Form:              input,
 			CPOS:              punctPOS,
 			POS:               punctPOS,
 		}
+		if l.MAType == "ud" {
+			m.CPOS, m.POS = "PUNCT", "_"
+		}
 		basics := []BasicMorphemes{BasicMorphemes{m}}
-		lat.AddAnalysis(nil, basics, numToken)
+		l.addAnalysis(lat, SOURCE_PUNCT, nil, basics, numToken)
 		return lat, false
 	}
 	if l.AlwaysNNP {
@@ -254,15 +447,12 @@ func (l *BGULex) AnalyzeToken(input string, startingNode, indexToken int) (*Latt
 		// oovLat := l.OOVAnalysis(input)
 		// lat.AddAnalysis(nil, oovLat, numToken)
 	}
-	hostLat, hostExists = l.Lex[input]
-	if !hostExists {
-		hostLat, hostExists = checkRegexes(input)
-	}
+	hostLat, source, hostExists = l.lookup(input)
 	if hostExists {
 		if logAnalyze {
 			log.Println("\tPrefix 0")
 		}
-		lat.AddAnalysis(nil, hostLat, numToken)
+		l.addAnalysis(lat, source, nil, hostLat, numToken)
 		anyExists = true
 	} else {
 		if !l.AlwaysNNP {
@@ -271,19 +461,28 @@ func (l *BGULex) AnalyzeToken(input string, startingNode, indexToken int) (*Latt
 			// lat.AddAnalysis(nil, oovLat, numToken)
 		}
 	}
-	for i := 1; i <= util.Min(l.MaxPrefixLen, len(input)); i++ {
+	inputLen := utf8.RuneCountInString(input)
+	for i := 1; i <= util.Min(l.MaxPrefixLen, inputLen); i++ {
 		if logAnalyze {
 			log.Println("\ti is", i)
 		}
 		found := l.analyzeTokenForLen(lat, input, startingNode, numToken, i)
 		anyExists = anyExists || found
 	}
+	if l.analyzeMixedToken(lat, input, numToken) {
+		anyExists = true
+	}
+	for _, part := range mweParts {
+		l.addAnalysis(lat, SOURCE_MWE, part.prefix, []BasicMorphemes{{part.host}}, numToken)
+		anyExists = true
+	}
 	if !anyExists {
 		// if logAnalyze {
 		if l.LogOOV {
 			log.Println("Token", numToken, "is OOV:", input)
 		}
-		for i := 1; i < util.Min(l.MaxPrefixLen, len(input)); i++ {
+		// prefixes of OOV tokens are guessed by the OOV guesser if set
+		for i := 1; l.OOVGuesser == nil && i < util.Min(l.MaxPrefixLen, inputLen); i++ {
 			if logAnalyze {
 				log.Println("\ti is", i)
 			}
@@ -308,12 +507,13 @@ func (l *BGULex) Analyze(input []string) (LatticeSentence, interface{}) {
 		oovFlag interface{}
 	)
 	oovInd = make(BasicSentence, len(input))
+	mweParts := l.mweParts(input)
 	for i, token := range input {
 		if l.Stats != nil {
 			l.Stats.TotalTokens++
 			l.Stats.AddToken(token)
 		}
-		lat, oovFlag = l.AnalyzeToken(token, curNode, i)
+		lat, oovFlag = l.analyzeToken(token, curNode, i, mweParts[i])
 		if oovFlag.(bool) {
 			oovInd[i] = Token("1")
 		} else {
@@ -0,0 +1,120 @@
+package ma
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"yap/nlp/format/lex"
+)
+
+const testPrefixes = `ב ב PREPOSITION:: ב^ה PREPOSITION+DEF::
+ה ה DEF::
+ו ו CONJ::
+וב ו^ב CONJ+PREPOSITION:: ו^ב^ה CONJ+PREPOSITION+DEF::
+ל ל PREPOSITION:: ל^ה PREPOSITION+DEF::
+`
+
+func newTestLex(t *testing.T) *BGULex {
+	file, err := ioutil.TempFile("", "prefixes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString(testPrefixes)
+	file.Close()
+	l := &BGULex{MAType: "spmrl"}
+	l.LoadPrefixes(file.Name())
+	tokens, err := lex.ReadUser(strings.NewReader("בית\t_\tNN\tgen=M|num=S\n"), l.MAType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.AddUserLex(tokens, false)
+	return l
+}
+
+func TestSplitPrefix(t *testing.T) {
+	tests := []struct {
+		input        string
+		prefixLen    int
+		prefix, host string
+		ok           bool
+	}{
+		{"בבית", 1, "ב", "בית", true},
+		{"בבית", 2, "בב", "ית", true},
+		{"לGoogle", 1, "ל", "Google", true},
+		{"ב-2020", 2, "ב-", "2020", true},
+		{"ב", 1, "ב", "", true},
+		{"ב", 2, "", "", false},
+	}
+	for _, test := range tests {
+		prefix, host, ok := splitPrefix(test.input, test.prefixLen)
+		if prefix != test.prefix || host != test.host || ok != test.ok {
+			t.Errorf("splitPrefix(%q, %d) = %q, %q, %v, expected %q, %q, %v", test.input, test.prefixLen, prefix, host, ok, test.prefix, test.host, test.ok)
+		}
+	}
+}
+
+func TestSplitMixedToken(t *testing.T) {
+	tests := []struct {
+		input        string
+		prefix, host string
+		ok           bool
+	}{
+		{"ב-2020", "ב", "2020", true},
+		{"ב־2020", "ב", "2020", true},
+		{"לGoogle", "ל", "Google", true},
+		{"ה-CEO", "ה", "CEO", true},
+		{"וב-3.5", "וב", "3.5", true},
+		{"בבית", "", "", false},
+		{"Google", "", "", false},
+		{"ב-", "", "", false},
+		{"ב--2020", "", "", false},
+		{"ב-Gooגל", "", "", false},
+	}
+	for _, test := range tests {
+		prefix, host, ok := splitMixedToken(test.input)
+		if prefix != test.prefix || host != test.host || ok != test.ok {
+			t.Errorf("splitMixedToken(%q) = %q, %q, %v, expected %q, %q, %v", test.input, prefix, host, ok, test.prefix, test.host, test.ok)
+		}
+	}
+}
+
+func TestAnalyzeMixedToken(t *testing.T) {
+	l := newTestLex(t)
+	tests := []struct {
+		input           string
+		expected, other []string // form:POS of morphemes expected (not expected) in the lattice
+		oov             bool
+	}{
+		{"ב-2020", []string{"ב:PREPOSITION", "ה:DEF", "2020:CD"}, []string{"-2020:NCD", "-2020:NNP"}, false},
+		{"לGoogle", []string{"ל:PREPOSITION", "Google:NNP"}, nil, false},
+		{"ה-CEO", []string{"ה:DEF", "CEO:NNP"}, []string{"-CEO:NNP"}, false},
+		{"וב-2020", []string{"ו:CONJ", "ב:PREPOSITION", "2020:CD"}, nil, false},
+		{"בבית", []string{"ב:PREPOSITION", "בית:NN"}, nil, false},
+		{"2020", []string{"2020:CD"}, nil, false},
+		{"Google", []string{"Google:NNP"}, []string{"oogle:NNP"}, true},
+		{"ואקמול", []string{"ואקמול:NNP", "ו:CONJ", "אקמול:NNP"}, nil, true},
+	}
+	for _, test := range tests {
+		lat, oov := l.AnalyzeToken(test.input, 0, 0)
+		morphs := make(map[string]bool)
+		for _, m := range lat.Morphemes {
+			morphs[m.Form+":"+m.CPOS] = true
+		}
+		for _, expected := range test.expected {
+			if !morphs[expected] {
+				t.Errorf("%s: expected %s in %v", test.input, expected, morphs)
+			}
+		}
+		for _, other := range test.other {
+			if morphs[other] {
+				t.Errorf("%s: unexpected %s in %v", test.input, other, morphs)
+			}
+		}
+		if oov.(bool) != test.oov {
+			t.Errorf("%s: got OOV %v, expected %v", test.input, oov, test.oov)
+		}
+	}
+}
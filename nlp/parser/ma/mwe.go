@@ -0,0 +1,74 @@
+package ma
+
+import (
+	. "yap/nlp/types"
+
+	"strings"
+)
+
+// mweLen returns the number of tokens of a lexicon token, more than 1 for
+// multi-token expressions
+func mweLen(token string) int {
+	return strings.Count(token, MWE_SEPARATOR) + 1
+}
+
+func (l *BGULex) setMaxMWELen() {
+	l.MaxMWELen = 1
+	for token := range l.Lex {
+		if n := mweLen(token); n > l.MaxMWELen {
+			l.MaxMWELen = n
+		}
+	}
+}
+
+// mwePart is an analysis of a token as part of a multi-token expression,
+// following the analysis of a prefix of the first token (nil for none)
+type mwePart struct {
+	prefix BasicMorphemes
+	host   *Morpheme
+}
+
+// mweParts returns the analyses of each token of a sentence as part of the
+// multi-token expressions of the lexicon found in the sentence, with or
+// without a prefix (e.g. בתל אביב): for each single morpheme analysis of an
+// expression, a morpheme per token (see MWEPart). Each expression adds an
+// alternative path through the lattices of its tokens to their
+// compositional analyses.
+func (l *BGULex) mweParts(input []string) [][]mwePart {
+	parts := make([][]mwePart, len(input))
+	for i := range input {
+		if strings.Contains(input[i], MWE_SEPARATOR) {
+			continue
+		}
+		for n := 2; n <= l.MaxMWELen && i+n <= len(input); n++ {
+			tokens := make([]string, n)
+			copy(tokens, input[i:i+n])
+			for prefixLen := 0; prefixLen <= l.MaxPrefixLen; prefixLen++ {
+				prefixStr, host, ok := splitPrefix(input[i], prefixLen)
+				if !ok || len(host) == 0 {
+					break
+				}
+				prefixes := []BasicMorphemes{nil}
+				if prefixLen > 0 {
+					prefixes = l.Prefixes[prefixStr]
+				}
+				tokens[0] = host
+				for _, analysis := range l.Lex[strings.Join(tokens, MWE_SEPARATOR)] {
+					if len(analysis) != 1 {
+						continue
+					}
+					for _, prefix := range prefixes {
+						for k := range tokens {
+							part := mwePart{host: MWEPart(analysis[0], tokens, k+1)}
+							if k == 0 {
+								part.prefix = prefix
+							}
+							parts[i+k] = append(parts[i+k], part)
+						}
+					}
+				}
+			}
+		}
+	}
+	return parts
+}
@@ -0,0 +1,59 @@
+package ma
+
+import (
+	"strings"
+	"testing"
+
+	"yap/nlp/format/lex"
+)
+
+func TestAnalyzeMWE(t *testing.T) {
+	l := newTestLex(t)
+	tokens, err := lex.ReadUser(strings.NewReader("בית ספר\t_\tNN\tgen=M|num=S\t1\n"), l.MAType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.AddUserLex(tokens, false)
+	if l.MaxMWELen != 2 {
+		t.Fatalf("Expected max MWE length 2, got %d", l.MaxMWELen)
+	}
+	lats, _ := l.Analyze([]string{"לבית", "ספר", "גדול"})
+	tests := []struct {
+		token    int
+		expected []string // form:POS:features of MWE morphemes expected in the lattice
+	}{
+		{0, []string{"ל:PREPOSITION:", "בית:NN:gen=M|mwe=1/2|num=S"}},
+		{1, []string{"ספר:NN:mwe=2/2"}},
+		{2, nil},
+	}
+	for _, test := range tests {
+		morphs := make(map[string]bool)
+		var mwes int
+		for _, m := range lats[test.token].Morphemes {
+			morphs[m.Form+":"+m.CPOS+":"+m.FeatureStr] = true
+			if _, exists := m.Features["mwe"]; exists {
+				mwes++
+			}
+		}
+		for _, expected := range test.expected {
+			if !morphs[expected] {
+				t.Errorf("Token %d: expected %s in %v", test.token, expected, morphs)
+			}
+		}
+		if test.expected == nil && mwes > 0 {
+			t.Errorf("Token %d: expected no MWE morphemes, got %v", test.token, morphs)
+		}
+	}
+}
+
+func TestMWELen(t *testing.T) {
+	l := newTestLex(t)
+	tokens, err := lex.ReadUser(strings.NewReader("_\t_\tyyUNDERSCORE\n__init__\t_\tNNP\n"), l.MAType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.AddUserLex(tokens, false)
+	if l.MaxMWELen != 1 {
+		t.Errorf("Expected max MWE length 1 for tokens with underscores, got %d", l.MaxMWELen)
+	}
+}
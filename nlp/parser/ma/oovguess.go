@@ -0,0 +1,275 @@
+package ma
+
+import (
+	"yap/alg/graph"
+	. "yap/nlp/types"
+
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// OOVTemplate is an analysis of a token learned from gold data with the host
+// form left out: the prefix and suffix morphemes around the host, and the
+// surface of the token they take
+type OOVTemplate struct {
+	PrefixSurface, SuffixSurface    string
+	Prefix, Suffix                  BasicMorphemes
+	HostCPOS, HostPOS, HostFeatures string
+}
+
+// Applies returns true if a token has the prefix and suffix surface of the
+// template, and a non-empty host between them
+func (t *OOVTemplate) Applies(token string) bool {
+	return len(token) > len(t.PrefixSurface)+len(t.SuffixSurface) &&
+		strings.HasPrefix(token, t.PrefixSurface) && strings.HasSuffix(token, t.SuffixSurface)
+}
+
+func (t *OOVTemplate) key() string {
+	morphs := make([]string, 0, len(t.Prefix)+len(t.Suffix)+1)
+	for _, m := range t.Prefix {
+		morphs = append(morphs, m.String())
+	}
+	morphs = append(morphs, fmt.Sprintf("_-%s-%s-%s", t.HostCPOS, t.HostPOS, t.HostFeatures))
+	for _, m := range t.Suffix {
+		morphs = append(morphs, m.String())
+	}
+	return t.PrefixSurface + "+" + t.SuffixSurface + ":" + strings.Join(morphs, " ")
+}
+
+// newOOVTemplate returns the template of a gold analysis of a token; the
+// host is the longest morpheme whose form appears in the token, morphemes
+// before (after) it are prefixes (suffixes)
+func newOOVTemplate(token string, morphs BasicMorphemes) (*OOVTemplate, bool) {
+	host, hostAt := -1, -1
+	for i, m := range morphs {
+		if len(m.Form) == 0 || (host >= 0 && len(m.Form) <= len(morphs[host].Form)) {
+			continue
+		}
+		if at := strings.Index(token, m.Form); at >= 0 {
+			host, hostAt = i, at
+		}
+	}
+	if host < 0 {
+		return nil, false
+	}
+	t := &OOVTemplate{
+		PrefixSurface: token[:hostAt],
+		SuffixSurface: token[hostAt+len(morphs[host].Form):],
+		HostCPOS:      morphs[host].CPOS,
+		HostPOS:       morphs[host].POS,
+		HostFeatures:  morphs[host].FeatureStr,
+	}
+	t.Prefix, t.Suffix = templateMorphemes(morphs[:host]), templateMorphemes(morphs[host+1:])
+	return t, true
+}
+
+func templateMorphemes(morphs BasicMorphemes) BasicMorphemes {
+	if len(morphs) == 0 {
+		return nil
+	}
+	copied := make(BasicMorphemes, len(morphs))
+	for i, m := range morphs {
+		copied[i] = &Morpheme{
+			BasicDirectedEdge: graph.BasicDirectedEdge{i, i, i + 1},
+			Form:              m.Form,
+			Lemma:             m.Lemma,
+			CPOS:              m.CPOS,
+			POS:               m.POS,
+			FeatureStr:        m.FeatureStr,
+		}
+	}
+	return copied
+}
+
+// tokenShape returns the character classes of a token, with repeats
+// collapsed: א for Hebrew letters, A/a for Latin letters, 9 for digits and
+// the character itself otherwise (e.g. ב-2020 is א-9)
+func tokenShape(token string) string {
+	var shape []rune
+	for _, r := range token {
+		c := r
+		switch {
+		case isHebrewLetter(r):
+			c = 'א'
+		case unicode.Is(unicode.Latin, r) && unicode.IsUpper(r):
+			c = 'A'
+		case unicode.Is(unicode.Latin, r):
+			c = 'a'
+		case unicode.IsDigit(r):
+			c = '9'
+		}
+		if len(shape) == 0 || shape[len(shape)-1] != c {
+			shape = append(shape, c)
+		}
+	}
+	return string(shape)
+}
+
+type oovFeature struct {
+	name   string
+	weight float64
+}
+
+// oovFeatures returns the features of a token the guesser conditions on,
+// with their weights: a prior shared by all tokens, the token's shape, and
+// its prefixes and suffixes of up to maxAffix runes
+func oovFeatures(token string, maxAffix int) []oovFeature {
+	runes := []rune(token)
+	features := []oovFeature{{"all", 1}, {"shape:" + tokenShape(token), 1}}
+	for k := 1; k <= maxAffix && k <= len(runes); k++ {
+		features = append(features,
+			oovFeature{fmt.Sprintf("p%d:%s", k, string(runes[:k])), float64(k)},
+			oovFeature{fmt.Sprintf("s%d:%s", k, string(runes[len(runes)-k:])), float64(k)})
+	}
+	return features
+}
+
+type oovObservation struct {
+	token  string
+	morphs BasicMorphemes
+}
+
+// OOVGuesser analyzes unknown tokens by the distribution of analysis
+// templates (segmentation into prefix, host and suffix, and their POS and
+// features) over the affixes and shape of rare tokens of gold data
+type OOVGuesser struct {
+	// longest token prefix and suffix (in runes) conditioned on
+	MaxAffix int
+	// only tokens at most this frequent in the gold data are learned from
+	RareFreq int
+	// guesses are pruned to the most probable MaxAnalyses with at least
+	// MinProb probability
+	MaxAnalyses int
+	MinProb     float64
+
+	Templates []*OOVTemplate
+	// template counts by feature, and feature counts
+	Counts        map[string]map[int]int
+	FeatureCounts map[string]int
+
+	observed  []oovObservation
+	tokenFreq map[string]int
+}
+
+// OOVGuess is an analysis of an unknown token; Host holds the host and any
+// suffix morphemes
+type OOVGuess struct {
+	Prefix, Host BasicMorphemes
+	Prob         float64
+}
+
+func NewOOVGuesser(maxAffix, rareFreq, maxAnalyses int, minProb float64) *OOVGuesser {
+	return &OOVGuesser{
+		MaxAffix:      maxAffix,
+		RareFreq:      rareFreq,
+		MaxAnalyses:   maxAnalyses,
+		MinProb:       minProb,
+		Counts:        make(map[string]map[int]int),
+		FeatureCounts: make(map[string]int),
+	}
+}
+
+// Observe adds a token and its gold analysis to learn from on Train
+func (g *OOVGuesser) Observe(token string, morphs BasicMorphemes) {
+	if g.tokenFreq == nil {
+		g.tokenFreq = make(map[string]int)
+	}
+	g.tokenFreq[token]++
+	g.observed = append(g.observed, oovObservation{token, morphs})
+}
+
+// Train learns the templates of the rare observed tokens, and returns the
+// number of tokens learned from
+func (g *OOVGuesser) Train() int {
+	index := make(map[string]int, len(g.Templates))
+	for i, t := range g.Templates {
+		index[t.key()] = i
+	}
+	var learned int
+	for _, obs := range g.observed {
+		if g.tokenFreq[obs.token] > g.RareFreq {
+			continue
+		}
+		t, ok := newOOVTemplate(obs.token, obs.morphs)
+		if !ok {
+			continue
+		}
+		key := t.key()
+		i, exists := index[key]
+		if !exists {
+			i = len(g.Templates)
+			index[key] = i
+			g.Templates = append(g.Templates, t)
+		}
+		for _, feature := range oovFeatures(obs.token, g.MaxAffix) {
+			counts, exists := g.Counts[feature.name]
+			if !exists {
+				counts = make(map[int]int)
+				g.Counts[feature.name] = counts
+			}
+			counts[i]++
+			g.FeatureCounts[feature.name]++
+		}
+		learned++
+	}
+	g.observed, g.tokenFreq = nil, nil
+	return learned
+}
+
+// Guess returns the analyses of a token, most probable first; a template's
+// score is the sum over the token's features of the template's relative
+// frequency with the feature, discounted for rare features, times the
+// feature's weight
+func (g *OOVGuesser) Guess(token string) []OOVGuess {
+	scores := make(map[int]float64)
+	for _, feature := range oovFeatures(token, g.MaxAffix) {
+		total := float64(g.FeatureCounts[feature.name])
+		for i, count := range g.Counts[feature.name] {
+			if g.Templates[i].Applies(token) {
+				scores[i] += feature.weight * float64(count) / (total + 1)
+			}
+		}
+	}
+	ranked := make([]int, 0, len(scores))
+	for i := range scores {
+		ranked = append(ranked, i)
+	}
+	sort.Slice(ranked, func(a, b int) bool {
+		if scores[ranked[a]] != scores[ranked[b]] {
+			return scores[ranked[a]] > scores[ranked[b]]
+		}
+		return ranked[a] < ranked[b]
+	})
+	var sum float64
+	for _, i := range ranked {
+		sum += scores[i]
+	}
+	guesses := make([]OOVGuess, 0, g.MaxAnalyses)
+	for _, i := range ranked {
+		prob := scores[i] / sum
+		if len(guesses) == g.MaxAnalyses || prob < g.MinProb {
+			break
+		}
+		t := g.Templates[i]
+		hostForm := token[len(t.PrefixSurface) : len(token)-len(t.SuffixSurface)]
+		host := make(BasicMorphemes, 0, len(t.Suffix)+1)
+		host = append(host, &Morpheme{
+			BasicDirectedEdge: graph.BasicDirectedEdge{0, 0, 1},
+			Form:              hostForm,
+			Lemma:             "_",
+			CPOS:              t.HostCPOS,
+			POS:               t.HostPOS,
+			FeatureStr:        t.HostFeatures,
+		})
+		host = append(host, templateMorphemes(t.Suffix)...)
+		guesses = append(guesses, OOVGuess{templateMorphemes(t.Prefix), host, prob})
+	}
+	return guesses
+}
+
+// String returns the size of the guesser
+func (g *OOVGuesser) String() string {
+	return fmt.Sprintf("%d templates, %d features, max affix %d runes", len(g.Templates), len(g.FeatureCounts), g.MaxAffix)
+}
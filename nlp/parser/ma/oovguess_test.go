@@ -0,0 +1,173 @@
+package ma
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"yap/alg/graph"
+	. "yap/nlp/types"
+)
+
+// testAnalysis returns the morphemes of form/CPOS/features triplets
+func testAnalysis(morphs ...string) BasicMorphemes {
+	analysis := make(BasicMorphemes, len(morphs))
+	for i, morph := range morphs {
+		split := strings.Split(morph, "/")
+		analysis[i] = &Morpheme{
+			BasicDirectedEdge: graph.BasicDirectedEdge{i, i, i + 1},
+			Form:              split[0],
+			Lemma:             split[0],
+			CPOS:              split[1],
+			POS:               split[1],
+			FeatureStr:        split[2],
+		}
+	}
+	return analysis
+}
+
+func newTestGuesser() *OOVGuesser {
+	g := NewOOVGuesser(3, 1, 10, 0.01)
+	g.Observe("בגן", testAnalysis("ב/ADP/_", "ה/DET/_", "גן/NOUN/Gender=Masc|Number=Sing"))
+	g.Observe("בספר", testAnalysis("ב/ADP/_", "ה/DET/_", "ספר/NOUN/Gender=Masc|Number=Sing"))
+	g.Observe("בחדר", testAnalysis("ב/ADP/_", "חדר/NOUN/Gender=Masc|Number=Sing"))
+	g.Observe("ספרו", testAnalysis("ספר/NOUN/Gender=Masc|Number=Sing", "של/ADP/_", "הוא/PRON/Gender=Masc|Number=Sing|Person=3"))
+	g.Observe("ילדים", testAnalysis("ילדים/NOUN/Gender=Masc|Number=Plur"))
+	g.Observe("ספרים", testAnalysis("ספרים/NOUN/Gender=Masc|Number=Plur"))
+	g.Observe("שולחן", testAnalysis("שולחן/NOUN/Gender=Masc|Number=Sing"))
+	// frequent tokens are not learned from
+	g.Observe("את", testAnalysis("את/ADP/_"))
+	g.Observe("את", testAnalysis("את/ADP/_"))
+	g.Train()
+	return g
+}
+
+func TestOOVTemplate(t *testing.T) {
+	tests := []struct {
+		token                        string
+		analysis                     BasicMorphemes
+		prefixSurface, suffixSurface string
+		prefix, suffix               int
+		ok                           bool
+	}{
+		{"בבית", testAnalysis("ב/ADP/_", "ה/DET/_", "בית/NOUN/_"), "ב", "", 2, 0, true},
+		{"ספרו", testAnalysis("ספר/NOUN/_", "של/ADP/_", "הוא/PRON/_"), "", "ו", 0, 2, true},
+		{"שלו", testAnalysis("של/ADP/_", "הוא/PRON/_"), "", "ו", 0, 1, true},
+		{"בית", testAnalysis("בית/NOUN/_"), "", "", 0, 0, true},
+		{"ביתה", testAnalysis("בית/NOUN/_", "ה/ADP/_"), "", "ה", 0, 1, true},
+		{"בית", testAnalysis("ספר/NOUN/_"), "", "", 0, 0, false},
+	}
+	for _, test := range tests {
+		template, ok := newOOVTemplate(test.token, test.analysis)
+		if ok != test.ok {
+			t.Errorf("%s: got ok %v, expected %v", test.token, ok, test.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if template.PrefixSurface != test.prefixSurface || template.SuffixSurface != test.suffixSurface ||
+			len(template.Prefix) != test.prefix || len(template.Suffix) != test.suffix {
+			t.Errorf("%s: got %q %d + host + %q %d, expected %q %d + host + %q %d", test.token,
+				template.PrefixSurface, len(template.Prefix), template.SuffixSurface, len(template.Suffix),
+				test.prefixSurface, test.prefix, test.suffixSurface, test.suffix)
+		}
+	}
+}
+
+func TestTokenShape(t *testing.T) {
+	tests := map[string]string{
+		"בבית":   "א",
+		"ב-2020": "א-9",
+		"Google": "Aa",
+		"לCNN":   "אA",
+		"3.5%":   "9.9%",
+	}
+	for token, expected := range tests {
+		if shape := tokenShape(token); shape != expected {
+			t.Errorf("%s: got shape %q, expected %q", token, shape, expected)
+		}
+	}
+}
+
+func TestOOVGuess(t *testing.T) {
+	g := newTestGuesser()
+	tests := []struct {
+		token, first string // form/CPOS of the morphemes of the first guess
+	}{
+		{"בכיסא", "ב/ADP ה/DET כיסא/NOUN"},
+		{"שולחנים", "שולחנים/NOUN"},
+		{"שירו", "שיר/NOUN של/ADP הוא/PRON"},
+	}
+	for _, test := range tests {
+		guesses := g.Guess(test.token)
+		if len(guesses) == 0 {
+			t.Errorf("%s: no guesses", test.token)
+			continue
+		}
+		var morphs []string
+		for _, m := range append(append(BasicMorphemes{}, guesses[0].Prefix...), guesses[0].Host...) {
+			morphs = append(morphs, m.Form+"/"+m.CPOS)
+		}
+		if first := strings.Join(morphs, " "); first != test.first {
+			t.Errorf("%s: got first guess %s, expected %s", test.token, first, test.first)
+		}
+		var sum float64
+		for i, guess := range guesses {
+			if i > 0 && guess.Prob > guesses[i-1].Prob {
+				t.Errorf("%s: guesses are not ranked: %v", test.token, guesses)
+			}
+			sum += guess.Prob
+		}
+		if sum > 1.0001 {
+			t.Errorf("%s: guess probabilities sum to %v", test.token, sum)
+		}
+	}
+	for _, guess := range g.Guess("אתם") {
+		if guess.Host[0].CPOS == "ADP" {
+			t.Errorf("got a guess of a frequent token: %v", guess)
+		}
+	}
+	g.MaxAnalyses = 1
+	if guesses := g.Guess("בכיסא"); len(guesses) != 1 {
+		t.Errorf("got %d guesses, expected the max of 1", len(guesses))
+	}
+}
+
+func TestMADictOOVGuesser(t *testing.T) {
+	m := &MADict{Data: TokenDictionary{}, OOVGuesser: newTestGuesser()}
+	var buf bytes.Buffer
+	if err := m.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	read := new(MADict)
+	if err := read.Read(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if read.OOVGuesser == nil || len(read.OOVGuesser.Templates) != len(m.OOVGuesser.Templates) {
+		t.Fatalf("OOV guesser was not read back: %v", read.OOVGuesser)
+	}
+	lats, _ := read.Analyze([]string{"בכיסא"})
+	forms := make(map[string]bool)
+	for _, morph := range lats[0].Morphemes {
+		forms[morph.Form] = true
+	}
+	if !forms["ב"] || !forms["כיסא"] {
+		t.Errorf("got lattice %v, expected the guessed segmentation ב + ה + כיסא", lats[0].Morphemes)
+	}
+}
+
+func TestBGULexOOVGuesser(t *testing.T) {
+	l := newTestLex(t)
+	l.OOVGuesser = newTestGuesser()
+	sources := make(map[string]string)
+	for _, e := range l.Explain("בכיסא") {
+		sources[e.Source+":"+e.Segmentation()] = e.Morphemes[len(e.Morphemes)-1].Lemma
+	}
+	if lemma, exists := sources["prefix+oov:ב + ה + כיסא"]; !exists || lemma != "כיסא" {
+		t.Errorf("expected the guessed segmentation ב + ה + כיסא with the host lemma, got %v", sources)
+	}
+	if _, exists := sources["oov:בכיסא"]; !exists {
+		t.Errorf("expected the guessed whole token analysis, got %v", sources)
+	}
+}
@@ -0,0 +1,217 @@
+package ma
+
+import (
+	"yap/alg/graph"
+	"yap/nlp/format/lex"
+	. "yap/nlp/types"
+
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Recognizer analyzes tokens, and hosts following prefixes, that are
+// recognized by their form rather than found in the lexicon
+type Recognizer interface {
+	Name() string
+	Recognize(input, maType string) ([]BasicMorphemes, bool)
+}
+
+// PatternRecognizer recognizes inputs matching a regular expression; its
+// analyses are given as POS-features (as OOVMSRS), e.g. NNP-gen=M|num=S
+type PatternRecognizer struct {
+	Label    string
+	RE       *regexp.Regexp
+	Analyses []string
+	// the lemma of the analyses is the input, otherwise it is empty
+	Lemma bool
+}
+
+var _ Recognizer = &PatternRecognizer{}
+
+func (r *PatternRecognizer) Name() string {
+	return r.Label
+}
+
+func (r *PatternRecognizer) Recognize(input, maType string) ([]BasicMorphemes, bool) {
+	if !r.RE.MatchString(input) {
+		return nil, false
+	}
+	analyses := make([]BasicMorphemes, len(r.Analyses))
+	for i, msr := range r.Analyses {
+		morph := msrMorpheme(input, msr, maType)
+		if !r.Lemma {
+			morph.Lemma = ""
+		}
+		analyses[i] = BasicMorphemes{morph}
+	}
+	return analyses, true
+}
+
+// PunctRecognizer recognizes punctuation marks, mapped to their POS
+type PunctRecognizer map[string]string
+
+var _ Recognizer = PunctRecognizer{}
+
+func (r PunctRecognizer) Name() string {
+	return "punct"
+}
+
+func (r PunctRecognizer) Recognize(input, maType string) ([]BasicMorphemes, bool) {
+	POS, exists := r[input]
+	if !exists {
+		return nil, false
+	}
+	return []BasicMorphemes{{msrMorpheme(input, POS+"-", maType)}}, true
+}
+
+// DefaultRecognizers are the recognizers of an analyzer without configured
+// recognizers: numbers, and other tokens with digits
+var DefaultRecognizers = []Recognizer{
+	&PatternRecognizer{"number", regexp.MustCompile(`^\d+(\.\d+)?$|^\d{1,3}(,\d{3})*(\.\d+)?$`), []string{"CD-"}, false},
+	&PatternRecognizer{"digits", regexp.MustCompile(`\d`), []string{"NCD-"}, false},
+}
+
+// RecognizerConf is a configuration of recognizers (see conf/recognizers.yaml)
+type RecognizerConf struct {
+	// Punct are punctuation marks in addition to the ASCII PUNCT marks
+	Punct       map[string]string `yaml:"punct"`
+	Recognizers []struct {
+		Name     string   `yaml:"name"`
+		Pattern  string   `yaml:"pattern"`
+		Analyses []string `yaml:"analyses"`
+		Lemma    bool     `yaml:"lemma"`
+	} `yaml:"recognizers"`
+}
+
+// LoadRecognizers returns the recognizers of a YAML configuration, in
+// order, following a punct recognizer if punctuation marks are configured
+func LoadRecognizers(data []byte) ([]Recognizer, error) {
+	conf := &RecognizerConf{}
+	if err := yaml.UnmarshalStrict(data, conf); err != nil {
+		return nil, err
+	}
+	recognizers := make([]Recognizer, 0, len(conf.Recognizers)+1)
+	if len(conf.Punct) > 0 {
+		punct := make(PunctRecognizer, len(PUNCT)+len(conf.Punct))
+		for mark, POS := range PUNCT {
+			punct[mark] = POS
+		}
+		for mark, POS := range conf.Punct {
+			punct[mark] = POS
+		}
+		recognizers = append(recognizers, punct)
+	}
+	for i, recognizer := range conf.Recognizers {
+		if len(recognizer.Name) == 0 || len(recognizer.Analyses) == 0 {
+			return nil, fmt.Errorf("Recognizer %d: expected a name and analyses", i+1)
+		}
+		re, err := regexp.Compile(recognizer.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("Recognizer %s: %v", recognizer.Name, err)
+		}
+		recognizers = append(recognizers, &PatternRecognizer{recognizer.Name, re, recognizer.Analyses, recognizer.Lemma})
+	}
+	return recognizers, nil
+}
+
+func LoadRecognizersFile(filename string) ([]Recognizer, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return LoadRecognizers(data)
+}
+
+// SelectRecognizers returns the recognizers of comma separated names, in
+// their order; all recognizers if names is empty, none if it is "none"
+func SelectRecognizers(recognizers []Recognizer, names string) ([]Recognizer, error) {
+	if len(names) == 0 {
+		return recognizers, nil
+	}
+	selected := make(map[string]bool)
+	if names != "none" {
+		for _, name := range strings.Split(names, ",") {
+			selected[strings.TrimSpace(name)] = true
+		}
+	}
+	result := make([]Recognizer, 0, len(selected))
+	for _, recognizer := range recognizers {
+		if selected[recognizer.Name()] {
+			result = append(result, recognizer)
+			delete(selected, recognizer.Name())
+		}
+	}
+	for name := range selected {
+		return nil, fmt.Errorf("Unknown recognizer %v, expected one of %v", name, RecognizerNames(recognizers))
+	}
+	return result, nil
+}
+
+// RecognizerNames returns the names of recognizers
+func RecognizerNames(recognizers []Recognizer) string {
+	names := make([]string, len(recognizers))
+	for i, recognizer := range recognizers {
+		names[i] = recognizer.Name()
+	}
+	return strings.Join(names, ", ")
+}
+
+// msrMorpheme returns a morpheme of a form analyzed as POS-features (as
+// OOVMSRS), converted to UD for the ud MA type, with no XPOS as the UD
+// lexicon analyses
+func msrMorpheme(form, msr, maType string) *Morpheme {
+	msrsplit := strings.SplitN(msr, "-", 2)
+	morph := &Morpheme{
+		BasicDirectedEdge: graph.BasicDirectedEdge{0, 0, 1},
+		Form:              form,
+		Lemma:             form,
+		CPOS:              msrsplit[0],
+	}
+	if len(msrsplit) > 1 {
+		morph.FeatureStr = msrsplit[1]
+	}
+	morph.POS = morph.CPOS
+	if maType == "ud" {
+		lex.UDMorpheme(morph)
+		morph.POS = "_"
+	}
+	return morph
+}
+
+func (l *BGULex) recognizers() []Recognizer {
+	if l.Recognizers == nil {
+		return DefaultRecognizers
+	}
+	return l.Recognizers
+}
+
+// recognize returns the analyses of the first recognizer of an input, and
+// its name
+func (l *BGULex) recognize(input string) ([]BasicMorphemes, string, bool) {
+	for _, recognizer := range l.recognizers() {
+		if analyses, exists := recognizer.Recognize(input, l.MAType); exists {
+			return analyses, recognizer.Name(), true
+		}
+	}
+	return nil, "", false
+}
+
+// punct returns the POS of a punctuation mark of PUNCT, or of a punct
+// recognizer of the analyzer
+func (l *BGULex) punct(input string) (string, bool) {
+	if POS, exists := PUNCT[input]; exists {
+		return POS, true
+	}
+	for _, recognizer := range l.recognizers() {
+		if punct, ok := recognizer.(PunctRecognizer); ok {
+			if POS, exists := punct[input]; exists {
+				return POS, true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,205 @@
+package ma
+
+import (
+	"testing"
+
+	. "yap/nlp/types"
+)
+
+// testRecognizers returns the recognizers of the configuration shipped in conf
+func testRecognizers(t *testing.T) []Recognizer {
+	recognizers, err := LoadRecognizersFile("../../../conf/recognizers.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return recognizers
+}
+
+func TestRecognizers(t *testing.T) {
+	recognizers := testRecognizers(t)
+	tests := []struct {
+		input, name, POS, lemma string
+	}{
+		{"12/05/2020", "date", "NCD", "12/05/2020"},
+		{"2020-05-12", "date", "NCD", "2020-05-12"},
+		{"12:30", "time", "NCD", "12:30"},
+		{"50%", "percent", "NCD", "50%"},
+		{"%3.5", "percent", "NCD", "%3.5"},
+		{"₪100", "currency", "NCD", "₪100"},
+		{"1,000$", "currency", "NCD", "1,000$"},
+		{"21st", "ordinal", "JJ", "21st"},
+		{"12th", "ordinal", "JJ", "12th"},
+		{"2020", "number", "CD", ""},
+		{"1,000.5", "number", "CD", ""},
+		{"https://example.com/a?b=c", "url", "NNP", "https://example.com/a?b=c"},
+		{"www.example.co.il", "url", "NNP", "www.example.co.il"},
+		{"user@example.com", "email", "NNP", "user@example.com"},
+		{"#בחירות2020", "hashtag", "NNP", "#בחירות2020"},
+		{"@user_1", "mention", "NNP", "@user_1"},
+		{"A4", "digits", "NCD", ""},
+		{"Google", "latin", "NNP", "Google"},
+		{"Ben-Gurion", "latin", "NNP", "Ben-Gurion"},
+		{"😀", "emoji", "INTJ", "😀"},
+		{"־", "punct", "yyDASH", "־"},
+		{"״", "punct", "yyQUOT", "״"},
+		{"…", "punct", "yyELPS", "…"},
+		{"בית", "", "", ""},
+		{"25:70", "digits", "NCD", ""},
+	}
+	for _, test := range tests {
+		var (
+			name     string
+			analyses []BasicMorphemes
+		)
+		for _, recognizer := range recognizers {
+			if recognized, exists := recognizer.Recognize(test.input, "spmrl"); exists {
+				name, analyses = recognizer.Name(), recognized
+				break
+			}
+		}
+		if name != test.name {
+			t.Errorf("%s: recognized by %q, expected %q", test.input, name, test.name)
+			continue
+		}
+		if len(test.name) > 0 && (analyses[0][0].POS != test.POS || analyses[0][0].Form != test.input || analyses[0][0].Lemma != test.lemma) {
+			t.Errorf("%s: got analysis %v, expected POS %s lemma %q", test.input, analyses[0][0], test.POS, test.lemma)
+		}
+	}
+}
+
+func TestRecognizeUD(t *testing.T) {
+	tests := []struct {
+		input string
+		CPOS  []string
+	}{
+		{"Google", []string{"PROPN", "NOUN"}},
+		{"2020", []string{"NUM"}},
+		{"—", []string{"PUNCT"}},
+	}
+	l := &BGULex{MAType: "ud", Recognizers: testRecognizers(t)}
+	for _, test := range tests {
+		analyses, _, exists := l.recognize(test.input)
+		if !exists || len(analyses) != len(test.CPOS) {
+			t.Errorf("%s: got %v, expected %v", test.input, analyses, test.CPOS)
+			continue
+		}
+		// UD analyses have no XPOS, as the UD lexicon analyses
+		for i, CPOS := range test.CPOS {
+			if analyses[i][0].CPOS != CPOS || analyses[i][0].POS != "_" {
+				t.Errorf("%s: got CPOS %s POS %s, expected %s _", test.input, analyses[i][0].CPOS, analyses[i][0].POS, CPOS)
+			}
+		}
+	}
+}
+
+func TestRecognizedHosts(t *testing.T) {
+	l := newTestLex(t)
+	l.Recognizers = testRecognizers(t)
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"ב12:30", []string{"ב:PREPOSITION", "12:30:NCD"}},
+		{"ב50%", []string{"ב:PREPOSITION", "50%:NCD"}},
+		{"ב-₪100", []string{"ב:PREPOSITION", "₪100:NCD"}},
+		{"ל@user", []string{"ל:PREPOSITION", "@user:NNP"}},
+		{"“", []string{"“:yyQUOT"}},
+	}
+	for _, test := range tests {
+		lat, _ := l.AnalyzeToken(test.input, 0, 0)
+		morphs := make(map[string]bool)
+		for _, m := range lat.Morphemes {
+			morphs[m.Form+":"+m.CPOS] = true
+		}
+		for _, expected := range test.expected {
+			if !morphs[expected] {
+				t.Errorf("%s: expected %s in %v", test.input, expected, morphs)
+			}
+		}
+	}
+}
+
+func TestDefaultRecognizers(t *testing.T) {
+	l := newTestLex(t)
+	tests := []struct {
+		input    string
+		expected []string // form:POS:lemma of the morphemes of the lattice
+	}{
+		{"2020", []string{"2020:CD:"}},
+		{"A4", []string{"A4:NCD:"}},
+		{"ב-2020", []string{"ב:PREPOSITION:ב", "ה:DEF:ה", "2020:CD:"}},
+		{",", []string{",:yyCM:"}},
+	}
+	for _, test := range tests {
+		lat, _ := l.AnalyzeToken(test.input, 0, 0)
+		morphs := make(map[string]bool)
+		for _, m := range lat.Morphemes {
+			morphs[m.Form+":"+m.CPOS+":"+m.Lemma] = true
+		}
+		for _, expected := range test.expected {
+			if !morphs[expected] {
+				t.Errorf("%s: expected %s in %v", test.input, expected, morphs)
+			}
+		}
+	}
+	// only the ASCII punctuation marks and numbers are recognized by default
+	for _, input := range []string{"Google", "😀", "https://example.com", "—", "12:30x"} {
+		if analyses, name, exists := l.recognize(input); exists && name != "digits" {
+			t.Errorf("%s: recognized by %s as %v, expected no default recognizer", input, name, analyses)
+		}
+		if _, exists := l.punct(input); exists {
+			t.Errorf("%s: expected no default punctuation mark", input)
+		}
+	}
+}
+
+func TestNoRecognizers(t *testing.T) {
+	l := newTestLex(t)
+	l.Recognizers = []Recognizer{}
+	for _, input := range []string{"“", "2020"} {
+		if lat, oov := l.AnalyzeToken(input, 0, 0); !oov.(bool) {
+			t.Errorf("%s: got %v, expected an OOV token without recognizers", input, lat.Morphemes)
+		}
+	}
+	if POS, exists := l.punct("."); !exists || POS != "yyDOT" {
+		t.Errorf("Got %s %v for ., expected yyDOT", POS, exists)
+	}
+}
+
+func TestLoadRecognizers(t *testing.T) {
+	recognizers, err := LoadRecognizers([]byte("recognizers:\n  - name: code\n    pattern: '^[A-Z]{2}\\d+$'\n    analyses: [NNP-, NN-gen=M|num=S]\n    lemma: true\n"))
+	if err != nil || len(recognizers) != 1 {
+		t.Fatalf("Got %v %v, expected a single recognizer", recognizers, err)
+	}
+	analyses, exists := recognizers[0].Recognize("AB12", "spmrl")
+	if !exists || len(analyses) != 2 || analyses[1][0].CPOS != "NN" || analyses[1][0].FeatureStr != "gen=M|num=S" || analyses[1][0].Lemma != "AB12" {
+		t.Errorf("Got %v %v, expected NNP and NN analyses of AB12", analyses, exists)
+	}
+	for _, conf := range []string{
+		"recognizers:\n  - name: bad\n    pattern: '('\n    analyses: [NNP-]\n",
+		"recognizers:\n  - pattern: 'a'\n    analyses: [NNP-]\n",
+		"recognizers:\n  - name: nothing\n    pattern: 'a'\n",
+		"recognisers: []\n",
+	} {
+		if _, err := LoadRecognizers([]byte(conf)); err == nil {
+			t.Errorf("Expected an error loading %q", conf)
+		}
+	}
+}
+
+func TestSelectRecognizers(t *testing.T) {
+	all := testRecognizers(t)
+	recognizers, err := SelectRecognizers(all, "url, date")
+	if err != nil || len(recognizers) != 2 || recognizers[0].Name() != "date" || recognizers[1].Name() != "url" {
+		t.Errorf("Got %v %v, expected date and url", recognizers, err)
+	}
+	if recognizers, err = SelectRecognizers(all, "none"); err != nil || recognizers == nil || len(recognizers) != 0 {
+		t.Errorf("Got %v %v, expected no recognizers", recognizers, err)
+	}
+	if _, err = SelectRecognizers(all, "dates"); err == nil {
+		t.Error("Expected an unknown recognizer error")
+	}
+	if _, err = SelectRecognizers(DefaultRecognizers, "url"); err == nil {
+		t.Error("Expected an unknown recognizer error for a recognizer that is not configured")
+	}
+}
@@ -0,0 +1,54 @@
+package ma
+
+import (
+	. "yap/nlp/types"
+	"yap/util"
+
+	"strings"
+)
+
+// UD_DEFINITE_POS are the UD POS tags of hosts that are definite following
+// the definite article (e.g. ה+בית), as in the Hebrew UD treebank
+var UD_DEFINITE_POS = map[string]bool{
+	"NOUN": true,
+	"ADJ":  true,
+	"NUM":  true,
+}
+
+// isUDArticle returns whether a prefix ends with the definite article
+func isUDArticle(prefix BasicMorphemes) bool {
+	if len(prefix) == 0 {
+		return false
+	}
+	last := prefix[len(prefix)-1]
+	return last.CPOS == "DET" && last.Features["PronType"] == "Art"
+}
+
+// udDefinite returns the analyses of hosts following the definite article,
+// adding Definite=Def to the first morpheme of hosts that are definite (see
+// UD_DEFINITE_POS, and participles) and not already marked as definite or
+// construct
+func udDefinite(hosts []BasicMorphemes) []BasicMorphemes {
+	definite := make([]BasicMorphemes, len(hosts))
+	for i, host := range hosts {
+		definite[i] = host
+		if len(host) == 0 {
+			continue
+		}
+		first := host[0]
+		if _, exists := first.Features["Definite"]; exists {
+			continue
+		}
+		if !UD_DEFINITE_POS[first.CPOS] && first.Features["VerbForm"] != "Part" {
+			continue
+		}
+		morph := first.Copy()
+		var pairs []string
+		if len(morph.FeatureStr) > 0 {
+			pairs = strings.Split(morph.FeatureStr, "|")
+		}
+		morph.FeatureStr, morph.Features = util.MergeUDFeatureValues(append(pairs, "Definite=Def"))
+		definite[i] = append(BasicMorphemes{morph}, host[1:]...)
+	}
+	return definite
+}
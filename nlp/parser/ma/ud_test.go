@@ -0,0 +1,119 @@
+package ma
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"yap/nlp/format/lattice"
+	"yap/nlp/format/lex"
+	nlp "yap/nlp/types"
+	"yap/util"
+)
+
+func newTestUDLex(t *testing.T) *BGULex {
+	file, err := ioutil.TempFile("", "prefixes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString(testPrefixes)
+	file.Close()
+	l := &BGULex{MAType: "ud"}
+	l.LoadPrefixes(file.Name())
+	tokens, err := lex.ReadUser(strings.NewReader("בית\t_\tNN\tgen=M|num=S\n"), l.MAType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.AddUserLex(tokens, false)
+	return l
+}
+
+func TestAnalyzeUD(t *testing.T) {
+	l := newTestUDLex(t)
+	tests := []struct {
+		input           string
+		expected, other []string // form:POS:features of morphemes expected (not expected) in the lattice
+	}{
+		{"בית", []string{"בית:NOUN:Gender=Masc|Number=Sing"}, nil},
+		{"הבית", []string{"ה:DET:PronType=Art", "בית:NOUN:Definite=Def|Gender=Masc|Number=Sing"}, []string{"בית:NOUN:Gender=Masc|Number=Sing"}},
+		{"בבית", []string{"ב:ADP:", "ה:DET:PronType=Art", "בית:NOUN:Definite=Def|Gender=Masc|Number=Sing", "בית:NOUN:Gender=Masc|Number=Sing"}, nil},
+		{"גוגל", []string{"גוגל:NOUN:Gender=Masc|Number=Plur,Sing", "גוגל:PROPN:"}, []string{"גוגל:NN:gen=M|num=S"}},
+		{"2020", []string{"2020:NUM:"}, nil},
+	}
+	for _, test := range tests {
+		lat, _ := l.AnalyzeToken(test.input, 0, 0)
+		morphs := make(map[string]bool)
+		for _, m := range lat.Morphemes {
+			morphs[m.Form+":"+m.CPOS+":"+m.FeatureStr] = true
+		}
+		for _, expected := range test.expected {
+			if !morphs[expected] {
+				t.Errorf("%s: expected %s in %v", test.input, expected, morphs)
+			}
+		}
+		for _, other := range test.other {
+			if morphs[other] {
+				t.Errorf("%s: expected no %s in %v", test.input, other, morphs)
+			}
+		}
+	}
+}
+
+// udMorphemes returns the UD fields of the morphemes of each token, sorted
+func udMorphemes(sent nlp.LatticeSentence) [][]string {
+	morphemes := make([][]string, len(sent))
+	for i, lat := range sent {
+		for _, m := range lat.Morphemes {
+			morphemes[i] = append(morphemes[i], fmt.Sprintf("%d %d %s %s %s %s", m.From(), m.To(), m.Form, m.Lemma, m.CPOS, m.FeatureStr))
+		}
+		sort.Strings(morphemes[i])
+	}
+	return morphemes
+}
+
+// TestUDLatticeRoundTrip writes a UD lattice of the analyzer as hebma -format
+// ud, and reads it as joint -conllu
+func TestUDLatticeRoundTrip(t *testing.T) {
+	l := newTestUDLex(t)
+	l.Recognizers = testRecognizers(t)
+	sent, _ := l.Analyze([]string{"בבית", "הבית", "2020", "Google", "—"})
+	buf := new(bytes.Buffer)
+	if err := lattice.UDWrite(buf, lattice.Sentence2LatticeCorpus([]nlp.LatticeSentence{sent}, nil), nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	written := buf.String()
+	for _, line := range strings.Split(written, "\n") {
+		if fields := strings.Split(line, "\t"); len(fields) > 5 && fields[5] != "_" {
+			t.Errorf("Got XPOS %s in %s, expected _", fields[5], line)
+		}
+	}
+
+	lattices, err := lattice.ULRead(strings.NewReader(written), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lattices) != 1 {
+		t.Fatalf("Got %d lattices, expected 1", len(lattices))
+	}
+	corpus := lattice.Lattice2SentenceCorpus(lattices, util.NewEnumSet(10), util.NewEnumSet(10), util.NewEnumSet(10), util.NewEnumSet(10), util.NewEnumSet(10), util.NewEnumSet(10))
+	read := corpus[0].(nlp.LatticeSentence)
+	if len(read) != len(sent) {
+		t.Fatalf("Read %d tokens, expected %d", len(read), len(sent))
+	}
+	expected, got := udMorphemes(sent), udMorphemes(read)
+	for i := range sent {
+		if read[i].Token != sent[i].Token {
+			t.Errorf("Read token %s, expected %s", read[i].Token, sent[i].Token)
+		}
+		if !reflect.DeepEqual(got[i], expected[i]) {
+			t.Errorf("Read morphemes of %s\n%s\nexpected\n%s", sent[i].Token, strings.Join(got[i], "\n"), strings.Join(expected[i], "\n"))
+		}
+	}
+}
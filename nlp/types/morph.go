@@ -28,6 +28,8 @@ type EMorpheme struct {
 	EFCPOS, EPOS     int
 	EFeatures        int
 	EMHost, EMSuffix int
+	// characters of the original text the morpheme spans, if known
+	Range *TextRange
 }
 
 var _ DepNode = &Morpheme{}
@@ -383,6 +385,8 @@ type Lattice struct {
 	Spellouts       Spellouts
 	Next            map[int][]int
 	BottomId, TopId int
+	// characters of the original text the token spans, if known
+	Range *TextRange
 }
 
 func (l *Lattice) Signature() string {
@@ -822,6 +826,7 @@ func NewRootLattice() Lattice {
 		make(map[int][]int),
 		0,
 		0,
+		nil,
 	}
 	return *lat
 }
@@ -0,0 +1,168 @@
+package types
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// MWE_SEPARATOR joins the tokens of a multi-token expression in lexicons
+	// (given space separated in user lexicons); a control character, so that
+	// no lexicon or input token contains it
+	MWE_SEPARATOR = "\u001F"
+	// MWE_FEATURE marks the morpheme of the k-th of the n tokens of a
+	// multi-token expression, as mwe=k/n
+	MWE_FEATURE = "mwe"
+	// MWE_HEAD_FEATURE is the (1-based) head token of the analysis of a
+	// multi-token expression in a lexicon
+	MWE_HEAD_FEATURE = "mwehead"
+	// MWE_MISC_KEY is the MISC attribute of CoNLL-U (and lattice) rows of
+	// morphemes of multi-token expressions, holding the first and last token
+	// of the expression
+	MWE_MISC_KEY = "MWE"
+)
+
+// MWEPart returns the morpheme of the k-th (1-based) of the tokens of a
+// multi-token expression, given the expression's single morpheme analysis:
+// the form of the token, with the lemma and POS of the expression, and the
+// features of the expression if the token is its head, or the expression
+// has no head (see MWE_HEAD_FEATURE)
+func MWEPart(analysis *Morpheme, tokens []string, k int) *Morpheme {
+	head := analysis.Features[MWE_HEAD_FEATURE]
+	features := make(map[string]string, len(analysis.Features)+1)
+	if len(head) == 0 || head == fmt.Sprint(k) {
+		for name, value := range analysis.Features {
+			if name != MWE_HEAD_FEATURE {
+				features[name] = value
+			}
+		}
+	}
+	features[MWE_FEATURE] = fmt.Sprintf("%d/%d", k, len(tokens))
+	var featureStrs []string
+	for _, feature := range strings.Split(analysis.FeatureStr, "|") {
+		name := strings.Split(feature, "=")[0]
+		if _, exists := features[name]; exists && name != MWE_FEATURE {
+			featureStrs = append(featureStrs, feature)
+		}
+	}
+	featureStrs = append(featureStrs, MWE_FEATURE+"="+features[MWE_FEATURE])
+	sort.Strings(featureStrs)
+	part := &Morpheme{
+		Form:       tokens[k-1],
+		Lemma:      analysis.Lemma,
+		CPOS:       analysis.CPOS,
+		POS:        analysis.POS,
+		Features:   features,
+		FeatureStr: strings.Join(featureStrs, "|"),
+	}
+	part.BasicDirectedEdge[2] = 1
+	return part
+}
+
+// MWESpan returns the first and last token of the multi-token expression of
+// a morpheme of a token, given its features; ok is false if the morpheme is
+// not of a multi-token expression
+func MWESpan(features map[string]string, tokenID int) (first, last int, ok bool) {
+	value, exists := features[MWE_FEATURE]
+	if !exists {
+		return 0, 0, false
+	}
+	var k, n int
+	if _, err := fmt.Sscanf(value, "%d/%d", &k, &n); err != nil || k < 1 || k > n {
+		return 0, 0, false
+	}
+	first = tokenID - k + 1
+	return first, first + n - 1, true
+}
+
+// MWEMisc returns the MISC attribute of the span of the multi-token
+// expression of a morpheme of a token, MWE=first-last, or an empty string
+func MWEMisc(features map[string]string, tokenID int) string {
+	first, last, ok := MWESpan(features, tokenID)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%s=%d-%d", MWE_MISC_KEY, first, last)
+}
+
+// mweKey identifies a multi-token expression of a morpheme of a token, by
+// its first token, length and analysis
+type mweKey struct {
+	first, n    int
+	lemma, CPOS string
+}
+
+// PartialMWEs returns the morphemes of a disambiguated sentence marked as
+// part of a multi-token expression (see MWE_FEATURE) whose other tokens were
+// not disambiguated as part of the same expression
+func PartialMWEs(morphs []*EMorpheme) map[*EMorpheme]bool {
+	parts := make(map[mweKey]map[int]bool)
+	keys := make(map[*EMorpheme]mweKey)
+	for _, m := range morphs {
+		if m == nil {
+			continue
+		}
+		value, exists := m.Features[MWE_FEATURE]
+		if !exists {
+			continue
+		}
+		var k, n int
+		if _, err := fmt.Sscanf(value, "%d/%d", &k, &n); err != nil || k < 1 || k > n {
+			continue
+		}
+		key := mweKey{m.TokenID - k + 1, n, m.Lemma, m.CPOS}
+		if parts[key] == nil {
+			parts[key] = make(map[int]bool, n)
+		}
+		parts[key][k] = true
+		keys[m] = key
+	}
+	var partial map[*EMorpheme]bool
+	for m, key := range keys {
+		if len(parts[key]) < key.n {
+			if partial == nil {
+				partial = make(map[*EMorpheme]bool)
+			}
+			partial[m] = true
+		}
+	}
+	return partial
+}
+
+// GraphPartialMWEs returns the partial multi-token expression morphemes of a
+// disambiguated morphological dependency graph (see PartialMWEs)
+func GraphPartialMWEs(graph MorphDependencyGraph) map[*EMorpheme]bool {
+	var morphs []*EMorpheme
+	for _, nodeID := range graph.GetVertices() {
+		morphs = append(morphs, graph.GetMorpheme(nodeID))
+	}
+	return PartialMWEs(morphs)
+}
+
+// MappingsPartialMWEs returns the partial multi-token expression morphemes
+// of the mappings of the tokens of a disambiguated sentence (see PartialMWEs)
+func MappingsPartialMWEs(mappings Mappings) map[*EMorpheme]bool {
+	var morphs []*EMorpheme
+	for _, mapping := range mappings {
+		if mapping != nil {
+			morphs = append(morphs, mapping.Spellout...)
+		}
+	}
+	return PartialMWEs(morphs)
+}
+
+// WithoutMWE returns a copy of a morpheme without the mark of a multi-token
+// expression, for a partial expression (see PartialMWEs)
+func WithoutMWE(m *EMorpheme) *EMorpheme {
+	copied := m.Copy()
+	delete(copied.Features, MWE_FEATURE)
+	var featureStrs []string
+	for _, feature := range strings.Split(copied.FeatureStr, "|") {
+		if len(feature) > 0 && !strings.HasPrefix(feature, MWE_FEATURE+"=") {
+			featureStrs = append(featureStrs, feature)
+		}
+	}
+	copied.FeatureStr = strings.Join(featureStrs, "|")
+	return copied
+}
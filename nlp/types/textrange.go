@@ -0,0 +1,144 @@
+package types
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// TEXT_RANGE_KEY is the MISC attribute of CoNLL-U (and lattice) rows holding
+// the character range of a token or morpheme in the original text
+const TEXT_RANGE_KEY = "TokenRange"
+
+// TextRange is a range of characters (runes) of a text, End exclusive
+type TextRange struct {
+	Start, End int
+}
+
+func (r TextRange) String() string {
+	return fmt.Sprintf("%d:%d", r.Start, r.End)
+}
+
+// Misc returns the range as a MISC attribute, TokenRange=start:end
+func (r TextRange) Misc() string {
+	return TEXT_RANGE_KEY + "=" + r.String()
+}
+
+// ParseTextRange parses a start:end range
+func ParseTextRange(value string) (TextRange, error) {
+	var r TextRange
+	if _, err := fmt.Sscanf(value, "%d:%d", &r.Start, &r.End); err != nil {
+		return r, fmt.Errorf("Error parsing text range %q: %v", value, err)
+	}
+	if r.End < r.Start {
+		return r, fmt.Errorf("Error parsing text range %q: end before start", value)
+	}
+	return r, nil
+}
+
+// MiscTextRange returns the TokenRange attribute of | separated MISC
+// attributes, nil if there is none
+func MiscTextRange(misc string) (*TextRange, error) {
+	for _, attr := range strings.Split(misc, "|") {
+		if strings.HasPrefix(attr, TEXT_RANGE_KEY+"=") {
+			r, err := ParseTextRange(attr[len(TEXT_RANGE_KEY)+1:])
+			if err != nil {
+				return nil, err
+			}
+			return &r, nil
+		}
+	}
+	return nil, nil
+}
+
+// AddMisc adds an attribute to | separated MISC attributes, where _ is none
+func AddMisc(misc, attr string) string {
+	if len(misc) == 0 || misc == "_" {
+		return attr
+	}
+	return misc + "|" + attr
+}
+
+// alignForm returns the range of the characters of token taken by a morpheme
+// of form starting at character at: the form itself if the token has it
+// there, otherwise none (e.g. an implicit definite article); the last
+// morpheme of the token takes the rest of it (e.g. a pronominal suffix).
+// Underscores marking clitics in UD forms (e.g. _של_) are ignored.
+func alignForm(token []rune, at int, form string, last bool) TextRange {
+	if at > len(token) {
+		at = len(token)
+	}
+	if trimmed := strings.Trim(form, "_"); len(trimmed) > 0 {
+		form = trimmed
+	}
+	r := TextRange{at, at}
+	if formRunes := []rune(form); len(formRunes) <= len(token)-at && string(token[at:at+len(formRunes)]) == form {
+		r.End = at + len(formRunes)
+	}
+	if last {
+		r.End = len(token)
+	}
+	return r
+}
+
+// AlignForms returns the ranges of the characters of a token taken by the
+// morphemes of a segmentation of it, given their forms
+func AlignForms(token string, forms []string) []TextRange {
+	runes := []rune(token)
+	ranges := make([]TextRange, len(forms))
+	var at int
+	for i, form := range forms {
+		ranges[i] = alignForm(runes, at, form, i == len(forms)-1)
+		at = ranges[i].End
+	}
+	return ranges
+}
+
+// SetRanges sets the range of the token of the lattice and of each of its
+// morphemes in the original text, mapping a range of characters of the token
+// to the text; morphemes are aligned to the token as in AlignForms, along
+// the paths of the lattice
+func (l *Lattice) SetRanges(toText func(TextRange) TextRange) {
+	runes := []rune(string(l.Token))
+	tokenRange := toText(TextRange{0, len(runes)})
+	l.Range = &tokenRange
+	morphs := make(Morphemes, len(l.Morphemes))
+	copy(morphs, l.Morphemes)
+	sort.SliceStable(morphs, func(i, j int) bool { return morphs[i].From() < morphs[j].From() })
+	at := map[int]int{l.BottomId: 0}
+	for _, m := range morphs {
+		start, exists := at[m.From()]
+		if !exists {
+			continue
+		}
+		r := alignForm(runes, start, m.Form, m.To() == l.TopId)
+		if _, exists := at[m.To()]; !exists {
+			at[m.To()] = r.End
+		}
+		textRange := toText(r)
+		m.Range = &textRange
+	}
+}
+
+// Range returns the characters of the original text the morphemes of a
+// spellout span, nil if unknown
+func (s Spellout) Range() *TextRange {
+	var r *TextRange
+	for _, m := range s {
+		if m == nil || m.Range == nil {
+			continue
+		}
+		if r == nil {
+			copied := *m.Range
+			r = &copied
+			continue
+		}
+		if m.Range.Start < r.Start {
+			r.Start = m.Range.Start
+		}
+		if m.Range.End > r.End {
+			r.End = m.Range.End
+		}
+	}
+	return r
+}
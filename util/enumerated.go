@@ -27,6 +27,17 @@ func (e *EnumSet) RebuildIndex() {
 	}
 }
 
+// RebuildEnum rebuilds the value to index map from the index
+// (e.g. of an enum set serialized without it)
+func (e *EnumSet) RebuildEnum() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.Enum = make(map[interface{}]int, len(e.Index))
+	for i, v := range e.Index {
+		e.Enum[v] = i
+	}
+}
+
 func (e *EnumSet) Add(value interface{}) (int, bool) {
 	if e.Frozen {
 		panic("Cannot add value to frozen enum set")
@@ -176,6 +176,91 @@ func Heb2UDFeaturesString(features string) string {
 	return strings.Join(udPairs, "|")
 }
 
+// isHebFeature returns whether a feature is a feature of the Hebrew lexicon
+// Heb2UDFeature converts
+func isHebFeature(feature string) bool {
+	switch feature {
+	case "tense=BEINONI", "type=TOINFINITIVE", "tense=IMPERATIVE":
+		return true
+	}
+	pair := strings.Split(feature, "=")
+	if len(pair) != 2 {
+		return false
+	}
+	if pair[0] == "binyan" {
+		return true
+	}
+	if propMap, exists := HEB2UDFeatureNameLookup[pair[0]]; exists {
+		_, valExists := propMap.ValueMap[pair[1]]
+		return valExists
+	}
+	return false
+}
+
+// Heb2UDMSR converts a POS and | separated features of the Hebrew lexicon
+// (e.g. NNT and gen=M|num=P|num=S) to UD: the UPOS, and the features of the
+// UPOS (see HEB2UDPOS) with the converted features, where the values of a
+// feature given more than once are joined by commas (Number=Plur,Sing).
+// POS tags and features that are not of the Hebrew lexicon (e.g. already in
+// UD) are kept as is.
+func Heb2UDMSR(POS, features string) (string, string, map[string]string) {
+	var udPairs []string
+	if udMSR, exists := HEB2UDPOS[POS]; exists {
+		udSplit := strings.SplitN(udMSR, "-", 2)
+		POS = udSplit[0]
+		if len(udSplit) > 1 {
+			udPairs = append(udPairs, strings.Split(udSplit[1], "|")...)
+		}
+	} else if strings.HasPrefix(POS, "yy") {
+		POS = "PUNCT"
+	}
+	if len(features) > 0 && features != "_" {
+		for _, feature := range strings.Split(features, "|") {
+			if isHebFeature(feature) {
+				feature = Heb2UDFeature(feature)
+			}
+			if len(feature) > 0 {
+				udPairs = append(udPairs, feature)
+			}
+		}
+	}
+	featureStr, featureMap := MergeUDFeatureValues(udPairs)
+	return POS, featureStr, featureMap
+}
+
+// MergeUDFeatureValues returns the sorted | separated UD features of
+// name=value pairs, and their map, joining the values of a feature given
+// more than once by commas (e.g. Number=Plur,Sing, not Number=Plur|Number=Sing)
+func MergeUDFeatureValues(pairs []string) (string, map[string]string) {
+	values := make(map[string][]string, len(pairs))
+	for _, feature := range pairs {
+		pair := strings.SplitN(feature, "=", 2)
+		if len(pair) != 2 {
+			continue
+		}
+		values[pair[0]] = append(values[pair[0]], strings.Split(pair[1], ",")...)
+	}
+	names := make([]string, 0, len(values))
+	featureMap := make(map[string]string, len(values))
+	for name, nameValues := range values {
+		sort.Strings(nameValues)
+		unique := nameValues[:1]
+		for _, value := range nameValues[1:] {
+			if value != unique[len(unique)-1] {
+				unique = append(unique, value)
+			}
+		}
+		featureMap[name] = strings.Join(unique, ",")
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	featureStrs := make([]string, len(names))
+	for i, name := range names {
+		featureStrs[i] = name + "=" + featureMap[name]
+	}
+	return strings.Join(featureStrs, "|"), featureMap
+}
+
 func MergeFeatureStrs(feat1, feat2 string) (string, map[string]string) {
 	if len(feat2) > 0 && len(feat1) == 0 {
 		feat1, feat2 = feat2, feat1
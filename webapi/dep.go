@@ -92,6 +92,7 @@ func DepParserInitialize(cmd *commander.Command, args []string) {
 	app.DepEWPOS = serialization.EWPOS
 	app.DepEMHost = serialization.EMHost
 	app.DepEMSuffix = serialization.EMSuffix
+	depClusters := app.RestoreClusters(serialization.Clusters)
 	log.Println("Loaded model")
 
 	conf := &SimpleConfiguration{
@@ -102,6 +103,7 @@ func DepParserInitialize(cmd *commander.Command, args []string) {
 		EMSuffix:      app.DepEMSuffix,
 		ERel:          app.DepERel,
 		ETrans:        app.DepETrans,
+		Clusters:      depClusters,
 		TerminalStack: terminalStack,
 		TerminalQueue: 0,
 	}
@@ -134,6 +136,7 @@ func DepParseDisambiguatedLattice(input string) string {
 		sents[i] = instance.(nlp.LatticeSentence).TaggedSentence()
 	}
 	parsedGraphs := app.Parse(sents, depBeam)
+	parsedGraphs = app.ValidateGraphsWith(parsedGraphs, app.DepERel, depBeam)
 	graphAsConll := conll.Graph2ConllCorpus(parsedGraphs, app.DepEMHost, app.DepEMSuffix)
 	buf := new(bytes.Buffer)
 	conll.Write(buf, graphAsConll)
@@ -2,11 +2,11 @@ package webapi
 
 import (
 	"yap/nlp/format/lattice"
+	"yap/nlp/format/lex"
 	"yap/nlp/format/raw"
 	"log"
 	"fmt"
 	nlp "yap/nlp/types"
-	"yap/util"
 	"yap/nlp/parser/ma"
 	"yap/app"
 	"strings"
@@ -24,38 +24,39 @@ var (
 )
 
 func HebrewMorphAnalyazerInitialize(cmd *commander.Command, args []string) {
-	prefixLocation, found := util.LocateFile(app.HebMaPrefixFile, app.HEB_MA_DEFAULT_DATA_DIRS)
-	if !found {
-		panic(fmt.Sprintf("Lexicon prefix file not found: %v", app.HebMaPrefixFile))
-	}
-	lexiconLocation, found := util.LocateFile(app.HebMaLexiconFile, app.HEB_MA_DEFAULT_DATA_DIRS)
-	if !found {
+	for _, missing := range app.LocateHebMaLexicon() {
+		if missing == "prefix" {
+			panic(fmt.Sprintf("Lexicon prefix file not found: %v", app.HebMaPrefixFile))
+		}
 		panic(fmt.Sprintf("Lexicon file not found: %v", app.HebMaLexiconFile))
 	}
-	app.HebMaPrefixFile = prefixLocation
-	app.HebMaLexiconFile = lexiconLocation
 	app.HebMAConfigOut()
+	app.SetupRawNormalizer()
 	maData = new(ma.BGULex)
 	maData.MAType = "spmrl"
-	log.Println("Reading Morphological Analyzer BGU Prefixes")
-	maData.LoadPrefixes(app.HebMaPrefixFile)
-	log.Println("Reading Morphological Analyzer BGU Lexicon")
-	maData.LoadLex(app.HebMaLexiconFile, app.HebMaNnpnofeats)
+	app.LoadHebMaLexicon(maData)
+	app.LoadHebMaUserLex(maData)
+	app.SetupHebMaRecognizers(maData)
+	app.LoadHebMaOOVGuesser(maData)
 	log.Println()
 	maData.AlwaysNNP = app.HebMaAlwaysnnp
 	maData.LogOOV = app.HebMaShowoov
 
 }
 
-func HebrewMorphAnalyzeRawSentences(input string) string {
+// HebrewMorphAnalyzeRawSentences returns the lattices of raw input, and the
+// characters of the input each token and each morpheme of its lattice spans
+func HebrewMorphAnalyzeRawSentences(input string) (string, []TokenRange) {
 	maLock.Lock()
 	var (
-		reader io.Reader
-		sents []nlp.BasicSentence
-		err error
+		reader  io.Reader
+		sents   []nlp.BasicSentence
+		offsets [][]*raw.TokenOffsets
+		tokens  []TokenRange
+		err     error
 	)
 	reader = strings.NewReader(input)
-	sents, err = raw.Read(reader, 0)
+	sents, offsets, err = raw.ReadWithOffsets(reader, 0, app.RawNormalizer)
 	if err != nil {
 		panic(fmt.Sprintf("Failed reading raw input - %v", err))
 	}
@@ -70,11 +71,93 @@ func HebrewMorphAnalyzeRawSentences(input string) string {
 	for i, sent := range sents {
 		//log.SetPrefix(fmt.Sprintf("%v graph# %v ", prefix, i))
 		lattices[i], oovInd[i] = maData.Analyze(sent.Tokens())
+		app.SetRawRanges(lattices[i], offsets[i])
+		tokens = append(tokens, latticeRanges(i+1, lattices[i], offsets[i])...)
 	}
 	log.Println()
 	output := lattice.Sentence2LatticeCorpus(lattices, maHebrew)
 	buf := new(bytes.Buffer)
 	err = lattice.Write(buf, output)
 	maLock.Unlock()
-	return buf.String()
+	return buf.String(), tokens
+}
+
+// latticeRanges returns the ranges of the tokens of a sentence and of the
+// morphemes of their lattices
+func latticeRanges(sentence int, lattices nlp.LatticeSentence, offsets []*raw.TokenOffsets) []TokenRange {
+	tokens := make([]TokenRange, 0, len(lattices))
+	for i, lat := range lattices {
+		if lat.Range == nil {
+			continue
+		}
+		token := TokenRange{
+			Sentence: sentence,
+			Token:    i + 1,
+			Form:     string(lat.Token),
+			Start:    lat.Range.Start,
+			End:      lat.Range.End,
+			offsets:  offsets[i],
+		}
+		for _, m := range lat.Morphemes {
+			if m.Range == nil {
+				continue
+			}
+			token.MA = append(token.MA, MorphemeRange{m.From(), m.To(), m.Form, m.Range.Start, m.Range.End})
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+// addMDRanges sets the ranges of the morphemes of the disambiguated lattices
+// of the tokens, aligning the morphemes of each token to its form
+func addMDRanges(tokens []TokenRange, mdLattice string) {
+	lattices, err := lattice.Read(strings.NewReader(mdLattice), 0)
+	if err != nil {
+		log.Println("Failed reading disambiguated lattices -", err)
+		return
+	}
+	byToken := make(map[[2]int]*TokenRange, len(tokens))
+	for i, token := range tokens {
+		byToken[[2]int{token.Sentence, token.Token}] = &tokens[i]
+	}
+	for i, lat := range lattices {
+		var edges []lattice.Edge
+		for node := 0; node <= lat.MaxKey(); node++ {
+			edges = append(edges, lat[node]...)
+		}
+		for start := 0; start < len(edges); {
+			end := start
+			for end < len(edges) && edges[end].Token == edges[start].Token {
+				end++
+			}
+			token, exists := byToken[[2]int{i + 1, edges[start].Token}]
+			if exists {
+				forms := make([]string, end-start)
+				for j, edge := range edges[start:end] {
+					forms[j] = edge.Word
+				}
+				for j, r := range nlp.AlignForms(token.Form, forms) {
+					edge := edges[start+j]
+					r = token.offsets.Range(r)
+					token.MD = append(token.MD, MorphemeRange{edge.Start, edge.End, edge.Word, r.Start, r.End})
+				}
+			}
+			start = end
+		}
+	}
+}
+
+// HebrewMorphAnalyzerAddLex adds user lexicon entries (see lex.ReadUser) to
+// the lexicon of the analyzer, returning the number of analyses added
+func HebrewMorphAnalyzerAddLex(input string, override bool) (int, error) {
+	tokens, err := lex.ReadUser(strings.NewReader(input), maData.MAType)
+	if err != nil {
+		return 0, err
+	}
+	maLock.Lock()
+	defer maLock.Unlock()
+	added := maData.AddUserLex(tokens, override)
+	log.Println("Added", added, "analyses of", len(tokens), "user lexicon entries")
+	return added, nil
 }
@@ -2,6 +2,7 @@ package webapi
 
 import (
 	"log"
+	"yap/nlp/format/clusters"
 	"yap/nlp/format/conll"
 	"yap/nlp/parser/disambig"
 	"yap/nlp/parser/joint"
@@ -29,6 +30,7 @@ var (
 	model *transitionmodel.AvgMatrixSparse
 	terminalStack int
 	paramFunc nlp.MDParam
+	jointClusters *clusters.Set
 	jointLock sync.Mutex
 )
 
@@ -132,6 +134,7 @@ func JointParserInitialize() {
 	app.EMorphProp = serialization.EMorphProp
 	app.ETrans = serialization.ETrans
 	app.ETokens = serialization.ETokens
+	jointClusters = app.RestoreClusters(serialization.Clusters)
 	log.Println("Loaded model")
 	arcSystem = &ArcEager{
 		ArcStandard: ArcStandard{
@@ -178,11 +181,13 @@ func JointParseAmbiguousLattices(input string) (string, string, string) {
 			EMSuffix: app.EMSuffix,
 			ERel: app.ERel,
 			ETrans: app.ETrans,
+			Clusters: jointClusters,
 			TerminalStack: terminalStack,
 			TerminalQueue: 0,
 		},
 		MDConfig: disambig.MDConfig{
 			ETokens: app.ETokens,
+			Clusters: jointClusters,
 			POP: app.POP,
 			Transitions: app.ETrans,
 			ParamFunc: paramFunc,
@@ -201,7 +206,8 @@ func JointParseAmbiguousLattices(input string) (string, string, string) {
 	beam.Model = model
 	beam.ShortTempAgenda = true
 	parsedGraphs := app.Parse(predAmbLat, beam)
-	graphAsConll := conll.MorphGraph2ConllCorpus(parsedGraphs)
+	depGraphs := app.ValidateGraphs(parsedGraphs, beam)
+	graphAsConll := conll.MorphGraph2ConllCorpus(depGraphs)
 	buf1 := new(bytes.Buffer)
 	conll.Write(buf1, graphAsConll)
 	conllDepOut := buf1.String()
@@ -75,6 +75,7 @@ func MorphDisambiguatorInitialize(cmd *commander.Command, args []string) {
 	app.MdEMorphProp = serialization.EMorphProp
 	app.MdETrans = serialization.ETrans
 	app.MdETokens = serialization.ETokens
+	mdClusters := app.RestoreClusters(serialization.Clusters)
 
 	mdTrans = &disambig.MDTrans{
 		ParamFunc:   paramFunc,
@@ -88,6 +89,7 @@ func MorphDisambiguatorInitialize(cmd *commander.Command, args []string) {
 
 	conf := &disambig.MDConfig{
 		ETokens:     app.MdETokens,
+		Clusters:    mdClusters,
 		POP:         app.POP,
 		Transitions: app.MdETrans,
 		ParamFunc:   paramFunc,
@@ -12,7 +12,9 @@ import (
 	"yap/nlp/format/conll"
 	"yap/nlp/format/lattice"
 	"yap/nlp/format/lex"
+	"yap/nlp/format/raw"
 	"yap/nlp/parser/joint"
+	"yap/nlp/parser/ma"
 	"yap/nlp/types"
 )
 
@@ -24,13 +26,41 @@ type Request struct {
 	Text          string `json:text`
 	AmbLattice    string `json:amb_lattice`
 	DisambLattice string `json:disamb_lattice`
+	Lexicon       string `json:"lexicon"`
+	Override      bool   `json:"override"`
 }
 
 type Data struct {
-	MALattice string `json:"ma_lattice,omitempty"`
-	MDLattice string `json:"md_lattice,omitempty"`
-	DepTree   string `json:"dep_tree,omitempty"`
-	Error     error  `json:"error,omitempty"`
+	MALattice string       `json:"ma_lattice,omitempty"`
+	MDLattice string       `json:"md_lattice,omitempty"`
+	DepTree   string       `json:"dep_tree,omitempty"`
+	Tokens    []TokenRange `json:"tokens,omitempty"`
+	LexAdded  int          `json:"lex_added,omitempty"`
+	Error     error        `json:"error,omitempty"`
+}
+
+// TokenRange is a token of the request text, by sentence and token number
+// as in the lattices, with the characters of the text it spans (end
+// exclusive); MA and MD are the morphemes of its ambiguous and disambiguated
+// lattices, by their nodes, with the characters they span
+type TokenRange struct {
+	Sentence int             `json:"sentence"`
+	Token    int             `json:"token"`
+	Form     string          `json:"form"`
+	Start    int             `json:"start"`
+	End      int             `json:"end"`
+	MA       []MorphemeRange `json:"ma_morphemes,omitempty"`
+	MD       []MorphemeRange `json:"md_morphemes,omitempty"`
+
+	offsets *raw.TokenOffsets
+}
+
+type MorphemeRange struct {
+	From  int    `json:"from"`
+	To    int    `json:"to"`
+	Form  string `json:"form"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
 }
 
 func HebrewMorphAnalyzerHandler(resp http.ResponseWriter, req *http.Request) {
@@ -42,8 +72,8 @@ func HebrewMorphAnalyzerHandler(resp http.ResponseWriter, req *http.Request) {
 		return
 	}
 	rawText := strings.Replace(request.Text, " ", "\n", -1)
-	maLattice := HebrewMorphAnalyzeRawSentences(rawText)
-	data := Data{MALattice: maLattice}
+	maLattice, tokens := HebrewMorphAnalyzeRawSentences(rawText)
+	data := Data{MALattice: maLattice, Tokens: tokens}
 	respondWithJSON(resp, http.StatusOK, data)
 }
 
@@ -86,10 +116,11 @@ func HebrewPipelineHandler(resp http.ResponseWriter, req *http.Request) {
 		return
 	}
 	rawText := strings.Replace(request.Text, " ", "\n", -1)
-	maLattice := HebrewMorphAnalyzeRawSentences(rawText)
+	maLattice, tokens := HebrewMorphAnalyzeRawSentences(rawText)
 	mdLattice := MorphDisambiguateLattices(maLattice)
 	depTree := DepParseDisambiguatedLattice(mdLattice)
-	data := Data{MALattice: maLattice, MDLattice: mdLattice, DepTree: depTree}
+	addMDRanges(tokens, mdLattice)
+	data := Data{MALattice: maLattice, MDLattice: mdLattice, DepTree: depTree, Tokens: tokens}
 	respondWithJSON(resp, http.StatusOK, data)
 }
 
@@ -102,9 +133,30 @@ func HebrewJointHandler(resp http.ResponseWriter, req *http.Request) {
 		return
 	}
 	rawText := strings.Replace(request.Text, " ", "\n", -1)
-	maLattice := HebrewMorphAnalyzeRawSentences(rawText)
+	maLattice, tokens := HebrewMorphAnalyzeRawSentences(rawText)
 	depTree, mdLattice, _ := JointParseAmbiguousLattices(maLattice)
-	data := Data{MALattice: maLattice, MDLattice: mdLattice, DepTree: depTree}
+	addMDRanges(tokens, mdLattice)
+	data := Data{MALattice: maLattice, MDLattice: mdLattice, DepTree: depTree, Tokens: tokens}
+	respondWithJSON(resp, http.StatusOK, data)
+}
+
+func HebrewLexiconHandler(resp http.ResponseWriter, req *http.Request) {
+	request := Request{}
+	err := json.NewDecoder(req.Body).Decode(&request)
+	if err != nil {
+		data := Data{Error: err}
+		respondWithJSON(resp, http.StatusBadRequest, data)
+		return
+	}
+	lexicon := strings.Replace(request.Lexicon, "\\t", "\t", -1)
+	lexicon = strings.Replace(lexicon, "\\n", "\n", -1)
+	added, err := HebrewMorphAnalyzerAddLex(lexicon, request.Override)
+	if err != nil {
+		data := Data{Error: err}
+		respondWithJSON(resp, http.StatusBadRequest, data)
+		return
+	}
+	data := Data{LexAdded: added}
 	respondWithJSON(resp, http.StatusOK, data)
 }
 
@@ -133,11 +185,18 @@ listen to api requests
 `,
 		Flag: *flag.NewFlagSet("api", flag.ExitOnError),
 	}
-	cmd.Flag.StringVar(&app.HebMaPrefixFile, "ma_prefix", "bgupreflex_withdef.utf8.hr", "Prefix file for morphological analyzer")
-	cmd.Flag.StringVar(&app.HebMaLexiconFile, "ma_lexicon", "bgulex.utf8.hr", "Lexicon file for morphological analyzer")
+	cmd.Flag.StringVar(&app.HebMaPrefixFile, "ma_prefix", "bgupreflex_withdef.utf8.hr", "Prefix file for morphological analyzer (not used with a compiled lexicon)")
+	cmd.Flag.StringVar(&app.HebMaLexiconFile, "ma_lexicon", "bgulex.utf8.hr", "Lexicon file for morphological analyzer, or compiled lexicon (see lex compile)")
 	cmd.Flag.BoolVar(&app.HebMaAlwaysnnp, "ma_always_nnp", false, "Always add NNP to tokens and prefixed subtokens")
 	cmd.Flag.BoolVar(&app.HebMaNnpnofeats, "ma_add_nnp_no_feats", false, "Add NNP in lex but without features")
 	cmd.Flag.BoolVar(&app.HebMaShowoov, "ma_show_oov", false, "Output OOV tokens")
+	cmd.Flag.StringVar(&app.HebMaUserLexFiles, "ma_user_lex", "", "Comma separated user lexicon files for morphological analyzer")
+	cmd.Flag.StringVar(&app.HebMaRecognizers, "ma_recognizers", "", "Comma separated pattern recognizers for morphological analyzer, or none (default: all)")
+	cmd.Flag.StringVar(&app.HebMaRecognizersConf, "ma_recognizers_conf", "", "Pattern recognizers configuration file for morphological analyzer, e.g. recognizers.yaml (default: recognizers ["+ma.RecognizerNames(ma.DefaultRecognizers)+"])")
+	cmd.Flag.BoolVar(&app.HebMaUserLexOverride, "ma_user_lex_override", false, "User lexicon analyses replace the lexicon analyses of their tokens")
+	cmd.Flag.StringVar(&app.RawNormalization, "normalize", raw.DEFAULT_NORMALIZATION, "Comma separated normalization steps of input text, all or none ["+strings.Join(raw.NORMALIZATION_STEPS, ", ")+"]")
+	cmd.Flag.StringVar(&app.RawNormVariantsFile, "norm_variants", "", "Spelling variants file (tab separated variant and standard form) of the variants normalization step")
+	cmd.Flag.StringVar(&app.HebMaOOVGuesserFile, "ma_oov_guesser", "", "Dictionary learned by malearn -oovguesser, whose OOV guesser analyzes OOV tokens")
 	cmd.Flag.BoolVar(&lex.LOG_FAILURES, "ma_show_lex_error", false, "Log errors encountered when loading the lexicon")
 	cmd.Flag.IntVar(&app.BeamSize, "beam", 64, "Beam size")
 	cmd.Flag.BoolVar(&app.UsePOP, "use_end_token", true, "Use end token (pop)")
@@ -153,10 +212,12 @@ listen to api requests
 	cmd.Flag.StringVar(&app.JointModelFile, "joint_model_name", "joint_arc_zeager_model_temp_i33.b64", "Joint model file")
 	cmd.Flag.StringVar(&app.JointStrategy, "joint_strategy", "ArcGreedy", "Joint Strategy: ["+joint.JointStrategies+"]")
 	cmd.Flag.StringVar(&app.OracleStrategy, "joint_oracle_strategy", "ArcGreedy", "Oracle Strategy: ["+joint.OracleStrategies+"]")
+	cmd.Flag.StringVar(&app.RepairStr, "repair", "none", "Repair parsed graphs that are not well formed trees [none, root, score (keep the highest scored root)]")
 	return cmd
 }
 
 func StartAPIServer(cmd *commander.Command, args []string) error {
+	app.RepairStrategy()
 	HebrewMorphAnalyazerInitialize(cmd, args)
 	MorphDisambiguatorInitialize(cmd, args)
 	DepParserInitialize(cmd, args)
@@ -167,6 +228,7 @@ func StartAPIServer(cmd *commander.Command, args []string) error {
 	router.HandleFunc("/yap/heb/dep", DepParserHandler)
 	router.HandleFunc("/yap/heb/pipeline", HebrewPipelineHandler)
 	router.HandleFunc("/yap/heb/joint", HebrewJointHandler)
+	router.HandleFunc("/yap/heb/lex", HebrewLexiconHandler).Methods("POST")
 	log.Fatal(http.ListenAndServe(":8000", router))
 	return nil
 }